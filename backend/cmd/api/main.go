@@ -2,15 +2,28 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/Sa-Te/IAV/backend/internal/geoip"
 	"github.com/Sa-Te/IAV/backend/internal/server"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 func runMigrations(db *pgxpool.Pool) {
+	_, err := db.Exec(context.Background(), `CREATE TABLE IF NOT EXISTS schema_migrations (
+		name TEXT PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`)
+	if err != nil {
+		log.Fatalf("Failed to create schema_migrations table: %v", err)
+	}
 
 	applyMigration(db, "migrations/001_create_users_table.sql", "users")
 	applyMigration(db, "migrations/002_create_media_items_table.sql", "media_items")
@@ -32,23 +45,74 @@ func runMigrations(db *pgxpool.Pool) {
 	applyMigration(db, "migrations/018_create_devices_settings_tables.sql", "devices_settings")
 	applyMigration(db, "migrations/019_create_misc_tables.sql", "misc")
 	applyMigration(db, "migrations/020_dedup_messages_and_activity.sql", "dedup")
+	applyMigration(db, "migrations/021_create_two_factor_tables.sql", "two_factor")
+	applyMigration(db, "migrations/022_create_api_tokens_table.sql", "api_tokens")
+	applyMigration(db, "migrations/023_create_ig_accounts.sql", "ig_accounts")
+	applyMigration(db, "migrations/024_create_share_links.sql", "share_links")
+	applyMigration(db, "migrations/025_add_user_roles_and_imports.sql", "roles_imports")
+	applyMigration(db, "migrations/026_add_search_vectors.sql", "search_vectors")
+	applyMigration(db, "migrations/027_create_connection_snapshots.sql", "connection_snapshots")
+	applyMigration(db, "migrations/028_create_ip_locations.sql", "ip_locations")
+	applyMigration(db, "migrations/029_add_two_factor_attempts.sql", "two_factor_attempts")
 }
 
 func applyMigration(db *pgxpool.Pool, filepath string, tableName string) {
+	// Each migration runs once; schema_migrations remembers which ones already went in.
+	var applied bool
+	err := db.QueryRow(context.Background(),
+		`SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE name = $1)`, filepath).Scan(&applied)
+	if err != nil {
+		log.Fatalf("Failed to check %s migration status: %v", tableName, err)
+	}
+	if applied {
+		return
+	}
+
 	migrationSQL, err := os.ReadFile(filepath)
 	if err != nil {
 		log.Fatalf(`Failed to read %s migrations file: %v`, tableName, err)
 	}
 
 	_, err = db.Exec(context.Background(), string(migrationSQL))
-	if err != nil {
+	if err != nil && !alreadyApplied(err) {
 		fmt.Printf("could not apply %s migration: %v\n", tableName, err)
+		return
+	}
+	if err != nil {
+		fmt.Printf("%s migration was already applied before tracking: %v\n", tableName, err)
 	} else {
 		fmt.Printf("%s table migration successful\n", tableName)
 	}
+
+	_, err = db.Exec(context.Background(), `INSERT INTO schema_migrations (name) VALUES ($1) ON CONFLICT DO NOTHING`, filepath)
+	if err != nil {
+		fmt.Printf("could not record %s migration: %v\n", tableName, err)
+	}
+}
+
+// alreadyApplied reports whether a migration failed only because its objects exist.
+// Databases created before schema_migrations existed re-ran every file on each boot,
+// so the first tracked run sees these errors for the older, non-idempotent files.
+func alreadyApplied(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	switch pgErr.Code {
+	case "42P07", // duplicate_table (also raised for duplicate constraint/index names)
+		"42701", // duplicate_column
+		"42710": // duplicate_object
+		return true
+	}
+	return false
 }
 
 func main() {
+	// Archive timestamps come from time.Unix, which is in the local zone, and pgx writes
+	// their wall clock into the TIMESTAMP columns as is. The store reads those columns
+	// as UTC, so the process runs in UTC whatever TZ the host or container has.
+	time.Local = time.UTC
+
 	connStr := os.Getenv("DATABASE_URL")
 	if connStr == "" {
 		// We can set a fallback for local running without Docker, but the env var is primary.
@@ -69,5 +133,30 @@ func main() {
 
 	// Pass the entire pool to the server
 	apiServer := server.NewAPIServer(db)
+	if mb := os.Getenv("RESPONSE_CACHE_MB"); mb != "" {
+		size, err := strconv.Atoi(mb)
+		if err != nil || size < 0 {
+			log.Fatalf("RESPONSE_CACHE_MB must be a non-negative number of megabytes, got %q", mb)
+		}
+		if size > 0 {
+			apiServer.EnableResponseCache(int64(size) << 20)
+			log.Printf("In-process response cache enabled (%d MB)", size)
+		}
+	}
+	if paths := os.Getenv("GEOIP_DB_PATH"); paths != "" {
+		geo, err := geoip.Open(strings.Split(paths, ",")...)
+		if err != nil {
+			log.Fatalf("GEOIP_DB_PATH: %v", err)
+		}
+		defer geo.Close()
+		apiServer.EnableGeoIP(geo)
+		log.Printf("Offline IP geolocation enabled (%s)", paths)
+	}
+	if proxies := os.Getenv("TRUSTED_PROXIES"); proxies != "" {
+		if err := apiServer.TrustProxies(strings.Split(proxies, ",")); err != nil {
+			log.Fatalf("TRUSTED_PROXIES: %v", err)
+		}
+		log.Printf("Trusting X-Forwarded-For from %s", proxies)
+	}
 	apiServer.Run()
 }
@@ -0,0 +1,95 @@
+// Package geoip places IP addresses with MaxMind-format (mmdb) databases read from local
+// files, such as GeoLite2-City and GeoLite2-ASN. Lookups never touch the network, so
+// they work in air-gapped installs.
+package geoip
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/Sa-Te/IAV/backend/internal/models"
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// DB is a set of databases. A nil *DB is valid and knows no address.
+type DB struct {
+	readers []*maxminddb.Reader
+}
+
+// record is the part of the City, Country and ASN layouts a lookup reads. Fields a
+// database doesn't have stay zero.
+type record struct {
+	Country struct {
+		ISOCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"country"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	Location struct {
+		Latitude  *float64 `maxminddb:"latitude"`
+		Longitude *float64 `maxminddb:"longitude"`
+	} `maxminddb:"location"`
+	ASN   uint   `maxminddb:"autonomous_system_number"`
+	ASOrg string `maxminddb:"autonomous_system_organization"`
+}
+
+// Open loads the database files at paths. Each can be a city, country or ASN database;
+// a lookup merges what they know.
+func Open(paths ...string) (*DB, error) {
+	if len(paths) == 0 {
+		return nil, errors.New("geoip: no database files given")
+	}
+	db := &DB{}
+	for _, path := range paths {
+		r, err := maxminddb.Open(path)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("geoip: open %s: %w", path, err)
+		}
+		db.readers = append(db.readers, r)
+	}
+	return db, nil
+}
+
+func (db *DB) Close() error {
+	if db == nil {
+		return nil
+	}
+	var errs []error
+	for _, r := range db.readers {
+		errs = append(errs, r.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Lookup places ip. ok is false when ip doesn't parse or no database knows it; a
+// database that can't read the address, such as an IPv4-only one given IPv6, is skipped.
+func (db *DB) Lookup(ip string) (loc models.IPLocation, ok bool) {
+	addr := net.ParseIP(ip)
+	if db == nil || addr == nil {
+		return loc, false
+	}
+	loc.IPAddress = ip
+	for _, r := range db.readers {
+		var rec record
+		if _, found, err := r.LookupNetwork(addr, &rec); err != nil || !found {
+			continue
+		}
+		ok = true
+		if loc.CountryCode == "" {
+			loc.CountryCode, loc.Country = rec.Country.ISOCode, rec.Country.Names["en"]
+		}
+		if loc.City == "" {
+			loc.City = rec.City.Names["en"]
+		}
+		if loc.Latitude == nil && loc.Longitude == nil {
+			loc.Latitude, loc.Longitude = rec.Location.Latitude, rec.Location.Longitude
+		}
+		if loc.ASN == 0 {
+			loc.ASN, loc.ASOrg = int(rec.ASN), rec.ASOrg
+		}
+	}
+	return loc, ok
+}
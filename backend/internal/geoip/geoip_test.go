@@ -0,0 +1,147 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// The tests build their databases by hand rather than shipping MaxMind's files: an
+// IPv4 search tree that leads one /24 network to one record.
+
+type mmdbMap map[string]any
+
+// encode writes v in the MaxMind DB data format: strings, float64 doubles, uint32s,
+// uint16s, maps and string slices of up to 284 bytes or entries are all these tests need.
+func encode(buf *bytes.Buffer, v any) {
+	// sizes from 29 to 284 take one extra byte, after the extended type if there is one
+	control := func(typ, size int) {
+		small := min(size, 29)
+		if typ <= 7 {
+			buf.WriteByte(byte(typ<<5 | small))
+		} else {
+			buf.WriteByte(byte(small))
+			buf.WriteByte(byte(typ - 7))
+		}
+		if size >= 29 {
+			buf.WriteByte(byte(size - 29))
+		}
+	}
+	switch v := v.(type) {
+	case string:
+		control(2, len(v))
+		buf.WriteString(v)
+	case float64:
+		control(3, 8)
+		binary.Write(buf, binary.BigEndian, math.Float64bits(v))
+	case uint16:
+		control(5, 2)
+		binary.Write(buf, binary.BigEndian, v)
+	case uint32:
+		control(6, 4)
+		binary.Write(buf, binary.BigEndian, v)
+	case []string:
+		control(11, len(v))
+		for _, s := range v {
+			encode(buf, s)
+		}
+	case mmdbMap:
+		control(7, len(v))
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			encode(buf, k)
+			encode(buf, v[k])
+		}
+	default:
+		panic("unsupported value")
+	}
+}
+
+// writeDB writes a database that maps network, a /24, to data.
+func writeDB(t *testing.T, network string, data mmdbMap) string {
+	_, ipnet, err := net.ParseCIDR(network)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ip := ipnet.IP.To4()
+	const nodeCount = 24
+	var file bytes.Buffer
+	// node i tests bit i: the network's side leads on, the other side to "no data";
+	// the last node points at the record at the start of the data section
+	for i := 0; i < nodeCount; i++ {
+		next := uint32(i + 1)
+		if i == nodeCount-1 {
+			next = nodeCount + 16
+		}
+		left, right := uint32(nodeCount), next
+		if ip[i/8]&(0x80>>(i%8)) == 0 {
+			left, right = next, nodeCount
+		}
+		file.Write([]byte{byte(left >> 16), byte(left >> 8), byte(left), byte(right >> 16), byte(right >> 8), byte(right)})
+	}
+	file.Write(make([]byte, 16))
+	encode(&file, data)
+	file.WriteString("\xAB\xCD\xEFMaxMind.com")
+	encode(&file, mmdbMap{
+		"node_count":                  uint32(nodeCount),
+		"record_size":                 uint16(24),
+		"ip_version":                  uint16(4),
+		"database_type":               "Test",
+		"languages":                   []string{"en"},
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint32(1700000000),
+		"description":                 mmdbMap{"en": "test"},
+	})
+
+	path := filepath.Join(t.TempDir(), "test.mmdb")
+	if err := os.WriteFile(path, file.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLookup(t *testing.T) {
+	city := writeDB(t, "203.0.113.0/24", mmdbMap{
+		"country":  mmdbMap{"iso_code": "DE", "names": mmdbMap{"en": "Germany"}},
+		"city":     mmdbMap{"names": mmdbMap{"en": "Berlin", "de": "Berlin"}},
+		"location": mmdbMap{"latitude": 52.52, "longitude": 13.405},
+	})
+	asn := writeDB(t, "203.0.113.0/24", mmdbMap{
+		"autonomous_system_number":       uint32(64496),
+		"autonomous_system_organization": "Example Net",
+	})
+	db, err := Open(city, asn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	loc, ok := db.Lookup("203.0.113.7")
+	if !ok || loc.IPAddress != "203.0.113.7" || loc.CountryCode != "DE" || loc.Country != "Germany" || loc.City != "Berlin" ||
+		*loc.Latitude != 52.52 || loc.ASN != 64496 || loc.ASOrg != "Example Net" {
+		t.Errorf("Lookup = %+v, %v", loc, ok)
+	}
+	for _, ip := range []string{"198.51.100.1", "2001:db8::1", "not an ip"} {
+		if loc, ok := db.Lookup(ip); ok {
+			t.Errorf("Lookup(%q) = %+v, want not found", ip, loc)
+		}
+	}
+
+	var none *DB
+	if _, ok := none.Lookup("203.0.113.7"); ok {
+		t.Error("a nil DB should know no address")
+	}
+	if _, err := Open(filepath.Join(t.TempDir(), "missing.mmdb")); err == nil {
+		t.Error("opening a missing file should fail")
+	}
+}
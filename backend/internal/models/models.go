@@ -6,10 +6,30 @@ import (
 
 // ---Database Models------
 type User struct {
-	ID           int       `db:"id"`
-	Email        string    `db:"email"`
-	PasswordHash string    `db:"password_hash"`
-	CreatedAt    time.Time `db:"created_at"`
+	ID           int        `db:"id"`
+	Email        string     `db:"email"`
+	PasswordHash string     `db:"password_hash"`
+	Role         string     `db:"role"`
+	CreatedAt    time.Time  `db:"created_at"`
+	DisabledAt   *time.Time `db:"disabled_at"`
+	// TOTPSecret is set from enrollment on; 2FA is only enforced once TOTPEnabled.
+	TOTPEnabled  bool    `db:"totp_enabled"`
+	TOTPSecret   *string `db:"totp_secret"`
+	TOTPLastStep int64   `db:"totp_last_step"`
+}
+
+// RecoveryCode is one of a user's unused 2FA recovery codes, bcrypt-hashed.
+type RecoveryCode struct {
+	ID       int    `db:"id"`
+	CodeHash string `db:"code_hash"`
+}
+
+type IGAccount struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	Name      string    `json:"name"`
+	IsDefault bool      `json:"is_default"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type MediaItem struct {
@@ -299,6 +319,210 @@ type OffMetaActivity struct {
 	EventAt   time.Time `json:"event_at"`
 }
 
+type APIToken struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scope      string     `json:"scope"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+type ShareLink struct {
+	ID             int        `json:"id"`
+	UserID         int        `json:"user_id"`
+	AccountID      int        `json:"account_id"`
+	Name           string     `json:"name"`
+	Sections       []string   `json:"sections"`
+	DateFrom       *time.Time `json:"date_from"`
+	DateTo         *time.Time `json:"date_to"`
+	CollectionName *string    `json:"collection_name"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	RevokedAt      *time.Time `json:"revoked_at"`
+}
+
+type ShareLinkAccess struct {
+	ID         int       `json:"id"`
+	AccessedAt time.Time `json:"accessed_at"`
+	Path       string    `json:"path"`
+	IPAddress  *string   `json:"ip_address"`
+	UserAgent  *string   `json:"user_agent"`
+}
+
+type AdminUser struct {
+	ID           int        `json:"id"`
+	Email        string     `json:"email"`
+	Role         string     `json:"role"`
+	CreatedAt    time.Time  `json:"created_at"`
+	DisabledAt   *time.Time `json:"disabled_at"`
+	AccountCount int        `json:"account_count"`
+	ImportCount  int        `json:"import_count"`
+	LastImportAt *time.Time `json:"last_import_at"`
+	StorageBytes int64      `json:"storage_bytes"`
+}
+
+type SearchHit struct {
+	Type    string     `json:"type"`
+	ID      int        `json:"id"`
+	Label   string     `json:"label"`
+	Snippet string     `json:"snippet"`
+	Rank    float32    `json:"rank"`
+	At      *time.Time `json:"at"`
+	Link    string     `json:"link"`
+	// Ref is what Link is built from: the uri, conversation or URL of the source record.
+	Ref string `json:"-"`
+}
+
+type TimelineEvent struct {
+	Type     string    `json:"type"`
+	Subtype  *string   `json:"subtype"`
+	ID       int       `json:"id"`
+	At       time.Time `json:"at"`
+	Username *string   `json:"username"`
+	Text     *string   `json:"text"`
+	// Source is the table the event came from; ids are only unique within one table.
+	Source string `json:"-"`
+}
+
+// RelationshipEntry is one account in a reciprocity list. FollowingSince is when you followed
+// them and FollowerSince when they followed you; Labels are the other connection lists
+// (close_friend, restricted, blocked, story_hidden_from) the account is on.
+type RelationshipEntry struct {
+	ID             int        `json:"id"`
+	Username       string     `json:"username"`
+	FollowingSince *time.Time `json:"following_since"`
+	FollowerSince  *time.Time `json:"follower_since"`
+	Labels         []string   `json:"labels"`
+}
+
+// ConnectionSnapshot is the follower and following counts of one import's archive.
+type ConnectionSnapshot struct {
+	ImportID  int       `json:"import_id"`
+	TakenAt   time.Time `json:"taken_at"`
+	Followers int       `json:"followers"`
+	Following int       `json:"following"`
+}
+
+// SnapshotEntry is one connection as an import's archive listed it.
+type SnapshotEntry struct {
+	ImportID       int       `json:"import_id"`
+	Username       string    `json:"username"`
+	ConnectionType string    `json:"connection_type"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// ConversationStats aggregates one conversation's messages. Sent counts the messages of
+// the archive's owner. A reply is a message answering the other side within the reply
+// window; the averages are nil when there were none.
+type ConversationStats struct {
+	ConversationID       string    `json:"conversation_id"`
+	Participants         string    `json:"participants"`
+	Messages             int       `json:"messages"`
+	Sent                 int       `json:"sent"`
+	Received             int       `json:"received"`
+	FirstMessageAt       time.Time `json:"first_message_at"`
+	LastMessageAt        time.Time `json:"last_message_at"`
+	YourReplies          int       `json:"your_replies"`
+	YourAvgReplySeconds  *float64  `json:"your_avg_reply_seconds"`
+	TheirReplies         int       `json:"their_replies"`
+	TheirAvgReplySeconds *float64  `json:"their_avg_reply_seconds"`
+}
+
+// MessageDay is how many messages a conversation had on one (UTC) day.
+type MessageDay struct {
+	ConversationID string    `json:"conversation_id"`
+	Day            time.Time `json:"day"`
+	Messages       int       `json:"messages"`
+}
+
+// ActivityHour counts one category's events on a day of the week (0 is Sunday) and hour.
+type ActivityHour struct {
+	Category string `json:"category"`
+	Weekday  int    `json:"weekday"`
+	Hour     int    `json:"hour"`
+	Count    int    `json:"count"`
+}
+
+// ActivityMonth counts one category's events in a month, given as its first day.
+type ActivityMonth struct {
+	Category string    `json:"category"`
+	Month    time.Time `json:"month"`
+	Count    int       `json:"count"`
+}
+
+// AdViews summarises the ads seen from one advertiser account. Impressions without a
+// date count towards Views but not the dates.
+type AdViews struct {
+	Author    string     `json:"author"`
+	Views     int        `json:"views"`
+	FirstSeen *time.Time `json:"first_seen"`
+	LastSeen  *time.Time `json:"last_seen"`
+}
+
+// OffMetaApp summarises the events one app or website sent Meta about the account.
+type OffMetaApp struct {
+	AppName    string    `json:"app_name"`
+	Events     int       `json:"events"`
+	EventTypes []string  `json:"event_types"`
+	FirstSeen  time.Time `json:"first_seen"`
+	LastSeen   time.Time `json:"last_seen"`
+}
+
+// InteractionCount is how often the account interacted with one username in one way.
+type InteractionCount struct {
+	Username string    `json:"username"`
+	Kind     string    `json:"kind"`
+	Count    int       `json:"count"`
+	FirstAt  time.Time `json:"first_at"`
+	LastAt   time.Time `json:"last_at"`
+}
+
+// Interaction is one like, comment, story reply or message directed at another account.
+// Detail is the comment, answer or message text, or the liked post's URL.
+type Interaction struct {
+	Kind   string    `json:"kind"`
+	ID     int       `json:"id"`
+	At     time.Time `json:"at"`
+	Detail *string   `json:"detail"`
+}
+
+// MemoryItem is one dated record of the "on this day" memories: a post or story, an
+// archived post, a profile photo or the first message of a conversation.
+type MemoryItem struct {
+	Type           string    `json:"type"`
+	ID             int       `json:"id"`
+	At             time.Time `json:"at"`
+	URI            *string   `json:"uri"`
+	Text           *string   `json:"text"`
+	ConversationID *string   `json:"conversation_id,omitempty"`
+	Participants   *string   `json:"participants,omitempty"`
+}
+
+// IPLocation is where the offline geolocation database places an IP address. Fields
+// the database doesn't know are empty; ASN 0 means no autonomous system.
+type IPLocation struct {
+	IPAddress   string   `json:"ip_address"`
+	CountryCode string   `json:"country_code"`
+	Country     string   `json:"country"`
+	City        string   `json:"city"`
+	Latitude    *float64 `json:"latitude"`
+	Longitude   *float64 `json:"longitude"`
+	ASN         int      `json:"asn"`
+	ASOrg       string   `json:"as_org"`
+}
+
+// OwnText is one caption, comment or message the account wrote.
+type OwnText struct {
+	Source string    `json:"source"`
+	ID     int       `json:"id"`
+	At     time.Time `json:"at"`
+	Text   string    `json:"text"`
+}
+
 // JSON Parsing Models
 type InstagramPostWrapper struct {
 	Media []InstagramPost `json:"media"`
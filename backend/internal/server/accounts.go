@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/Sa-Te/IAV/backend/internal/store"
+)
+
+// ensureDefaultAccount returns the user's default Instagram account, creating it if needed.
+func (s *APIServer) ensureDefaultAccount(ctx context.Context, userID int) (int, error) {
+	return s.store.Accounts.DefaultAccount(ctx, userID)
+}
+
+// resolveAccount maps an `account` reference (an ig_accounts id or name) to one of the
+// user's accounts. An empty reference selects the default account.
+func (s *APIServer) resolveAccount(ctx context.Context, userID int, ref string) (int, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return s.ensureDefaultAccount(ctx, userID)
+	}
+	return s.store.Accounts.FindAccount(ctx, userID, ref)
+}
+
+// accountFromRequest resolves the request's `account` parameter, writing the error
+// response itself when it can't. Handlers call it right after reading the user id.
+func (s *APIServer) accountFromRequest(w http.ResponseWriter, r *http.Request, userID int) (int, bool) {
+	accountID, err := s.resolveAccount(r.Context(), userID, r.FormValue("account"))
+	if errors.Is(err, store.ErrNotFound) {
+		writeJSONError(w, http.StatusNotFound, "Instagram account not found")
+		return 0, false
+	}
+	if err != nil {
+		log.Printf("Failed to resolve account for user %d: %v", userID, err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to resolve Instagram account")
+		return 0, false
+	}
+	return accountID, true
+}
+
+// accountUploadDir is where an account's unzipped archive lives. Archives uploaded
+// before accounts existed sit directly under uploads/<userID>.
+func accountUploadDir(userID, accountID int) string {
+	return filepath.Join("uploads", fmt.Sprintf("%d", userID), fmt.Sprintf("%d", accountID))
+}
+
+// listAccounts handles GET /api/v1/accounts.
+func (s *APIServer) listAccounts(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(int)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+	if _, err := s.ensureDefaultAccount(r.Context(), userID); err != nil {
+		log.Printf("Failed to ensure default account for user %d: %v", userID, err)
+	}
+
+	accounts, err := s.store.Accounts.ListAccounts(r.Context(), userID)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve accounts")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(accounts)
+}
+
+// createAccount handles POST /api/v1/accounts.
+func (s *APIServer) createAccount(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(int)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+
+	var reqBody struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	name := strings.TrimPrefix(strings.TrimSpace(reqBody.Name), "@")
+	if name == "" {
+		writeJSONError(w, http.StatusBadRequest, "Account name is required")
+		return
+	}
+
+	a, err := s.store.Accounts.CreateAccount(r.Context(), userID, name)
+	if errors.Is(err, store.ErrConflict) {
+		writeJSONError(w, http.StatusConflict, "An account with this name already exists")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to create account for user %d: %v", userID, err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to create account")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(a)
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/Sa-Te/IAV/backend/internal/store"
+)
+
+const (
+	roleUser  = "user"
+	roleAdmin = "admin"
+)
+
+// userUploadRoot holds every archive a user has uploaded, across all their accounts.
+func userUploadRoot(userID int) string {
+	return filepath.Join("uploads", fmt.Sprintf("%d", userID))
+}
+
+// dirSize sums the sizes of the regular files under root. A missing root is empty.
+func dirSize(root string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			total += info.Size()
+		}
+		return nil
+	})
+	return total, err
+}
+
+// adminListUsersHandler handles GET /api/v1/admin/users.
+func (s *APIServer) adminListUsersHandler(w http.ResponseWriter, r *http.Request) {
+	users, err := s.store.Users.ListUsers(r.Context())
+	if err != nil {
+		log.Printf("Failed to list users: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve users")
+		return
+	}
+
+	for i := range users {
+		size, err := dirSize(userUploadRoot(users[i].ID))
+		if err != nil {
+			log.Printf("Failed to measure uploads for user %d: %v", users[i].ID, err)
+		}
+		users[i].StorageBytes = size
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(users)
+}
+
+// adminTarget reads the acting admin from the context and the target user from the path.
+func adminTarget(w http.ResponseWriter, r *http.Request) (adminID, targetID int, ok bool) {
+	adminID, ok = r.Context().Value(userIDKey).(int)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
+		return 0, 0, false
+	}
+	targetID, ok = pathInt(w, r, "id", "user id")
+	return adminID, targetID, ok
+}
+
+// adminDisableUserHandler handles POST /api/v1/admin/users/{id}/disable.
+func (s *APIServer) adminDisableUserHandler(w http.ResponseWriter, r *http.Request) {
+	s.adminSetUserDisabled(w, r, true)
+}
+
+// adminEnableUserHandler handles POST /api/v1/admin/users/{id}/enable.
+func (s *APIServer) adminEnableUserHandler(w http.ResponseWriter, r *http.Request) {
+	s.adminSetUserDisabled(w, r, false)
+}
+
+func (s *APIServer) adminSetUserDisabled(w http.ResponseWriter, r *http.Request, disable bool) {
+	adminID, targetID, ok := adminTarget(w, r)
+	if !ok {
+		return
+	}
+	// an admin locking themselves out would leave nobody able to undo it
+	if disable && targetID == adminID {
+		writeJSONError(w, http.StatusBadRequest, "You cannot disable your own account")
+		return
+	}
+
+	err := s.store.Users.SetUserDisabled(r.Context(), targetID, disable)
+	if errors.Is(err, store.ErrNotFound) {
+		writeJSONError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to update user")
+		return
+	}
+	log.Printf("Admin %d set disabled=%t for user %d", adminID, disable, targetID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// adminDeleteUserHandler handles DELETE /api/v1/admin/users/{id}: it removes the user,
+// everything imported for them and their files under uploads/.
+func (s *APIServer) adminDeleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	adminID, targetID, ok := adminTarget(w, r)
+	if !ok {
+		return
+	}
+	if targetID == adminID {
+		writeJSONError(w, http.StatusBadRequest, "You cannot delete your own account")
+		return
+	}
+
+	err := s.store.Users.DeleteUser(r.Context(), targetID)
+	if errors.Is(err, store.ErrNotFound) {
+		writeJSONError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to delete user %d: %v", targetID, err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to delete user")
+		return
+	}
+	if err := os.RemoveAll(userUploadRoot(targetID)); err != nil {
+		log.Printf("Failed to remove uploads for deleted user %d: %v", targetID, err)
+	}
+	log.Printf("Admin %d deleted user %d", adminID, targetID)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDirSize(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "1", "media"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(root, "1", "a.json"), make([]byte, 10), 0o644)
+	os.WriteFile(filepath.Join(root, "1", "media", "b.jpg"), make([]byte, 32), 0o644)
+
+	size, err := dirSize(root)
+	if err != nil || size != 42 {
+		t.Errorf("dirSize = %d, %v; want 42", size, err)
+	}
+
+	size, err = dirSize(filepath.Join(root, "missing"))
+	if err != nil || size != 0 {
+		t.Errorf("dirSize(missing) = %d, %v; want 0, nil", size, err)
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	handler := requireRole(roleAdmin, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	for role, want := range map[string]int{roleAdmin: http.StatusNoContent, roleUser: http.StatusForbidden, "": http.StatusForbidden} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/users", nil)
+		req = req.WithContext(context.WithValue(req.Context(), userRoleKey, role))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("role %q: status %d, want %d", role, rec.Code, want)
+		}
+	}
+}
+
+func TestAdminDisableUser(t *testing.T) {
+	s, mem, _ := newTestServer(t)
+	adminID, err := mem.CreateUser(context.Background(), "admin@example.com", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID, err := mem.CreateUser(context.Background(), "user@example.com", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	disable := func(target int) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/users/x/disable", nil)
+		req.SetPathValue("id", fmt.Sprint(target))
+		return serveAs(adminID, s.adminDisableUserHandler, req).Code
+	}
+	if code := disable(userID); code != http.StatusNoContent {
+		t.Fatalf("disable: %d", code)
+	}
+	if code := disable(999); code != http.StatusNotFound {
+		t.Errorf("disabling an unknown user: %d, want 404", code)
+	}
+
+	// the disabled user's existing tokens stop working
+	rec := httptest.NewRecorder()
+	s.serveAsUser(rec, httptest.NewRequest(http.MethodGet, "/api/v1/media", nil), http.NotFoundHandler(), userID, scopeSession)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("disabled user: status %d, want 403", rec.Code)
+	}
+}
@@ -0,0 +1,216 @@
+package server
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Sa-Te/IAV/backend/internal/models"
+)
+
+// advertiserEntry is one advertiser of the ad report: whether it is on the archive's
+// list of advertisers using your information, and the ads seen from it.
+type advertiserEntry struct {
+	Name        string     `json:"name"`
+	HasYourInfo bool       `json:"has_your_info"`
+	AdsSeen     int        `json:"ads_seen"`
+	FirstSeen   *time.Time `json:"first_seen"`
+	LastSeen    *time.Time `json:"last_seen"`
+}
+
+type adReport struct {
+	Summary struct {
+		AdvertisersWithYourInfo int `json:"advertisers_with_your_info"`
+		AdvertisersSeen         int `json:"advertisers_seen"`
+		AdvertisersBoth         int `json:"advertisers_both"`
+		AdsSeen                 int `json:"ads_seen"`
+		OffMetaApps             int `json:"off_meta_apps"`
+		OffMetaEvents           int `json:"off_meta_events"`
+		Interests               int `json:"interests"`
+		AdTopics                int `json:"ad_topics"`
+		LocationsOfInterest     int `json:"locations_of_interest"`
+	} `json:"summary"`
+	Advertisers         []advertiserEntry        `json:"advertisers"`
+	OffMetaApps         []models.OffMetaApp      `json:"off_meta_apps"`
+	Interests           []models.AIInterest      `json:"interests"`
+	AdTopics            []string                 `json:"ad_topics"`
+	InferredLocation    *models.InferredLocation `json:"inferred_location"`
+	LocationsOfInterest []string                 `json:"locations_of_interest"`
+}
+
+// advertiserKey matches an advertiser's display name ("Nike Running") with the account
+// its ads were seen from ("nikerunning"): letters and digits only, lowercased. Names
+// with neither are kept as they are.
+func advertiserKey(name string) string {
+	key := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+	if key == "" {
+		return name
+	}
+	return key
+}
+
+// mergeAdvertisers cross-references the advertisers using your information with the
+// ads seen, most ads first.
+func mergeAdvertisers(withYourInfo []string, views []models.AdViews) []advertiserEntry {
+	entries := make([]advertiserEntry, 0, len(withYourInfo)+len(views))
+	byKey := map[string]int{}
+	for _, name := range withYourInfo {
+		key := advertiserKey(name)
+		if _, ok := byKey[key]; ok {
+			continue
+		}
+		byKey[key] = len(entries)
+		entries = append(entries, advertiserEntry{Name: name, HasYourInfo: true})
+	}
+	for _, v := range views {
+		key := advertiserKey(v.Author)
+		i, ok := byKey[key]
+		if !ok {
+			i = len(entries)
+			byKey[key] = i
+			entries = append(entries, advertiserEntry{Name: v.Author})
+		}
+		e := &entries[i]
+		e.AdsSeen += v.Views
+		if v.FirstSeen != nil && (e.FirstSeen == nil || v.FirstSeen.Before(*e.FirstSeen)) {
+			e.FirstSeen = v.FirstSeen
+		}
+		if v.LastSeen != nil && (e.LastSeen == nil || v.LastSeen.After(*e.LastSeen)) {
+			e.LastSeen = v.LastSeen
+		}
+	}
+	slices.SortStableFunc(entries, func(a, b advertiserEntry) int {
+		return cmp.Or(cmp.Compare(b.AdsSeen, a.AdsSeen), strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)))
+	})
+	return entries
+}
+
+// getAdReportHandler serves /api/v1/insights/ads, the ad targeting report, as JSON or,
+// with format=csv, as one CSV row per advertiser, app, interest, topic and location.
+func (s *APIServer) getAdReportHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+	accountID, ok := s.accountFromRequest(w, r, userID)
+	if !ok {
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		writeJSONError(w, http.StatusBadRequest, "format must be json or csv")
+		return
+	}
+
+	ctx := r.Context()
+	var report adReport
+	advertisers, err := s.store.Interests.ListAdvertisers(ctx, accountID)
+	var views []models.AdViews
+	if err == nil {
+		views, err = s.store.Insights.AdViewsByAuthor(ctx, accountID)
+	}
+	if err == nil {
+		report.OffMetaApps, err = s.store.Insights.OffMetaApps(ctx, accountID)
+	}
+	if err == nil {
+		report.Interests, err = s.store.Interests.ListAIInterests(ctx, accountID)
+	}
+	if err == nil {
+		report.AdTopics, err = s.store.Interests.ListAdTopics(ctx, accountID)
+	}
+	if err == nil {
+		report.InferredLocation, err = s.store.Interests.InferredLocation(ctx, accountID)
+	}
+	if err == nil {
+		report.LocationsOfInterest, err = s.store.Interests.ListLocationsOfInterest(ctx, accountID)
+	}
+	if err != nil {
+		log.Printf("Database query error in getAdReportHandler: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to build the ad report")
+		return
+	}
+
+	report.Advertisers = mergeAdvertisers(advertisers, views)
+	for _, a := range report.Advertisers {
+		report.Summary.AdsSeen += a.AdsSeen
+		if a.HasYourInfo {
+			report.Summary.AdvertisersWithYourInfo++
+		}
+		if a.AdsSeen > 0 {
+			report.Summary.AdvertisersSeen++
+		}
+		if a.HasYourInfo && a.AdsSeen > 0 {
+			report.Summary.AdvertisersBoth++
+		}
+	}
+	for _, app := range report.OffMetaApps {
+		report.Summary.OffMetaEvents += app.Events
+	}
+	report.Summary.OffMetaApps = len(report.OffMetaApps)
+	report.Summary.Interests = len(report.Interests)
+	report.Summary.AdTopics = len(report.AdTopics)
+	report.Summary.LocationsOfInterest = len(report.LocationsOfInterest)
+
+	if format == "csv" {
+		writeAdReportCSV(w, report)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+func writeAdReportCSV(w http.ResponseWriter, report adReport) {
+	date := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339)
+	}
+
+	rows := [][]string{{"section", "name", "detail", "count", "first_seen", "last_seen"}}
+	for _, a := range report.Advertisers {
+		detail := ""
+		if a.HasYourInfo {
+			detail = "has your information"
+		}
+		rows = append(rows, []string{"advertiser", a.Name, detail, strconv.Itoa(a.AdsSeen), date(a.FirstSeen), date(a.LastSeen)})
+	}
+	for _, app := range report.OffMetaApps {
+		rows = append(rows, []string{"off_meta_app", app.AppName, strings.Join(app.EventTypes, ";"),
+			strconv.Itoa(app.Events), date(&app.FirstSeen), date(&app.LastSeen)})
+	}
+	for _, i := range report.Interests {
+		rows = append(rows, []string{"interest", i.InterestDescription, "", "", date(i.DetectedAt), ""})
+	}
+	for _, topic := range report.AdTopics {
+		rows = append(rows, []string{"ad_topic", topic, "", "", "", ""})
+	}
+	if report.InferredLocation != nil {
+		rows = append(rows, []string{"inferred_location", report.InferredLocation.CityName, "", "", "", ""})
+	}
+	for _, location := range report.LocationsOfInterest {
+		rows = append(rows, []string{"location_of_interest", location, "", "", "", ""})
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="ad-report.csv"`)
+	cw := csv.NewWriter(w)
+	cw.WriteAll(rows)
+	if err := cw.Error(); err != nil {
+		log.Printf("Failed to write ad report CSV: %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Sa-Te/IAV/backend/internal/models"
+)
+
+func TestAdReportHandler(t *testing.T) {
+	s, mem, accountID := newTestServer(t)
+	archive := mem.Archive(accountID)
+	nike, acme := "nikerunning", "acme.shop"
+	archive.Advertisers = []string{"Nike Running", "Globex"}
+	archive.Activity = []models.ActivityLog{
+		{ID: 1, ActivityType: "ad_viewed", Author: &nike, Timestamp: day(3)},
+		{ID: 2, ActivityType: "ad_viewed", Author: &nike, Timestamp: day(9)},
+		{ID: 3, ActivityType: "ad_viewed", Author: &acme, Timestamp: day(5)},
+		{ID: 4, ActivityType: "post_viewed", Author: &acme, Timestamp: day(5)},
+	}
+	archive.OffMeta = []models.OffMetaActivity{
+		{ID: 1, AppName: "Shop App", EventType: "PURCHASE", EventAt: day(4)},
+		{ID: 2, AppName: "Shop App", EventType: "PAGE_VIEW", EventAt: day(2)},
+	}
+	archive.AdTopics = []string{"Running"}
+	archive.InferredLocation = &models.InferredLocation{CityName: "Berlin"}
+
+	get := func(target string) *httptest.ResponseRecorder {
+		return serveAs(testUserID, s.getAdReportHandler, httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	body := decodeBody[adReport](t, get("/api/v1/insights/ads"))
+	if len(body.Advertisers) != 3 {
+		t.Fatalf("advertisers = %+v", body.Advertisers)
+	}
+	first := body.Advertisers[0]
+	if first.Name != "Nike Running" || !first.HasYourInfo || first.AdsSeen != 2 || !first.FirstSeen.Equal(day(3)) || !first.LastSeen.Equal(day(9)) {
+		t.Errorf("nike = %+v", first)
+	}
+	if second := body.Advertisers[1]; second.Name != "acme.shop" || second.HasYourInfo || second.AdsSeen != 1 {
+		t.Errorf("acme = %+v", second)
+	}
+	if third := body.Advertisers[2]; third.Name != "Globex" || third.AdsSeen != 0 || third.FirstSeen != nil {
+		t.Errorf("globex = %+v", third)
+	}
+	summary := body.Summary
+	if summary.AdvertisersWithYourInfo != 2 || summary.AdvertisersSeen != 2 || summary.AdvertisersBoth != 1 || summary.AdsSeen != 3 ||
+		summary.OffMetaApps != 1 || summary.OffMetaEvents != 2 || summary.AdTopics != 1 {
+		t.Errorf("summary = %+v", summary)
+	}
+	if app := body.OffMetaApps[0]; app.Events != 2 || !app.FirstSeen.Equal(day(2)) || !app.LastSeen.Equal(day(4)) {
+		t.Errorf("off-Meta app = %+v", app)
+	}
+
+	rec := get("/api/v1/insights/ads?format=csv")
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") || rec.Header().Get("Content-Disposition") == "" {
+		t.Errorf("CSV headers = %v", rec.Header())
+	}
+	rows, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// header, three advertisers, one app, one topic and the inferred location
+	if len(rows) != 7 || rows[1][0] != "advertiser" || rows[1][1] != "Nike Running" || rows[1][3] != "2" || rows[1][4] != "2024-03-03T12:00:00Z" {
+		t.Errorf("CSV rows = %q", rows)
+	}
+	if rows[4][0] != "off_meta_app" || rows[4][2] != "PAGE_VIEW;PURCHASE" || rows[6][0] != "inferred_location" {
+		t.Errorf("CSV rows = %q", rows)
+	}
+
+	if rec := get("/api/v1/insights/ads?format=xml"); rec.Code != http.StatusBadRequest {
+		t.Errorf("format=xml: status %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,228 @@
+package server
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Archive data only changes when an import completes, so every archive GET response
+// is a function of (user, account, latest import, URL). cacheMiddleware turns that
+// into an ETag, answers matching If-None-Match requests with 304 without running the
+// handler, and, when EnableResponseCache was called, replays stored bodies for repeat
+// requests from other clients or after the browser dropped its copy.
+
+// cacheEpoch is folded into every ETag so a restart (possibly with a new response
+// format) never revalidates bodies produced by the previous process.
+var cacheEpoch = fmt.Sprintf("%d", time.Now().UnixNano())
+
+func archiveETag(userID, accountID, version int, requestURI string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d|%d|%d|%s", cacheEpoch, userID, accountID, version, requestURI)))
+	return `W/"` + hex.EncodeToString(sum[:12]) + `"`
+}
+
+// etagMatches implements the weak comparison If-None-Match calls for.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == want {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheMiddleware must be layered inside authMiddleware and only around handlers
+// whose output depends on nothing but the archive and the request URL.
+func (s *APIServer) cacheMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(userIDKey).(int)
+		if !ok || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		// an unknown account is the handler's error to report
+		accountID, err := s.resolveAccount(r.Context(), userID, r.URL.Query().Get("account"))
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		version, err := s.store.Imports.LatestImport(r.Context(), accountID)
+		if err != nil {
+			log.Printf("Failed to read import version for account %d: %v", accountID, err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		etag := archiveETag(userID, accountID, version, r.URL.RequestURI())
+		w.Header().Set("Vary", "Authorization")
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.Header().Set("ETag", etag)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		if entry, ok := s.cache.get(etag); ok {
+			setCacheHeaders(w, etag)
+			w.Header().Set("Content-Type", entry.contentType)
+			if entry.contentDisposition != "" {
+				w.Header().Set("Content-Disposition", entry.contentDisposition)
+			}
+			w.Header().Set("X-Cache", "HIT")
+			w.WriteHeader(http.StatusOK)
+			w.Write(entry.body)
+			return
+		}
+
+		rec := &cachingResponseWriter{ResponseWriter: w, etag: etag, capture: s.cache != nil}
+		next.ServeHTTP(rec, r)
+		if rec.status == http.StatusOK && rec.capture {
+			s.cache.add(&cachedResponse{
+				key:                etag,
+				accountID:          accountID,
+				contentType:        w.Header().Get("Content-Type"),
+				contentDisposition: w.Header().Get("Content-Disposition"),
+				body:               rec.body.Bytes(),
+			})
+		}
+	})
+}
+
+func setCacheHeaders(w http.ResponseWriter, etag string) {
+	w.Header().Set("ETag", etag)
+	// let the browser keep the body but revalidate it on every use
+	w.Header().Set("Cache-Control", "private, no-cache")
+}
+
+// cachingResponseWriter adds the ETag to successful responses only and, when a
+// response cache is configured, keeps a copy of the body.
+type cachingResponseWriter struct {
+	http.ResponseWriter
+	etag    string
+	status  int
+	capture bool
+	body    bytes.Buffer
+}
+
+func (cw *cachingResponseWriter) WriteHeader(status int) {
+	if cw.status != 0 {
+		return
+	}
+	cw.status = status
+	if status == http.StatusOK {
+		setCacheHeaders(cw.ResponseWriter, cw.etag)
+	}
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+func (cw *cachingResponseWriter) Write(b []byte) (int, error) {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.capture && cw.status == http.StatusOK {
+		if cw.body.Len()+len(b) > maxCachedResponseBytes {
+			cw.capture = false
+			cw.body = bytes.Buffer{}
+		} else {
+			cw.body.Write(b)
+		}
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// --- In-process response cache ---
+
+// maxCachedResponseBytes keeps a single huge response (a full media list, say) from
+// evicting everything else.
+const maxCachedResponseBytes = 4 << 20
+
+type cachedResponse struct {
+	key                string
+	accountID          int
+	contentType        string
+	contentDisposition string // set by downloads such as the CSV ad report
+	body               []byte
+}
+
+// responseCache is a byte-bounded LRU. A nil *responseCache is a valid, always-empty cache.
+type responseCache struct {
+	mu       sync.Mutex
+	maxBytes int64
+	used     int64
+	order    *list.List // front = most recently used
+	entries  map[string]*list.Element
+}
+
+func newResponseCache(maxBytes int64) *responseCache {
+	return &responseCache{maxBytes: maxBytes, order: list.New(), entries: map[string]*list.Element{}}
+}
+
+// EnableResponseCache keeps up to maxBytes of archive responses in memory.
+func (s *APIServer) EnableResponseCache(maxBytes int64) {
+	s.cache = newResponseCache(maxBytes)
+}
+
+func (c *responseCache) get(key string) (*cachedResponse, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*cachedResponse), true
+}
+
+func (c *responseCache) add(entry *cachedResponse) {
+	if c == nil || int64(len(entry.body)) > c.maxBytes {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[entry.key]; ok {
+		c.remove(el)
+	}
+	c.entries[entry.key] = c.order.PushFront(entry)
+	c.used += int64(len(entry.body))
+	for c.used > c.maxBytes {
+		c.remove(c.order.Back())
+	}
+}
+
+// invalidateAccount drops everything cached for an account. Its ETags have already
+// changed with the new import; this just frees the memory straight away.
+func (c *responseCache) invalidateAccount(accountID int) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for el := c.order.Front(); el != nil; {
+		next := el.Next()
+		if el.Value.(*cachedResponse).accountID == accountID {
+			c.remove(el)
+		}
+		el = next
+	}
+}
+
+// remove must be called with c.mu held.
+func (c *responseCache) remove(el *list.Element) {
+	entry := c.order.Remove(el).(*cachedResponse)
+	delete(c.entries, entry.key)
+	c.used -= int64(len(entry.body))
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestArchiveETagChangesWithImport(t *testing.T) {
+	a := archiveETag(1, 2, 3, "/api/v1/media")
+	if a != archiveETag(1, 2, 3, "/api/v1/media") {
+		t.Error("ETag must be stable for the same inputs")
+	}
+	if a == archiveETag(1, 2, 4, "/api/v1/media") {
+		t.Error("ETag must change with the import version")
+	}
+	if a == archiveETag(1, 2, 3, "/api/v1/media?type=story") {
+		t.Error("ETag must change with the query")
+	}
+}
+
+func TestEtagMatches(t *testing.T) {
+	etag := `W/"abc"`
+	for header, want := range map[string]bool{
+		``:               false,
+		`W/"abc"`:        true,
+		`"abc"`:          true,
+		`"xyz", W/"abc"`: true,
+		`*`:              true,
+		`W/"abcd"`:       false,
+	} {
+		if got := etagMatches(header, etag); got != want {
+			t.Errorf("etagMatches(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
+
+func TestCachingResponseWriterOnlyTagsSuccess(t *testing.T) {
+	rec := httptest.NewRecorder()
+	cw := &cachingResponseWriter{ResponseWriter: rec, etag: `W/"x"`, capture: true}
+	cw.Write([]byte(`{"ok":true}`))
+	if rec.Header().Get("ETag") != `W/"x"` || cw.body.String() != `{"ok":true}` {
+		t.Errorf("200 response: etag %q, captured %q", rec.Header().Get("ETag"), cw.body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	cw = &cachingResponseWriter{ResponseWriter: rec, etag: `W/"x"`, capture: true}
+	writeJSONError(cw, http.StatusBadRequest, "nope")
+	if rec.Header().Get("ETag") != "" || cw.body.Len() != 0 {
+		t.Error("error responses must not be tagged or captured")
+	}
+}
+
+func TestResponseCacheEvictsAndInvalidates(t *testing.T) {
+	c := newResponseCache(10)
+	c.add(&cachedResponse{key: "a", accountID: 1, body: []byte("1234")})
+	c.add(&cachedResponse{key: "b", accountID: 2, body: []byte("1234")})
+	c.get("a") // a is now most recently used
+	c.add(&cachedResponse{key: "c", accountID: 1, body: []byte("1234")})
+
+	if _, ok := c.get("b"); ok {
+		t.Error("least recently used entry should have been evicted")
+	}
+	if _, ok := c.get("a"); !ok {
+		t.Error("recently used entry should survive")
+	}
+
+	c.invalidateAccount(1)
+	if len(c.entries) != 0 || c.used != 0 {
+		t.Errorf("invalidateAccount left %d entries, %d bytes", len(c.entries), c.used)
+	}
+
+	c.add(&cachedResponse{key: "big", body: []byte(strings.Repeat("x", 11))})
+	if _, ok := c.get("big"); ok {
+		t.Error("entries larger than the cache must not be stored")
+	}
+
+	var disabled *responseCache
+	disabled.add(&cachedResponse{key: "a"})
+	if _, ok := disabled.get("a"); ok {
+		t.Error("nil cache must stay empty")
+	}
+}
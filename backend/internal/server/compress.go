@@ -0,0 +1,247 @@
+package server
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Message and activity lists run to several megabytes of JSON, which compresses
+// roughly tenfold. compressMiddleware wraps the whole mux and negotiates br or gzip
+// per request; media files are left alone since JPEG and MP4 are already compressed.
+
+// Responses shorter than this aren't worth the encoder overhead.
+const minCompressBytes = 1024
+
+const (
+	encodingBrotli = "br"
+	encodingGzip   = "gzip"
+)
+
+// uncompressedPrefixes are routes serving stored media files as-is.
+var uncompressedPrefixes = []string{"/api/v1/mediafile/", "/api/v1/shared/mediafile/"}
+
+var gzipWriters = sync.Pool{New: func() interface{} {
+	w, _ := gzip.NewWriterLevel(io.Discard, gzip.DefaultCompression)
+	return w
+}}
+
+var brotliWriters = sync.Pool{New: func() interface{} {
+	// level 4 compresses JSON better than gzip at about the same speed
+	return brotli.NewWriterLevel(io.Discard, 4)
+}}
+
+// negotiateEncoding picks br or gzip from an Accept-Encoding header, honouring q-values
+// and preferring br on a tie. It returns "" when neither is acceptable.
+func negotiateEncoding(acceptEncoding string) string {
+	qualities := map[string]float64{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.TrimSpace(key) == "q" {
+				if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		qualities[name] = q
+	}
+
+	quality := func(encoding string) float64 {
+		if q, ok := qualities[encoding]; ok {
+			return q
+		}
+		if q, ok := qualities["*"]; ok {
+			return q
+		}
+		return 0
+	}
+	br, gz := quality(encodingBrotli), quality(encodingGzip)
+	switch {
+	case br > 0 && br >= gz:
+		return encodingBrotli
+	case gz > 0:
+		return encodingGzip
+	}
+	return ""
+}
+
+func compressMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, prefix := range uncompressedPrefixes {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		w.Header().Add("Vary", "Accept-Encoding")
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressResponseWriter{ResponseWriter: w, encoding: encoding}
+		defer cw.Close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// compressResponseWriter buffers the start of a response until it knows whether the
+// body is big enough to compress, then either starts an encoder or passes it through.
+type compressResponseWriter struct {
+	http.ResponseWriter
+	encoding string
+	status   int
+	buf      []byte
+	encoder  io.WriteCloser
+	decided  bool
+}
+
+func (cw *compressResponseWriter) WriteHeader(status int) {
+	if cw.status != 0 {
+		return
+	}
+	cw.status = status
+	// bodiless and informational responses go straight through
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		cw.passThrough()
+	}
+}
+
+func (cw *compressResponseWriter) Write(b []byte) (int, error) {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.decided {
+		if cw.encoder != nil {
+			return cw.encoder.Write(b)
+		}
+		return cw.ResponseWriter.Write(b)
+	}
+	cw.buf = append(cw.buf, b...)
+	if len(cw.buf) >= minCompressBytes {
+		if err := cw.start(); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// start decides how the response is sent and flushes whatever was buffered.
+func (cw *compressResponseWriter) start() error {
+	h := cw.Header()
+	if h.Get("Content-Encoding") != "" || len(cw.buf) < minCompressBytes {
+		cw.passThrough()
+	} else {
+		cw.decided = true
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+		// an ETag identifies the uncompressed representation
+		if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			h.Set("ETag", "W/"+etag)
+		}
+		cw.ResponseWriter.WriteHeader(cw.status)
+		cw.encoder = cw.newEncoder()
+	}
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if cw.encoder != nil {
+		_, err = cw.encoder.Write(buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+func (cw *compressResponseWriter) passThrough() {
+	if cw.decided {
+		return
+	}
+	cw.decided = true
+	cw.ResponseWriter.WriteHeader(cw.status)
+}
+
+func (cw *compressResponseWriter) newEncoder() io.WriteCloser {
+	if cw.encoding == encodingBrotli {
+		bw := brotliWriters.Get().(*brotli.Writer)
+		bw.Reset(cw.ResponseWriter)
+		return &pooledEncoder{WriteCloser: bw, release: func() { brotliWriters.Put(bw) }}
+	}
+	gw := gzipWriters.Get().(*gzip.Writer)
+	gw.Reset(cw.ResponseWriter)
+	return &pooledEncoder{WriteCloser: gw, release: func() { gzipWriters.Put(gw) }}
+}
+
+// Close sends a short buffered body uncompressed, or finishes the encoded stream.
+func (cw *compressResponseWriter) Close() error {
+	if !cw.decided {
+		if cw.status == 0 {
+			// the handler wrote nothing at all; net/http will send its default 200
+			return nil
+		}
+		if err := cw.start(); err != nil {
+			return err
+		}
+	}
+	if cw.encoder == nil {
+		return nil
+	}
+	err := cw.encoder.Close()
+	cw.encoder = nil
+	return err
+}
+
+// Flush lets streaming handlers push data out early. Whatever is buffered is
+// committed to the chosen encoding first.
+func (cw *compressResponseWriter) Flush() {
+	if !cw.decided && cw.status != 0 {
+		cw.start()
+	}
+	if f, ok := cw.encoder.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (cw *compressResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(cw.ResponseWriter).Hijack()
+}
+
+// pooledEncoder returns its gzip or brotli writer to the pool once the stream is closed.
+type pooledEncoder struct {
+	io.WriteCloser
+	release func()
+}
+
+func (p *pooledEncoder) Close() error {
+	err := p.WriteCloser.Close()
+	p.release()
+	return err
+}
+
+func (p *pooledEncoder) Flush() error {
+	if f, ok := p.WriteCloser.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	for header, want := range map[string]string{
+		"":                        "",
+		"identity":                "",
+		"gzip":                    "gzip",
+		"gzip, deflate, br":       "br",
+		"br;q=0.5, gzip":          "gzip",
+		"br;q=0, gzip;q=0":        "",
+		"*":                       "br",
+		"*;q=0.1, gzip;q=0.9":     "gzip",
+		"GZIP; q=1.0":             "gzip",
+		"br; q=0.8, gzip; q=0.8":  "br",
+		"deflate, compress;q=0.5": "",
+	} {
+		if got := negotiateEncoding(header); got != want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func compressTestHandler(body string) http.Handler {
+	return compressMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, body)
+	}))
+}
+
+func TestCompressMiddlewareEncodesLargeResponses(t *testing.T) {
+	body := strings.Repeat(`{"sender_name":"someone","content":"hello"},`, 200)
+	for _, tc := range []struct {
+		accept string
+		decode func(io.Reader) (io.Reader, error)
+	}{
+		{"gzip", func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+		{"br", func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil }},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/messages", nil)
+		req.Header.Set("Accept-Encoding", tc.accept)
+		rec := httptest.NewRecorder()
+		compressTestHandler(body).ServeHTTP(rec, req)
+
+		if got := rec.Header().Get("Content-Encoding"); got != tc.accept {
+			t.Fatalf("%s: Content-Encoding = %q", tc.accept, got)
+		}
+		if rec.Body.Len() >= len(body) {
+			t.Errorf("%s: body was not compressed (%d bytes)", tc.accept, rec.Body.Len())
+		}
+		dec, err := tc.decode(rec.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := io.ReadAll(dec)
+		if err != nil || string(decoded) != body {
+			t.Errorf("%s: round trip failed: %v", tc.accept, err)
+		}
+	}
+}
+
+func TestCompressMiddlewareLeavesSmallResponses(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/profile", nil)
+	req.Header.Set("Accept-Encoding", "gzip, br")
+	rec := httptest.NewRecorder()
+	compressTestHandler(`{"username":"someone"}`).ServeHTTP(rec, req)
+
+	if rec.Header().Get("Content-Encoding") != "" {
+		t.Error("small responses must not be compressed")
+	}
+	if rec.Body.String() != `{"username":"someone"}` {
+		t.Errorf("body = %q", rec.Body.String())
+	}
+	if rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Errorf("Vary = %q", rec.Header().Get("Vary"))
+	}
+}
+
+func TestCompressMiddlewareSkipsMediaFiles(t *testing.T) {
+	body := strings.Repeat("x", 4*minCompressBytes)
+	for _, path := range []string{"/api/v1/mediafile/media/posts/1.jpg", "/api/v1/shared/mediafile/media/posts/1.jpg"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept-Encoding", "gzip, br")
+		rec := httptest.NewRecorder()
+		compressTestHandler(body).ServeHTTP(rec, req)
+
+		if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != body {
+			t.Errorf("%s: media must be served as-is", path)
+		}
+	}
+}
+
+func TestCompressMiddlewareKeepsNotModified(t *testing.T) {
+	handler := compressMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/media", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotModified || rec.Header().Get("Content-Encoding") != "" || rec.Body.Len() != 0 {
+		t.Errorf("304 was altered: code %d, encoding %q", rec.Code, rec.Header().Get("Content-Encoding"))
+	}
+}
@@ -0,0 +1,192 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Sa-Te/IAV/backend/internal/geoip"
+	"github.com/Sa-Te/IAV/backend/internal/models"
+)
+
+// ipLocator places IP addresses; *geoip.DB is the real one.
+type ipLocator interface {
+	Lookup(ip string) (models.IPLocation, bool)
+}
+
+// EnableGeoIP places login, logout and signup IP addresses with db from now on.
+// A nil db leaves geolocation off.
+func (s *APIServer) EnableGeoIP(db *geoip.DB) {
+	// a nil *geoip.DB stored in the interface would not compare equal to nil
+	if db != nil {
+		s.geo = db
+	}
+}
+
+// locateIPs looks up the account's IP addresses that have no location yet and saves
+// what the database knows. Without a database it does nothing.
+func (s *APIServer) locateIPs(ctx context.Context, accountID int) error {
+	if s.geo == nil {
+		return nil
+	}
+	ips, err := s.store.Security.UnlocatedIPs(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	var located []models.IPLocation
+	for _, ip := range ips {
+		if loc, ok := s.geo.Lookup(ip); ok {
+			located = append(located, loc)
+		}
+	}
+	if len(located) == 0 {
+		return nil
+	}
+	return s.store.Security.SaveIPLocations(ctx, accountID, located)
+}
+
+// loginPlace is a country and city logged in from, over the whole history or one month.
+type loginPlace struct {
+	CountryCode string    `json:"country_code"`
+	Country     string    `json:"country"`
+	City        string    `json:"city"`
+	Logins      int       `json:"logins"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+type locationMonth struct {
+	Month  string       `json:"month"` // 2006-01
+	Places []loginPlace `json:"places"`
+}
+
+type locatedLogin struct {
+	ID         int                `json:"id"`
+	LoggedInAt time.Time          `json:"logged_in_at"`
+	IPAddress  string             `json:"ip_address"`
+	Location   *models.IPLocation `json:"location"`
+}
+
+// countPlaces adds a login at loc to places, keyed by country and city.
+func countPlaces(places []loginPlace, loc models.IPLocation, at time.Time) []loginPlace {
+	for i := range places {
+		p := &places[i]
+		if p.CountryCode == loc.CountryCode && p.City == loc.City {
+			p.Logins++
+			if at.Before(p.FirstSeen) {
+				p.FirstSeen = at
+			}
+			if at.After(p.LastSeen) {
+				p.LastSeen = at
+			}
+			return places
+		}
+	}
+	return append(places, loginPlace{CountryCode: loc.CountryCode, Country: loc.Country, City: loc.City, Logins: 1, FirstSeen: at, LastSeen: at})
+}
+
+func sortPlaces(places []loginPlace) {
+	slices.SortStableFunc(places, func(a, b loginPlace) int {
+		return cmp.Or(cmp.Compare(b.Logins, a.Logins), strings.Compare(a.CountryCode, b.CountryCode), strings.Compare(a.City, b.City))
+	})
+}
+
+// getLoginLocationsHandler serves /api/v1/insights/login-locations: where the account
+// logged in from, overall and month by month, as placed by the offline geolocation
+// database. Addresses imported before the database was configured are placed first.
+func (s *APIServer) getLoginLocationsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+	accountID, ok := s.accountFromRequest(w, r, userID)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	err := s.locateIPs(ctx, accountID)
+	var locations []models.IPLocation
+	if err == nil {
+		locations, err = s.store.Security.ListIPLocations(ctx, accountID)
+	}
+	var logins []models.LoginHistory
+	if err == nil {
+		logins, err = s.store.Security.ListLogins(ctx, accountID, 0)
+	}
+	var signup *models.SignupInfo
+	if err == nil {
+		signup, err = s.store.Security.SignupInfo(ctx, accountID)
+	}
+	if err != nil {
+		log.Printf("Database query error in getLoginLocationsHandler: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to get login locations")
+		return
+	}
+
+	byIP := make(map[string]*models.IPLocation, len(locations))
+	for i := range locations {
+		byIP[locations[i].IPAddress] = &locations[i]
+	}
+
+	type SignupLocation struct {
+		IPAddress  string             `json:"ip_address"`
+		SignedUpAt *time.Time         `json:"signed_up_at"`
+		Location   *models.IPLocation `json:"location"`
+	}
+	type Response struct {
+		GeoIPEnabled bool `json:"geoip_enabled"`
+		Summary      struct {
+			Logins    int `json:"logins"`
+			Located   int `json:"located"`
+			Countries int `json:"countries"`
+			Places    int `json:"places"`
+		} `json:"summary"`
+		Signup *SignupLocation `json:"signup"`
+		Places []loginPlace    `json:"places"`
+		Months []locationMonth `json:"months"`
+		Logins []locatedLogin  `json:"logins"` // the latest securityHistoryLimit
+	}
+	resp := Response{GeoIPEnabled: s.geo != nil, Places: []loginPlace{}, Months: []locationMonth{}, Logins: []locatedLogin{}}
+	if signup != nil && signup.SignupIP != "" {
+		resp.Signup = &SignupLocation{IPAddress: signup.SignupIP, SignedUpAt: signup.SignedUpAt, Location: byIP[signup.SignupIP]}
+	}
+
+	// logins are newest first; months are built oldest first
+	countries := map[string]bool{}
+	for i := len(logins) - 1; i >= 0; i-- {
+		l := logins[i]
+		loc := byIP[l.IPAddress]
+		if loc == nil {
+			continue
+		}
+		resp.Summary.Located++
+		countries[loc.CountryCode] = true
+		resp.Places = countPlaces(resp.Places, *loc, l.LoggedInAt)
+		month := l.LoggedInAt.UTC().Format("2006-01")
+		if n := len(resp.Months); n == 0 || resp.Months[n-1].Month != month {
+			resp.Months = append(resp.Months, locationMonth{Month: month})
+		}
+		last := &resp.Months[len(resp.Months)-1]
+		last.Places = countPlaces(last.Places, *loc, l.LoggedInAt)
+	}
+	sortPlaces(resp.Places)
+	for _, m := range resp.Months {
+		sortPlaces(m.Places)
+	}
+	for _, l := range atMost(logins, securityHistoryLimit) {
+		resp.Logins = append(resp.Logins, locatedLogin{ID: l.ID, LoggedInAt: l.LoggedInAt, IPAddress: l.IPAddress, Location: byIP[l.IPAddress]})
+	}
+	resp.Summary.Logins = len(logins)
+	resp.Summary.Countries = len(countries)
+	resp.Summary.Places = len(resp.Places)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Sa-Te/IAV/backend/internal/models"
+)
+
+type fakeLocator map[string]models.IPLocation
+
+func (f fakeLocator) Lookup(ip string) (models.IPLocation, bool) {
+	loc, ok := f[ip]
+	loc.IPAddress = ip
+	return loc, ok
+}
+
+func TestLoginLocationsHandler(t *testing.T) {
+	s, mem, accountID := newTestServer(t)
+	s.geo = fakeLocator{
+		"198.51.100.1": {CountryCode: "DE", Country: "Germany", City: "Berlin"},
+		"203.0.113.9":  {CountryCode: "FR", Country: "France", City: "Paris"},
+	}
+	a := mem.Archive(accountID)
+	a.Logins = []models.LoginHistory{
+		{ID: 4, IPAddress: "192.0.2.1", LoggedInAt: time.Date(2024, 4, 2, 0, 0, 0, 0, time.UTC)},
+		{ID: 3, IPAddress: "203.0.113.9", LoggedInAt: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{ID: 2, IPAddress: "198.51.100.1", LoggedInAt: day(10)},
+		{ID: 1, IPAddress: "198.51.100.1", LoggedInAt: day(1)},
+	}
+	a.SignupInfo = &models.SignupInfo{SignupIP: "198.51.100.1"}
+
+	rec := serveAs(testUserID, s.getLoginLocationsHandler, httptest.NewRequest(http.MethodGet, "/api/v1/insights/login-locations", nil))
+	body := decodeBody[struct {
+		GeoIPEnabled bool `json:"geoip_enabled"`
+		Summary      struct {
+			Logins    int `json:"logins"`
+			Located   int `json:"located"`
+			Countries int `json:"countries"`
+		} `json:"summary"`
+		Signup struct {
+			Location *models.IPLocation `json:"location"`
+		} `json:"signup"`
+		Places []loginPlace    `json:"places"`
+		Months []locationMonth `json:"months"`
+		Logins []locatedLogin  `json:"logins"`
+	}](t, rec)
+	if !body.GeoIPEnabled || body.Summary.Logins != 4 || body.Summary.Located != 3 || body.Summary.Countries != 2 {
+		t.Errorf("summary = %+v", body.Summary)
+	}
+	if body.Signup.Location == nil || body.Signup.Location.City != "Berlin" {
+		t.Errorf("signup = %+v", body.Signup)
+	}
+	if len(body.Places) != 2 || body.Places[0].City != "Berlin" || body.Places[0].Logins != 2 || !body.Places[0].LastSeen.Equal(day(10)) {
+		t.Errorf("places = %+v", body.Places)
+	}
+	if len(body.Months) != 2 || body.Months[0].Month != "2024-03" || body.Months[1].Places[0].City != "Paris" {
+		t.Errorf("months = %+v", body.Months)
+	}
+	// an address the database doesn't know stays unplaced
+	if len(body.Logins) != 4 || body.Logins[0].Location != nil || body.Logins[1].Location.Country != "France" {
+		t.Errorf("logins = %+v", body.Logins)
+	}
+	if saved, _ := s.store.Security.ListIPLocations(t.Context(), accountID); len(saved) != 2 {
+		t.Errorf("saved locations = %+v", saved)
+	}
+}
+
+// A nil database must leave s.geo a nil interface, not one holding a nil *geoip.DB.
+func TestEnableGeoIPWithoutDatabase(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	s.EnableGeoIP(nil)
+	if s.geo != nil {
+		t.Errorf("geo = %#v, want nil", s.geo)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"archive/zip"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -14,6 +15,7 @@ import (
 	"time"
 
 	"github.com/Sa-Te/IAV/backend/internal/models"
+	"github.com/Sa-Te/IAV/backend/internal/store"
 	"github.com/golang-jwt/jwt/v5"
 
 	"golang.org/x/crypto/bcrypt"
@@ -28,7 +30,7 @@ type AdInterestsResponse struct {
 }
 
 // FileProcessor defines the signature for any function that can process a specific file from the Instagram archive.
-type FileProcessor func(s *APIServer, path string, userID int) error
+type FileProcessor func(s *APIServer, path string, userID, accountID int) error
 
 // processorMap maps a filename suffix to the appropriate processor function.
 // This is the core of our refactoring. To support a new file, you just add an entry here.
@@ -109,7 +111,7 @@ var processorMap = map[string]FileProcessor{
 
 // processArchive is now a simple dispatcher. Its only responsibility is to walk the directory
 // and delegate the actual file processing to the correct function from the processorMap.
-func (s *APIServer) processArchive(rootPath string, userID int) {
+func (s *APIServer) processArchive(rootPath string, userID, accountID int) {
 	log.Println("----Starting to process unzipped archive at:", rootPath)
 
 	err := filepath.Walk(rootPath, func(path string, info os.FileInfo, err error) error {
@@ -125,7 +127,7 @@ func (s *APIServer) processArchive(rootPath string, userID int) {
 		filename := filepath.Base(path)
 		if strings.HasPrefix(filename, "message_") && strings.HasSuffix(filename, ".json") &&
 			(strings.Contains(path, "/messages/inbox/") || strings.Contains(path, "/messages/message_requests/")) {
-			if err := s.processMessageFile(path, userID); err != nil {
+			if err := s.processMessageFile(path, userID, accountID); err != nil {
 				log.Printf("ERROR processing message file %s: %v", path, err)
 			}
 			return nil
@@ -135,7 +137,7 @@ func (s *APIServer) processArchive(rootPath string, userID int) {
 		for suffix, processor := range processorMap {
 			if strings.HasSuffix(path, suffix) {
 				log.Printf("Found '%s', dispatching to its processor.", suffix)
-				if err := processor(s, path, userID); err != nil {
+				if err := processor(s, path, userID, accountID); err != nil {
 					log.Printf("ERROR processing file %s: %v", path, err)
 				}
 				return nil
@@ -155,7 +157,7 @@ func (s *APIServer) processArchive(rootPath string, userID int) {
 // Each function below has a single responsibility: to parse one specific JSON file
 // and insert its data into the database. They all implement the `FileProcessor` type.
 
-func (s *APIServer) processPosts(path string, userID int) error {
+func (s *APIServer) processPosts(path string, userID, accountID int) error {
 	file, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("failed to open posts file from disk: %w", err)
@@ -171,21 +173,20 @@ func (s *APIServer) processPosts(path string, userID int) error {
 	}
 
 	log.Println("--- Inserting/Updating Posts in Database ---")
+	var items []models.MediaItem
 	for _, wrapper := range postWrappers {
 		for _, post := range wrapper.Media {
-			sqlStatement := `INSERT INTO media_items (user_id, uri, caption, taken_at, media_type) VALUES ($1, $2, $3, $4, $5) ON CONFLICT (user_id, uri) DO NOTHING;`
-			takenAt := time.Unix(post.CreationTimeStamp, 0)
-			_, err := s.db.Exec(context.Background(), sqlStatement, userID, post.URI, post.Title, takenAt, "post")
-			if err != nil {
-				log.Printf("Failed to insert post with URI %s: %v\n", post.URI, err)
-			}
+			items = append(items, models.MediaItem{URI: post.URI, Caption: post.Title, TakenAt: time.Unix(post.CreationTimeStamp, 0), MediaType: "post"})
 		}
 	}
+	if err := s.store.Media.SaveMedia(context.Background(), userID, accountID, items); err != nil {
+		log.Printf("Failed to insert posts: %v\n", err)
+	}
 	log.Println("--- Finished Processing Posts ---")
 	return nil
 }
 
-func (s *APIServer) processStories(path string, userID int) error {
+func (s *APIServer) processStories(path string, userID, accountID int) error {
 	file, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("failed to open stories file from disk: %w", err)
@@ -201,19 +202,18 @@ func (s *APIServer) processStories(path string, userID int) error {
 	}
 
 	log.Println("--- Inserting Stories into Database ---")
+	var items []models.MediaItem
 	for _, story := range storyWrapper.Stories {
-		sqlStatement := `INSERT INTO media_items (user_id, uri, caption, taken_at, media_type) VALUES ($1, $2, $3, $4, $5) ON CONFLICT (user_id, uri) DO NOTHING;`
-		takenAt := time.Unix(story.CreationTimeStamp, 0)
-		_, err := s.db.Exec(context.Background(), sqlStatement, userID, story.URI, story.Title, takenAt, "story")
-		if err != nil {
-			log.Printf("Failed to insert story with URI %s: %v\n", story.URI, err)
-		}
+		items = append(items, models.MediaItem{URI: story.URI, Caption: story.Title, TakenAt: time.Unix(story.CreationTimeStamp, 0), MediaType: "story"})
+	}
+	if err := s.store.Media.SaveMedia(context.Background(), userID, accountID, items); err != nil {
+		log.Printf("Failed to insert stories: %v\n", err)
 	}
 	log.Println("--- Finished Inserting Stories ---")
 	return nil
 }
 
-func (s *APIServer) processSyncedContacts(path string, userID int) error {
+func (s *APIServer) processSyncedContacts(path string, userID, accountID int) error {
 	file, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("failed to open synced_contacts.json: %w", err)
@@ -226,27 +226,39 @@ func (s *APIServer) processSyncedContacts(path string, userID int) error {
 	}
 
 	log.Println("--- Inserting Synced Contacts into Database ---")
+	var contacts []models.Connection
 	for _, contactItem := range contactsWrapper.ContactInfo {
 		contactName := strings.TrimSpace(contactItem.StringMapData.FirstName.Value + " " + contactItem.StringMapData.LastName.Value)
 		if contactName == "" {
 			continue
 		}
 		contactInfo := contactItem.StringMapData.ContactInfo.Value
-		sqlStatement := `
-            INSERT INTO connections (user_id, username, connection_type, timestamp, contact_info) 
-            VALUES ($1, $2, $3, $4, $5) 
-            ON CONFLICT (user_id, username, connection_type) 
-            DO UPDATE SET contact_info = EXCLUDED.contact_info;`
-		_, err := s.db.Exec(context.Background(), sqlStatement, userID, contactName, "contact", time.Now(), contactInfo)
-		if err != nil {
-			log.Printf("Failed to upsert contact %s: %v\n", contactName, err)
-		}
+		contacts = append(contacts, models.Connection{Username: contactName, ConnectionType: "contact", Timestamp: time.Now(), ContactInfo: &contactInfo})
 	}
+	s.saveConnections(userID, accountID, "contacts", contacts)
 	log.Println("--- Finished Processing Synced Contacts ---")
 	return nil
 }
 
-func (s *APIServer) processFollowers(path string, userID int) error {
+// relationshipConnections turns one of the archive's relationship lists into connections.
+func relationshipConnections(items []models.Relationship, connectionType string) []models.Connection {
+	var connections []models.Connection
+	for _, item := range items {
+		for _, stringData := range item.StringListData {
+			connections = append(connections, models.Connection{Username: stringData.Value, ConnectionType: connectionType, Timestamp: time.Unix(stringData.Timestamp, 0)})
+		}
+	}
+	return connections
+}
+
+// saveConnections stores a processor's connections, logging rather than failing on bad rows.
+func (s *APIServer) saveConnections(userID, accountID int, what string, connections []models.Connection) {
+	if err := s.store.Connections.SaveConnections(context.Background(), userID, accountID, connections); err != nil {
+		log.Printf("Failed to upsert %s: %v\n", what, err)
+	}
+}
+
+func (s *APIServer) processFollowers(path string, userID, accountID int) error {
 	file, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("failed to open followers_1.json: %w", err)
@@ -259,21 +271,12 @@ func (s *APIServer) processFollowers(path string, userID int) error {
 	}
 
 	log.Println("--- Inserting Followers into Database ---")
-	for _, item := range followers {
-		for _, stringData := range item.StringListData {
-			sqlStatement := `INSERT INTO connections (user_id, username, connection_type, timestamp) VALUES ($1, $2, $3, $4) ON CONFLICT (user_id, username, connection_type) DO NOTHING;`
-			timestamp := time.Unix(stringData.Timestamp, 0)
-			_, err := s.db.Exec(context.Background(), sqlStatement, userID, stringData.Value, "follower", timestamp)
-			if err != nil {
-				log.Printf("Failed to upsert follower %s: %v\n", stringData.Value, err)
-			}
-		}
-	}
+	s.saveConnections(userID, accountID, "followers", relationshipConnections(followers, "follower"))
 	log.Println("--- Finished Processing Followers ---")
 	return nil
 }
 
-func (s *APIServer) processFollowing(path string, userID int) error {
+func (s *APIServer) processFollowing(path string, userID, accountID int) error {
 	file, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("failed to open following.json: %w", err)
@@ -292,21 +295,12 @@ func (s *APIServer) processFollowing(path string, userID int) error {
 	}
 
 	log.Println("--- Inserting Following into Database ---")
-	for _, item := range following {
-		for _, stringData := range item.StringListData {
-			sqlStatement := `INSERT INTO connections (user_id, username, connection_type, timestamp) VALUES ($1, $2, $3, $4) ON CONFLICT (user_id, username, connection_type) DO NOTHING;`
-			timestamp := time.Unix(stringData.Timestamp, 0)
-			_, err := s.db.Exec(context.Background(), sqlStatement, userID, stringData.Value, "following", timestamp)
-			if err != nil {
-				log.Printf("Failed to upsert following %s: %v\n", stringData.Value, err)
-			}
-		}
-	}
+	s.saveConnections(userID, accountID, "following", relationshipConnections(following, "following"))
 	log.Println("--- Finished Processing Following ---")
 	return nil
 }
 
-func (s *APIServer) processBlockedProfiles(path string, userID int) error {
+func (s *APIServer) processBlockedProfiles(path string, userID, accountID int) error {
 	file, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("failed to open blocked_profiles.json: %w", err)
@@ -319,26 +313,18 @@ func (s *APIServer) processBlockedProfiles(path string, userID int) error {
 	}
 
 	log.Println("-----Inserting Blocked Profiles into DB-----")
+	var blocked []models.Connection
 	for _, user := range wrapper.BlockedUsers {
 		if len(user.StringData) > 0 {
-			username := user.Title
-			timestamp := time.Unix(user.StringData[0].Timestamp, 0)
-			sqlStatement := `
-				INSERT INTO connections (user_id, username, connection_type, timestamp) 
-				VALUES ($1, $2, $3, $4) 
-				ON CONFLICT (user_id, username, connection_type) 
-				DO UPDATE SET timestamp = EXCLUDED.timestamp;`
-			_, err := s.db.Exec(context.Background(), sqlStatement, userID, username, "blocked", timestamp)
-			if err != nil {
-				log.Printf("Failed to upsert blocked profile %s: %v\n", username, err)
-			}
+			blocked = append(blocked, models.Connection{Username: user.Title, ConnectionType: "blocked", Timestamp: time.Unix(user.StringData[0].Timestamp, 0)})
 		}
 	}
+	s.saveConnections(userID, accountID, "blocked profiles", blocked)
 	log.Println("--- Finished Processing Blocked Profiles ---")
 	return nil
 }
 
-func (s *APIServer) processCloseFriends(path string, userID int) error {
+func (s *APIServer) processCloseFriends(path string, userID, accountID int) error {
 	file, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("failed to open close_friends.json: %w", err)
@@ -351,26 +337,12 @@ func (s *APIServer) processCloseFriends(path string, userID int) error {
 	}
 
 	log.Println("--- Inserting Close Friends into Database ---")
-	for _, item := range wrapper.CloseFriends {
-		for _, stringData := range item.StringListData {
-			username := stringData.Value
-			timestamp := time.Unix(stringData.Timestamp, 0)
-			sqlStatement := `
-				INSERT INTO connections (user_id, username, connection_type, timestamp) 
-				VALUES ($1, $2, $3, $4) 
-				ON CONFLICT (user_id, username, connection_type) 
-				DO UPDATE SET timestamp = EXCLUDED.timestamp;`
-			_, err := s.db.Exec(context.Background(), sqlStatement, userID, username, "close_friend", timestamp)
-			if err != nil {
-				log.Printf("Failed to upsert close friend %s: %v\n", username, err)
-			}
-		}
-	}
+	s.saveConnections(userID, accountID, "close friends", relationshipConnections(wrapper.CloseFriends, "close_friend"))
 	log.Println("--- Finished Processing Close Friends ---")
 	return nil
 }
 
-func (s *APIServer) processFollowRequestsReceived(path string, userID int) error {
+func (s *APIServer) processFollowRequestsReceived(path string, userID, accountID int) error {
 	file, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("failed to open follow_requests_you've_received.json: %w", err)
@@ -383,24 +355,12 @@ func (s *APIServer) processFollowRequestsReceived(path string, userID int) error
 	}
 
 	log.Println("--- Inserting Received Follow Requests into Database ---")
-	for _, item := range wrapper.Requests {
-		for _, stringData := range item.StringListData {
-			username := stringData.Value
-			timestamp := time.Unix(stringData.Timestamp, 0)
-			sqlStatement := `
-				INSERT INTO connections (user_id, username, connection_type, timestamp) VALUES ($1, $2, $3, $4) 
-				ON CONFLICT (user_id, username, connection_type) DO UPDATE SET timestamp = EXCLUDED.timestamp;`
-			_, err := s.db.Exec(context.Background(), sqlStatement, userID, username, "request_received", timestamp)
-			if err != nil {
-				log.Printf("Failed to upsert received request from %s: %v\n", username, err)
-			}
-		}
-	}
+	s.saveConnections(userID, accountID, "received requests", relationshipConnections(wrapper.Requests, "request_received"))
 	log.Println("--- Finished Processing Received Follow Requests ---")
 	return nil
 }
 
-func (s *APIServer) processHideStoryFrom(path string, userID int) error {
+func (s *APIServer) processHideStoryFrom(path string, userID, accountID int) error {
 	file, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("failed to open hide_story_from.json: %w", err)
@@ -413,24 +373,12 @@ func (s *APIServer) processHideStoryFrom(path string, userID int) error {
 	}
 
 	log.Println("--- Inserting Hide Story From into Database ---")
-	for _, item := range wrapper.HiddenFrom {
-		for _, stringData := range item.StringListData {
-			username := stringData.Value
-			timestamp := time.Unix(stringData.Timestamp, 0)
-			sqlStatement := `
-				INSERT INTO connections (user_id, username, connection_type, timestamp) VALUES ($1, $2, $3, $4) 
-				ON CONFLICT (user_id, username, connection_type) DO UPDATE SET timestamp = EXCLUDED.timestamp;`
-			_, err := s.db.Exec(context.Background(), sqlStatement, userID, username, "story_hidden_from", timestamp)
-			if err != nil {
-				log.Printf("Failed to upsert hide story from %s: %v\n", username, err)
-			}
-		}
-	}
+	s.saveConnections(userID, accountID, "hide story from", relationshipConnections(wrapper.HiddenFrom, "story_hidden_from"))
 	log.Println("--- Finished Processing Hide Story From ---")
 	return nil
 }
 
-func (s *APIServer) processFollowingHashtags(path string, userID int) error {
+func (s *APIServer) processFollowingHashtags(path string, userID, accountID int) error {
 	file, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("failed to open following_hashtags.json: %w", err)
@@ -443,24 +391,20 @@ func (s *APIServer) processFollowingHashtags(path string, userID int) error {
 	}
 
 	log.Println("--- Inserting Followed Hashtags into Database ---")
+	var hashtags []models.FollowedHashtag
 	for _, item := range wrapper.Hashtags {
 		for _, stringData := range item.StringListData {
-			hashtagName := stringData.Value
-			timestamp := time.Unix(stringData.Timestamp, 0)
-			sqlStatement := `
-				INSERT INTO followed_hashtags (user_id, name, timestamp) VALUES ($1, $2, $3) 
-				ON CONFLICT (user_id, name) DO UPDATE SET timestamp = EXCLUDED.timestamp;`
-			_, err := s.db.Exec(context.Background(), sqlStatement, userID, hashtagName, timestamp)
-			if err != nil {
-				log.Printf("Failed to upsert followed hashtag #%s: %v\n", hashtagName, err)
-			}
+			hashtags = append(hashtags, models.FollowedHashtag{Name: stringData.Value, Timestamp: time.Unix(stringData.Timestamp, 0)})
 		}
 	}
+	if err := s.store.Connections.SaveFollowedHashtags(context.Background(), userID, accountID, hashtags); err != nil {
+		log.Printf("Failed to upsert followed hashtags: %v\n", err)
+	}
 	log.Println("--- Finished Processing Followed Hashtags ---")
 	return nil
 }
 
-func (s *APIServer) processPendingFollowRequests(path string, userID int) error {
+func (s *APIServer) processPendingFollowRequests(path string, userID, accountID int) error {
 	file, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("failed to open pending_follow_requests.json: %w", err)
@@ -473,24 +417,12 @@ func (s *APIServer) processPendingFollowRequests(path string, userID int) error
 	}
 
 	log.Println("--- Processing Sent Follow Requests ---")
-	for _, item := range wrapper.Requests {
-		for _, stringData := range item.StringListData {
-			username := stringData.Value
-			timestamp := time.Unix(stringData.Timestamp, 0)
-			sqlStatement := `
-				INSERT INTO connections (user_id, username, connection_type, timestamp) VALUES ($1, $2, $3, $4) 
-				ON CONFLICT (user_id, username, connection_type) DO UPDATE SET timestamp = EXCLUDED.timestamp;`
-			_, err := s.db.Exec(context.Background(), sqlStatement, userID, username, "request_sent", timestamp)
-			if err != nil {
-				log.Printf("failed to upsert sent request to %s: %v\n", username, err)
-			}
-		}
-	}
+	s.saveConnections(userID, accountID, "sent requests", relationshipConnections(wrapper.Requests, "request_sent"))
 	log.Println("--- Finished Processing Sent Follow Requests ---")
 	return nil
 }
 
-func (s *APIServer) processRecentFollowRequests(path string, userID int) error {
+func (s *APIServer) processRecentFollowRequests(path string, userID, accountID int) error {
 	file, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("failed to open recent_follow_requests.json: %w", err)
@@ -503,24 +435,12 @@ func (s *APIServer) processRecentFollowRequests(path string, userID int) error {
 	}
 
 	log.Println("--- Processing Permanent/Recent Follow Requests ---")
-	for _, item := range wrapper.Requests {
-		for _, stringData := range item.StringListData {
-			username := stringData.Value
-			timestamp := time.Unix(stringData.Timestamp, 0)
-			sqlStatement := `
-				INSERT INTO connections (user_id, username, connection_type, timestamp) VALUES ($1, $2, $3, $4) 
-				ON CONFLICT (user_id, username, connection_type) DO UPDATE SET timestamp = EXCLUDED.timestamp;`
-			_, err := s.db.Exec(context.Background(), sqlStatement, userID, username, "request_sent_permanent", timestamp)
-			if err != nil {
-				log.Printf("failed to upsert permanent sent request to %s: %v\n", username, err)
-			}
-		}
-	}
+	s.saveConnections(userID, accountID, "permanent sent requests", relationshipConnections(wrapper.Requests, "request_sent_permanent"))
 	log.Println("--- Finished Processing Permanent/Recent Follow Requests ---")
 	return nil
 }
 
-func (s *APIServer) processRecentlyUnfollowed(path string, userID int) error {
+func (s *APIServer) processRecentlyUnfollowed(path string, userID, accountID int) error {
 	file, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("failed to open recently_unfollowed_profiles.json: %w", err)
@@ -533,24 +453,12 @@ func (s *APIServer) processRecentlyUnfollowed(path string, userID int) error {
 	}
 
 	log.Println("--- Processing Unfollowed Users ---")
-	for _, item := range wrapper.Unfollowed {
-		for _, stringData := range item.StringListData {
-			username := stringData.Value
-			timestamp := time.Unix(stringData.Timestamp, 0)
-			sqlStatement := `
-				INSERT INTO connections (user_id, username, connection_type, timestamp) VALUES ($1, $2, $3, $4) 
-				ON CONFLICT (user_id, username, connection_type) DO UPDATE SET timestamp = EXCLUDED.timestamp;`
-			_, err := s.db.Exec(context.Background(), sqlStatement, userID, username, "unfollowed", timestamp)
-			if err != nil {
-				log.Printf("failed to upsert unfollowed user %s: %v\n", username, err)
-			}
-		}
-	}
+	s.saveConnections(userID, accountID, "unfollowed users", relationshipConnections(wrapper.Unfollowed, "unfollowed"))
 	log.Println("--- Finished Processing Unfollowed Users ---")
 	return nil
 }
 
-func (s *APIServer) processRemovedSuggestions(path string, userID int) error {
+func (s *APIServer) processRemovedSuggestions(path string, userID, accountID int) error {
 	file, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("failed to open removed_suggestions.json: %w", err)
@@ -563,24 +471,12 @@ func (s *APIServer) processRemovedSuggestions(path string, userID int) error {
 	}
 
 	log.Println("--- Processing Removed Suggestions ---")
-	for _, item := range wrapper.Dismissed {
-		for _, stringData := range item.StringListData {
-			username := stringData.Value
-			timestamp := time.Unix(stringData.Timestamp, 0)
-			sqlStatement := `
-				INSERT INTO connections (user_id, username, connection_type, timestamp) VALUES ($1, $2, $3, $4) 
-				ON CONFLICT (user_id, username, connection_type) DO UPDATE SET timestamp = EXCLUDED.timestamp;`
-			_, err := s.db.Exec(context.Background(), sqlStatement, userID, username, "suggestion_removed", timestamp)
-			if err != nil {
-				log.Printf("failed to upsert removed suggestion %s: %v\n", username, err)
-			}
-		}
-	}
+	s.saveConnections(userID, accountID, "removed suggestions", relationshipConnections(wrapper.Dismissed, "suggestion_removed"))
 	log.Println("--- Finished Processing Removed Suggestions ---")
 	return nil
 }
 
-func (s *APIServer) processRestrictedProfiles(path string, userID int) error {
+func (s *APIServer) processRestrictedProfiles(path string, userID, accountID int) error {
 	file, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("failed to open restricted_profiles.json: %w", err)
@@ -593,24 +489,12 @@ func (s *APIServer) processRestrictedProfiles(path string, userID int) error {
 	}
 
 	log.Println("--- Processing Restricted Profiles ---")
-	for _, item := range wrapper.Restricted {
-		for _, stringData := range item.StringListData {
-			username := stringData.Value
-			timestamp := time.Unix(stringData.Timestamp, 0)
-			sqlStatement := `
-				INSERT INTO connections (user_id, username, connection_type, timestamp) VALUES ($1, $2, $3, $4) 
-				ON CONFLICT (user_id, username, connection_type) DO UPDATE SET timestamp = EXCLUDED.timestamp;`
-			_, err := s.db.Exec(context.Background(), sqlStatement, userID, username, "restricted", timestamp)
-			if err != nil {
-				log.Printf("failed to upsert restricted user %s: %v\n", username, err)
-			}
-		}
-	}
+	s.saveConnections(userID, accountID, "restricted users", relationshipConnections(wrapper.Restricted, "restricted"))
 	log.Println("--- Finished Processing Restricted Profiles ---")
 	return nil
 }
 
-func (s *APIServer) processAdvertisers(path string, userID int) error {
+func (s *APIServer) processAdvertisers(path string, userID, accountID int) error {
 	file, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("failed to open advertisers file from disk: %w", err)
@@ -623,18 +507,18 @@ func (s *APIServer) processAdvertisers(path string, userID int) error {
 	}
 
 	log.Println("--- Inserting Ad Advertisers into Database ---")
+	var names []string
 	for _, ad := range wrapper.CustomAudiences {
-		sqlStatement := `INSERT INTO ad_advertisers (user_id, advertiser_name) VALUES ($1, $2) ON CONFLICT (user_id, advertiser_name) DO NOTHING;`
-		_, err := s.db.Exec(context.Background(), sqlStatement, userID, ad.AdvertiserName)
-		if err != nil {
-			log.Printf("Failed to insert ad advertiser %s: %v\n", ad.AdvertiserName, err)
-		}
+		names = append(names, ad.AdvertiserName)
+	}
+	if err := s.store.Interests.SaveAdvertisers(context.Background(), userID, accountID, names); err != nil {
+		log.Printf("Failed to insert ad advertisers: %v\n", err)
 	}
 	log.Println("--- Finished Processing Ad Advertisers ---")
 	return nil
 }
 
-func (s *APIServer) processAdTopics(path string, userID int) error {
+func (s *APIServer) processAdTopics(path string, userID, accountID int) error {
 	file, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("failed to open ad topics file from disk: %w", err)
@@ -647,17 +531,17 @@ func (s *APIServer) processAdTopics(path string, userID int) error {
 	}
 
 	log.Println("--- Inserting Ad Topics into Database ---")
+	var names []string
 	for _, label := range wrapper.LabelValues {
 		if label.Label == "Name" { // Ensure we're only getting the topics under the "Name" label
 			for _, topic := range label.Vec {
-				sqlStatement := `INSERT INTO ad_topics (user_id, topic_name) VALUES ($1, $2) ON CONFLICT (user_id, topic_name) DO NOTHING;`
-				_, err := s.db.Exec(context.Background(), sqlStatement, userID, topic.Value)
-				if err != nil {
-					log.Printf("Failed to insert ad topic %s: %v\n", topic.Value, err)
-				}
+				names = append(names, topic.Value)
 			}
 		}
 	}
+	if err := s.store.Interests.SaveAdTopics(context.Background(), userID, accountID, names); err != nil {
+		log.Printf("Failed to insert ad topics: %v\n", err)
+	}
 	log.Println("--- Finished Processing Ad Topics ---")
 	return nil
 }
@@ -670,11 +554,6 @@ func writeJSONError(w http.ResponseWriter, statusCode int, message string) {
 }
 
 func (s *APIServer) loginHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	//decode the request and put it into a new user struct
 	var reqBody struct {
 		Email    string `json:"email"`
@@ -687,35 +566,42 @@ func (s *APIServer) loginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var userId int
-	var storedHash string
-	sqlStatement := `SELECT id, password_hash FROM users WHERE email= $1`
-
-	//get the single row from DB
-	err = s.db.QueryRow(context.Background(), sqlStatement, reqBody.Email).Scan(&userId, &storedHash)
+	user, err := s.store.Users.UserByEmail(r.Context(), reqBody.Email)
 	if err != nil {
 		// This handles both "user not found" and other database errors.
 		writeJSONError(w, http.StatusUnauthorized, "Invalid Email or Password")
 		return
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(storedHash), []byte(reqBody.Password))
+	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(reqBody.Password))
 
 	if err != nil {
 		writeJSONError(w, http.StatusUnauthorized, "Invalid Email or Password")
 		return
 	}
 
-	//create a token with claims
-	claims := jwt.MapClaims{
-		"userID": userId,
-		"exp":    time.Now().Add(time.Hour * 24).Unix(),
+	if user.DisabledAt != nil {
+		writeJSONError(w, http.StatusForbidden, "This account has been disabled")
+		return
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
-	//sign the token
-	var secretKey = []byte("complete-random-string-that-is-ver-long")
-	tokenString, err := token.SignedString(secretKey)
+	// With 2FA on, the password only earns a short-lived challenge token that
+	// must be exchanged at /api/v1/login/2fa together with a TOTP or recovery code.
+	if user.TOTPEnabled {
+		challenge, err := issueTwoFactorChallenge(user.ID)
+		if err != nil {
+			http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"two_factor_required": true,
+			"challenge_token":     challenge,
+		})
+		return
+	}
+
+	tokenString, err := issueSessionToken(user.ID)
 	if err != nil {
 		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
 		return
@@ -726,12 +612,20 @@ func (s *APIServer) loginHandler(w http.ResponseWriter, r *http.Request) {
 
 }
 
-func (s *APIServer) registerHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
+// issueSessionToken creates the 24h JWT used as a Bearer token by the frontend.
+func issueSessionToken(userID int) (string, error) {
+	//create a token with claims
+	claims := jwt.MapClaims{
+		"userID": userID,
+		"exp":    time.Now().Add(time.Hour * 24).Unix(),
 	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
+	//sign the token
+	return token.SignedString(jwtSecretKey)
+}
+
+func (s *APIServer) registerHandler(w http.ResponseWriter, r *http.Request) {
 	type requestBody struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
@@ -752,24 +646,22 @@ func (s *APIServer) registerHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sqlStatement := `INSERT INTO users(email, password_hash) VALUES ($1, $2)`
-	_, err = s.db.Exec(context.Background(), sqlStatement, body.Email, string(hashedPass))
+	userID, err := s.store.Users.CreateUser(r.Context(), body.Email, string(hashedPass))
 	if err != nil {
 		http.Error(w, "Failed to create user", http.StatusInternalServerError)
 		return
 	}
 
+	if _, err := s.ensureDefaultAccount(r.Context(), userID); err != nil {
+		log.Printf("Failed to create default account for user %d: %v", userID, err)
+	}
+
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]string{"message": "User created successfully"})
 }
 
 func (s *APIServer) uploadHandler(w http.ResponseWriter, r *http.Request) {
 	//read the uploaded file
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	userID, ok := r.Context().Value(userIDKey).(int)
 	if !ok {
 		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
@@ -782,15 +674,21 @@ func (s *APIServer) uploadHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	file, _, err := r.FormFile("archiveFile")
+	// the archive is attached to the Instagram account chosen in the `account` form field
+	accountID, ok := s.accountFromRequest(w, r, userID)
+	if !ok {
+		return
+	}
+
+	file, header, err := r.FormFile("archiveFile")
 	if err != nil {
 		http.Error(w, "Invalid file key. Expected 'archiveFile'.", http.StatusBadRequest)
 		return
 	}
 	defer file.Close()
 
-	//dedicated directory for user's unzipped files
-	userUploadDir := fmt.Sprintf("uploads/%d", userID)
+	//dedicated directory for the account's unzipped files
+	userUploadDir := accountUploadDir(userID, accountID)
 	if err := os.MkdirAll(userUploadDir, os.ModePerm); err != nil {
 		log.Printf("Failed to create user upload directory: %v", err)
 		http.Error(w, "Failed to process file on server.", http.StatusInternalServerError)
@@ -825,8 +723,26 @@ func (s *APIServer) uploadHandler(w http.ResponseWriter, r *http.Request) {
 	// Defer removal of temp zip after successful unzip
 	defer os.Remove(tempZipPath)
 
+	// connection rows this archive touches get a last_seen_at from here on
+	started, err := s.store.Imports.Now(context.Background())
+	if err != nil {
+		http.Error(w, "Failed to process archive.", http.StatusInternalServerError)
+		return
+	}
+
 	// Call our new, clean processor
-	s.processArchive(userUploadDir, userID)
+	s.processArchive(userUploadDir, userID, accountID)
+
+	importID, err := s.store.Imports.CreateImport(context.Background(), userID, accountID, header.Size)
+	if err != nil {
+		log.Printf("Failed to record import for user %d: %v", userID, err)
+	} else if err := s.store.Snapshots.SaveSnapshot(context.Background(), accountID, importID, started); err != nil {
+		log.Printf("Failed to snapshot connections of import %d: %v", importID, err)
+	}
+	if err := s.locateIPs(context.Background(), accountID); err != nil {
+		log.Printf("Failed to locate IP addresses of account %d: %v", accountID, err)
+	}
+	s.cache.invalidateAccount(accountID)
 
 	//send back success message
 	w.WriteHeader(http.StatusOK)
@@ -840,24 +756,16 @@ func (s *APIServer) getHashtagsHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
 		return
 	}
+	accountID, ok := s.accountFromRequest(w, r, userID)
+	if !ok {
+		return
+	}
 
-	sqlStatement := `SELECT id, user_id, name, timestamp FROM followed_hashtags WHERE user_id=$1 ORDER BY name ASC`
-	rows, err := s.db.Query(context.Background(), sqlStatement, userID)
+	hashtags, err := s.store.Connections.ListFollowedHashtags(r.Context(), accountID)
 	if err != nil {
 		http.Error(w, "Failed to get followed hashtags", http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
-
-	hashtags := make([]models.FollowedHashtag, 0)
-	for rows.Next() {
-		var h models.FollowedHashtag
-		if err := rows.Scan(&h.ID, &h.UserID, &h.Name, &h.Timestamp); err != nil {
-			log.Printf("Failed to scan hashtag row: %v", err)
-			continue
-		}
-		hashtags = append(hashtags, h)
-	}
 
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
@@ -870,31 +778,31 @@ func (s *APIServer) getConnectionsHandler(w http.ResponseWriter, r *http.Request
 		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
 		return
 	}
+	accountID, ok := s.accountFromRequest(w, r, userID)
+	if !ok {
+		return
+	}
 
-	sqlStatement := `SELECT id, user_id, username, connection_type, timestamp, contact_info FROM connections WHERE user_id=$1`
+	pg, ok := pageFromRequest(w, r)
+	if !ok {
+		return
+	}
 
-	rows, err := s.db.Query(context.Background(), sqlStatement, userID)
+	q, ok := listQueryFromRequest(w, r, connectionsListSpec)
+	if !ok {
+		return
+	}
+
+	connections, err := s.store.Connections.ListConnections(r.Context(), accountID, listOptions(q, pg))
 	if err != nil {
 		log.Printf("Database query error in getConnectionsHandler: %v", err)
 		http.Error(w, "Failed to get connections", http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
-
-	connections := make([]models.Connection, 0)
-	for rows.Next() {
-		var conn models.Connection
-		err := rows.Scan(&conn.ID, &conn.UserID, &conn.Username, &conn.ConnectionType, &conn.Timestamp, &conn.ContactInfo)
-		if err != nil {
-			log.Printf("Failed to scan connection row: %v", err)
-			continue
-		}
-		connections = append(connections, conn)
-	}
 
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(connections)
+	json.NewEncoder(w).Encode(newPage(connections, pg, func(c models.Connection) pageCursor { return pageCursor{At: c.Timestamp, Key: c.Username, ID: c.ID} }))
 }
 
 func (s *APIServer) getMediaItemsHandler(w http.ResponseWriter, r *http.Request) {
@@ -903,34 +811,30 @@ func (s *APIServer) getMediaItemsHandler(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
 		return
 	}
-
-	sqlStatement := `SELECT id, user_id, uri, caption, taken_at, media_type FROM media_items WHERE user_id=$1`
-
-	rows, err := s.db.Query(context.Background(), sqlStatement, userID)
-	if err != nil {
-		http.Error(w, "Failed to get media items", http.StatusInternalServerError)
+	accountID, ok := s.accountFromRequest(w, r, userID)
+	if !ok {
 		return
 	}
-	defer rows.Close()
-
-	mediaItems := make([]models.MediaItem, 0)
-
-	for rows.Next() {
-		var item models.MediaItem
 
-		err := rows.Scan(&item.ID, &item.UserID, &item.URI, &item.Caption, &item.TakenAt, &item.MediaType)
-		if err != nil {
-			log.Printf("Failed to scan row: %v", err)
-			continue //skip the row if error
-		}
+	pg, ok := pageFromRequest(w, r)
+	if !ok {
+		return
+	}
 
-		mediaItems = append(mediaItems, item)
+	q, ok := listQueryFromRequest(w, r, mediaListSpec)
+	if !ok {
+		return
+	}
 
+	mediaItems, err := s.store.Media.ListMedia(r.Context(), accountID, listOptions(q, pg))
+	if err != nil {
+		http.Error(w, "Failed to get media items", http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(mediaItems)
+	json.NewEncoder(w).Encode(newPage(mediaItems, pg, func(m models.MediaItem) pageCursor { return pageCursor{At: m.TakenAt, ID: m.ID} }))
 }
 
 func (s *APIServer) serveMediaFileHandler(w http.ResponseWriter, r *http.Request) {
@@ -940,15 +844,43 @@ func (s *APIServer) serveMediaFileHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	//extract file path from URL and trim prefix to get relative path
-	URLfilePath := strings.TrimPrefix(r.URL.Path, "/api/v1/mediafile/")
+	accountID, ok := s.accountFromRequest(w, r, userId)
+	if !ok {
+		return
+	}
 
-	//construct full, safe path; prevents user from accessing files from other directory
-	fullPath := filepath.Join("uploads", fmt.Sprintf("%d", userId), URLfilePath)
+	//relative path of the file inside the archive
+	URLfilePath := r.PathValue("path")
 
+	fullPath, err := s.mediaFilePath(r.Context(), userId, accountID, URLfilePath)
+	if err != nil {
+		log.Printf("Failed to resolve media file for user %d: %v", userId, err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to look up media file")
+		return
+	}
 	http.ServeFile(w, r, fullPath)
 }
 
+// mediaFilePath maps a path relative to an archive root onto the account's upload dir.
+func (s *APIServer) mediaFilePath(ctx context.Context, userID, accountID int, relPath string) (string, error) {
+	//construct full, safe path; prevents user from accessing files from other directory
+	fullPath := filepath.Join(accountUploadDir(userID, accountID), filepath.Clean("/"+relPath))
+	if _, err := os.Stat(fullPath); !os.IsNotExist(err) {
+		return fullPath, nil
+	}
+
+	// archives uploaded before multi-account support were unzipped straight into
+	// uploads/<userID>; they belong to the default account, so only it looks there
+	defaultID, err := s.ensureDefaultAccount(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if accountID == defaultID {
+		fullPath = filepath.Join("uploads", fmt.Sprintf("%d", userID), filepath.Clean("/"+relPath))
+	}
+	return fullPath, nil
+}
+
 func unzip(src, dest string) error {
 	r, err := zip.OpenReader(src)
 	if err != nil {
@@ -1002,87 +934,70 @@ func (s *APIServer) getAdInterestsHandler(w http.ResponseWriter, r *http.Request
 		writeJSONError(w, http.StatusInternalServerError, "Could not determine user.")
 		return
 	}
-
-	response := AdInterestsResponse{
-		Advertisers: make([]string, 0),
-		Topics:      make([]string, 0),
+	accountID, ok := s.accountFromRequest(w, r, userID)
+	if !ok {
+		return
 	}
+
+	var response AdInterestsResponse
 	var err error
 
-	// Fetch Advertisers
-	advRows, err := s.db.Query(context.Background(), `SELECT advertiser_name FROM ad_advertisers WHERE user_id=$1`, userID)
+	response.Advertisers, err = s.store.Interests.ListAdvertisers(r.Context(), accountID)
 	if err != nil {
 		log.Printf("ERROR fetching advertisers for user %d: %v", userID, err)
 		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve ad interests.")
 		return
 	}
-	defer advRows.Close()
-
-	for advRows.Next() {
-		var name string
-		if err := advRows.Scan(&name); err == nil {
-			response.Advertisers = append(response.Advertisers, name)
-		}
-	}
 
-	// Fetch Topics
-	topicRows, err := s.db.Query(context.Background(), `SELECT topic_name FROM ad_topics WHERE user_id=$1`, userID)
+	response.Topics, err = s.store.Interests.ListAdTopics(r.Context(), accountID)
 	if err != nil {
 		log.Printf("ERROR fetching topics for user %d: %v", userID, err)
 		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve ad interests.")
 		return
 	}
-	defer topicRows.Close()
-
-	for topicRows.Next() {
-		var name string
-		if err := topicRows.Scan(&name); err == nil {
-			response.Topics = append(response.Topics, name)
-		}
-	}
 
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(response)
 }
 
-func (s *APIServer) processAdsViewed(path string, userID int) error {
+func (s *APIServer) processAdsViewed(path string, userID, accountID int) error {
 	var wrapper models.AdsViewedWrapper
 	if err := decodeActivityFile(path, &wrapper); err != nil {
 		return err
 	}
 	log.Println("--- Inserting Ads Viewed into Database ---")
-	return s.insertActivityImpressions(userID, "ad_viewed", wrapper.Impressions)
+	return s.insertActivityImpressions(userID, accountID, "ad_viewed", wrapper.Impressions)
 }
 
-func (s *APIServer) processPostsViewed(path string, userID int) error {
+func (s *APIServer) processPostsViewed(path string, userID, accountID int) error {
 	var wrapper models.PostsViewedWrapper
 	if err := decodeActivityFile(path, &wrapper); err != nil {
 		return err
 	}
 	log.Println("--- Inserting Posts Viewed into Database ---")
-	return s.insertActivityImpressions(userID, "post_viewed", wrapper.Impressions)
+	return s.insertActivityImpressions(userID, accountID, "post_viewed", wrapper.Impressions)
 }
 
-func (s *APIServer) processVideosWatched(path string, userID int) error {
+func (s *APIServer) processVideosWatched(path string, userID, accountID int) error {
 	var wrapper models.VideosWatchedWrapper
 	if err := decodeActivityFile(path, &wrapper); err != nil {
 		return err
 	}
 	log.Println("--- Inserting Videos Watched into Database ---")
-	return s.insertActivityImpressions(userID, "video_watched", wrapper.Impressions)
+	return s.insertActivityImpressions(userID, accountID, "video_watched", wrapper.Impressions)
 }
 
-func (s *APIServer) processSuggestedProfilesViewed(path string, userID int) error {
+func (s *APIServer) processSuggestedProfilesViewed(path string, userID, accountID int) error {
 	var wrapper models.SuggestedProfilesViewedWrapper
 	if err := decodeActivityFile(path, &wrapper); err != nil {
 		return err
 	}
 	log.Println("--- Inserting Suggested Profiles Viewed into Database ---")
-	return s.insertActivityImpressions(userID, "suggested_profile_viewed", wrapper.Impressions)
+	return s.insertActivityImpressions(userID, accountID, "suggested_profile_viewed", wrapper.Impressions)
 }
 
-func (s *APIServer) processPostsNotInterested(path string, userID int) error {
+func (s *APIServer) processPostsNotInterested(path string, userID, accountID int) error {
 	file, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("failed to open file %s: %w", path, err)
@@ -1095,8 +1010,7 @@ func (s *APIServer) processPostsNotInterested(path string, userID int) error {
 	}
 
 	log.Println("--- Inserting 'Not Interested' Posts into Database ---")
-	sqlStatement := `INSERT INTO activity_log (user_id, activity_type, timestamp, details) VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING;`
-
+	var activity []models.ActivityLog
 	for _, item := range wrapper.Impressions {
 		var timestamp int64
 		var href string
@@ -1110,13 +1024,12 @@ func (s *APIServer) processPostsNotInterested(path string, userID int) error {
 		}
 
 		if timestamp != 0 {
-			ts := time.Unix(timestamp, 0)
-			_, err := s.db.Exec(context.Background(), sqlStatement, userID, "post_not_interested", ts, href)
-			if err != nil {
-				log.Printf("Failed to insert 'not interested' activity: %v", err)
-			}
+			activity = append(activity, models.ActivityLog{ActivityType: "post_not_interested", Timestamp: time.Unix(timestamp, 0), Details: &href})
 		}
 	}
+	if err := s.store.Activity.SaveActivity(context.Background(), userID, accountID, activity); err != nil {
+		log.Printf("Failed to insert 'not interested' activity: %v", err)
+	}
 	return nil
 }
 
@@ -1135,8 +1048,8 @@ func decodeActivityFile(path string, wrapper interface{}) error {
 }
 
 // Helper function to insert a batch of generic activity impressions
-func (s *APIServer) insertActivityImpressions(userID int, activityType string, impressions []models.ActivityImpression) error {
-	sqlStatement := `INSERT INTO activity_log (user_id, activity_type, author, timestamp) VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING;`
+func (s *APIServer) insertActivityImpressions(userID, accountID int, activityType string, impressions []models.ActivityImpression) error {
+	var activity []models.ActivityLog
 	for _, impression := range impressions {
 		author := impression.StringMapData.Author.Value
 		if author == "" {
@@ -1144,12 +1057,11 @@ func (s *APIServer) insertActivityImpressions(userID int, activityType string, i
 			author = impression.StringMapData.Username.Value
 		}
 
-		ts := time.Unix(impression.StringMapData.Timestamp.Timestamp, 0)
-		_, err := s.db.Exec(context.Background(), sqlStatement, userID, activityType, author, ts)
-		if err != nil {
-			// Log error but continue processing other entries
-			log.Printf("Failed to insert %s activity for author %s: %v", activityType, author, err)
-		}
+		activity = append(activity, models.ActivityLog{ActivityType: activityType, Author: &author, Timestamp: time.Unix(impression.StringMapData.Timestamp.Timestamp, 0)})
+	}
+	if err := s.store.Activity.SaveActivity(context.Background(), userID, accountID, activity); err != nil {
+		// Failed rows don't stop the others
+		log.Printf("Failed to insert %s activity: %v", activityType, err)
 	}
 	return nil
 }
@@ -1160,28 +1072,29 @@ func (s *APIServer) getActivityLogHandler(w http.ResponseWriter, r *http.Request
 		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
 		return
 	}
+	accountID, ok := s.accountFromRequest(w, r, userID)
+	if !ok {
+		return
+	}
 
-	rows, err := s.db.Query(context.Background(),
-		`SELECT id, user_id, activity_type, author, timestamp, details FROM activity_log WHERE user_id=$1 ORDER BY timestamp DESC LIMIT 5000`, userID)
+	pg, ok := pageFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	q, ok := listQueryFromRequest(w, r, activityListSpec)
+	if !ok {
+		return
+	}
+	activities, err := s.store.Activity.ListActivity(r.Context(), accountID, listOptions(q, pg))
 	if err != nil {
 		log.Printf("Failed to query activity log for user %d: %v", userID, err)
 		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve activity log")
 		return
 	}
-	defer rows.Close()
-
-	activities := make([]models.ActivityLog, 0)
-	for rows.Next() {
-		var a models.ActivityLog
-		if err := rows.Scan(&a.ID, &a.UserID, &a.ActivityType, &a.Author, &a.Timestamp, &a.Details); err != nil {
-			log.Printf("Failed to scan activity log row: %v", err)
-			continue
-		}
-		activities = append(activities, a)
-	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(activities)
+	json.NewEncoder(w).Encode(newPage(activities, pg, func(a models.ActivityLog) pageCursor { return pageCursor{At: a.Timestamp, ID: a.ID} }))
 }
 
 func (s *APIServer) getLikesHandler(w http.ResponseWriter, r *http.Request) {
@@ -1190,51 +1103,30 @@ func (s *APIServer) getLikesHandler(w http.ResponseWriter, r *http.Request) {
 		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
 		return
 	}
+	accountID, ok := s.accountFromRequest(w, r, userID)
+	if !ok {
+		return
+	}
 	type Response struct {
 		PostLikes    []models.PostLike    `json:"post_likes"`
 		CommentLikes []models.CommentLike `json:"comment_likes"`
 		StoryLikes   []models.StoryLike   `json:"story_likes"`
 	}
-	resp := Response{
-		PostLikes:    make([]models.PostLike, 0),
-		CommentLikes: make([]models.CommentLike, 0),
-		StoryLikes:   make([]models.StoryLike, 0),
-	}
+	var resp Response
 
-	rows, err := s.db.Query(context.Background(),
-		`SELECT id, user_id, creator_username, post_url, liked_at FROM post_likes WHERE user_id=$1 ORDER BY liked_at DESC`, userID)
+	ctx := r.Context()
+	var err error
+	resp.PostLikes, err = s.store.Interactions.ListPostLikes(ctx, accountID)
 	if err == nil {
-		defer rows.Close()
-		for rows.Next() {
-			var l models.PostLike
-			if err := rows.Scan(&l.ID, &l.UserID, &l.CreatorUsername, &l.PostURL, &l.LikedAt); err == nil {
-				resp.PostLikes = append(resp.PostLikes, l)
-			}
-		}
+		resp.CommentLikes, err = s.store.Interactions.ListCommentLikes(ctx, accountID)
 	}
-
-	rows2, err := s.db.Query(context.Background(),
-		`SELECT id, user_id, owner_username, post_url, liked_at FROM comment_likes WHERE user_id=$1 ORDER BY liked_at DESC`, userID)
 	if err == nil {
-		defer rows2.Close()
-		for rows2.Next() {
-			var l models.CommentLike
-			if err := rows2.Scan(&l.ID, &l.UserID, &l.OwnerUsername, &l.PostURL, &l.LikedAt); err == nil {
-				resp.CommentLikes = append(resp.CommentLikes, l)
-			}
-		}
+		resp.StoryLikes, err = s.store.Interactions.ListStoryLikes(ctx, accountID)
 	}
-
-	rows3, err := s.db.Query(context.Background(),
-		`SELECT id, user_id, creator_username, liked_at FROM story_likes WHERE user_id=$1 ORDER BY liked_at DESC`, userID)
-	if err == nil {
-		defer rows3.Close()
-		for rows3.Next() {
-			var l models.StoryLike
-			if err := rows3.Scan(&l.ID, &l.UserID, &l.CreatorUsername, &l.LikedAt); err == nil {
-				resp.StoryLikes = append(resp.StoryLikes, l)
-			}
-		}
+	if err != nil {
+		log.Printf("Failed to query likes for user %d: %v", userID, err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve likes")
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -1247,241 +1139,301 @@ func (s *APIServer) getCommentsHandler(w http.ResponseWriter, r *http.Request) {
 		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
 		return
 	}
+	accountID, ok := s.accountFromRequest(w, r, userID)
+	if !ok {
+		return
+	}
 	type Response struct {
 		PostComments []models.PostComment `json:"post_comments"`
 		ReelComments []models.ReelComment `json:"reel_comments"`
 	}
-	resp := Response{
-		PostComments: make([]models.PostComment, 0),
-		ReelComments: make([]models.ReelComment, 0),
-	}
+	var resp Response
 
-	rows, err := s.db.Query(context.Background(),
-		`SELECT id, user_id, post_owner_username, comment_text, commented_at FROM post_comments WHERE user_id=$1 ORDER BY commented_at DESC`, userID)
+	var err error
+	resp.PostComments, err = s.store.Interactions.ListPostComments(r.Context(), accountID)
 	if err == nil {
-		defer rows.Close()
-		for rows.Next() {
-			var c models.PostComment
-			if err := rows.Scan(&c.ID, &c.UserID, &c.PostOwnerUsername, &c.CommentText, &c.CommentedAt); err == nil {
-				resp.PostComments = append(resp.PostComments, c)
-			}
-		}
+		resp.ReelComments, err = s.store.Interactions.ListReelComments(r.Context(), accountID)
 	}
-
-	rows2, err := s.db.Query(context.Background(),
-		`SELECT id, user_id, reel_owner_username, comment_text, commented_at FROM reel_comments WHERE user_id=$1 ORDER BY commented_at DESC`, userID)
-	if err == nil {
-		defer rows2.Close()
-		for rows2.Next() {
-			var c models.ReelComment
-			if err := rows2.Scan(&c.ID, &c.UserID, &c.ReelOwnerUsername, &c.CommentText, &c.CommentedAt); err == nil {
-				resp.ReelComments = append(resp.ReelComments, c)
-			}
-		}
+	if err != nil {
+		log.Printf("Failed to query comments for user %d: %v", userID, err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve comments")
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
 
+// getPostCommentHandler handles GET /api/v1/comments/post/{id}: one comment on a post,
+// the record a search hit links to.
+func (s *APIServer) getPostCommentHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(int)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+	accountID, ok := s.accountFromRequest(w, r, userID)
+	if !ok {
+		return
+	}
+	id, ok := pathInt(w, r, "id", "comment id")
+	if !ok {
+		return
+	}
+
+	comment, err := s.store.Interactions.FindPostComment(r.Context(), accountID, id)
+	if errors.Is(err, store.ErrNotFound) {
+		writeJSONError(w, http.StatusNotFound, "Comment not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to query post comment %d for user %d: %v", id, userID, err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve comment")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(comment)
+}
+
+// getReelCommentHandler handles GET /api/v1/comments/reel/{id}, like getPostCommentHandler.
+func (s *APIServer) getReelCommentHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(int)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+	accountID, ok := s.accountFromRequest(w, r, userID)
+	if !ok {
+		return
+	}
+	id, ok := pathInt(w, r, "id", "comment id")
+	if !ok {
+		return
+	}
+
+	comment, err := s.store.Interactions.FindReelComment(r.Context(), accountID, id)
+	if errors.Is(err, store.ErrNotFound) {
+		writeJSONError(w, http.StatusNotFound, "Comment not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to query reel comment %d for user %d: %v", id, userID, err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve comment")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(comment)
+}
+
 func (s *APIServer) getSavedHandler(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(userIDKey).(int)
 	if !ok {
 		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
 		return
 	}
-	type Response struct {
-		SavedMedia       []models.SavedMedia           `json:"saved_media"`
-		Collections      []models.SavedCollection      `json:"collections"`
-		CollectionItems  []models.SavedCollectionItem  `json:"collection_items"`
+	accountID, ok := s.accountFromRequest(w, r, userID)
+	if !ok {
+		return
+	}
+	type Collection struct {
+		models.SavedCollection
+		ItemCount int                          `json:"item_count"`
+		Items     []models.SavedCollectionItem `json:"items"`
 	}
-	resp := Response{
-		SavedMedia:      make([]models.SavedMedia, 0),
-		Collections:     make([]models.SavedCollection, 0),
-		CollectionItems: make([]models.SavedCollectionItem, 0),
+	type Counts struct {
+		SavedMedia      int `json:"saved_media"`
+		Collections     int `json:"collections"`
+		CollectionItems int `json:"collection_items"`
 	}
+	type Response struct {
+		SavedMedia  []models.SavedMedia `json:"saved_media"`
+		Collections []*Collection       `json:"collections"`
+		Counts      Counts              `json:"counts"`
+	}
+	resp := Response{Collections: make([]*Collection, 0)}
 
-	rows, err := s.db.Query(context.Background(),
-		`SELECT id, user_id, creator_username, post_url, saved_at FROM saved_media WHERE user_id=$1 ORDER BY saved_at DESC`, userID)
-	if err == nil {
-		defer rows.Close()
-		for rows.Next() {
-			var m models.SavedMedia
-			if err := rows.Scan(&m.ID, &m.UserID, &m.CreatorUsername, &m.PostURL, &m.SavedAt); err == nil {
-				resp.SavedMedia = append(resp.SavedMedia, m)
-			}
-		}
+	ctx := r.Context()
+	var err error
+	resp.SavedMedia, err = s.store.Saved.ListSavedMedia(ctx, accountID)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve saved posts")
+		return
 	}
 
-	rows2, err := s.db.Query(context.Background(),
-		`SELECT id, user_id, collection_name, created_at, updated_at FROM saved_collections WHERE user_id=$1`, userID)
-	if err == nil {
-		defer rows2.Close()
-		for rows2.Next() {
-			var c models.SavedCollection
-			if err := rows2.Scan(&c.ID, &c.UserID, &c.CollectionName, &c.CreatedAt, &c.UpdatedAt); err == nil {
-				resp.Collections = append(resp.Collections, c)
-			}
-		}
+	collections, err := s.store.Saved.ListCollections(ctx, accountID)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve saved collections")
+		return
+	}
+	byName := map[string]*Collection{}
+	for _, sc := range collections {
+		c := &Collection{SavedCollection: sc, Items: make([]models.SavedCollectionItem, 0)}
+		byName[c.CollectionName] = c
+		resp.Collections = append(resp.Collections, c)
 	}
 
-	rows3, err := s.db.Query(context.Background(),
-		`SELECT id, user_id, collection_name, item_url, creator_username, added_at FROM saved_collection_items WHERE user_id=$1 ORDER BY added_at DESC`, userID)
-	if err == nil {
-		defer rows3.Close()
-		for rows3.Next() {
-			var ci models.SavedCollectionItem
-			if err := rows3.Scan(&ci.ID, &ci.UserID, &ci.CollectionName, &ci.ItemURL, &ci.CreatorUsername, &ci.AddedAt); err == nil {
-				resp.CollectionItems = append(resp.CollectionItems, ci)
-			}
+	items, err := s.store.Saved.ListCollectionItems(ctx, accountID, "", store.ListOptions{Sort: store.SortDate})
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve saved collection items")
+		return
+	}
+	for _, ci := range items {
+		if c, ok := byName[ci.CollectionName]; ok {
+			c.Items = append(c.Items, ci)
+			c.ItemCount++
+			resp.Counts.CollectionItems++
 		}
 	}
 
+	resp.Counts.SavedMedia = len(resp.SavedMedia)
+	resp.Counts.Collections = len(resp.Collections)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
 
-func (s *APIServer) getProfileHandler(w http.ResponseWriter, r *http.Request) {
+var savedCollectionListSpec = listSpec{
+	dated:       true,
+	byUsername:  true,
+	sorts:       []string{store.SortDate},
+	defaultSort: store.SortDate,
+}
+
+// getSavedCollectionHandler handles GET /api/v1/saved/collections/{name}: one collection
+// with a page of its items.
+func (s *APIServer) getSavedCollectionHandler(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(userIDKey).(int)
 	if !ok {
 		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
 		return
 	}
+	accountID, ok := s.accountFromRequest(w, r, userID)
+	if !ok {
+		return
+	}
+	pg, ok := pageFromRequest(w, r)
+	if !ok {
+		return
+	}
+	q, ok := listQueryFromRequest(w, r, savedCollectionListSpec)
+	if !ok {
+		return
+	}
+
+	name := r.PathValue("name")
+
 	type Response struct {
-		Profile *models.UserProfile     `json:"profile"`
-		Changes []models.ProfileChange  `json:"changes"`
-		Photos  []models.ProfilePhoto   `json:"photos"`
+		Collection models.SavedCollection       `json:"collection"`
+		ItemCount  int                          `json:"item_count"`
+		Items      []models.SavedCollectionItem `json:"items"`
+		NextCursor *string                      `json:"next_cursor"`
 	}
-	resp := Response{
-		Changes: make([]models.ProfileChange, 0),
-		Photos:  make([]models.ProfilePhoto, 0),
+	var resp Response
+
+	var err error
+	resp.Collection, resp.ItemCount, err = s.store.Saved.Collection(r.Context(), accountID, name)
+	if errors.Is(err, store.ErrNotFound) {
+		writeJSONError(w, http.StatusNotFound, "Collection not found")
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve collection")
+		return
 	}
 
-	var p models.UserProfile
-	err := s.db.QueryRow(context.Background(),
-		`SELECT id, user_id, COALESCE(email,''), COALESCE(phone_number,''), COALESCE(username,''),
-		        COALESCE(bio,''), COALESCE(gender,''), date_of_birth::TEXT, COALESCE(profile_photo_uri,'')
-		 FROM user_profile WHERE user_id=$1`, userID).
-		Scan(&p.ID, &p.UserID, &p.Email, &p.PhoneNumber, &p.Username, &p.Bio, &p.Gender, &p.DateOfBirth, &p.ProfilePhotoURI)
-	if err == nil {
-		resp.Profile = &p
+	items, err := s.store.Saved.ListCollectionItems(r.Context(), accountID, name, listOptions(q, pg))
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve collection items")
+		return
 	}
+	itemPage := newPage(items, pg, func(ci models.SavedCollectionItem) pageCursor { return pageCursor{At: ci.AddedAt, ID: ci.ID} })
+	resp.Items, resp.NextCursor = itemPage.Items, itemPage.NextCursor
 
-	rows, err := s.db.Query(context.Background(),
-		`SELECT id, user_id, field_changed, COALESCE(previous_value,''), COALESCE(new_value,''), changed_at
-		 FROM profile_changes WHERE user_id=$1 ORDER BY changed_at DESC`, userID)
-	if err == nil {
-		defer rows.Close()
-		for rows.Next() {
-			var c models.ProfileChange
-			if err := rows.Scan(&c.ID, &c.UserID, &c.FieldChanged, &c.PreviousValue, &c.NewValue, &c.ChangedAt); err == nil {
-				resp.Changes = append(resp.Changes, c)
-			}
-		}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (s *APIServer) getProfileHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(int)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+	accountID, ok := s.accountFromRequest(w, r, userID)
+	if !ok {
+		return
+	}
+	type Response struct {
+		Profile *models.UserProfile    `json:"profile"`
+		Changes []models.ProfileChange `json:"changes"`
+		Photos  []models.ProfilePhoto  `json:"photos"`
 	}
+	var resp Response
 
-	rows2, err := s.db.Query(context.Background(),
-		`SELECT id, user_id, photo_uri, set_at FROM profile_photos WHERE user_id=$1 ORDER BY set_at DESC`, userID)
+	ctx := r.Context()
+	var err error
+	resp.Profile, err = s.store.Profile.Profile(ctx, accountID)
 	if err == nil {
-		defer rows2.Close()
-		for rows2.Next() {
-			var ph models.ProfilePhoto
-			if err := rows2.Scan(&ph.ID, &ph.UserID, &ph.PhotoURI, &ph.SetAt); err == nil {
-				resp.Photos = append(resp.Photos, ph)
-			}
-		}
+		resp.Changes, err = s.store.Profile.ListProfileChanges(ctx, accountID)
+	}
+	if err == nil {
+		resp.Photos, err = s.store.Profile.ListProfilePhotos(ctx, accountID)
+	}
+	if err != nil {
+		log.Printf("Failed to query profile for user %d: %v", userID, err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve profile")
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
 
+// securityHistoryLimit caps the login and logout lists, which run to thousands of rows.
+const securityHistoryLimit = 200
+
 func (s *APIServer) getSecurityHandler(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(userIDKey).(int)
 	if !ok {
 		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
 		return
 	}
+	accountID, ok := s.accountFromRequest(w, r, userID)
+	if !ok {
+		return
+	}
 	type Response struct {
-		LoginHistory      []models.LoginHistory        `json:"login_history"`
-		LogoutHistory     []models.LogoutHistory       `json:"logout_history"`
-		PasswordChanges   []models.PasswordChange      `json:"password_changes"`
-		PrivacyChanges    []models.PrivacyChange       `json:"privacy_changes"`
-		AccountStatus     []models.AccountStatusEntry  `json:"account_status"`
-		SignupInfo        *models.SignupInfo            `json:"signup_info"`
-	}
-	resp := Response{
-		LoginHistory:    make([]models.LoginHistory, 0),
-		LogoutHistory:   make([]models.LogoutHistory, 0),
-		PasswordChanges: make([]models.PasswordChange, 0),
-		PrivacyChanges:  make([]models.PrivacyChange, 0),
-		AccountStatus:   make([]models.AccountStatusEntry, 0),
-	}
-
-	if rows, err := s.db.Query(context.Background(),
-		`SELECT id, user_id, COALESCE(ip_address,''), COALESCE(user_agent,''), COALESCE(language_code,''), logged_in_at
-		 FROM login_history WHERE user_id=$1 ORDER BY logged_in_at DESC LIMIT 200`, userID); err == nil {
-		defer rows.Close()
-		for rows.Next() {
-			var l models.LoginHistory
-			if err := rows.Scan(&l.ID, &l.UserID, &l.IPAddress, &l.UserAgent, &l.LanguageCode, &l.LoggedInAt); err == nil {
-				resp.LoginHistory = append(resp.LoginHistory, l)
-			}
-		}
+		LoginHistory    []models.LoginHistory       `json:"login_history"`
+		LogoutHistory   []models.LogoutHistory      `json:"logout_history"`
+		PasswordChanges []models.PasswordChange     `json:"password_changes"`
+		PrivacyChanges  []models.PrivacyChange      `json:"privacy_changes"`
+		AccountStatus   []models.AccountStatusEntry `json:"account_status"`
+		SignupInfo      *models.SignupInfo          `json:"signup_info"`
 	}
+	var resp Response
 
-	if rows, err := s.db.Query(context.Background(),
-		`SELECT id, user_id, COALESCE(ip_address,''), COALESCE(user_agent,''), logged_out_at
-		 FROM logout_history WHERE user_id=$1 ORDER BY logged_out_at DESC LIMIT 200`, userID); err == nil {
-		defer rows.Close()
-		for rows.Next() {
-			var l models.LogoutHistory
-			if err := rows.Scan(&l.ID, &l.UserID, &l.IPAddress, &l.UserAgent, &l.LoggedOutAt); err == nil {
-				resp.LogoutHistory = append(resp.LogoutHistory, l)
-			}
-		}
+	ctx := r.Context()
+	var err error
+	resp.LoginHistory, err = s.store.Security.ListLogins(ctx, accountID, securityHistoryLimit)
+	if err == nil {
+		resp.LogoutHistory, err = s.store.Security.ListLogouts(ctx, accountID, securityHistoryLimit)
 	}
-
-	if rows, err := s.db.Query(context.Background(),
-		`SELECT id, user_id, changed_at FROM password_change_history WHERE user_id=$1 ORDER BY changed_at DESC`, userID); err == nil {
-		defer rows.Close()
-		for rows.Next() {
-			var p models.PasswordChange
-			if err := rows.Scan(&p.ID, &p.UserID, &p.ChangedAt); err == nil {
-				resp.PasswordChanges = append(resp.PasswordChanges, p)
-			}
-		}
+	if err == nil {
+		resp.PasswordChanges, err = s.store.Security.ListPasswordChanges(ctx, accountID)
 	}
-
-	if rows, err := s.db.Query(context.Background(),
-		`SELECT id, user_id, privacy_status, changed_at FROM privacy_changes WHERE user_id=$1 ORDER BY changed_at DESC`, userID); err == nil {
-		defer rows.Close()
-		for rows.Next() {
-			var p models.PrivacyChange
-			if err := rows.Scan(&p.ID, &p.UserID, &p.PrivacyStatus, &p.ChangedAt); err == nil {
-				resp.PrivacyChanges = append(resp.PrivacyChanges, p)
-			}
-		}
+	if err == nil {
+		resp.PrivacyChanges, err = s.store.Security.ListPrivacyChanges(ctx, accountID)
 	}
-
-	if rows, err := s.db.Query(context.Background(),
-		`SELECT id, user_id, activation_type, COALESCE(reason,''), changed_at FROM account_status_history WHERE user_id=$1 ORDER BY changed_at DESC`, userID); err == nil {
-		defer rows.Close()
-		for rows.Next() {
-			var a models.AccountStatusEntry
-			if err := rows.Scan(&a.ID, &a.UserID, &a.ActivationType, &a.Reason, &a.ChangedAt); err == nil {
-				resp.AccountStatus = append(resp.AccountStatus, a)
-			}
-		}
+	if err == nil {
+		resp.AccountStatus, err = s.store.Security.ListAccountStatus(ctx, accountID)
 	}
-
-	var si models.SignupInfo
-	if err := s.db.QueryRow(context.Background(),
-		`SELECT id, user_id, COALESCE(username_at_signup,''), COALESCE(email_at_signup,''),
-		        COALESCE(signup_ip,''), COALESCE(device_model,''), signed_up_at
-		 FROM signup_info WHERE user_id=$1`, userID).
-		Scan(&si.ID, &si.UserID, &si.UsernameAtSignup, &si.EmailAtSignup, &si.SignupIP, &si.DeviceModel, &si.SignedUpAt); err == nil {
-		resp.SignupInfo = &si
+	if err == nil {
+		resp.SignupInfo, err = s.store.Security.SignupInfo(ctx, accountID)
+	}
+	if err != nil {
+		log.Printf("Failed to query security history for user %d: %v", userID, err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve security history")
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -1494,23 +1446,56 @@ func (s *APIServer) getSearchHistoryHandler(w http.ResponseWriter, r *http.Reque
 		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
 		return
 	}
-	rows, err := s.db.Query(context.Background(),
-		`SELECT id, user_id, search_query, search_type, searched_at FROM search_history WHERE user_id=$1 ORDER BY searched_at DESC`, userID)
+	accountID, ok := s.accountFromRequest(w, r, userID)
+	if !ok {
+		return
+	}
+	pg, ok := pageFromRequest(w, r)
+	if !ok {
+		return
+	}
+	q, ok := listQueryFromRequest(w, r, searchHistoryListSpec)
+	if !ok {
+		return
+	}
+	result, err := s.store.Activity.ListSearchHistory(r.Context(), accountID, listOptions(q, pg))
 	if err != nil {
 		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve search history")
 		return
 	}
-	defer rows.Close()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newPage(result, pg, func(e models.SearchHistoryEntry) pageCursor { return pageCursor{At: e.SearchedAt, ID: e.ID} }))
+}
 
-	result := make([]models.SearchHistoryEntry, 0)
-	for rows.Next() {
-		var s models.SearchHistoryEntry
-		if err := rows.Scan(&s.ID, &s.UserID, &s.SearchQuery, &s.SearchType, &s.SearchedAt); err == nil {
-			result = append(result, s)
-		}
+// getSearchHandler handles GET /api/v1/search-history/{id}: one past search, the record
+// a search hit links to.
+func (s *APIServer) getSearchHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(int)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+	accountID, ok := s.accountFromRequest(w, r, userID)
+	if !ok {
+		return
+	}
+	id, ok := pathInt(w, r, "id", "search id")
+	if !ok {
+		return
+	}
+
+	entry, err := s.store.Activity.FindSearch(r.Context(), accountID, id)
+	if errors.Is(err, store.ErrNotFound) {
+		writeJSONError(w, http.StatusNotFound, "Search not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to query search %d for user %d: %v", id, userID, err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve search")
+		return
 	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	json.NewEncoder(w).Encode(entry)
 }
 
 func (s *APIServer) getStoryInteractionsHandler(w http.ResponseWriter, r *http.Request) {
@@ -1519,6 +1504,10 @@ func (s *APIServer) getStoryInteractionsHandler(w http.ResponseWriter, r *http.R
 		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
 		return
 	}
+	accountID, ok := s.accountFromRequest(w, r, userID)
+	if !ok {
+		return
+	}
 	type Response struct {
 		Polls     []models.StoryPoll        `json:"polls"`
 		Quizzes   []models.StoryQuiz        `json:"quizzes"`
@@ -1526,67 +1515,27 @@ func (s *APIServer) getStoryInteractionsHandler(w http.ResponseWriter, r *http.R
 		Sliders   []models.StoryEmojiSlider `json:"emoji_sliders"`
 		Reactions []models.StoryReaction    `json:"reactions"`
 	}
-	resp := Response{
-		Polls:     make([]models.StoryPoll, 0),
-		Quizzes:   make([]models.StoryQuiz, 0),
-		Questions: make([]models.StoryQuestion, 0),
-		Sliders:   make([]models.StoryEmojiSlider, 0),
-		Reactions: make([]models.StoryReaction, 0),
-	}
-
-	if rows, err := s.db.Query(context.Background(),
-		`SELECT id, user_id, creator_username, COALESCE(poll_answer,''), answered_at FROM story_polls WHERE user_id=$1 ORDER BY answered_at DESC`, userID); err == nil {
-		defer rows.Close()
-		for rows.Next() {
-			var p models.StoryPoll
-			if err := rows.Scan(&p.ID, &p.UserID, &p.CreatorUsername, &p.PollAnswer, &p.AnsweredAt); err == nil {
-				resp.Polls = append(resp.Polls, p)
-			}
-		}
-	}
+	var resp Response
 
-	if rows, err := s.db.Query(context.Background(),
-		`SELECT id, user_id, creator_username, COALESCE(quiz_answer,''), answered_at FROM story_quizzes WHERE user_id=$1 ORDER BY answered_at DESC`, userID); err == nil {
-		defer rows.Close()
-		for rows.Next() {
-			var q models.StoryQuiz
-			if err := rows.Scan(&q.ID, &q.UserID, &q.CreatorUsername, &q.QuizAnswer, &q.AnsweredAt); err == nil {
-				resp.Quizzes = append(resp.Quizzes, q)
-			}
-		}
+	ctx := r.Context()
+	var err error
+	resp.Polls, err = s.store.Interactions.ListStoryPolls(ctx, accountID)
+	if err == nil {
+		resp.Quizzes, err = s.store.Interactions.ListStoryQuizzes(ctx, accountID)
 	}
-
-	if rows, err := s.db.Query(context.Background(),
-		`SELECT id, user_id, creator_username, responded_at FROM story_questions WHERE user_id=$1 ORDER BY responded_at DESC`, userID); err == nil {
-		defer rows.Close()
-		for rows.Next() {
-			var q models.StoryQuestion
-			if err := rows.Scan(&q.ID, &q.UserID, &q.CreatorUsername, &q.RespondedAt); err == nil {
-				resp.Questions = append(resp.Questions, q)
-			}
-		}
+	if err == nil {
+		resp.Questions, err = s.store.Interactions.ListStoryQuestions(ctx, accountID)
 	}
-
-	if rows, err := s.db.Query(context.Background(),
-		`SELECT id, user_id, creator_username, COALESCE(slider_value,0), responded_at FROM story_emoji_sliders WHERE user_id=$1 ORDER BY responded_at DESC`, userID); err == nil {
-		defer rows.Close()
-		for rows.Next() {
-			var sl models.StoryEmojiSlider
-			if err := rows.Scan(&sl.ID, &sl.UserID, &sl.CreatorUsername, &sl.SliderValue, &sl.RespondedAt); err == nil {
-				resp.Sliders = append(resp.Sliders, sl)
-			}
-		}
+	if err == nil {
+		resp.Sliders, err = s.store.Interactions.ListStoryEmojiSliders(ctx, accountID)
 	}
-
-	if rows, err := s.db.Query(context.Background(),
-		`SELECT id, user_id, creator_username, responded_at FROM story_reactions WHERE user_id=$1 ORDER BY responded_at DESC`, userID); err == nil {
-		defer rows.Close()
-		for rows.Next() {
-			var rx models.StoryReaction
-			if err := rows.Scan(&rx.ID, &rx.UserID, &rx.CreatorUsername, &rx.RespondedAt); err == nil {
-				resp.Reactions = append(resp.Reactions, rx)
-			}
-		}
+	if err == nil {
+		resp.Reactions, err = s.store.Interactions.ListStoryReactions(ctx, accountID)
+	}
+	if err != nil {
+		log.Printf("Failed to query story interactions for user %d: %v", userID, err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve story interactions")
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -1599,117 +1548,131 @@ func (s *APIServer) getMessagesHandler(w http.ResponseWriter, r *http.Request) {
 		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
 		return
 	}
+	accountID, ok := s.accountFromRequest(w, r, userID)
+	if !ok {
+		return
+	}
+	pg, ok := pageFromRequest(w, r)
+	if !ok {
+		return
+	}
+	q, ok := listQueryFromRequest(w, r, messagesListSpec)
+	if !ok {
+		return
+	}
 	type Response struct {
 		Conversations []models.MessageConversation `json:"conversations"`
 		Messages      []models.Message             `json:"messages"`
+		NextCursor    *string                      `json:"next_cursor"`
 	}
-	resp := Response{
-		Conversations: make([]models.MessageConversation, 0),
-		Messages:      make([]models.Message, 0),
-	}
-
-	if rows, err := s.db.Query(context.Background(),
-		`SELECT id, user_id, conversation_id, participants, COALESCE(thread_type,'')
-		 FROM message_conversations mc WHERE mc.user_id=$1
-		 ORDER BY (SELECT MAX(sent_at) FROM messages m WHERE m.user_id=$1 AND m.conversation_id=mc.conversation_id) DESC NULLS LAST`,
-		userID); err == nil {
-		defer rows.Close()
-		for rows.Next() {
-			var c models.MessageConversation
-			if err := rows.Scan(&c.ID, &c.UserID, &c.ConversationID, &c.Participants, &c.ThreadType); err == nil {
-				resp.Conversations = append(resp.Conversations, c)
-			}
+	resp := Response{Conversations: make([]models.MessageConversation, 0)}
+
+	// the conversation list comes with the first page only; later pages just continue the messages
+	var err error
+	if pg.After == nil {
+		resp.Conversations, err = s.store.Messages.ListConversations(r.Context(), accountID)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve conversations")
+			return
 		}
 	}
 
-	// If a specific conversation is requested, return all its messages; otherwise return recent 100 per conversation
-	convID := r.URL.Query().Get("conversation_id")
-	if convID != "" {
-		if rows, err := s.db.Query(context.Background(),
-			`SELECT id, user_id, conversation_id, sender_name, COALESCE(content,''), sent_at
-			 FROM messages WHERE user_id=$1 AND conversation_id=$2 ORDER BY sent_at ASC`, userID, convID); err == nil {
-			defer rows.Close()
-			for rows.Next() {
-				var m models.Message
-				if err := rows.Scan(&m.ID, &m.UserID, &m.ConversationID, &m.SenderName, &m.Content, &m.SentAt); err == nil {
-					resp.Messages = append(resp.Messages, m)
-				}
-			}
-		}
-	} else {
-		if rows, err := s.db.Query(context.Background(),
-			`SELECT id, user_id, conversation_id, sender_name, COALESCE(content,''), sent_at
-			 FROM messages WHERE user_id=$1 ORDER BY sent_at DESC LIMIT 2000`, userID); err == nil {
-			defer rows.Close()
-			for rows.Next() {
-				var m models.Message
-				if err := rows.Scan(&m.ID, &m.UserID, &m.ConversationID, &m.SenderName, &m.Content, &m.SentAt); err == nil {
-					resp.Messages = append(resp.Messages, m)
-				}
-			}
-		}
+	// newest messages across all conversations come first unless `order` says otherwise
+	resp.Messages, err = s.store.Messages.ListMessages(r.Context(), accountID, "", listOptions(q, pg))
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve messages")
+		return
 	}
 
+	msgPage := newPage(resp.Messages, pg, messageCursor)
+	resp.Messages, resp.NextCursor = msgPage.Items, msgPage.NextCursor
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
 
-func (s *APIServer) getTopicsHandler(w http.ResponseWriter, r *http.Request) {
+// getConversationHandler handles GET /api/v1/messages/{conversationID}: one conversation's
+// messages, oldest first like a chat unless `order` says otherwise.
+func (s *APIServer) getConversationHandler(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(userIDKey).(int)
 	if !ok {
 		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
 		return
 	}
-	type Response struct {
-		AIInterests       []models.AIInterest      `json:"ai_interests"`
-		Topics            []models.UserTopic       `json:"topics"`
-		InferredLocation  *models.InferredLocation `json:"inferred_location"`
-		LocationsOfInterest []string               `json:"locations_of_interest"`
-	}
-	resp := Response{
-		AIInterests:         make([]models.AIInterest, 0),
-		Topics:              make([]models.UserTopic, 0),
-		LocationsOfInterest: make([]string, 0),
-	}
-
-	if rows, err := s.db.Query(context.Background(),
-		`SELECT id, user_id, interest_description, detected_at FROM ai_interests WHERE user_id=$1`, userID); err == nil {
-		defer rows.Close()
-		for rows.Next() {
-			var a models.AIInterest
-			if err := rows.Scan(&a.ID, &a.UserID, &a.InterestDescription, &a.DetectedAt); err == nil {
-				resp.AIInterests = append(resp.AIInterests, a)
-			}
-		}
+	accountID, ok := s.accountFromRequest(w, r, userID)
+	if !ok {
+		return
+	}
+	pg, ok := pageFromRequest(w, r)
+	if !ok {
+		return
 	}
+	q, ok := listQueryFromRequest(w, r, messagesListSpec)
+	if !ok {
+		return
+	}
+	convID := r.PathValue("conversationID")
 
-	if rows, err := s.db.Query(context.Background(),
-		`SELECT id, user_id, topic_name FROM user_topics WHERE user_id=$1 ORDER BY topic_name`, userID); err == nil {
-		defer rows.Close()
-		for rows.Next() {
-			var t models.UserTopic
-			if err := rows.Scan(&t.ID, &t.UserID, &t.TopicName); err == nil {
-				resp.Topics = append(resp.Topics, t)
-			}
-		}
+	exists, err := s.store.Messages.ConversationExists(r.Context(), accountID, convID)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve messages")
+		return
+	}
+	if !exists {
+		writeJSONError(w, http.StatusNotFound, "Conversation not found")
+		return
 	}
 
-	var loc models.InferredLocation
-	if err := s.db.QueryRow(context.Background(),
-		`SELECT id, user_id, COALESCE(city_name,'') FROM inferred_location WHERE user_id=$1`, userID).
-		Scan(&loc.ID, &loc.UserID, &loc.CityName); err == nil {
-		resp.InferredLocation = &loc
+	opts := listOptions(q, pg)
+	opts.Ascending = q.Ascending || r.URL.Query().Get("order") == ""
+	messages, err := s.store.Messages.ListMessages(r.Context(), accountID, convID, opts)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve messages")
+		return
 	}
 
-	if rows, err := s.db.Query(context.Background(),
-		`SELECT location_name FROM locations_of_interest WHERE user_id=$1 ORDER BY location_name`, userID); err == nil {
-		defer rows.Close()
-		for rows.Next() {
-			var loc string
-			if err := rows.Scan(&loc); err == nil {
-				resp.LocationsOfInterest = append(resp.LocationsOfInterest, loc)
-			}
-		}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newPage(messages, pg, messageCursor))
+}
+
+func messageCursor(m models.Message) pageCursor {
+	return pageCursor{At: m.SentAt, ID: m.ID}
+}
+
+func (s *APIServer) getTopicsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(int)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+	accountID, ok := s.accountFromRequest(w, r, userID)
+	if !ok {
+		return
+	}
+	type Response struct {
+		AIInterests         []models.AIInterest      `json:"ai_interests"`
+		Topics              []models.UserTopic       `json:"topics"`
+		InferredLocation    *models.InferredLocation `json:"inferred_location"`
+		LocationsOfInterest []string                 `json:"locations_of_interest"`
+	}
+	var resp Response
+
+	ctx := r.Context()
+	var err error
+	resp.AIInterests, err = s.store.Interests.ListAIInterests(ctx, accountID)
+	if err == nil {
+		resp.Topics, err = s.store.Interests.ListTopics(ctx, accountID)
+	}
+	if err == nil {
+		resp.InferredLocation, err = s.store.Interests.InferredLocation(ctx, accountID)
+	}
+	if err == nil {
+		resp.LocationsOfInterest, err = s.store.Interests.ListLocationsOfInterest(ctx, accountID)
+	}
+	if err != nil {
+		log.Printf("Failed to query topics for user %d: %v", userID, err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve topics")
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -1722,23 +1685,25 @@ func (s *APIServer) getOffMetaActivityHandler(w http.ResponseWriter, r *http.Req
 		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
 		return
 	}
-	rows, err := s.db.Query(context.Background(),
-		`SELECT id, user_id, app_name, event_type, event_id, event_at FROM off_meta_activity WHERE user_id=$1 ORDER BY event_at DESC`, userID)
+	accountID, ok := s.accountFromRequest(w, r, userID)
+	if !ok {
+		return
+	}
+	pg, ok := pageFromRequest(w, r)
+	if !ok {
+		return
+	}
+	q, ok := listQueryFromRequest(w, r, offMetaListSpec)
+	if !ok {
+		return
+	}
+	result, err := s.store.Activity.ListOffMetaActivity(r.Context(), accountID, listOptions(q, pg))
 	if err != nil {
 		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve off-meta activity")
 		return
 	}
-	defer rows.Close()
-
-	result := make([]models.OffMetaActivity, 0)
-	for rows.Next() {
-		var a models.OffMetaActivity
-		if err := rows.Scan(&a.ID, &a.UserID, &a.AppName, &a.EventType, &a.EventID, &a.EventAt); err == nil {
-			result = append(result, a)
-		}
-	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	json.NewEncoder(w).Encode(newPage(result, pg, func(a models.OffMetaActivity) pageCursor { return pageCursor{At: a.EventAt, ID: a.ID} }))
 }
 
 func (s *APIServer) getArchivedPostsHandler(w http.ResponseWriter, r *http.Request) {
@@ -1747,21 +1712,15 @@ func (s *APIServer) getArchivedPostsHandler(w http.ResponseWriter, r *http.Reque
 		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
 		return
 	}
-	rows, err := s.db.Query(context.Background(),
-		`SELECT id, user_id, uri, COALESCE(caption,''), COALESCE(taken_at, NOW()) FROM archived_posts WHERE user_id=$1 ORDER BY taken_at DESC`, userID)
+	accountID, ok := s.accountFromRequest(w, r, userID)
+	if !ok {
+		return
+	}
+	result, err := s.store.Media.ListArchivedPosts(r.Context(), accountID)
 	if err != nil {
 		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve archived posts")
 		return
 	}
-	defer rows.Close()
-
-	result := make([]models.ArchivedPost, 0)
-	for rows.Next() {
-		var a models.ArchivedPost
-		if err := rows.Scan(&a.ID, &a.UserID, &a.URI, &a.Caption, &a.TakenAt); err == nil {
-			result = append(result, a)
-		}
-	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
 }
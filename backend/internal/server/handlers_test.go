@@ -0,0 +1,294 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sa-Te/IAV/backend/internal/models"
+	"github.com/Sa-Te/IAV/backend/internal/store"
+)
+
+const testUserID = 7
+
+// newTestServer serves from an in-memory store holding the test user's default account.
+func newTestServer(t *testing.T) (*APIServer, *store.Memory, int) {
+	t.Helper()
+	mem := store.NewMemory()
+	accountID, err := mem.DefaultAccount(context.Background(), testUserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &APIServer{store: mem.Store()}, mem, accountID
+}
+
+// serveAs calls a handler directly, as authMiddleware would after authenticating the user.
+func serveAs(userID int, h http.HandlerFunc, req *http.Request) *httptest.ResponseRecorder {
+	req = req.WithContext(context.WithValue(req.Context(), userIDKey, userID))
+	rec := httptest.NewRecorder()
+	h(rec, req)
+	return rec
+}
+
+func decodeBody[T any](t *testing.T, rec *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal(rec.Body.Bytes(), &v); err != nil {
+		t.Fatalf("decode %q: %v", rec.Body.String(), err)
+	}
+	return v
+}
+
+func day(d int) time.Time {
+	return time.Date(2024, 3, d, 12, 0, 0, 0, time.UTC)
+}
+
+func TestMediaHandlerPagesWithCursor(t *testing.T) {
+	s, mem, accountID := newTestServer(t)
+	archive := mem.Archive(accountID)
+	for i := 1; i <= 5; i++ {
+		archive.Media = append(archive.Media, models.MediaItem{ID: i, UserID: testUserID, URI: "media/posts/x.jpg", TakenAt: day(i), MediaType: "post"})
+	}
+	archive.Media = append(archive.Media, models.MediaItem{ID: 6, UserID: testUserID, TakenAt: day(6), MediaType: "story"})
+
+	var ids []int
+	url := "/api/v1/media?type=post&limit=2"
+	for pages := 0; url != ""; pages++ {
+		if pages > 5 {
+			t.Fatal("cursor never ran out")
+		}
+		rec := serveAs(testUserID, s.getMediaItemsHandler, httptest.NewRequest(http.MethodGet, url, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: %d %s", url, rec.Code, rec.Body.String())
+		}
+		pg := decodeBody[page[models.MediaItem]](t, rec)
+		for _, m := range pg.Items {
+			ids = append(ids, m.ID)
+		}
+		url = ""
+		if pg.NextCursor != nil {
+			url = "/api/v1/media?type=post&limit=2&cursor=" + *pg.NextCursor
+		}
+	}
+	if fmt.Sprint(ids) != "[5 4 3 2 1]" {
+		t.Errorf("paged ids = %v, want [5 4 3 2 1]", ids)
+	}
+}
+
+func TestConnectionsHandlerFiltersAndSortsByUsername(t *testing.T) {
+	s, mem, accountID := newTestServer(t)
+	mem.Archive(accountID).Connections = []models.Connection{
+		{ID: 1, Username: "zoe", ConnectionType: "follower", Timestamp: day(1)},
+		{ID: 2, Username: "adam", ConnectionType: "follower", Timestamp: day(2)},
+		{ID: 3, Username: "anna", ConnectionType: "following", Timestamp: day(3)},
+		{ID: 4, Username: "Marta", ConnectionType: "follower", Timestamp: day(4)},
+	}
+
+	rec := serveAs(testUserID, s.getConnectionsHandler,
+		httptest.NewRequest(http.MethodGet, "/api/v1/connections?type=follower&username=A&sort=username&order=asc", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	var names []string
+	for _, c := range decodeBody[page[models.Connection]](t, rec).Items {
+		names = append(names, c.Username)
+	}
+	if strings.Join(names, ",") != "Marta,adam" {
+		t.Errorf("usernames = %v, want [Marta adam]", names)
+	}
+}
+
+func TestArchiveHandlersResolveTheAccountParameter(t *testing.T) {
+	s, mem, defaultID := newTestServer(t)
+	other, err := mem.CreateAccount(context.Background(), testUserID, "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mem.Archive(defaultID).Hashtags = []models.FollowedHashtag{{ID: 1, Name: "travel"}}
+	mem.Archive(other.ID).Hashtags = []models.FollowedHashtag{{ID: 2, Name: "golang"}, {ID: 3, Name: "code"}}
+
+	for target, want := range map[string]string{
+		"/api/v1/hashtags":              "travel",
+		"/api/v1/hashtags?account=work": "code,golang",
+	} {
+		rec := serveAs(testUserID, s.getHashtagsHandler, httptest.NewRequest(http.MethodGet, target, nil))
+		var names []string
+		for _, h := range decodeBody[[]models.FollowedHashtag](t, rec) {
+			names = append(names, h.Name)
+		}
+		if strings.Join(names, ",") != want {
+			t.Errorf("%s = %v, want %s", target, names, want)
+		}
+	}
+
+	// another user's account name must not resolve
+	rec := serveAs(testUserID+1, s.getHashtagsHandler, httptest.NewRequest(http.MethodGet, "/api/v1/hashtags?account=work", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("foreign account: status %d, want 404", rec.Code)
+	}
+}
+
+func TestCreateAccountRejectsDuplicateNames(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	for i, want := range []int{http.StatusCreated, http.StatusConflict} {
+		rec := serveAs(testUserID, s.createAccount,
+			httptest.NewRequest(http.MethodPost, "/api/v1/accounts", strings.NewReader(`{"name":"@work"}`)))
+		if rec.Code != want {
+			t.Errorf("attempt %d: status %d, want %d", i+1, rec.Code, want)
+		}
+	}
+
+	rec := serveAs(testUserID, s.listAccounts, httptest.NewRequest(http.MethodGet, "/api/v1/accounts", nil))
+	var names []string
+	for _, a := range decodeBody[[]models.IGAccount](t, rec) {
+		names = append(names, a.Name)
+	}
+	if strings.Join(names, ",") != "default,work" {
+		t.Errorf("accounts = %v", names)
+	}
+}
+
+// Only the default account falls back to the pre-accounts uploads/<userID> dir.
+func TestMediaFilePathFallsBackForTheDefaultAccountOnly(t *testing.T) {
+	s, mem, accountID := newTestServer(t)
+	other, err := mem.CreateAccount(context.Background(), testUserID, "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(t.TempDir())
+	legacy := filepath.Join("uploads", fmt.Sprint(testUserID), "media", "1.jpg")
+	if err := os.MkdirAll(filepath.Dir(legacy), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(legacy, []byte("jpg"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := s.mediaFilePath(context.Background(), testUserID, accountID, "media/1.jpg"); err != nil || got != legacy {
+		t.Errorf("default account: %q, %v, want %q", got, err, legacy)
+	}
+	want := filepath.Join(accountUploadDir(testUserID, other.ID), "media", "1.jpg")
+	if got, err := s.mediaFilePath(context.Background(), testUserID, other.ID, "media/1.jpg"); err != nil || got != want {
+		t.Errorf("other account: %q, %v, want %q", got, err, want)
+	}
+}
+
+func TestConversationHandler(t *testing.T) {
+	s, mem, accountID := newTestServer(t)
+	archive := mem.Archive(accountID)
+	archive.Conversations = []models.MessageConversation{{ID: 1, ConversationID: "jane_123"}, {ID: 2, ConversationID: "bob_456"}}
+	archive.Messages = []models.Message{
+		{ID: 1, ConversationID: "jane_123", SenderName: "jane", Content: "hi", SentAt: day(1)},
+		{ID: 2, ConversationID: "bob_456", SenderName: "bob", Content: "yo", SentAt: day(2)},
+		{ID: 3, ConversationID: "jane_123", SenderName: "me", Content: "hello", SentAt: day(3)},
+	}
+
+	conversation := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/messages/"+id, nil)
+		req.SetPathValue("conversationID", id)
+		return serveAs(testUserID, s.getConversationHandler, req)
+	}
+
+	rec := conversation("jane_123")
+	var contents []string
+	for _, m := range decodeBody[page[models.Message]](t, rec).Items {
+		contents = append(contents, m.Content)
+	}
+	if strings.Join(contents, ",") != "hi,hello" {
+		t.Errorf("messages = %v, want oldest first", contents)
+	}
+
+	if rec := conversation("nobody"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown conversation: status %d, want 404", rec.Code)
+	}
+
+	// the overview lists the conversation with the latest message first
+	rec = serveAs(testUserID, s.getMessagesHandler, httptest.NewRequest(http.MethodGet, "/api/v1/messages", nil))
+	body := decodeBody[struct {
+		Conversations []models.MessageConversation `json:"conversations"`
+		Messages      []models.Message             `json:"messages"`
+	}](t, rec)
+	if len(body.Conversations) != 2 || body.Conversations[0].ConversationID != "jane_123" {
+		t.Errorf("conversations = %+v", body.Conversations)
+	}
+	if len(body.Messages) != 3 || body.Messages[0].ID != 3 {
+		t.Errorf("messages = %+v, want newest first", body.Messages)
+	}
+}
+
+func TestSavedCollectionHandler(t *testing.T) {
+	s, mem, accountID := newTestServer(t)
+	archive := mem.Archive(accountID)
+	archive.Collections = []models.SavedCollection{{ID: 1, UserID: testUserID, CollectionName: "Recipes", CreatedAt: day(1)}}
+	archive.CollectionItems = []models.SavedCollectionItem{
+		{ID: 1, CollectionName: "Recipes", ItemURL: "https://instagram.com/p/1", AddedAt: day(2)},
+		{ID: 2, CollectionName: "Trips", ItemURL: "https://instagram.com/p/2", AddedAt: day(3)},
+	}
+
+	collection := func(name string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/saved/collections/"+name, nil)
+		req.SetPathValue("name", name)
+		return serveAs(testUserID, s.getSavedCollectionHandler, req)
+	}
+
+	// Trips is only named by its item, so it has no id or dates but still exists
+	rec := collection("Trips")
+	body := decodeBody[struct {
+		Collection models.SavedCollection       `json:"collection"`
+		ItemCount  int                          `json:"item_count"`
+		Items      []models.SavedCollectionItem `json:"items"`
+	}](t, rec)
+	if rec.Code != http.StatusOK || body.ItemCount != 1 || body.Collection.ID != 0 || body.Collection.UserID != testUserID {
+		t.Errorf("Trips: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	if rec := collection("Nope"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown collection: status %d, want 404", rec.Code)
+	}
+
+	rec = serveAs(testUserID, s.getSavedHandler, httptest.NewRequest(http.MethodGet, "/api/v1/saved", nil))
+	saved := decodeBody[struct {
+		Collections []struct {
+			CollectionName string `json:"collection_name"`
+			ItemCount      int    `json:"item_count"`
+		} `json:"collections"`
+		Counts map[string]int `json:"counts"`
+	}](t, rec)
+	if len(saved.Collections) != 2 || saved.Collections[0].CollectionName != "Recipes" || saved.Counts["collection_items"] != 2 {
+		t.Errorf("saved = %s", rec.Body.String())
+	}
+}
+
+func TestSecurityHandlerEncodesEmptyArchive(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	rec := serveAs(testUserID, s.getSecurityHandler, httptest.NewRequest(http.MethodGet, "/api/v1/security", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d", rec.Code)
+	}
+	// empty sections are [] rather than null so the frontend can map over them
+	want := `{"login_history":[],"logout_history":[],"password_changes":[],"privacy_changes":[],"account_status":[],"signup_info":null}`
+	if got := strings.TrimSpace(rec.Body.String()); got != want {
+		t.Errorf("body = %s\nwant   %s", got, want)
+	}
+}
+
+func TestSecurityHandlerCapsLoginHistory(t *testing.T) {
+	s, mem, accountID := newTestServer(t)
+	archive := mem.Archive(accountID)
+	for i := 0; i < securityHistoryLimit+10; i++ {
+		archive.Logins = append(archive.Logins, models.LoginHistory{ID: i + 1, LoggedInAt: day(1).Add(time.Duration(i) * time.Minute)})
+	}
+	rec := serveAs(testUserID, s.getSecurityHandler, httptest.NewRequest(http.MethodGet, "/api/v1/security", nil))
+	body := decodeBody[struct {
+		LoginHistory []models.LoginHistory `json:"login_history"`
+	}](t, rec)
+	if len(body.LoginHistory) != securityHistoryLimit || body.LoginHistory[0].ID != securityHistoryLimit+10 {
+		t.Errorf("got %d logins starting at id %d", len(body.LoginHistory), body.LoginHistory[0].ID)
+	}
+}
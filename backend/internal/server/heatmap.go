@@ -0,0 +1,129 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	_ "time/tzdata" // the alpine image has no zoneinfo for time.LoadLocation
+
+	"github.com/Sa-Te/IAV/backend/internal/models"
+	"github.com/Sa-Te/IAV/backend/internal/store"
+)
+
+// activityMonth is one month of the heatmap's history, e.g. "2024-03".
+type activityMonth struct {
+	Month      string         `json:"month"`
+	Total      int            `json:"total"`
+	Categories map[string]int `json:"categories"`
+}
+
+// parseCategories reads the comma-separated `category` parameter; all categories when absent.
+func parseCategories(r *http.Request) ([]string, error) {
+	v := r.URL.Query().Get("category")
+	if v == "" {
+		return store.ActivityCategories, nil
+	}
+	var categories []string
+	for _, c := range strings.Split(v, ",") {
+		c = strings.TrimSpace(c)
+		if c == "" || containsString(categories, c) {
+			continue
+		}
+		if !containsString(store.ActivityCategories, c) {
+			return nil, fmt.Errorf("unknown category %q (expected one of %s)", c, strings.Join(store.ActivityCategories, ", "))
+		}
+		categories = append(categories, c)
+	}
+	if len(categories) == 0 {
+		return nil, fmt.Errorf("category must name at least one category")
+	}
+	return categories, nil
+}
+
+// parseTimeZone reads the `tz` parameter, an IANA time zone name; UTC when absent.
+func parseTimeZone(r *http.Request) (*time.Location, error) {
+	name := r.URL.Query().Get("tz")
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil || name == "Local" {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	return loc, nil
+}
+
+// getActivityHeatmapHandler serves /api/v1/insights/activity: when the archive's events
+// happened, by day of week and hour and by month, in the requested time zone.
+func (s *APIServer) getActivityHeatmapHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+	accountID, ok := s.accountFromRequest(w, r, userID)
+	if !ok {
+		return
+	}
+
+	categories, err := parseCategories(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	loc, err := parseTimeZone(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	hours, err := s.store.Insights.ActivityByHour(r.Context(), accountID, categories, loc)
+	var months []models.ActivityMonth
+	if err == nil {
+		months, err = s.store.Insights.ActivityByMonth(r.Context(), accountID, categories, loc)
+	}
+	if err != nil {
+		log.Printf("Database query error in getActivityHeatmapHandler: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to get activity")
+		return
+	}
+
+	// Weekdays count from Sunday (0), as time.Weekday and Postgres' DOW do.
+	type Response struct {
+		TimeZone   string          `json:"time_zone"`
+		Categories []string        `json:"categories"`
+		Total      int             `json:"total"`
+		ByCategory map[string]int  `json:"by_category"`
+		Heatmap    [7][24]int      `json:"heatmap"`
+		ByHour     [24]int         `json:"by_hour"`
+		ByWeekday  [7]int          `json:"by_weekday"`
+		Months     []activityMonth `json:"months"`
+	}
+	resp := Response{TimeZone: loc.String(), Categories: categories, ByCategory: map[string]int{}, Months: []activityMonth{}}
+	for _, c := range categories {
+		resp.ByCategory[c] = 0
+	}
+	for _, h := range hours {
+		resp.Total += h.Count
+		resp.ByCategory[h.Category] += h.Count
+		resp.Heatmap[h.Weekday][h.Hour] += h.Count
+		resp.ByHour[h.Hour] += h.Count
+		resp.ByWeekday[h.Weekday] += h.Count
+	}
+	// months arrive in order, so each one only needs comparing with the last
+	for _, m := range months {
+		month := m.Month.Format("2006-01")
+		if n := len(resp.Months); n == 0 || resp.Months[n-1].Month != month {
+			resp.Months = append(resp.Months, activityMonth{Month: month, Categories: map[string]int{}})
+		}
+		last := &resp.Months[len(resp.Months)-1]
+		last.Total += m.Count
+		last.Categories[m.Category] += m.Count
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Sa-Te/IAV/backend/internal/models"
+)
+
+func TestActivityHeatmapHandler(t *testing.T) {
+	s, mem, accountID := newTestServer(t)
+	archive := mem.Archive(accountID)
+	// Sunday 2024-03-31 23:30 UTC is already Monday 01:30 in Berlin, which switched to summer time that night
+	late := time.Date(2024, 3, 31, 23, 30, 0, 0, time.UTC)
+	archive.Messages = []models.Message{{ID: 1, SentAt: late}, {ID: 2, SentAt: day(5)}}
+	archive.PostLikes = []models.PostLike{{ID: 1, LikedAt: day(5)}}
+	archive.Activity = []models.ActivityLog{{ID: 1, ActivityType: "ad_viewed", Timestamp: day(6)}, {ID: 2, ActivityType: "post_viewed"}}
+
+	type response struct {
+		TimeZone   string         `json:"time_zone"`
+		Total      int            `json:"total"`
+		ByCategory map[string]int `json:"by_category"`
+		Heatmap    [7][24]int     `json:"heatmap"`
+		ByHour     [24]int        `json:"by_hour"`
+		Months     []struct {
+			Month      string         `json:"month"`
+			Total      int            `json:"total"`
+			Categories map[string]int `json:"categories"`
+		} `json:"months"`
+	}
+	get := func(target string) *httptest.ResponseRecorder {
+		return serveAs(testUserID, s.getActivityHeatmapHandler, httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	// undated impressions are left out
+	body := decodeBody[response](t, get("/api/v1/insights/activity"))
+	if body.TimeZone != "UTC" || body.Total != 4 || body.ByCategory["messages"] != 2 || body.ByCategory["views"] != 0 {
+		t.Errorf("totals: %+v", body)
+	}
+	if body.Heatmap[time.Sunday][23] != 1 || body.ByHour[12] != 3 || len(body.Months) != 1 || body.Months[0].Categories["likes"] != 1 {
+		t.Errorf("UTC buckets: %+v", body)
+	}
+
+	body = decodeBody[response](t, get("/api/v1/insights/activity?tz=Europe/Berlin&category=messages"))
+	if body.Total != 2 || body.Heatmap[time.Monday][1] != 1 || body.ByHour[13] != 1 {
+		t.Errorf("Berlin buckets: %+v", body)
+	}
+	if len(body.Months) != 2 || body.Months[0].Month != "2024-03" || body.Months[1].Month != "2024-04" || body.Months[1].Total != 1 {
+		t.Errorf("Berlin months: %+v", body.Months)
+	}
+
+	for _, target := range []string{"/api/v1/insights/activity?tz=Mars/Olympus", "/api/v1/insights/activity?category=naps", "/api/v1/insights/activity?category=,"} {
+		if rec := get(target); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", target, rec.Code)
+		}
+	}
+}
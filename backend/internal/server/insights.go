@@ -0,0 +1,229 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/Sa-Te/IAV/backend/internal/models"
+	"github.com/Sa-Te/IAV/backend/internal/store"
+)
+
+// Reciprocity lists: accounts you follow that follow you back, that don't, and that
+// follow you without you following them.
+const (
+	relationshipMutuals          = "mutuals"
+	relationshipNotFollowingBack = "not_following_back"
+	relationshipFans             = "fans"
+)
+
+var relationshipLists = []string{relationshipNotFollowingBack, relationshipFans, relationshipMutuals}
+
+// relationshipLabels are the connection types reported alongside the follow state.
+var relationshipLabels = []string{"close_friend", "restricted", "blocked", "story_hidden_from"}
+
+var relationshipsListSpec = listSpec{
+	byUsername:  true,
+	extraParams: []string{"list", "label"},
+}
+
+// relationshipCounts is how many accounts fall in each reciprocity list. Neither counts
+// labelled accounts that don't follow in either direction, such as blocked ones.
+type relationshipCounts struct {
+	Mutuals          int `json:"mutuals"`
+	NotFollowingBack int `json:"not_following_back"`
+	Fans             int `json:"fans"`
+	Neither          int `json:"neither"`
+}
+
+func (c *relationshipCounts) add(list string) {
+	switch list {
+	case relationshipMutuals:
+		c.Mutuals++
+	case relationshipNotFollowingBack:
+		c.NotFollowingBack++
+	case relationshipFans:
+		c.Fans++
+	default:
+		c.Neither++
+	}
+}
+
+// relationshipAnalysis holds every account of the connections, grouped by reciprocity
+// list and ordered by username.
+type relationshipAnalysis struct {
+	Followers int
+	Following int
+	Counts    relationshipCounts
+	Lists     map[string][]models.RelationshipEntry
+	Overlaps  map[string]relationshipCounts
+}
+
+// relationshipList returns which reciprocity list an entry belongs to, or "" when
+// neither side follows the other.
+func relationshipList(e models.RelationshipEntry) string {
+	switch {
+	case e.FollowingSince != nil && e.FollowerSince != nil:
+		return relationshipMutuals
+	case e.FollowingSince != nil:
+		return relationshipNotFollowingBack
+	case e.FollowerSince != nil:
+		return relationshipFans
+	}
+	return ""
+}
+
+// analyzeRelationships joins the follower, following and label rows by username.
+// Usernames are matched case-insensitively, as Instagram does.
+func analyzeRelationships(connections []models.Connection) relationshipAnalysis {
+	entries := map[string]*models.RelationshipEntry{}
+	var order []string
+	for _, c := range connections {
+		key := strings.ToLower(c.Username)
+		e, ok := entries[key]
+		if !ok {
+			e = &models.RelationshipEntry{ID: c.ID, Username: c.Username, Labels: []string{}}
+			entries[key] = e
+			order = append(order, key)
+		}
+		at := c.Timestamp
+		switch c.ConnectionType {
+		case "following":
+			e.FollowingSince, e.ID = &at, c.ID
+		case "follower":
+			// the entry keeps the id of its following row when it has one
+			e.FollowerSince = &at
+			if e.FollowingSince == nil {
+				e.ID = c.ID
+			}
+		default:
+			if slices.Contains(relationshipLabels, c.ConnectionType) && !slices.Contains(e.Labels, c.ConnectionType) {
+				e.Labels = append(e.Labels, c.ConnectionType)
+			}
+		}
+	}
+
+	a := relationshipAnalysis{Lists: map[string][]models.RelationshipEntry{}, Overlaps: map[string]relationshipCounts{}}
+	for _, label := range relationshipLabels {
+		a.Overlaps[label] = relationshipCounts{}
+	}
+	for _, list := range relationshipLists {
+		a.Lists[list] = []models.RelationshipEntry{}
+	}
+	for _, key := range order {
+		e := entries[key]
+		slices.SortFunc(e.Labels, func(x, y string) int {
+			return slices.Index(relationshipLabels, x) - slices.Index(relationshipLabels, y)
+		})
+		if e.FollowerSince != nil {
+			a.Followers++
+		}
+		if e.FollowingSince != nil {
+			a.Following++
+		}
+		list := relationshipList(*e)
+		if list != "" || len(e.Labels) > 0 {
+			a.Counts.add(list)
+		}
+		for _, label := range e.Labels {
+			overlap := a.Overlaps[label]
+			overlap.add(list)
+			a.Overlaps[label] = overlap
+		}
+		if list != "" {
+			a.Lists[list] = append(a.Lists[list], *e)
+		}
+	}
+	for _, entries := range a.Lists {
+		slices.SortFunc(entries, func(x, y models.RelationshipEntry) int { return strings.Compare(x.Username, y.Username) })
+	}
+	return a
+}
+
+// getRelationshipsHandler serves /api/v1/insights/relationships: counts for every
+// reciprocity list and label, and one list paged by username.
+func (s *APIServer) getRelationshipsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+	accountID, ok := s.accountFromRequest(w, r, userID)
+	if !ok {
+		return
+	}
+
+	pg, ok := pageFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	q, ok := listQueryFromRequest(w, r, relationshipsListSpec)
+	if !ok {
+		return
+	}
+
+	list := relationshipNotFollowingBack
+	if v := r.URL.Query().Get("list"); v != "" {
+		if !containsString(relationshipLists, v) {
+			writeJSONError(w, http.StatusBadRequest, "list must be one of "+strings.Join(relationshipLists, ", "))
+			return
+		}
+		list = v
+	}
+	label := r.URL.Query().Get("label")
+	if label != "" && !containsString(relationshipLabels, label) {
+		writeJSONError(w, http.StatusBadRequest, "label must be one of "+strings.Join(relationshipLabels, ", "))
+		return
+	}
+
+	connections, err := s.store.Connections.ListConnections(r.Context(), accountID, store.ListOptions{
+		Types: append([]string{"follower", "following"}, relationshipLabels...),
+		Sort:  store.SortDate,
+	})
+	if err != nil {
+		log.Printf("Database query error in getRelationshipsHandler: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to get connections")
+		return
+	}
+	analysis := analyzeRelationships(connections)
+
+	items := make([]models.RelationshipEntry, 0)
+	for _, e := range analysis.Lists[list] {
+		if pg.After != nil && e.Username <= pg.After.Key {
+			continue
+		}
+		if q.Username != "" && !strings.Contains(strings.ToLower(e.Username), strings.ToLower(q.Username)) {
+			continue
+		}
+		if label != "" && !slices.Contains(e.Labels, label) {
+			continue
+		}
+		items = append(items, e)
+		if len(items) > pg.Limit {
+			break
+		}
+	}
+	entries := newPage(items, pg, func(e models.RelationshipEntry) pageCursor { return pageCursor{Key: e.Username, ID: e.ID} })
+
+	type Response struct {
+		Counts struct {
+			Followers int `json:"followers"`
+			Following int `json:"following"`
+			relationshipCounts
+		} `json:"counts"`
+		Overlaps   map[string]relationshipCounts `json:"overlaps"`
+		List       string                        `json:"list"`
+		Items      []models.RelationshipEntry    `json:"items"`
+		NextCursor *string                       `json:"next_cursor"`
+	}
+	resp := Response{Overlaps: analysis.Overlaps, List: list, Items: entries.Items, NextCursor: entries.NextCursor}
+	resp.Counts.Followers = analysis.Followers
+	resp.Counts.Following = analysis.Following
+	resp.Counts.relationshipCounts = analysis.Counts
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
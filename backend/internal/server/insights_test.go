@@ -0,0 +1,98 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Sa-Te/IAV/backend/internal/models"
+)
+
+func TestAnalyzeRelationships(t *testing.T) {
+	a := analyzeRelationships([]models.Connection{
+		{ID: 1, Username: "anna", ConnectionType: "following", Timestamp: day(1)},
+		{ID: 2, Username: "anna", ConnectionType: "follower", Timestamp: day(2)},
+		{ID: 3, Username: "Bob", ConnectionType: "following", Timestamp: day(3)},
+		{ID: 4, Username: "bob", ConnectionType: "close_friend", Timestamp: day(3)},
+		{ID: 5, Username: "carl", ConnectionType: "follower", Timestamp: day(4)},
+		{ID: 6, Username: "carl", ConnectionType: "restricted", Timestamp: day(4)},
+		{ID: 7, Username: "troll", ConnectionType: "blocked", Timestamp: day(5)},
+		{ID: 8, Username: "anna", ConnectionType: "close_friend", Timestamp: day(5)},
+	})
+
+	if a.Followers != 2 || a.Following != 2 {
+		t.Errorf("followers %d, following %d, want 2 and 2", a.Followers, a.Following)
+	}
+	if want := (relationshipCounts{Mutuals: 1, NotFollowingBack: 1, Fans: 1, Neither: 1}); a.Counts != want {
+		t.Errorf("counts = %+v, want %+v", a.Counts, want)
+	}
+	if want := (relationshipCounts{Mutuals: 1, NotFollowingBack: 1}); a.Overlaps["close_friend"] != want {
+		t.Errorf("close_friend overlap = %+v, want %+v", a.Overlaps["close_friend"], want)
+	}
+	if want := (relationshipCounts{Neither: 1}); a.Overlaps["blocked"] != want {
+		t.Errorf("blocked overlap = %+v, want %+v", a.Overlaps["blocked"], want)
+	}
+
+	// usernames match case-insensitively and entries keep the id of their following row
+	bob := a.Lists[relationshipNotFollowingBack]
+	if len(bob) != 1 || bob[0].ID != 3 || bob[0].FollowerSince != nil || strings.Join(bob[0].Labels, ",") != "close_friend" {
+		t.Errorf("not following back = %+v", bob)
+	}
+	anna := a.Lists[relationshipMutuals]
+	if len(anna) != 1 || anna[0].ID != 1 || !anna[0].FollowerSince.Equal(day(2)) {
+		t.Errorf("mutuals = %+v", anna)
+	}
+}
+
+func TestRelationshipsHandlerPagesTheChosenList(t *testing.T) {
+	s, mem, accountID := newTestServer(t)
+	archive := mem.Archive(accountID)
+	for i, name := range []string{"dora", "ben", "cleo", "abe"} {
+		archive.Connections = append(archive.Connections, models.Connection{ID: i + 1, Username: name, ConnectionType: "follower", Timestamp: day(i + 1)})
+	}
+	archive.Connections = append(archive.Connections, models.Connection{ID: 9, Username: "cleo", ConnectionType: "restricted", Timestamp: day(9)})
+
+	type response struct {
+		Counts struct {
+			Followers int `json:"followers"`
+			Fans      int `json:"fans"`
+		} `json:"counts"`
+		Items      []models.RelationshipEntry `json:"items"`
+		NextCursor *string                    `json:"next_cursor"`
+	}
+	var names []string
+	url := "/api/v1/insights/relationships?list=fans&limit=3"
+	for pages := 0; url != ""; pages++ {
+		if pages > 3 {
+			t.Fatal("cursor never ran out")
+		}
+		rec := serveAs(testUserID, s.getRelationshipsHandler, httptest.NewRequest(http.MethodGet, url, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: %d %s", url, rec.Code, rec.Body.String())
+		}
+		body := decodeBody[response](t, rec)
+		if body.Counts.Followers != 4 || body.Counts.Fans != 4 {
+			t.Errorf("counts = %+v", body.Counts)
+		}
+		for _, e := range body.Items {
+			names = append(names, e.Username)
+		}
+		url = ""
+		if body.NextCursor != nil {
+			url = "/api/v1/insights/relationships?list=fans&limit=3&cursor=" + *body.NextCursor
+		}
+	}
+	if strings.Join(names, ",") != "abe,ben,cleo,dora" {
+		t.Errorf("fans = %v, want them by username", names)
+	}
+
+	rec := serveAs(testUserID, s.getRelationshipsHandler, httptest.NewRequest(http.MethodGet, "/api/v1/insights/relationships?list=fans&label=restricted", nil))
+	if items := decodeBody[response](t, rec).Items; len(items) != 1 || items[0].Username != "cleo" {
+		t.Errorf("restricted fans = %+v", items)
+	}
+	rec = serveAs(testUserID, s.getRelationshipsHandler, httptest.NewRequest(http.MethodGet, "/api/v1/insights/relationships?list=friends", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown list: status %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Sa-Te/IAV/backend/internal/models"
+	"github.com/Sa-Te/IAV/backend/internal/store"
+)
+
+const (
+	defaultInteractionsLimit = 20
+	maxInteractionsLimit     = 100
+)
+
+// interactionWeights scores each kind of interaction by the effort behind it: a comment
+// or an answered question says more than a tap on a heart.
+var interactionWeights = map[string]int{
+	store.InteractionPostLike:      1,
+	store.InteractionCommentLike:   1,
+	store.InteractionStoryLike:     1,
+	store.InteractionStoryReaction: 1,
+	store.InteractionMessage:       1,
+	store.InteractionStoryPoll:     2,
+	store.InteractionStoryQuiz:     2,
+	store.InteractionStorySlider:   2,
+	store.InteractionStoryQuestion: 3,
+	store.InteractionPostComment:   3,
+	store.InteractionReelComment:   3,
+}
+
+// interactionPerson is one username's interaction score, with the count of each kind.
+type interactionPerson struct {
+	Username  string         `json:"username"`
+	Score     int            `json:"score"`
+	Total     int            `json:"total"`
+	Breakdown map[string]int `json:"breakdown"`
+	FirstAt   time.Time      `json:"first_at"`
+	LastAt    time.Time      `json:"last_at"`
+}
+
+// scoreInteractions turns per-kind counts, ordered by username, into one entry per
+// username, highest score first.
+func scoreInteractions(counts []models.InteractionCount) []interactionPerson {
+	var people []interactionPerson
+	for _, c := range counts {
+		if n := len(people); n == 0 || people[n-1].Username != c.Username {
+			people = append(people, interactionPerson{Username: c.Username, Breakdown: map[string]int{}, FirstAt: c.FirstAt, LastAt: c.LastAt})
+		}
+		p := &people[len(people)-1]
+		p.Score += interactionWeights[c.Kind] * c.Count
+		p.Total += c.Count
+		p.Breakdown[c.Kind] += c.Count
+		if c.FirstAt.Before(p.FirstAt) {
+			p.FirstAt = c.FirstAt
+		}
+		if c.LastAt.After(p.LastAt) {
+			p.LastAt = c.LastAt
+		}
+	}
+	slices.SortStableFunc(people, func(a, b interactionPerson) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), cmp.Compare(b.Total, a.Total), strings.Compare(a.Username, b.Username))
+	})
+	return people
+}
+
+// getInteractionsHandler serves /api/v1/insights/interactions: the accounts you engage
+// with most across likes, comments, story replies and messages.
+func (s *APIServer) getInteractionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+	accountID, ok := s.accountFromRequest(w, r, userID)
+	if !ok {
+		return
+	}
+
+	limit := defaultInteractionsLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxInteractionsLimit {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxInteractionsLimit))
+			return
+		}
+		limit = n
+	}
+
+	me, err := s.ownerName(r, accountID)
+	var counts []models.InteractionCount
+	if err == nil {
+		counts, err = s.store.Insights.InteractionCounts(r.Context(), accountID, me)
+	}
+	if err != nil {
+		log.Printf("Database query error in getInteractionsHandler: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to get interactions")
+		return
+	}
+
+	type Response struct {
+		Me      string              `json:"me"`
+		Weights map[string]int      `json:"weights"`
+		People  []interactionPerson `json:"people"`
+	}
+	people := scoreInteractions(counts)
+	resp := Response{Me: me, Weights: interactionWeights, People: append(make([]interactionPerson, 0), atMost(people, limit)...)}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// getInteractionDetailHandler serves /api/v1/insights/interactions/{username}: the
+// username's score over every interaction and a page of them, oldest first.
+func (s *APIServer) getInteractionDetailHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+	accountID, ok := s.accountFromRequest(w, r, userID)
+	if !ok {
+		return
+	}
+	pg, ok := pageFromRequest(w, r)
+	if !ok {
+		return
+	}
+	username := strings.ToLower(strings.TrimSpace(r.PathValue("username")))
+
+	// the score covers every interaction, not just the ones on this page
+	me, err := s.ownerName(r, accountID)
+	var counts []models.InteractionCount
+	if err == nil {
+		counts, err = s.store.Insights.InteractionCounts(r.Context(), accountID, me)
+	}
+	var interactions []models.Interaction
+	if err == nil {
+		interactions, err = s.store.Insights.ListInteractions(r.Context(), accountID, me, username, listOptions(listQuery{}, pg))
+	}
+	if err != nil {
+		log.Printf("Database query error in getInteractionDetailHandler: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to get interactions")
+		return
+	}
+	counts = slices.DeleteFunc(counts, func(c models.InteractionCount) bool { return c.Username != username })
+	if len(counts) == 0 {
+		writeJSONError(w, http.StatusNotFound, "No interactions with this username")
+		return
+	}
+
+	type Response struct {
+		Me string `json:"me"`
+		interactionPerson
+		Interactions []models.Interaction `json:"interactions"`
+		NextCursor   *string              `json:"next_cursor"`
+	}
+	resp := Response{Me: me, interactionPerson: scoreInteractions(counts)[0]}
+	interactionPage := newPage(interactions, pg, func(i models.Interaction) pageCursor { return pageCursor{At: i.At, Key: i.Kind, ID: i.ID} })
+	resp.Interactions, resp.NextCursor = interactionPage.Items, interactionPage.NextCursor
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Sa-Te/IAV/backend/internal/models"
+)
+
+func TestInteractionsHandlers(t *testing.T) {
+	s, mem, accountID := newTestServer(t)
+	archive := mem.Archive(accountID)
+	archive.PostLikes = []models.PostLike{
+		{ID: 1, CreatorUsername: "jane", PostURL: "https://instagram.com/p/1", LikedAt: day(2)},
+		{ID: 2, CreatorUsername: "bob", LikedAt: day(3)},
+		{ID: 3, CreatorUsername: "bob", LikedAt: day(4)},
+	}
+	archive.PostComments = []models.PostComment{{ID: 1, PostOwnerUsername: "Jane", CommentText: "nice", CommentedAt: day(5)}}
+	archive.StoryPolls = []models.StoryPoll{{ID: 1, CreatorUsername: "jane", PollAnswer: "yes", AnsweredAt: day(1)}}
+	archive.Conversations = []models.MessageConversation{
+		{ConversationID: "jane_123", Participants: "Jane Doe, Me"},
+		{ConversationID: "bob_456", Participants: "Bob, Me"},
+		{ConversationID: "group_789", Participants: "Jane Doe, Bob, Me"},
+	}
+	archive.Messages = []models.Message{
+		{ID: 1, ConversationID: "jane_123", SenderName: "Me", Content: "hi", SentAt: day(6)},
+		{ID: 2, ConversationID: "jane_123", SenderName: "Jane Doe", Content: "hey", SentAt: day(6)}, // received, not counted
+		{ID: 3, ConversationID: "group_789", SenderName: "Me", Content: "all", SentAt: day(7)},      // group, not counted
+	}
+
+	rec := serveAs(testUserID, s.getInteractionsHandler, httptest.NewRequest(http.MethodGet, "/api/v1/insights/interactions", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	body := decodeBody[struct {
+		Me     string              `json:"me"`
+		People []interactionPerson `json:"people"`
+	}](t, rec)
+	if body.Me != "Me" || len(body.People) != 2 {
+		t.Fatalf("body = %+v", body)
+	}
+	// like 1 + comment 3 + poll 2 + message 1
+	jane := body.People[0]
+	if jane.Username != "jane" || jane.Score != 7 || jane.Total != 4 || jane.Breakdown["message"] != 1 || jane.Breakdown["post_comment"] != 1 ||
+		!jane.FirstAt.Equal(day(1)) || !jane.LastAt.Equal(day(6)) {
+		t.Errorf("jane = %+v", jane)
+	}
+	if bob := body.People[1]; bob.Username != "bob" || bob.Score != 2 {
+		t.Errorf("bob = %+v", bob)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/insights/interactions/Jane", nil)
+	req.SetPathValue("username", "Jane")
+	detail := decodeBody[struct {
+		Username     string               `json:"username"`
+		Score        int                  `json:"score"`
+		Interactions []models.Interaction `json:"interactions"`
+	}](t, serveAs(testUserID, s.getInteractionDetailHandler, req))
+	if detail.Username != "jane" || detail.Score != 7 || len(detail.Interactions) != 4 {
+		t.Fatalf("detail = %+v", detail)
+	}
+	if first := detail.Interactions[0]; first.Kind != "story_poll" || *first.Detail != "yes" {
+		t.Errorf("first interaction = %+v", first)
+	}
+	if last := detail.Interactions[3]; last.Kind != "message" || *last.Detail != "hi" {
+		t.Errorf("last interaction = %+v", last)
+	}
+
+	// paging keeps the score over every interaction
+	var kinds []string
+	url := "/api/v1/insights/interactions/jane?limit=3"
+	for url != "" {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		req.SetPathValue("username", "jane")
+		rec := serveAs(testUserID, s.getInteractionDetailHandler, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+		}
+		pg := decodeBody[struct {
+			Score        int                  `json:"score"`
+			Interactions []models.Interaction `json:"interactions"`
+			NextCursor   *string              `json:"next_cursor"`
+		}](t, rec)
+		if pg.Score != 7 {
+			t.Errorf("page score = %d, want 7", pg.Score)
+		}
+		for _, i := range pg.Interactions {
+			kinds = append(kinds, i.Kind)
+		}
+		url = ""
+		if pg.NextCursor != nil {
+			url = "/api/v1/insights/interactions/jane?limit=3&cursor=" + *pg.NextCursor
+		}
+	}
+	if fmt.Sprint(kinds) != "[story_poll post_like post_comment message]" {
+		t.Errorf("paged kinds = %v", kinds)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/insights/interactions/nobody", nil)
+	req.SetPathValue("username", "nobody")
+	if rec := serveAs(testUserID, s.getInteractionDetailHandler, req); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown username: status %d, want 404", rec.Code)
+	}
+	if rec := serveAs(testUserID, s.getInteractionsHandler, httptest.NewRequest(http.MethodGet, "/api/v1/insights/interactions?limit=0", nil)); rec.Code != http.StatusBadRequest {
+		t.Errorf("limit=0: status %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,211 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Sa-Te/IAV/backend/internal/store"
+)
+
+// Query parameters every list endpoint understands regardless of its listSpec.
+var commonListParams = []string{"account", "limit", "cursor"}
+
+// listSpec declares which filters and sorts an endpoint supports. The repository
+// behind the endpoint owns the columns; the spec only validates the request.
+type listSpec struct {
+	typed       bool     // accepts `type`
+	types       []string // allowed `type` values; nil accepts any value
+	dated       bool     // accepts `from` and `to`
+	byUsername  bool     // accepts `username`
+	sorts       []string // store.SortDate, store.SortUsername
+	defaultSort string
+	extraParams []string // endpoint-specific parameters the handler reads itself
+}
+
+type listQuery struct {
+	Types     []string
+	From      *time.Time
+	To        *time.Time // exclusive
+	Sort      string
+	Ascending bool
+	Username  string
+}
+
+// parseListQuery validates the filter and sort parameters against spec, rejecting
+// parameters the endpoint doesn't know so typos don't silently return everything.
+func parseListQuery(r *http.Request, spec listSpec) (listQuery, error) {
+	q := listQuery{Sort: spec.defaultSort}
+	values := r.URL.Query()
+
+	allowed := map[string]bool{}
+	for _, p := range commonListParams {
+		allowed[p] = true
+	}
+	for _, p := range spec.extraParams {
+		allowed[p] = true
+	}
+	if spec.typed {
+		allowed["type"] = true
+	}
+	if spec.dated {
+		allowed["from"], allowed["to"] = true, true
+	}
+	if spec.byUsername {
+		allowed["username"] = true
+	}
+	if len(spec.sorts) > 0 {
+		allowed["sort"], allowed["order"] = true, true
+	}
+	for name := range values {
+		if !allowed[name] {
+			return q, fmt.Errorf("unknown query parameter %q", name)
+		}
+	}
+
+	if v := values.Get("type"); v != "" {
+		for _, t := range strings.Split(v, ",") {
+			t = strings.TrimSpace(t)
+			if t == "" {
+				continue
+			}
+			if spec.types != nil && !containsString(spec.types, t) {
+				return q, fmt.Errorf("unknown type %q (expected one of %s)", t, strings.Join(spec.types, ", "))
+			}
+			q.Types = append(q.Types, t)
+		}
+	}
+
+	var err error
+	if q.From, err = parseListDate(values.Get("from"), false); err != nil {
+		return q, fmt.Errorf("from: %w", err)
+	}
+	if q.To, err = parseListDate(values.Get("to"), true); err != nil {
+		return q, fmt.Errorf("to: %w", err)
+	}
+	if q.From != nil && q.To != nil && !q.From.Before(*q.To) {
+		return q, fmt.Errorf("from must be before to")
+	}
+
+	q.Username = strings.TrimPrefix(strings.TrimSpace(values.Get("username")), "@")
+
+	if v := values.Get("sort"); v != "" {
+		if !containsString(spec.sorts, v) {
+			return q, fmt.Errorf("unknown sort %q (expected one of %s)", v, strings.Join(spec.sorts, ", "))
+		}
+		q.Sort = v
+	}
+	switch strings.ToLower(values.Get("order")) {
+	case "", "desc":
+	case "asc":
+		q.Ascending = true
+	default:
+		return q, fmt.Errorf("order must be asc or desc")
+	}
+	return q, nil
+}
+
+// listQueryFromRequest parses the filters, writing a 400 itself when they are invalid.
+func listQueryFromRequest(w http.ResponseWriter, r *http.Request, spec listSpec) (listQuery, bool) {
+	q, err := parseListQuery(r, spec)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return q, false
+	}
+	return q, true
+}
+
+// parseListDate accepts a plain date or an RFC 3339 timestamp. A plain `to` date
+// includes that whole day, so it is moved to the following midnight.
+func parseListDate(v string, endOfRange bool) (*time.Time, error) {
+	if v == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return nil, fmt.Errorf("expected a date like 2024-01-31 or an RFC 3339 timestamp")
+	}
+	if endOfRange {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
+
+// listOptions hands the parsed filters and page to a repository.
+func listOptions(q listQuery, pg pageRequest) store.ListOptions {
+	opts := store.ListOptions{
+		Types:     q.Types,
+		From:      q.From,
+		To:        q.To,
+		Username:  q.Username,
+		Sort:      q.Sort,
+		Ascending: q.Ascending,
+		Limit:     pg.Limit,
+	}
+	if pg.After != nil {
+		opts.After = &store.Cursor{At: pg.After.At, Key: pg.After.Key, ID: pg.After.ID}
+	}
+	return opts
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+var mediaListSpec = listSpec{
+	typed:       true,
+	types:       []string{"post", "story"},
+	dated:       true,
+	sorts:       []string{store.SortDate},
+	defaultSort: store.SortDate,
+}
+
+var connectionsListSpec = listSpec{
+	typed: true,
+	types: []string{"follower", "following", "contact", "blocked", "close_friend", "request_received",
+		"request_sent", "request_sent_permanent", "story_hidden_from", "unfollowed", "suggestion_removed", "restricted"},
+	dated:       true,
+	byUsername:  true,
+	sorts:       []string{store.SortDate, store.SortUsername},
+	defaultSort: store.SortDate,
+}
+
+var activityListSpec = listSpec{
+	typed:       true,
+	types:       []string{"ad_viewed", "post_viewed", "video_watched", "suggested_profile_viewed", "post_not_interested"},
+	dated:       true,
+	byUsername:  true,
+	sorts:       []string{store.SortDate},
+	defaultSort: store.SortDate,
+}
+
+var searchHistoryListSpec = listSpec{
+	typed:       true,
+	types:       []string{"user", "keyword"},
+	dated:       true,
+	sorts:       []string{store.SortDate},
+	defaultSort: store.SortDate,
+}
+
+var offMetaListSpec = listSpec{
+	typed:       true,
+	dated:       true,
+	sorts:       []string{store.SortDate},
+	defaultSort: store.SortDate,
+}
+
+var messagesListSpec = listSpec{
+	dated:       true,
+	byUsername:  true,
+	sorts:       []string{store.SortDate},
+	defaultSort: store.SortDate,
+}
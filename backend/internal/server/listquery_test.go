@@ -0,0 +1,51 @@
+package server
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sa-Te/IAV/backend/internal/store"
+)
+
+func TestParseListQuery(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/v1/connections?type=blocked,restricted&from=2023-01-01&to=2023-01-31&sort=username&order=asc&username=@jo_e", nil)
+	q, err := parseListQuery(req, connectionsListSpec)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(q.Types) != 2 || q.Sort != "username" || !q.Ascending || q.Username != "jo_e" {
+		t.Errorf("unexpected query %+v", q)
+	}
+	if !q.To.Equal(time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("to should include the whole last day, got %v", q.To)
+	}
+
+	opts := listOptions(q, pageRequest{Limit: 10, After: &pageCursor{Key: "jo", ID: 5}})
+	if opts.Sort != store.SortUsername || opts.Limit != 10 || opts.After == nil || opts.After.Key != "jo" || opts.After.ID != 5 {
+		t.Errorf("listOptions = %+v", opts)
+	}
+}
+
+func TestParseListQueryRejectsUnknownFields(t *testing.T) {
+	cases := map[string]string{
+		"/api/v1/media?media_type=story":              "unknown query parameter",
+		"/api/v1/media?type=reel":                     "unknown type",
+		"/api/v1/media?username=x":                    "unknown query parameter",
+		"/api/v1/media?sort=username":                 "unknown sort",
+		"/api/v1/media?order=up":                      "order must be",
+		"/api/v1/media?from=yesterday":                "from:",
+		"/api/v1/media?from=2024-02-01&to=2024-01-01": "from must be before to",
+	}
+	for url, wantErr := range cases {
+		_, err := parseListQuery(httptest.NewRequest("GET", url, nil), mediaListSpec)
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("%s: err = %v, want %q", url, err, wantErr)
+		}
+	}
+
+	if _, err := parseListQuery(httptest.NewRequest("GET", "/api/v1/media?account=2&limit=5&cursor=x&type=story", nil), mediaListSpec); err != nil {
+		t.Errorf("common parameters should be accepted: %v", err)
+	}
+}
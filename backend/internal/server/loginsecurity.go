@@ -0,0 +1,207 @@
+package server
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/Sa-Te/IAV/backend/internal/models"
+)
+
+// suspiciousChangeWindow is how soon after a login from a new device a password or
+// privacy change gets flagged.
+const suspiciousChangeWindow = 48 * time.Hour
+
+// Security flag types.
+const (
+	flagNewDeviceBeforePasswordChange = "new_device_before_password_change"
+	flagNewDeviceBeforePrivacyChange  = "new_device_before_privacy_change"
+	flagNewDeviceAndIP                = "new_device_and_ip" // neither was seen before
+)
+
+// loginDevice is one device the account logged in from.
+type loginDevice struct {
+	Device         string    `json:"device"`
+	Client         string    `json:"client"`
+	OS             string    `json:"os"`
+	Model          string    `json:"model"`
+	ClientVersions []string  `json:"client_versions"` // in the order first seen
+	IPs            []string  `json:"ips"`
+	Logins         int       `json:"logins"`
+	FirstSeen      time.Time `json:"first_seen"`
+	LastSeen       time.Time `json:"last_seen"`
+}
+
+// loginIP is one IP address the account logged in from.
+type loginIP struct {
+	IP        string    `json:"ip"`
+	Devices   []string  `json:"devices"`
+	Logins    int       `json:"logins"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// analyzedLogin is a login_history row with its user agent parsed, and whether its
+// device and IP had been seen before.
+type analyzedLogin struct {
+	models.LoginHistory
+	ParsedUserAgent userAgent `json:"parsed_user_agent"`
+	Device          string    `json:"device"`
+	NewDevice       bool      `json:"new_device"`
+	NewIP           bool      `json:"new_ip"`
+}
+
+type securityFlag struct {
+	Type    string    `json:"type"`
+	At      time.Time `json:"at"` // of the login
+	LoginID int       `json:"login_id"`
+	Device  string    `json:"device"`
+	IP      string    `json:"ip"`
+	Detail  string    `json:"detail"`
+}
+
+type loginAnalysis struct {
+	Devices []loginDevice
+	IPs     []loginIP
+	Logins  []analyzedLogin // newest first
+	Flags   []securityFlag  // newest first
+}
+
+// analyzeLogins groups the logins into devices and IPs and flags the unusual ones. The
+// archive's first login has nothing to compare with, so it is never flagged.
+func analyzeLogins(logins []models.LoginHistory, passwordChanges []models.PasswordChange, privacyChanges []models.PrivacyChange) loginAnalysis {
+	ordered := slices.Clone(logins)
+	slices.SortStableFunc(ordered, func(a, b models.LoginHistory) int {
+		return cmp.Or(a.LoggedInAt.Compare(b.LoggedInAt), cmp.Compare(a.ID, b.ID))
+	})
+
+	result := loginAnalysis{Devices: []loginDevice{}, IPs: []loginIP{}, Logins: make([]analyzedLogin, 0, len(ordered)), Flags: []securityFlag{}}
+	devices := map[string]int{}
+	ips := map[string]int{}
+	for _, l := range ordered {
+		ua := parseUserAgent(l.UserAgent)
+		login := analyzedLogin{LoginHistory: l, ParsedUserAgent: ua, Device: ua.device()}
+		if login.Device == "" {
+			login.Device = cmp.Or(l.UserAgent, "unknown")
+		}
+
+		i, seen := devices[login.Device]
+		if !seen {
+			i = len(result.Devices)
+			devices[login.Device] = i
+			result.Devices = append(result.Devices, loginDevice{Device: login.Device, Client: ua.Client, OS: ua.OS, Model: ua.Model,
+				ClientVersions: []string{}, IPs: []string{}, FirstSeen: l.LoggedInAt})
+		}
+		login.NewDevice = !seen
+		d := &result.Devices[i]
+		d.Logins++
+		d.LastSeen = l.LoggedInAt
+		if ua.ClientVersion != "" && !slices.Contains(d.ClientVersions, ua.ClientVersion) {
+			d.ClientVersions = append(d.ClientVersions, ua.ClientVersion)
+		}
+		if l.IPAddress != "" && !slices.Contains(d.IPs, l.IPAddress) {
+			d.IPs = append(d.IPs, l.IPAddress)
+		}
+
+		if l.IPAddress != "" {
+			j, seen := ips[l.IPAddress]
+			if !seen {
+				j = len(result.IPs)
+				ips[l.IPAddress] = j
+				result.IPs = append(result.IPs, loginIP{IP: l.IPAddress, Devices: []string{}, FirstSeen: l.LoggedInAt})
+			}
+			login.NewIP = !seen
+			ip := &result.IPs[j]
+			ip.Logins++
+			ip.LastSeen = l.LoggedInAt
+			if !slices.Contains(ip.Devices, login.Device) {
+				ip.Devices = append(ip.Devices, login.Device)
+			}
+		}
+		result.Logins = append(result.Logins, login)
+	}
+
+	flag := func(typ string, l analyzedLogin, detail string) {
+		result.Flags = append(result.Flags, securityFlag{Type: typ, At: l.LoggedInAt, LoginID: l.ID, Device: l.Device, IP: l.IPAddress, Detail: detail})
+	}
+	for i, l := range result.Logins {
+		if i == 0 || !l.NewDevice {
+			continue
+		}
+		if l.NewIP {
+			flag(flagNewDeviceAndIP, l, "first login from this device and this IP address")
+		}
+		for _, c := range passwordChanges {
+			if gap := c.ChangedAt.Sub(l.LoggedInAt); gap >= 0 && gap <= suspiciousChangeWindow {
+				flag(flagNewDeviceBeforePasswordChange, l, fmt.Sprintf("password changed %s later", gap.Round(time.Minute)))
+			}
+		}
+		for _, c := range privacyChanges {
+			if gap := c.ChangedAt.Sub(l.LoggedInAt); gap >= 0 && gap <= suspiciousChangeWindow {
+				flag(flagNewDeviceBeforePrivacyChange, l, fmt.Sprintf("account made %s %s later", c.PrivacyStatus, gap.Round(time.Minute)))
+			}
+		}
+	}
+
+	slices.Reverse(result.Logins)
+	slices.Reverse(result.Flags)
+	slices.SortStableFunc(result.Devices, func(a, b loginDevice) int { return b.LastSeen.Compare(a.LastSeen) })
+	slices.SortStableFunc(result.IPs, func(a, b loginIP) int { return b.LastSeen.Compare(a.LastSeen) })
+	return result
+}
+
+// getLoginSecurityHandler serves /api/v1/insights/security: the login history parsed
+// into devices and IP addresses, with flags on the logins worth a second look.
+func (s *APIServer) getLoginSecurityHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+	accountID, ok := s.accountFromRequest(w, r, userID)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	logins, err := s.store.Security.ListLogins(ctx, accountID, 0)
+	var passwordChanges []models.PasswordChange
+	if err == nil {
+		passwordChanges, err = s.store.Security.ListPasswordChanges(ctx, accountID)
+	}
+	var privacyChanges []models.PrivacyChange
+	if err == nil {
+		privacyChanges, err = s.store.Security.ListPrivacyChanges(ctx, accountID)
+	}
+	if err != nil {
+		log.Printf("Database query error in getLoginSecurityHandler: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to analyze logins")
+		return
+	}
+
+	analysis := analyzeLogins(logins, passwordChanges, privacyChanges)
+	type Response struct {
+		Summary struct {
+			Logins  int `json:"logins"`
+			Devices int `json:"devices"`
+			IPs     int `json:"ips"`
+			Flags   int `json:"flags"`
+		} `json:"summary"`
+		Flags   []securityFlag  `json:"flags"`
+		Devices []loginDevice   `json:"devices"`
+		IPs     []loginIP       `json:"ips"`
+		Logins  []analyzedLogin `json:"logins"` // the latest securityHistoryLimit
+	}
+	resp := Response{Flags: analysis.Flags, Devices: analysis.Devices, IPs: analysis.IPs, Logins: atMost(analysis.Logins, securityHistoryLimit)}
+	resp.Summary.Logins = len(analysis.Logins)
+	resp.Summary.Devices = len(analysis.Devices)
+	resp.Summary.IPs = len(analysis.IPs)
+	resp.Summary.Flags = len(analysis.Flags)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Sa-Te/IAV/backend/internal/models"
+)
+
+func TestParseUserAgent(t *testing.T) {
+	for ua, want := range map[string]userAgent{
+		"Instagram 275.0.0.27.98 Android (33/13; 420dpi; 1080x2220; samsung; SM-G991B; o1s; exynos2100; en_GB; 458229237)": {
+			Client: "Instagram", ClientVersion: "275.0.0.27.98", OS: "Android", OSVersion: "13", Model: "samsung SM-G991B"},
+		"Instagram 289.0.0.18.109 (iPhone14,5; iOS 16_5; en_US; en-US; scale=3.00; 1170x2532; 489720145) AppleWebKit/420+": {
+			Client: "Instagram", ClientVersion: "289.0.0.18.109", OS: "iOS", OSVersion: "16.5", Model: "iPhone14,5"},
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.2210.91": {
+			Client: "Edge", ClientVersion: "120.0.2210.91", OS: "Windows", OSVersion: "10.0"},
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Safari/605.1.15": {
+			Client: "Safari", ClientVersion: "17.1", OS: "macOS", OSVersion: "10.15.7"},
+		"Mozilla/5.0 (Linux; Android 13; Pixel 7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.0.0 Mobile Safari/537.36": {
+			Client: "Chrome", ClientVersion: "119.0.0.0", OS: "Android", OSVersion: "13"},
+		"curl/8.4.0": {},
+	} {
+		if got := parseUserAgent(ua); got != want {
+			t.Errorf("parseUserAgent(%q) = %+v, want %+v", ua, got, want)
+		}
+	}
+}
+
+func TestAnalyzeLogins(t *testing.T) {
+	const (
+		phone  = "Instagram 275.0.0.27.98 Android (33/13; 420dpi; 1080x2220; samsung; SM-G991B; o1s; exynos2100; en_GB; 458229237)"
+		update = "Instagram 276.1.0.26.103 Android (33/13; 420dpi; 1080x2220; samsung; SM-G991B; o1s; exynos2100; en_GB; 459311236)"
+		laptop = "Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0"
+	)
+	logins := []models.LoginHistory{
+		{ID: 4, IPAddress: "203.0.113.9", UserAgent: laptop, LoggedInAt: day(20)},
+		{ID: 3, IPAddress: "198.51.100.2", UserAgent: update, LoggedInAt: day(10)},
+		{ID: 2, IPAddress: "198.51.100.2", UserAgent: phone, LoggedInAt: day(5)},
+		{ID: 1, IPAddress: "198.51.100.1", UserAgent: phone, LoggedInAt: day(1)},
+	}
+	passwordChanges := []models.PasswordChange{{ID: 1, ChangedAt: day(20).Add(3 * time.Hour)}, {ID: 2, ChangedAt: day(2)}}
+	privacyChanges := []models.PrivacyChange{{ID: 1, PrivacyStatus: "public", ChangedAt: day(25)}}
+
+	a := analyzeLogins(logins, passwordChanges, privacyChanges)
+
+	// the app update keeps the phone one device
+	if len(a.Devices) != 2 || a.Devices[0].Device != "Firefox on Linux" || a.Devices[1].Device != "samsung SM-G991B" {
+		t.Fatalf("devices = %+v", a.Devices)
+	}
+	if phone := a.Devices[1]; phone.Logins != 3 || len(phone.ClientVersions) != 2 || len(phone.IPs) != 2 || !phone.FirstSeen.Equal(day(1)) {
+		t.Errorf("phone = %+v", phone)
+	}
+	if len(a.IPs) != 3 || a.IPs[1].IP != "198.51.100.2" || a.IPs[1].Logins != 2 || !a.IPs[1].FirstSeen.Equal(day(5)) {
+		t.Errorf("ips = %+v", a.IPs)
+	}
+	if a.Logins[0].ID != 4 || !a.Logins[0].NewDevice || !a.Logins[0].NewIP || a.Logins[1].NewDevice || !a.Logins[2].NewIP {
+		t.Errorf("logins = %+v", a.Logins)
+	}
+
+	// the laptop is new from a new IP, three hours before a password change; the privacy
+	// change comes too late, and the first login is never flagged
+	if len(a.Flags) != 2 || a.Flags[0].Type != flagNewDeviceBeforePasswordChange || a.Flags[0].Detail != "password changed 3h0m0s later" ||
+		a.Flags[1].Type != flagNewDeviceAndIP || a.Flags[1].LoginID != 4 {
+		t.Errorf("flags = %+v", a.Flags)
+	}
+}
+
+func TestLoginSecurityHandler(t *testing.T) {
+	s, mem, accountID := newTestServer(t)
+	mem.Archive(accountID).Logins = []models.LoginHistory{{ID: 1, IPAddress: "198.51.100.1", UserAgent: "curl/8.4.0", LoggedInAt: day(1)}}
+
+	rec := serveAs(testUserID, s.getLoginSecurityHandler, httptest.NewRequest(http.MethodGet, "/api/v1/insights/security", nil))
+	body := decodeBody[struct {
+		Summary struct {
+			Logins  int `json:"logins"`
+			Devices int `json:"devices"`
+		} `json:"summary"`
+		Devices []loginDevice `json:"devices"`
+	}](t, rec)
+	// an unrecognised user agent is its own device
+	if body.Summary.Logins != 1 || body.Summary.Devices != 1 || body.Devices[0].Device != "curl/8.4.0" {
+		t.Errorf("body = %+v", body)
+	}
+}
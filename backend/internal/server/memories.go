@@ -0,0 +1,86 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/Sa-Te/IAV/backend/internal/models"
+)
+
+// memoryYear is the memories of one earlier year.
+type memoryYear struct {
+	Year     int                 `json:"year"`
+	YearsAgo int                 `json:"years_ago"`
+	Items    []models.MemoryItem `json:"items"`
+}
+
+// memoryDays is the days of the month whose memories belong to date. In a year without
+// 29 February, 28 February also brings back the leap days.
+func memoryDays(date time.Time) []int {
+	if date.Month() == time.February && date.Day() == 28 && date.AddDate(0, 0, 1).Month() == time.March {
+		return []int{28, 29}
+	}
+	return []int{date.Day()}
+}
+
+// getOnThisDayHandler serves /api/v1/memories/on-this-day: posts, stories, archived
+// posts, profile photos and first messages from the same calendar day in earlier years,
+// newest year first.
+func (s *APIServer) getOnThisDayHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+	accountID, ok := s.accountFromRequest(w, r, userID)
+	if !ok {
+		return
+	}
+
+	loc, err := parseTimeZone(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	date := time.Now().In(loc)
+	if v := r.URL.Query().Get("date"); v != "" {
+		if date, err = time.ParseInLocation(time.DateOnly, v, loc); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "date must be YYYY-MM-DD")
+			return
+		}
+	}
+
+	items, err := s.store.Insights.OnThisDay(r.Context(), accountID, date.Year(), date.Month(), memoryDays(date), loc)
+	if err != nil {
+		log.Printf("Database query error in getOnThisDayHandler: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to get memories")
+		return
+	}
+
+	type Response struct {
+		Date     string       `json:"date"`
+		TimeZone string       `json:"time_zone"`
+		Total    int          `json:"total"`
+		Years    []memoryYear `json:"years"`
+	}
+	resp := Response{Date: date.Format(time.DateOnly), TimeZone: loc.String(), Total: len(items), Years: []memoryYear{}}
+	// items are oldest first; walking them backwards puts the latest year first
+	for i := len(items) - 1; i >= 0; i-- {
+		year := items[i].At.In(loc).Year()
+		if n := len(resp.Years); n == 0 || resp.Years[n-1].Year != year {
+			resp.Years = append(resp.Years, memoryYear{Year: year, YearsAgo: date.Year() - year})
+		}
+		last := &resp.Years[len(resp.Years)-1]
+		last.Items = append(last.Items, items[i])
+	}
+	// within a year the day still reads in order
+	for _, y := range resp.Years {
+		slices.Reverse(y.Items)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
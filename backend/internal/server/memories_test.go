@@ -0,0 +1,67 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Sa-Te/IAV/backend/internal/models"
+)
+
+func TestMemoryDays(t *testing.T) {
+	for date, want := range map[string]int{"2025-02-28": 2, "2024-02-28": 1, "2024-02-29": 1, "2025-03-01": 1} {
+		d, _ := time.Parse(time.DateOnly, date)
+		if got := memoryDays(d); len(got) != want {
+			t.Errorf("memoryDays(%s) = %v", date, got)
+		}
+	}
+}
+
+func TestOnThisDayHandler(t *testing.T) {
+	s, mem, accountID := newTestServer(t)
+	archive := mem.Archive(accountID)
+	at := func(y int, m time.Month, d, h int) time.Time { return time.Date(y, m, d, h, 0, 0, 0, time.UTC) }
+	archive.Media = []models.MediaItem{
+		{ID: 1, URI: "posts/beach.jpg", MediaType: "post", TakenAt: at(2022, 7, 14, 10)},
+		{ID: 2, URI: "stories/late.mp4", MediaType: "story", TakenAt: at(2022, 7, 14, 23)}, // 15 July in Berlin
+		{ID: 3, URI: "posts/today.jpg", MediaType: "post", TakenAt: at(2024, 7, 14, 9)},    // this year, not a memory
+	}
+	archive.ProfilePhotos = []models.ProfilePhoto{{ID: 1, PhotoURI: "profile.jpg", SetAt: at(2020, 7, 14, 8)}}
+	archive.Conversations = []models.MessageConversation{{ConversationID: "jane_1", Participants: "Jane, Me"}}
+	archive.Messages = []models.Message{
+		{ID: 1, ConversationID: "jane_1", SenderName: "Jane", Content: "hello!", SentAt: at(2022, 7, 14, 12)},
+		{ID: 2, ConversationID: "jane_1", SenderName: "Me", Content: "hi", SentAt: at(2022, 7, 14, 13)}, // not the first
+	}
+
+	type response struct {
+		Date  string       `json:"date"`
+		Total int          `json:"total"`
+		Years []memoryYear `json:"years"`
+	}
+	get := func(target string) *httptest.ResponseRecorder {
+		return serveAs(testUserID, s.getOnThisDayHandler, httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	body := decodeBody[response](t, get("/api/v1/memories/on-this-day?date=2024-07-14"))
+	if body.Date != "2024-07-14" || body.Total != 4 || len(body.Years) != 2 {
+		t.Fatalf("body = %+v", body)
+	}
+	y2022 := body.Years[0]
+	if y2022.Year != 2022 || y2022.YearsAgo != 2 || len(y2022.Items) != 3 ||
+		y2022.Items[0].ID != 1 || y2022.Items[1].Type != "first_message" || *y2022.Items[1].Participants != "Jane, Me" || y2022.Items[2].Type != "story" {
+		t.Errorf("2022 = %+v", y2022)
+	}
+	if y2020 := body.Years[1]; y2020.Year != 2020 || y2020.Items[0].Type != "profile_photo" {
+		t.Errorf("2020 = %+v", y2020)
+	}
+
+	body = decodeBody[response](t, get("/api/v1/memories/on-this-day?date=2024-07-15&tz=Europe/Berlin"))
+	if body.Total != 1 || body.Years[0].Items[0].ID != 2 {
+		t.Errorf("Berlin = %+v", body)
+	}
+
+	if rec := get("/api/v1/memories/on-this-day?date=14.07.2024"); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad date: status %d, want 400", rec.Code)
+	}
+}
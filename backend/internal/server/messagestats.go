@@ -0,0 +1,224 @@
+package server
+
+import (
+	"cmp"
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Sa-Te/IAV/backend/internal/models"
+)
+
+const (
+	// a message sent more than a day after the other side's starts a new exchange
+	// rather than replying to it
+	maxReplyGap      = 24 * time.Hour
+	busiestDaysLimit = 10
+)
+
+// dayCount is how many messages were sent on one day.
+type dayCount struct {
+	Date     string `json:"date"` // 2006-01-02
+	Messages int    `json:"messages"`
+}
+
+// dayStreak is a run of consecutive days with at least one message.
+type dayStreak struct {
+	Days  int    `json:"days"`
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// longestStreak finds the longest run in days, which must be ascending and distinct.
+// The earliest run wins a tie; nil when there are no days.
+func longestStreak(days []time.Time) *dayStreak {
+	var best *dayStreak
+	start := 0
+	for i := range days {
+		if i > 0 && !days[i-1].AddDate(0, 0, 1).Equal(days[i]) {
+			start = i
+		}
+		if best == nil || i-start+1 > best.Days {
+			best = &dayStreak{Days: i - start + 1, Start: days[start].Format(time.DateOnly), End: days[i].Format(time.DateOnly)}
+		}
+	}
+	return best
+}
+
+// busiestDays returns the n days with the most messages, ties broken by date.
+func busiestDays(counts map[time.Time]int, n int) []dayCount {
+	days := make([]time.Time, 0, len(counts))
+	for day := range counts {
+		days = append(days, day)
+	}
+	slices.SortFunc(days, func(a, b time.Time) int { return cmp.Or(cmp.Compare(counts[b], counts[a]), a.Compare(b)) })
+	result := make([]dayCount, 0, n)
+	for _, day := range atMost(days, n) {
+		result = append(result, dayCount{Date: day.Format(time.DateOnly), Messages: counts[day]})
+	}
+	return result
+}
+
+// sortedDays returns the days of counts in order.
+func sortedDays(counts map[time.Time]int) []time.Time {
+	days := make([]time.Time, 0, len(counts))
+	for day := range counts {
+		days = append(days, day)
+	}
+	slices.SortFunc(days, time.Time.Compare)
+	return days
+}
+
+// atMost keeps the first n rows; unlike the store's firstN, n 0 keeps none.
+func atMost[T any](rows []T, n int) []T {
+	if len(rows) > n {
+		return rows[:n]
+	}
+	return rows
+}
+
+// inferOwnerName guesses the archive owner's display name in messages: the participant
+// of the most conversations, as the owner takes part in all of them.
+func inferOwnerName(conversations []models.MessageConversation) string {
+	counts := map[string]int{}
+	for _, c := range conversations {
+		for _, name := range strings.Split(c.Participants, ", ") {
+			if name = strings.TrimSpace(name); name != "" {
+				counts[name]++
+			}
+		}
+	}
+	owner := ""
+	for name, n := range counts {
+		if n > counts[owner] || (n == counts[owner] && name < owner) {
+			owner = name
+		}
+	}
+	return owner
+}
+
+// ownerName is the `me` parameter or, without one, the owner's name inferred from the
+// account's conversations.
+func (s *APIServer) ownerName(r *http.Request, accountID int) (string, error) {
+	if me := strings.TrimSpace(r.URL.Query().Get("me")); me != "" {
+		return me, nil
+	}
+	conversations, err := s.store.Messages.ListConversations(r.Context(), accountID)
+	if err != nil {
+		return "", err
+	}
+	return inferOwnerName(conversations), nil
+}
+
+// weightedAverage combines per-conversation averages by their reply counts.
+func weightedAverage(stats []models.ConversationStats, avg func(models.ConversationStats) (*float64, int)) *float64 {
+	var sum float64
+	var n int
+	for _, s := range stats {
+		if a, count := avg(s); a != nil {
+			sum += *a * float64(count)
+			n += count
+		}
+	}
+	if n == 0 {
+		return nil
+	}
+	result := sum / float64(n)
+	return &result
+}
+
+// getMessageStatsHandler serves /api/v1/insights/messages: per-conversation counts,
+// reply times and streaks, and the same across all conversations. Days are UTC.
+func (s *APIServer) getMessageStatsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+	accountID, ok := s.accountFromRequest(w, r, userID)
+	if !ok {
+		return
+	}
+
+	me, err := s.ownerName(r, accountID)
+	var stats []models.ConversationStats
+	if err == nil {
+		stats, err = s.store.Insights.ConversationStats(r.Context(), accountID, me, maxReplyGap)
+	}
+	var days []models.MessageDay
+	if err == nil {
+		days, err = s.store.Insights.MessageDays(r.Context(), accountID)
+	}
+	if err != nil {
+		log.Printf("Database query error in getMessageStatsHandler: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to get message statistics")
+		return
+	}
+
+	type ConversationResponse struct {
+		models.ConversationStats
+		BusiestDay    *dayCount  `json:"busiest_day"`
+		LongestStreak *dayStreak `json:"longest_streak"`
+	}
+	type Response struct {
+		Me     string `json:"me"`
+		Totals struct {
+			Conversations        int        `json:"conversations"`
+			Messages             int        `json:"messages"`
+			Sent                 int        `json:"sent"`
+			Received             int        `json:"received"`
+			FirstMessageAt       *time.Time `json:"first_message_at"`
+			LastMessageAt        *time.Time `json:"last_message_at"`
+			YourAvgReplySeconds  *float64   `json:"your_avg_reply_seconds"`
+			TheirAvgReplySeconds *float64   `json:"their_avg_reply_seconds"`
+			LongestStreak        *dayStreak `json:"longest_streak"`
+		} `json:"totals"`
+		BusiestDays   []dayCount             `json:"busiest_days"`
+		Conversations []ConversationResponse `json:"conversations"`
+	}
+
+	perConversation := map[string]map[time.Time]int{}
+	overall := map[time.Time]int{}
+	for _, d := range days {
+		if perConversation[d.ConversationID] == nil {
+			perConversation[d.ConversationID] = map[time.Time]int{}
+		}
+		perConversation[d.ConversationID][d.Day] += d.Messages
+		overall[d.Day] += d.Messages
+	}
+
+	resp := Response{Me: me, BusiestDays: busiestDays(overall, busiestDaysLimit), Conversations: make([]ConversationResponse, 0, len(stats))}
+	for _, c := range stats {
+		conversation := ConversationResponse{ConversationStats: c}
+		counts := perConversation[c.ConversationID]
+		if busiest := busiestDays(counts, 1); len(busiest) > 0 {
+			conversation.BusiestDay = &busiest[0]
+		}
+		conversation.LongestStreak = longestStreak(sortedDays(counts))
+		resp.Conversations = append(resp.Conversations, conversation)
+
+		resp.Totals.Messages += c.Messages
+		resp.Totals.Sent += c.Sent
+		resp.Totals.Received += c.Received
+		if resp.Totals.FirstMessageAt == nil || c.FirstMessageAt.Before(*resp.Totals.FirstMessageAt) {
+			resp.Totals.FirstMessageAt = &c.FirstMessageAt
+		}
+		if resp.Totals.LastMessageAt == nil || c.LastMessageAt.After(*resp.Totals.LastMessageAt) {
+			resp.Totals.LastMessageAt = &c.LastMessageAt
+		}
+	}
+	resp.Totals.Conversations = len(stats)
+	resp.Totals.YourAvgReplySeconds = weightedAverage(stats, func(c models.ConversationStats) (*float64, int) {
+		return c.YourAvgReplySeconds, c.YourReplies
+	})
+	resp.Totals.TheirAvgReplySeconds = weightedAverage(stats, func(c models.ConversationStats) (*float64, int) {
+		return c.TheirAvgReplySeconds, c.TheirReplies
+	})
+	resp.Totals.LongestStreak = longestStreak(sortedDays(overall))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Sa-Te/IAV/backend/internal/models"
+)
+
+func TestLongestStreak(t *testing.T) {
+	d := func(day int) time.Time { return time.Date(2024, 2, day, 0, 0, 0, 0, time.UTC) }
+	streak := longestStreak([]time.Time{d(1), d(2), d(4), d(5), d(6), d(9), d(28), d(29), time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)})
+	// a tie goes to the earlier run; 2024-02-29 exists, so the month boundary continues the run
+	if streak == nil || *streak != (dayStreak{Days: 3, Start: "2024-02-04", End: "2024-02-06"}) {
+		t.Errorf("streak = %+v", streak)
+	}
+	if longestStreak(nil) != nil {
+		t.Error("no days should have no streak")
+	}
+}
+
+func TestInferOwnerName(t *testing.T) {
+	owner := inferOwnerName([]models.MessageConversation{
+		{Participants: "Jane Doe, Me Myself"},
+		{Participants: "Bob, Me Myself"},
+		{Participants: "Bob, Jane Doe, Me Myself"},
+	})
+	if owner != "Me Myself" {
+		t.Errorf("owner = %q", owner)
+	}
+}
+
+func TestMessageStatsHandler(t *testing.T) {
+	s, mem, accountID := newTestServer(t)
+	archive := mem.Archive(accountID)
+	at := func(d, h, m int) time.Time { return time.Date(2024, 3, d, h, m, 0, 0, time.UTC) }
+	archive.Conversations = []models.MessageConversation{
+		{ID: 1, ConversationID: "jane_1", Participants: "Jane, Me"},
+		{ID: 2, ConversationID: "bob_2", Participants: "Bob, Me"},
+	}
+	archive.Messages = []models.Message{
+		{ID: 1, ConversationID: "jane_1", SenderName: "Jane", SentAt: at(1, 10, 0)},
+		{ID: 2, ConversationID: "jane_1", SenderName: "Me", SentAt: at(1, 10, 10)},   // you reply after 10 minutes
+		{ID: 3, ConversationID: "jane_1", SenderName: "Jane", SentAt: at(1, 10, 12)}, // they reply after 2
+		{ID: 4, ConversationID: "jane_1", SenderName: "Me", SentAt: at(2, 9, 0)},     // 22h48m later, still a reply
+		{ID: 5, ConversationID: "jane_1", SenderName: "Jane", SentAt: at(5, 9, 0)},   // three days later, not a reply
+		{ID: 6, ConversationID: "bob_2", SenderName: "Bob", SentAt: at(3, 8, 0)},
+		{ID: 7, ConversationID: "bob_2", SenderName: "Me", SentAt: at(3, 8, 30)},
+	}
+
+	rec := serveAs(testUserID, s.getMessageStatsHandler, httptest.NewRequest(http.MethodGet, "/api/v1/insights/messages", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	body := decodeBody[struct {
+		Me     string `json:"me"`
+		Totals struct {
+			Messages            int        `json:"messages"`
+			Sent                int        `json:"sent"`
+			Received            int        `json:"received"`
+			YourAvgReplySeconds *float64   `json:"your_avg_reply_seconds"`
+			LongestStreak       *dayStreak `json:"longest_streak"`
+		} `json:"totals"`
+		BusiestDays   []dayCount `json:"busiest_days"`
+		Conversations []struct {
+			models.ConversationStats
+			BusiestDay    *dayCount  `json:"busiest_day"`
+			LongestStreak *dayStreak `json:"longest_streak"`
+		} `json:"conversations"`
+	}](t, rec)
+
+	if body.Me != "Me" || body.Totals.Messages != 7 || body.Totals.Sent != 3 || body.Totals.Received != 4 {
+		t.Errorf("totals = %+v (me %q)", body.Totals, body.Me)
+	}
+	// (10m + 22h48m + 30m) / 3
+	if avg := body.Totals.YourAvgReplySeconds; avg == nil || *avg != (10*60+22*3600+48*60+30*60)/3.0 {
+		t.Errorf("your average reply = %v", avg)
+	}
+	if streak := body.Totals.LongestStreak; streak == nil || *streak != (dayStreak{Days: 3, Start: "2024-03-01", End: "2024-03-03"}) {
+		t.Errorf("overall streak = %+v", streak)
+	}
+	if len(body.BusiestDays) != 4 || body.BusiestDays[0] != (dayCount{Date: "2024-03-01", Messages: 3}) {
+		t.Errorf("busiest days = %+v", body.BusiestDays)
+	}
+
+	if len(body.Conversations) != 2 {
+		t.Fatalf("conversations = %+v", body.Conversations)
+	}
+	jane := body.Conversations[0]
+	if jane.ConversationID != "jane_1" || jane.Participants != "Jane, Me" || jane.Messages != 5 || jane.Sent != 2 ||
+		jane.YourReplies != 2 || jane.TheirReplies != 1 || *jane.TheirAvgReplySeconds != 120 ||
+		!jane.FirstMessageAt.Equal(at(1, 10, 0)) || !jane.LastMessageAt.Equal(at(5, 9, 0)) {
+		t.Errorf("jane = %+v", jane.ConversationStats)
+	}
+	if *jane.LongestStreak != (dayStreak{Days: 2, Start: "2024-03-01", End: "2024-03-02"}) || *jane.BusiestDay != (dayCount{Date: "2024-03-01", Messages: 3}) {
+		t.Errorf("jane streak %+v, busiest day %+v", jane.LongestStreak, jane.BusiestDay)
+	}
+
+	// naming yourself explicitly turns the counts around
+	rec = serveAs(testUserID, s.getMessageStatsHandler, httptest.NewRequest(http.MethodGet, "/api/v1/insights/messages?me=Jane", nil))
+	again := decodeBody[struct {
+		Totals struct {
+			Sent int `json:"sent"`
+		} `json:"totals"`
+	}](t, rec)
+	if again.Totals.Sent != 3 {
+		t.Errorf("sent as Jane = %d, want 3", again.Totals.Sent)
+	}
+}
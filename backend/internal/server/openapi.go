@@ -0,0 +1,867 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sa-Te/IAV/backend/internal/models"
+	"github.com/Sa-Te/IAV/backend/internal/store"
+)
+
+// The OpenAPI document is built at startup rather than kept as a static file: query
+// parameters come from the same listSpecs the handlers validate against and schemas
+// are reflected from the models types. Response envelopes that handlers declare
+// inline are restated in apiRoutes; openapi_test.go checks them against the
+// handler source so the two can't drift apart.
+
+const openAPIPath = "/api/v1/openapi.json"
+
+var modelsPkgPath = reflect.TypeOf(models.User{}).PkgPath()
+
+// --- Document types (the subset of OpenAPI 3.0 this API needs) ---
+
+type openAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Description          string                    `json:"description,omitempty"`
+	Nullable             bool                      `json:"nullable,omitempty"`
+	Enum                 []string                  `json:"enum,omitempty"`
+	Items                *openAPISchema            `json:"items,omitempty"`
+	Properties           map[string]*openAPISchema `json:"properties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+	AdditionalProperties *openAPISchema            `json:"additionalProperties,omitempty"`
+	AllOf                []*openAPISchema          `json:"allOf,omitempty"`
+	OneOf                []*openAPISchema          `json:"oneOf,omitempty"`
+	Minimum              *int                      `json:"minimum,omitempty"`
+	Maximum              *int                      `json:"maximum,omitempty"`
+}
+
+type openAPIParameter struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Description string         `json:"description,omitempty"`
+	Required    bool           `json:"required,omitempty"`
+	Schema      *openAPISchema `json:"schema"`
+}
+
+type openAPIMediaType struct {
+	Schema *openAPISchema `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Ref         string                      `json:"$ref,omitempty"`
+	Description string                      `json:"description,omitempty"`
+	Content     map[string]openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIOperation struct {
+	Tags        []string                   `json:"tags"`
+	Summary     string                     `json:"summary"`
+	Description string                     `json:"description,omitempty"`
+	Security    []map[string][]string      `json:"security"`
+	Parameters  []openAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]openAPIResponse `json:"responses"`
+}
+
+type openAPISecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+type openAPIDocument struct {
+	OpenAPI string `json:"openapi"`
+	Info    struct {
+		Title   string `json:"title"`
+		Version string `json:"version"`
+	} `json:"info"`
+	Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+	Components struct {
+		Schemas         map[string]*openAPISchema        `json:"schemas"`
+		Responses       map[string]openAPIResponse       `json:"responses"`
+		SecuritySchemes map[string]openAPISecurityScheme `json:"securitySchemes"`
+	} `json:"components"`
+}
+
+// --- Schemas ---
+
+// schemaGenerator reflects Go types into schemas. Named models types become shared
+// components; everything else is inlined where it is used.
+type schemaGenerator struct {
+	components map[string]*openAPISchema
+}
+
+func (g *schemaGenerator) of(v interface{}) *openAPISchema {
+	return g.schema(reflect.TypeOf(v))
+}
+
+func (g *schemaGenerator) schema(t reflect.Type) *openAPISchema {
+	if t == reflect.TypeOf(time.Time{}) {
+		return &openAPISchema{Type: "string", Format: "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := g.schema(t.Elem())
+		if s.Ref != "" {
+			// OpenAPI 3.0 ignores siblings of $ref, so the reference is wrapped
+			return &openAPISchema{AllOf: []*openAPISchema{s}, Nullable: true}
+		}
+		s.Nullable = true
+		return s
+	case reflect.Bool:
+		return &openAPISchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &openAPISchema{Type: "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return &openAPISchema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &openAPISchema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &openAPISchema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &openAPISchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &openAPISchema{Type: "string", Format: "byte"}
+		}
+		return &openAPISchema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &openAPISchema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Interface:
+		return &openAPISchema{}
+	case reflect.Struct:
+		if t.PkgPath() != modelsPkgPath || t.Name() == "" {
+			return g.structSchema(t)
+		}
+		if _, ok := g.components[t.Name()]; !ok {
+			g.components[t.Name()] = &openAPISchema{} // placeholder in case the type refers to itself
+			g.components[t.Name()] = g.structSchema(t)
+		}
+		return &openAPISchema{Ref: "#/components/schemas/" + t.Name()}
+	}
+	panic(fmt.Sprintf("openapi: no schema for %s", t))
+}
+
+// structSchema follows encoding/json: embedded structs are flattened, `-` fields are
+// skipped and only fields without omitempty are required.
+func (g *schemaGenerator) structSchema(t reflect.Type) *openAPISchema {
+	s := &openAPISchema{Type: "object", Properties: map[string]*openAPISchema{}}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			embedded := g.structSchema(f.Type)
+			for prop, schema := range embedded.Properties {
+				s.Properties[prop] = schema
+			}
+			s.Required = append(s.Required, embedded.Required...)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s.Properties[name] = g.schema(f.Type)
+		if !strings.Contains(opts, "omitempty") {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return s
+}
+
+// pageOf describes the page[T] envelope of a paginated list of models.
+func (g *schemaGenerator) pageOf(item interface{}) *openAPISchema {
+	return &openAPISchema{
+		Type: "object",
+		Properties: map[string]*openAPISchema{
+			"items":       {Type: "array", Items: g.of(item)},
+			"next_cursor": {Type: "string", Nullable: true, Description: "Pass as `cursor` to fetch the next page; null on the last page."},
+		},
+		Required: []string{"items", "next_cursor"},
+	}
+}
+
+func arrayOf(items *openAPISchema) *openAPISchema {
+	return &openAPISchema{Type: "array", Items: items}
+}
+
+func stringEnum(values ...string) *openAPISchema {
+	return &openAPISchema{Type: "string", Enum: values}
+}
+
+func intRange(min, max int) *openAPISchema {
+	return &openAPISchema{Type: "integer", Minimum: &min, Maximum: &max}
+}
+
+// --- Parameters ---
+
+func queryParam(name, description string, schema *openAPISchema) openAPIParameter {
+	return openAPIParameter{Name: name, In: "query", Description: description, Schema: schema}
+}
+
+func pathParam(name, description string, schema *openAPISchema) openAPIParameter {
+	return openAPIParameter{Name: name, In: "path", Description: description, Required: true, Schema: schema}
+}
+
+var accountParam = queryParam("account", "Instagram account id or name; the default account when omitted.", &openAPISchema{Type: "string"})
+
+var meParam = queryParam("me", "Your sender name in the messages; inferred as the participant of the most conversations when omitted.", &openAPISchema{Type: "string"})
+
+func pageParams() []openAPIParameter {
+	return []openAPIParameter{
+		queryParam("limit", fmt.Sprintf("Page size, %d by default.", defaultPageLimit), intRange(1, maxPageLimit)),
+		queryParam("cursor", "The `next_cursor` of the previous page.", &openAPISchema{Type: "string"}),
+	}
+}
+
+// listParams documents the filters a listSpec accepts, in the order parseListQuery reads them.
+// Endpoint-specific extraParams are left to the caller.
+func listParams(spec listSpec) []openAPIParameter {
+	var params []openAPIParameter
+	if spec.typed {
+		schema := &openAPISchema{Type: "string"}
+		description := "Comma-separated list of types to include."
+		if spec.types != nil {
+			description += " One or more of: " + strings.Join(spec.types, ", ") + "."
+		}
+		params = append(params, queryParam("type", description, schema))
+	}
+	if spec.dated {
+		params = append(params,
+			queryParam("from", "Earliest date (2024-01-31) or RFC 3339 timestamp to include.", &openAPISchema{Type: "string"}),
+			queryParam("to", "Latest date to include (the whole day), or an exclusive RFC 3339 timestamp.", &openAPISchema{Type: "string"}))
+	}
+	if spec.byUsername {
+		params = append(params, queryParam("username", "Case-insensitive substring match on the username.", &openAPISchema{Type: "string"}))
+	}
+	if len(spec.sorts) > 0 {
+		params = append(params,
+			queryParam("sort", "Sort field, "+spec.defaultSort+" by default.", stringEnum(spec.sorts...)),
+			queryParam("order", "Sort direction, desc by default.", stringEnum("asc", "desc")))
+	}
+	return params
+}
+
+// paginatedListParams is the full parameter list of an account-scoped paginated list.
+func paginatedListParams(spec listSpec) []openAPIParameter {
+	params := append([]openAPIParameter{accountParam}, pageParams()...)
+	return append(params, listParams(spec)...)
+}
+
+// --- Routes ---
+
+type apiAuth int
+
+const (
+	authNone    apiAuth = iota
+	authRead            // session, or an API token of any scope
+	authImport          // session, or an API token with the import scope
+	authSession         // login sessions only
+	authAdmin           // an administrator's login session
+	authShare           // a share link token
+)
+
+var apiAuthDescriptions = map[apiAuth]string{
+	authImport:  "Requires a login session or an API token with the `import` scope.",
+	authSession: "Requires a login session; API tokens are rejected.",
+	authAdmin:   "Requires an administrator's login session.",
+}
+
+// apiRoute describes one operation of a route registered in Run.
+type apiRoute struct {
+	method, path string
+	tag, summary string
+	auth         apiAuth
+	params       []openAPIParameter
+	body         *openAPISchema // JSON request body
+	form         *openAPISchema // multipart/form-data request body
+	status       int            // success status; 200 when zero
+	response     *openAPISchema // JSON response; nil for an empty body
+	file         bool           // the response is a stored media file
+	csv          bool           // format=csv also serves the response as text/csv
+	cached       bool           // served through cacheMiddleware
+}
+
+func apiRoutes(g *schemaGenerator) []apiRoute {
+	credentials := g.of(struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}{})
+	sessionToken := g.of(struct {
+		Token string `json:"token"`
+	}{})
+	message := g.of(struct {
+		Message string `json:"message"`
+	}{})
+	idParam := func(description string) openAPIParameter {
+		return pathParam("id", description, &openAPISchema{Type: "integer"})
+	}
+	fileParam := pathParam("path", "Path of the file inside the archive, as in a media item's `uri`.", &openAPISchema{Type: "string"})
+	archiveParams := []openAPIParameter{accountParam}
+
+	return []apiRoute{
+		// authentication
+		{method: http.MethodPost, path: "/api/v1/register", tag: "auth", summary: "Create a user",
+			body: credentials, status: http.StatusCreated, response: message},
+		{method: http.MethodPost, path: "/api/v1/login", tag: "auth", summary: "Log in with email and password",
+			body: credentials,
+			response: &openAPISchema{OneOf: []*openAPISchema{sessionToken, g.of(struct {
+				TwoFactorRequired bool   `json:"two_factor_required"`
+				ChallengeToken    string `json:"challenge_token"`
+			}{})}}},
+		{method: http.MethodPost, path: "/api/v1/login/2fa", tag: "auth", summary: "Complete a login with a second factor",
+			body: g.of(struct {
+				ChallengeToken string `json:"challenge_token"`
+				Code           string `json:"code,omitempty"`
+				RecoveryCode   string `json:"recovery_code,omitempty"`
+			}{}),
+			response: sessionToken},
+		{method: http.MethodPost, path: "/api/v1/2fa/enroll", tag: "auth", summary: "Start two-factor enrollment",
+			auth: authSession,
+			response: g.of(struct {
+				Secret     string `json:"secret"`
+				OtpauthURI string `json:"otpauth_uri"`
+			}{})},
+		{method: http.MethodPost, path: "/api/v1/2fa/confirm", tag: "auth", summary: "Confirm two-factor enrollment",
+			auth: authSession,
+			body: g.of(struct {
+				Code string `json:"code"`
+			}{}),
+			response: g.of(struct {
+				Enabled       bool     `json:"enabled"`
+				RecoveryCodes []string `json:"recovery_codes"`
+			}{})},
+		{method: http.MethodPost, path: "/api/v1/2fa/disable", tag: "auth", summary: "Turn two-factor authentication off",
+			auth: authSession,
+			body: g.of(struct {
+				Password     string `json:"password"`
+				Code         string `json:"code,omitempty"`
+				RecoveryCode string `json:"recovery_code,omitempty"`
+			}{}),
+			response: g.of(struct {
+				Enabled bool `json:"enabled"`
+			}{})},
+
+		// personal API tokens
+		{method: http.MethodGet, path: "/api/v1/tokens", tag: "tokens", summary: "List API tokens",
+			auth: authSession, response: arrayOf(g.of(models.APIToken{}))},
+		{method: http.MethodPost, path: "/api/v1/tokens", tag: "tokens", summary: "Create an API token",
+			auth: authSession,
+			body: g.of(struct {
+				Name          string `json:"name"`
+				Scope         string `json:"scope"`
+				ExpiresInDays int    `json:"expires_in_days,omitempty"`
+			}{}),
+			status: http.StatusCreated,
+			response: g.of(struct {
+				models.APIToken
+				Token string `json:"token"`
+			}{})},
+		{method: http.MethodDelete, path: "/api/v1/tokens/{id}", tag: "tokens", summary: "Revoke an API token",
+			auth: authSession, params: []openAPIParameter{idParam("Token id.")}, status: http.StatusNoContent},
+
+		// share links
+		{method: http.MethodGet, path: "/api/v1/shares", tag: "shares", summary: "List share links",
+			auth: authSession, response: arrayOf(g.of(shareLinkResponse{}))},
+		{method: http.MethodPost, path: "/api/v1/shares", tag: "shares", summary: "Create a share link",
+			auth: authSession, params: archiveParams,
+			body: g.of(struct {
+				Name          string   `json:"name"`
+				Sections      []string `json:"sections"`
+				From          string   `json:"from,omitempty"`
+				To            string   `json:"to,omitempty"`
+				Collection    string   `json:"collection,omitempty"`
+				ExpiresInDays int      `json:"expires_in_days,omitempty"`
+			}{}),
+			status: http.StatusCreated, response: g.of(shareLinkResponse{})},
+		{method: http.MethodDelete, path: "/api/v1/shares/{id}", tag: "shares", summary: "Revoke a share link",
+			auth: authSession, params: []openAPIParameter{idParam("Share link id.")}, status: http.StatusNoContent},
+		{method: http.MethodGet, path: "/api/v1/shares/{id}/access-log", tag: "shares", summary: "List recent visits to a share link",
+			auth: authSession, params: []openAPIParameter{idParam("Share link id.")},
+			response: arrayOf(g.of(models.ShareLinkAccess{}))},
+
+		// shared (read-only) views
+		{method: http.MethodGet, path: "/api/v1/shared", tag: "shared", summary: "Describe what a share link exposes",
+			auth: authShare,
+			response: g.of(struct {
+				Name           string     `json:"name"`
+				Sections       []string   `json:"sections"`
+				DateFrom       *time.Time `json:"date_from"`
+				DateTo         *time.Time `json:"date_to"`
+				CollectionName *string    `json:"collection_name"`
+				ExpiresAt      time.Time  `json:"expires_at"`
+			}{})},
+		{method: http.MethodGet, path: "/api/v1/shared/media", tag: "shared", summary: "List shared media",
+			auth: authShare, params: pageParams(), response: g.pageOf(models.MediaItem{})},
+		{method: http.MethodGet, path: "/api/v1/shared/mediafile/{path}", tag: "shared", summary: "Download a shared media file",
+			auth: authShare, params: []openAPIParameter{fileParam}, file: true},
+		{method: http.MethodGet, path: "/api/v1/shared/saved", tag: "shared", summary: "List shared saved posts and collections",
+			auth: authShare,
+			response: g.of(struct {
+				SavedMedia  []models.SavedMedia `json:"saved_media"`
+				Collections []*struct {
+					models.SavedCollection
+					ItemCount int                          `json:"item_count"`
+					Items     []models.SavedCollectionItem `json:"items"`
+				} `json:"collections"`
+				Counts struct {
+					SavedMedia      int `json:"saved_media"`
+					Collections     int `json:"collections"`
+					CollectionItems int `json:"collection_items"`
+				} `json:"counts"`
+			}{})},
+
+		// administration
+		{method: http.MethodGet, path: "/api/v1/admin/users", tag: "admin", summary: "List users",
+			auth: authAdmin, response: arrayOf(g.of(models.AdminUser{}))},
+		{method: http.MethodPost, path: "/api/v1/admin/users/{id}/disable", tag: "admin", summary: "Disable a user",
+			auth: authAdmin, params: []openAPIParameter{idParam("User id.")}, status: http.StatusNoContent},
+		{method: http.MethodPost, path: "/api/v1/admin/users/{id}/enable", tag: "admin", summary: "Re-enable a user",
+			auth: authAdmin, params: []openAPIParameter{idParam("User id.")}, status: http.StatusNoContent},
+		{method: http.MethodDelete, path: "/api/v1/admin/users/{id}", tag: "admin", summary: "Delete a user and their archives",
+			auth: authAdmin, params: []openAPIParameter{idParam("User id.")}, status: http.StatusNoContent},
+
+		// Instagram accounts and imports
+		{method: http.MethodGet, path: "/api/v1/accounts", tag: "accounts", summary: "List Instagram accounts",
+			auth: authRead, response: arrayOf(g.of(models.IGAccount{}))},
+		{method: http.MethodPost, path: "/api/v1/accounts", tag: "accounts", summary: "Add an Instagram account",
+			auth: authSession,
+			body: g.of(struct {
+				Name string `json:"name"`
+			}{}),
+			status: http.StatusCreated, response: g.of(models.IGAccount{})},
+		{method: http.MethodPost, path: "/api/v1/upload", tag: "accounts", summary: "Import an Instagram data export",
+			auth: authImport,
+			form: &openAPISchema{
+				Type: "object",
+				Properties: map[string]*openAPISchema{
+					"account":     {Type: "string", Description: "Instagram account id or name; the default account when omitted."},
+					"archiveFile": {Type: "string", Format: "binary", Description: "The export's .zip file."},
+				},
+				Required: []string{"archiveFile"},
+			},
+			response: message},
+
+		// archive reads
+		{method: http.MethodGet, path: "/api/v1/media", tag: "archive", summary: "List posts and stories",
+			auth: authRead, params: paginatedListParams(mediaListSpec), response: g.pageOf(models.MediaItem{}), cached: true},
+		{method: http.MethodGet, path: "/api/v1/mediafile/{path}", tag: "archive", summary: "Download a media file",
+			auth: authRead, params: []openAPIParameter{accountParam, fileParam}, file: true},
+		{method: http.MethodGet, path: "/api/v1/connections", tag: "archive", summary: "List followers, following and other connections",
+			auth: authRead, params: paginatedListParams(connectionsListSpec), response: g.pageOf(models.Connection{}), cached: true},
+		{method: http.MethodGet, path: "/api/v1/hashtags", tag: "archive", summary: "List followed hashtags",
+			auth: authRead, params: archiveParams, response: arrayOf(g.of(models.FollowedHashtag{})), cached: true},
+		{method: http.MethodGet, path: "/api/v1/ad-interests", tag: "archive", summary: "List advertisers and ad topics",
+			auth: authRead, params: archiveParams, response: g.of(AdInterestsResponse{}), cached: true},
+		{method: http.MethodGet, path: "/api/v1/activity", tag: "archive", summary: "List viewed ads, posts and videos",
+			auth: authRead, params: paginatedListParams(activityListSpec), response: g.pageOf(models.ActivityLog{}), cached: true},
+		{method: http.MethodGet, path: "/api/v1/likes", tag: "archive", summary: "List liked posts, comments and stories",
+			auth: authRead, params: archiveParams,
+			response: g.of(struct {
+				PostLikes    []models.PostLike    `json:"post_likes"`
+				CommentLikes []models.CommentLike `json:"comment_likes"`
+				StoryLikes   []models.StoryLike   `json:"story_likes"`
+			}{}),
+			cached: true},
+		{method: http.MethodGet, path: "/api/v1/comments", tag: "archive", summary: "List comments on posts and reels",
+			auth: authRead, params: archiveParams,
+			response: g.of(struct {
+				PostComments []models.PostComment `json:"post_comments"`
+				ReelComments []models.ReelComment `json:"reel_comments"`
+			}{}),
+			cached: true},
+		{method: http.MethodGet, path: "/api/v1/comments/post/{id}", tag: "archive", summary: "Get one comment on a post",
+			auth: authRead, params: append([]openAPIParameter{idParam("Comment id.")}, archiveParams...),
+			response: g.of(models.PostComment{}), cached: true},
+		{method: http.MethodGet, path: "/api/v1/comments/reel/{id}", tag: "archive", summary: "Get one comment on a reel",
+			auth: authRead, params: append([]openAPIParameter{idParam("Comment id.")}, archiveParams...),
+			response: g.of(models.ReelComment{}), cached: true},
+		{method: http.MethodGet, path: "/api/v1/saved", tag: "archive", summary: "List saved posts and collections",
+			auth: authRead, params: archiveParams,
+			response: g.of(struct {
+				SavedMedia  []models.SavedMedia `json:"saved_media"`
+				Collections []*struct {
+					models.SavedCollection
+					ItemCount int                          `json:"item_count"`
+					Items     []models.SavedCollectionItem `json:"items"`
+				} `json:"collections"`
+				Counts struct {
+					SavedMedia      int `json:"saved_media"`
+					Collections     int `json:"collections"`
+					CollectionItems int `json:"collection_items"`
+				} `json:"counts"`
+			}{}),
+			cached: true},
+		{method: http.MethodGet, path: "/api/v1/saved/collections/{name}", tag: "archive", summary: "Get one saved collection with a page of its items",
+			auth: authRead,
+			params: append([]openAPIParameter{pathParam("name", "Collection name.", &openAPISchema{Type: "string"})},
+				paginatedListParams(savedCollectionListSpec)...),
+			response: g.of(struct {
+				Collection models.SavedCollection       `json:"collection"`
+				ItemCount  int                          `json:"item_count"`
+				Items      []models.SavedCollectionItem `json:"items"`
+				NextCursor *string                      `json:"next_cursor"`
+			}{}),
+			cached: true},
+		{method: http.MethodGet, path: "/api/v1/profile", tag: "archive", summary: "Get the profile and its history",
+			auth: authRead, params: archiveParams,
+			response: g.of(struct {
+				Profile *models.UserProfile    `json:"profile"`
+				Changes []models.ProfileChange `json:"changes"`
+				Photos  []models.ProfilePhoto  `json:"photos"`
+			}{}),
+			cached: true},
+		{method: http.MethodGet, path: "/api/v1/security", tag: "archive", summary: "Get login, password and privacy history",
+			auth: authRead, params: archiveParams,
+			response: g.of(struct {
+				LoginHistory    []models.LoginHistory       `json:"login_history"`
+				LogoutHistory   []models.LogoutHistory      `json:"logout_history"`
+				PasswordChanges []models.PasswordChange     `json:"password_changes"`
+				PrivacyChanges  []models.PrivacyChange      `json:"privacy_changes"`
+				AccountStatus   []models.AccountStatusEntry `json:"account_status"`
+				SignupInfo      *models.SignupInfo          `json:"signup_info"`
+			}{}),
+			cached: true},
+		{method: http.MethodGet, path: "/api/v1/search-history", tag: "archive", summary: "List past searches",
+			auth: authRead, params: paginatedListParams(searchHistoryListSpec), response: g.pageOf(models.SearchHistoryEntry{}), cached: true},
+		{method: http.MethodGet, path: "/api/v1/search-history/{id}", tag: "archive", summary: "Get one past search",
+			auth: authRead, params: append([]openAPIParameter{idParam("Search id.")}, archiveParams...),
+			response: g.of(models.SearchHistoryEntry{}), cached: true},
+		{method: http.MethodGet, path: "/api/v1/story-interactions", tag: "archive", summary: "List story poll, quiz, question, slider and reaction answers",
+			auth: authRead, params: archiveParams,
+			response: g.of(struct {
+				Polls     []models.StoryPoll        `json:"polls"`
+				Quizzes   []models.StoryQuiz        `json:"quizzes"`
+				Questions []models.StoryQuestion    `json:"questions"`
+				Sliders   []models.StoryEmojiSlider `json:"emoji_sliders"`
+				Reactions []models.StoryReaction    `json:"reactions"`
+			}{}),
+			cached: true},
+		{method: http.MethodGet, path: "/api/v1/messages", tag: "archive", summary: "List conversations and messages",
+			auth: authRead, params: paginatedListParams(messagesListSpec),
+			response: g.of(struct {
+				Conversations []models.MessageConversation `json:"conversations"`
+				Messages      []models.Message             `json:"messages"`
+				NextCursor    *string                      `json:"next_cursor"`
+			}{}),
+			cached: true},
+		{method: http.MethodGet, path: "/api/v1/messages/{conversationID}", tag: "archive", summary: "List one conversation's messages, oldest first unless `order` is given",
+			auth: authRead,
+			params: append([]openAPIParameter{pathParam("conversationID", "Conversation id, as in a conversation's `conversation_id`.", &openAPISchema{Type: "string"})},
+				paginatedListParams(messagesListSpec)...),
+			response: g.pageOf(models.Message{}), cached: true},
+		{method: http.MethodGet, path: "/api/v1/topics", tag: "archive", summary: "List inferred interests, topics and locations",
+			auth: authRead, params: archiveParams,
+			response: g.of(struct {
+				AIInterests         []models.AIInterest      `json:"ai_interests"`
+				Topics              []models.UserTopic       `json:"topics"`
+				InferredLocation    *models.InferredLocation `json:"inferred_location"`
+				LocationsOfInterest []string                 `json:"locations_of_interest"`
+			}{}),
+			cached: true},
+		{method: http.MethodGet, path: "/api/v1/off-meta-activity", tag: "archive", summary: "List activity shared by other apps and websites",
+			auth: authRead, params: paginatedListParams(offMetaListSpec), response: g.pageOf(models.OffMetaActivity{}), cached: true},
+		{method: http.MethodGet, path: "/api/v1/archived-posts", tag: "archive", summary: "List archived posts",
+			auth: authRead, params: archiveParams, response: arrayOf(g.of(models.ArchivedPost{})), cached: true},
+		{method: http.MethodGet, path: "/api/v1/search", tag: "archive", summary: "Search the archive's text",
+			auth: authRead,
+			params: append([]openAPIParameter{accountParam,
+				{Name: "q", In: "query", Required: true, Schema: &openAPISchema{Type: "string"},
+					Description: fmt.Sprintf("Web search syntax (\"quoted phrases\", OR, -excluded), at most %d characters.", maxSearchQueryLen)},
+				queryParam("limit", fmt.Sprintf("Number of hits, %d by default.", defaultSearchLimit), intRange(1, maxSearchLimit))},
+				listParams(searchListSpec)...),
+			response: g.of(struct {
+				Query string             `json:"query"`
+				Hits  []models.SearchHit `json:"hits"`
+			}{}),
+			cached: true},
+		{method: http.MethodGet, path: "/api/v1/timeline", tag: "archive", summary: "List everything in the archive by date",
+			auth: authRead, params: paginatedListParams(timelineListSpec), response: g.pageOf(models.TimelineEvent{}), cached: true},
+		{method: http.MethodGet, path: "/api/v1/memories/on-this-day", tag: "archive", summary: "List posts, stories, profile photos and first messages from this day in earlier years",
+			auth: authRead,
+			params: []openAPIParameter{accountParam,
+				queryParam("date", "The day to look back from, YYYY-MM-DD; today in tz by default.", &openAPISchema{Type: "string", Format: "date"}),
+				queryParam("tz", "IANA time zone of the calendar day, such as Europe/Berlin; UTC by default.", &openAPISchema{Type: "string"})},
+			response: g.of(struct {
+				Date     string       `json:"date"`
+				TimeZone string       `json:"time_zone"`
+				Total    int          `json:"total"`
+				Years    []memoryYear `json:"years"`
+			}{})},
+
+		// insights
+		{method: http.MethodGet, path: "/api/v1/insights/relationships", tag: "insights", summary: "Compare followers with following, and where close friends, restricted, blocked and hidden-story accounts fall",
+			auth: authRead,
+			params: append(paginatedListParams(relationshipsListSpec),
+				queryParam("list", "Which list to page through, "+relationshipNotFollowingBack+" by default.", stringEnum(relationshipLists...)),
+				queryParam("label", "Only list accounts that are also on this connection list.", stringEnum(relationshipLabels...))),
+			response: g.of(struct {
+				Counts struct {
+					Followers int `json:"followers"`
+					Following int `json:"following"`
+					relationshipCounts
+				} `json:"counts"`
+				Overlaps   map[string]relationshipCounts `json:"overlaps"`
+				List       string                        `json:"list"`
+				Items      []models.RelationshipEntry    `json:"items"`
+				NextCursor *string                       `json:"next_cursor"`
+			}{}),
+			cached: true},
+		{method: http.MethodGet, path: "/api/v1/insights/snapshots", tag: "insights", summary: "List follower and following counts per import, oldest first",
+			auth: authRead, params: archiveParams, response: arrayOf(g.of(models.ConnectionSnapshot{})), cached: true},
+		{method: http.MethodGet, path: "/api/v1/insights/snapshots/diff", tag: "insights", summary: "Compare the follower and following lists of two imports",
+			auth: authRead,
+			params: []openAPIParameter{accountParam,
+				queryParam("from", "Import id of the older snapshot; the one before `to` by default.", &openAPISchema{Type: "integer"}),
+				queryParam("to", "Import id of the newer snapshot; the latest by default.", &openAPISchema{Type: "integer"})},
+			response: g.of(struct {
+				From models.ConnectionSnapshot `json:"from"`
+				To   models.ConnectionSnapshot `json:"to"`
+				snapshotDiff
+			}{}),
+			cached: true},
+		{method: http.MethodGet, path: "/api/v1/insights/messages", tag: "insights", summary: "Get message counts, reply times, busiest days and streaks per conversation and overall",
+			auth: authRead,
+			params: []openAPIParameter{accountParam,
+				meParam},
+			response: g.of(struct {
+				Me     string `json:"me"`
+				Totals struct {
+					Conversations        int        `json:"conversations"`
+					Messages             int        `json:"messages"`
+					Sent                 int        `json:"sent"`
+					Received             int        `json:"received"`
+					FirstMessageAt       *time.Time `json:"first_message_at"`
+					LastMessageAt        *time.Time `json:"last_message_at"`
+					YourAvgReplySeconds  *float64   `json:"your_avg_reply_seconds"`
+					TheirAvgReplySeconds *float64   `json:"their_avg_reply_seconds"`
+					LongestStreak        *dayStreak `json:"longest_streak"`
+				} `json:"totals"`
+				BusiestDays   []dayCount `json:"busiest_days"`
+				Conversations []struct {
+					models.ConversationStats
+					BusiestDay    *dayCount  `json:"busiest_day"`
+					LongestStreak *dayStreak `json:"longest_streak"`
+				} `json:"conversations"`
+			}{}),
+			cached: true},
+		{method: http.MethodGet, path: "/api/v1/insights/activity", tag: "insights", summary: "Count activity by day of week and hour, and by month",
+			auth: authRead,
+			params: []openAPIParameter{accountParam,
+				queryParam("category", "Comma-separated categories to count, all by default. One or more of: "+strings.Join(store.ActivityCategories, ", ")+".", &openAPISchema{Type: "string"}),
+				queryParam("tz", "IANA time zone to bucket in, such as Europe/Berlin; UTC by default.", &openAPISchema{Type: "string"})},
+			response: g.of(struct {
+				TimeZone   string          `json:"time_zone"`
+				Categories []string        `json:"categories"`
+				Total      int             `json:"total"`
+				ByCategory map[string]int  `json:"by_category"`
+				Heatmap    [7][24]int      `json:"heatmap"`
+				ByHour     [24]int         `json:"by_hour"`
+				ByWeekday  [7]int          `json:"by_weekday"`
+				Months     []activityMonth `json:"months"`
+			}{}),
+			cached: true},
+		{method: http.MethodGet, path: "/api/v1/insights/ads", tag: "insights", summary: "Report advertisers, ads seen, off-Meta apps and inferred interests",
+			auth: authRead,
+			params: []openAPIParameter{accountParam,
+				queryParam("format", "Response format, json by default. csv downloads one row per advertiser, app, interest, topic and location.", stringEnum("json", "csv"))},
+			response: g.of(adReport{}),
+			csv:      true,
+			cached:   true},
+		{method: http.MethodGet, path: "/api/v1/insights/interactions", tag: "insights", summary: "Rank the accounts you interact with most",
+			auth: authRead,
+			params: []openAPIParameter{accountParam,
+				queryParam("limit", fmt.Sprintf("Number of accounts, %d by default.", defaultInteractionsLimit), intRange(1, maxInteractionsLimit)),
+				meParam},
+			response: g.of(struct {
+				Me      string              `json:"me"`
+				Weights map[string]int      `json:"weights"`
+				People  []interactionPerson `json:"people"`
+			}{}),
+			cached: true},
+		{method: http.MethodGet, path: "/api/v1/insights/interactions/{username}", tag: "insights", summary: "Score one account and list a page of your interactions with it",
+			auth: authRead,
+			params: append([]openAPIParameter{accountParam, pathParam("username", "Instagram username, case-insensitive.", &openAPISchema{Type: "string"}),
+				meParam}, pageParams()...),
+			response: g.of(struct {
+				Me string `json:"me"`
+				interactionPerson
+				Interactions []models.Interaction `json:"interactions"`
+				NextCursor   *string              `json:"next_cursor"`
+			}{}),
+			cached: true},
+		{method: http.MethodGet, path: "/api/v1/insights/security", tag: "insights", summary: "Group logins into devices and IP addresses and flag unusual ones",
+			auth: authRead, params: []openAPIParameter{accountParam},
+			response: g.of(struct {
+				Summary struct {
+					Logins  int `json:"logins"`
+					Devices int `json:"devices"`
+					IPs     int `json:"ips"`
+					Flags   int `json:"flags"`
+				} `json:"summary"`
+				Flags   []securityFlag  `json:"flags"`
+				Devices []loginDevice   `json:"devices"`
+				IPs     []loginIP       `json:"ips"`
+				Logins  []analyzedLogin `json:"logins"`
+			}{}),
+			cached: true},
+		{method: http.MethodGet, path: "/api/v1/insights/login-locations", tag: "insights", summary: "Place logins and the signup on a map with the offline geolocation database",
+			auth: authRead, params: []openAPIParameter{accountParam},
+			response: g.of(struct {
+				GeoIPEnabled bool `json:"geoip_enabled"`
+				Summary      struct {
+					Logins    int `json:"logins"`
+					Located   int `json:"located"`
+					Countries int `json:"countries"`
+					Places    int `json:"places"`
+				} `json:"summary"`
+				Signup *struct {
+					IPAddress  string             `json:"ip_address"`
+					SignedUpAt *time.Time         `json:"signed_up_at"`
+					Location   *models.IPLocation `json:"location"`
+				} `json:"signup"`
+				Places []loginPlace    `json:"places"`
+				Months []locationMonth `json:"months"`
+				Logins []locatedLogin  `json:"logins"`
+			}{}),
+			cached: true},
+		{method: http.MethodGet, path: "/api/v1/insights/words", tag: "insights", summary: "Count the words, hashtags, mentions and emoji in your captions, comments and sent messages",
+			auth: authRead,
+			params: append([]openAPIParameter{accountParam, meParam,
+				queryParam("conversation", "Only count the messages you sent in this conversation.", &openAPISchema{Type: "string"}),
+				queryParam("stopwords", "Comma-separated languages whose stopwords to leave out, or none; all of "+strings.Join(stopwordLanguages, ", ")+" by default.", &openAPISchema{Type: "string"}),
+				queryParam("limit", fmt.Sprintf("Number of words, hashtags, mentions and emoji each, %d by default.", defaultWordsLimit), intRange(1, maxWordsLimit))},
+				listParams(wordsListSpec)...),
+			response: g.of(struct {
+				Me        string   `json:"me"`
+				Stopwords []string `json:"stopwords"`
+				Summary   struct {
+					Texts       int            `json:"texts"`
+					Sources     map[string]int `json:"sources"`
+					Words       int            `json:"words"`
+					UniqueWords int            `json:"unique_words"`
+					Hashtags    int            `json:"hashtags"`
+					Mentions    int            `json:"mentions"`
+					Emoji       int            `json:"emoji"`
+				} `json:"summary"`
+				Languages []languageCount `json:"languages"`
+				Words     []termCount     `json:"words"`
+				Hashtags  []termCount     `json:"hashtags"`
+				Mentions  []termCount     `json:"mentions"`
+				Emoji     []termCount     `json:"emoji"`
+			}{}),
+			cached: true},
+
+		{method: http.MethodGet, path: openAPIPath, tag: "meta", summary: "This document",
+			response: &openAPISchema{Type: "object"}},
+	}
+}
+
+// --- Document ---
+
+func buildOpenAPIDocument() openAPIDocument {
+	g := &schemaGenerator{components: map[string]*openAPISchema{}}
+	var doc openAPIDocument
+	doc.OpenAPI = "3.0.3"
+	doc.Info.Title = "Instagram Archive Viewer API"
+	doc.Info.Version = "1"
+	doc.Paths = map[string]map[string]*openAPIOperation{}
+
+	errorResponse := openAPIResponse{Ref: "#/components/responses/Error"}
+	for _, rt := range apiRoutes(g) {
+		op := &openAPIOperation{
+			Tags:        []string{rt.tag},
+			Summary:     rt.summary,
+			Description: apiAuthDescriptions[rt.auth],
+			Security:    []map[string][]string{},
+			Parameters:  rt.params,
+			Responses:   map[string]openAPIResponse{"default": errorResponse},
+		}
+		switch rt.auth {
+		case authNone:
+		case authShare:
+			op.Security = []map[string][]string{{"shareToken": {}}, {"shareTokenQuery": {}}}
+		default:
+			op.Security = []map[string][]string{{"bearer": {}}}
+		}
+		if rt.body != nil {
+			op.RequestBody = &openAPIRequestBody{Required: true, Content: map[string]openAPIMediaType{"application/json": {rt.body}}}
+		}
+		if rt.form != nil {
+			op.RequestBody = &openAPIRequestBody{Required: true, Content: map[string]openAPIMediaType{"multipart/form-data": {rt.form}}}
+		}
+
+		status := rt.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		success := openAPIResponse{Description: http.StatusText(status)}
+		switch {
+		case rt.file:
+			success.Content = map[string]openAPIMediaType{"application/octet-stream": {&openAPISchema{Type: "string", Format: "binary"}}}
+		case rt.response != nil:
+			success.Content = map[string]openAPIMediaType{"application/json": {rt.response}}
+		}
+		if rt.csv {
+			success.Content["text/csv"] = openAPIMediaType{&openAPISchema{Type: "string"}}
+		}
+		op.Responses[fmt.Sprint(status)] = success
+		if rt.cached {
+			op.Responses["304"] = openAPIResponse{Description: "The archive has not changed since the ETag sent in If-None-Match."}
+		}
+
+		if doc.Paths[rt.path] == nil {
+			doc.Paths[rt.path] = map[string]*openAPIOperation{}
+		}
+		doc.Paths[rt.path][strings.ToLower(rt.method)] = op
+	}
+
+	doc.Components.Schemas = g.components
+	doc.Components.Schemas["Error"] = g.of(struct {
+		Error string `json:"error"`
+	}{})
+	doc.Components.Responses = map[string]openAPIResponse{
+		"Error": {Description: "Error", Content: map[string]openAPIMediaType{"application/json": {&openAPISchema{Ref: "#/components/schemas/Error"}}}},
+	}
+	doc.Components.SecuritySchemes = map[string]openAPISecurityScheme{
+		"bearer": {Type: "http", Scheme: "bearer",
+			Description: "A session token from /api/v1/login or a personal API token from /api/v1/tokens."},
+		"shareToken":      {Type: "apiKey", In: "header", Name: shareTokenHeader, Description: "The token of a share link."},
+		"shareTokenQuery": {Type: "apiKey", In: "query", Name: "token", Description: "The token of a share link, for <img> and <video> sources."},
+	}
+	return doc
+}
+
+var openAPIJSON = sync.OnceValue(func() []byte {
+	raw, err := json.MarshalIndent(buildOpenAPIDocument(), "", "  ")
+	if err != nil {
+		panic(fmt.Sprintf("openapi: %v", err))
+	}
+	return raw
+})
+
+// openAPIHandler serves the OpenAPI 3 description of the API at /api/v1/openapi.json.
+func (s *APIServer) openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPIJSON())
+}
@@ -0,0 +1,490 @@
+package server
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/constant"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Sa-Te/IAV/backend/internal/store"
+)
+
+// These tests compare the served OpenAPI document with the handler source: every
+// route registered in Run must be documented, and every value a handler passes to
+// json.Encoder.Encode must have the shape of one of its documented responses (and
+// the other way round).
+
+// jsonShape is what a client sees of a value's JSON encoding.
+type jsonShape struct {
+	kind     string // object, array, string, integer, number, boolean, any or invalid
+	nullable bool
+	props    map[string]*jsonShape
+	items    *jsonShape // arrays
+	values   *jsonShape // objects with arbitrary keys
+}
+
+// String renders the shape canonically, so equal shapes compare equal as strings.
+func (s *jsonShape) String() string {
+	var b strings.Builder
+	switch {
+	case s.props != nil:
+		names := make([]string, 0, len(s.props))
+		for name := range s.props {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		b.WriteString("{")
+		for i, name := range names {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(name + ": " + s.props[name].String())
+		}
+		b.WriteString("}")
+	case s.items != nil:
+		b.WriteString("[" + s.items.String() + "]")
+	case s.values != nil:
+		b.WriteString("map[" + s.values.String() + "]")
+	default:
+		b.WriteString(s.kind)
+	}
+	if s.nullable {
+		b.WriteString("?")
+	}
+	return b.String()
+}
+
+// --- Shapes from the document ---
+
+func schemaShape(doc map[string]interface{}, schema map[string]interface{}) *jsonShape {
+	if ref, ok := schema["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		components := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+		return schemaShape(doc, components[name].(map[string]interface{}))
+	}
+	nullable, _ := schema["nullable"].(bool)
+	if allOf, ok := schema["allOf"].([]interface{}); ok && len(allOf) == 1 {
+		s := *schemaShape(doc, allOf[0].(map[string]interface{}))
+		s.nullable = s.nullable || nullable
+		return &s
+	}
+
+	s := &jsonShape{nullable: nullable}
+	switch kind, _ := schema["type"].(string); kind {
+	case "":
+		s.kind = "any"
+	case "object":
+		s.kind = kind
+		if props, ok := schema["properties"].(map[string]interface{}); ok {
+			s.props = map[string]*jsonShape{}
+			for name, prop := range props {
+				s.props[name] = schemaShape(doc, prop.(map[string]interface{}))
+			}
+		} else if values, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+			s.values = schemaShape(doc, values)
+		}
+	case "array":
+		s.kind = kind
+		s.items = schemaShape(doc, schema["items"].(map[string]interface{}))
+	default:
+		s.kind = kind
+	}
+	return s
+}
+
+// --- Shapes from the source ---
+
+func typeShape(t types.Type) *jsonShape {
+	if named, ok := types.Unalias(t).(*types.Named); ok {
+		obj := named.Obj()
+		if obj.Pkg() != nil && obj.Pkg().Path() == "time" && obj.Name() == "Time" {
+			return &jsonShape{kind: "string"}
+		}
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Pointer:
+		s := *typeShape(u.Elem())
+		s.nullable = true
+		return &s
+	case *types.Basic:
+		switch info := u.Info(); {
+		case info&types.IsBoolean != 0:
+			return &jsonShape{kind: "boolean"}
+		case info&types.IsInteger != 0:
+			return &jsonShape{kind: "integer"}
+		case info&types.IsFloat != 0:
+			return &jsonShape{kind: "number"}
+		case info&types.IsString != 0:
+			return &jsonShape{kind: "string"}
+		}
+	case *types.Slice:
+		if b, ok := u.Elem().Underlying().(*types.Basic); ok && b.Kind() == types.Byte {
+			return &jsonShape{kind: "string"}
+		}
+		return &jsonShape{kind: "array", items: typeShape(u.Elem())}
+	case *types.Array:
+		return &jsonShape{kind: "array", items: typeShape(u.Elem())}
+	case *types.Map:
+		return &jsonShape{kind: "object", values: typeShape(u.Elem())}
+	case *types.Interface:
+		return &jsonShape{kind: "any"}
+	case *types.Struct:
+		s := &jsonShape{kind: "object", props: map[string]*jsonShape{}}
+		addStructFields(s, u)
+		return s
+	}
+	return &jsonShape{kind: "invalid"}
+}
+
+func addStructFields(s *jsonShape, st *types.Struct) {
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		name, _, _ := strings.Cut(reflect.StructTag(st.Tag(i)).Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if embedded, ok := f.Type().Underlying().(*types.Struct); ok && f.Embedded() && name == "" {
+			addStructFields(s, embedded)
+			continue
+		}
+		if !f.Exported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name()
+		}
+		s.props[name] = typeShape(f.Type())
+	}
+}
+
+// exprShape is typeShape for an Encode argument, except that map literals with
+// constant keys (map[string]interface{}{"token": ...}) become objects with those keys.
+func exprShape(info *types.Info, expr ast.Expr) *jsonShape {
+	if lit, ok := expr.(*ast.CompositeLit); ok {
+		if _, isMap := info.TypeOf(lit).Underlying().(*types.Map); isMap {
+			s := &jsonShape{kind: "object", props: map[string]*jsonShape{}}
+			for _, elt := range lit.Elts {
+				kv := elt.(*ast.KeyValueExpr)
+				key := info.Types[kv.Key].Value
+				if key == nil {
+					return typeShape(info.TypeOf(lit))
+				}
+				s.props[constant.StringVal(key)] = typeShape(info.TypeOf(kv.Value))
+			}
+			return s
+		}
+	}
+	return typeShape(info.TypeOf(expr))
+}
+
+// --- Loading the package ---
+
+// sourceImporter type-checks the models and store packages and the standard library
+// from source and stubs out every other import: handlers never encode values of those
+// packages' types, and the type errors the stubs cause elsewhere are ignored.
+var storePkgPath = reflect.TypeOf(store.Store{}).PkgPath()
+
+type sourceImporter struct {
+	fset     *token.FileSet
+	std      types.Importer
+	packages map[string]*types.Package
+}
+
+func (im *sourceImporter) Import(importPath string) (*types.Package, error) {
+	if pkg, ok := im.packages[importPath]; ok {
+		return pkg, nil
+	}
+	var pkg *types.Package
+	switch {
+	case importPath == modelsPkgPath:
+		pkg, _ = im.check("../models", importPath)
+	case importPath == storePkgPath:
+		pkg, _ = im.check("../store", importPath)
+	case !strings.Contains(strings.Split(importPath, "/")[0], "."):
+		return im.std.Import(importPath)
+	default:
+		pkg = types.NewPackage(importPath, path.Base(importPath))
+		pkg.MarkComplete()
+	}
+	im.packages[importPath] = pkg
+	return pkg, nil
+}
+
+func (im *sourceImporter) check(dir, importPath string) (*types.Package, *serverSource) {
+	pkgs, err := parser.ParseDir(im.fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		panic(err)
+	}
+	src := &serverSource{
+		files: map[string]*ast.File{},
+		info:  &types.Info{Types: map[ast.Expr]types.TypeAndValue{}},
+	}
+	var files []*ast.File
+	for _, p := range pkgs {
+		for name, f := range p.Files {
+			src.files[path.Base(name)] = f
+			files = append(files, f)
+		}
+	}
+	conf := types.Config{Importer: im, Error: func(error) {}}
+	pkg, _ := conf.Check(importPath, im.fset, files, src.info)
+	return pkg, src
+}
+
+type serverSource struct {
+	files map[string]*ast.File
+	info  *types.Info
+}
+
+// loadServerSource takes a few seconds, so the tests share one copy.
+var loadServerSource = sync.OnceValue(func() *serverSource {
+	fset := token.NewFileSet()
+	im := &sourceImporter{fset: fset, std: importer.ForCompiler(fset, "source", nil), packages: map[string]*types.Package{}}
+	_, src := im.check(".", "github.com/Sa-Te/IAV/backend/internal/server")
+	return src
+})
+
+// methods returns the *APIServer methods by name.
+func (src *serverSource) methods() map[string]*ast.FuncDecl {
+	methods := map[string]*ast.FuncDecl{}
+	for _, f := range src.files {
+		for _, decl := range f.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv != nil {
+				methods[fn.Name.Name] = fn
+			}
+		}
+	}
+	return methods
+}
+
+type registeredRoute struct {
+	pattern string // "GET /api/v1/media", as passed to the mux
+	handler string
+	idents  map[string]bool // every name in the handler expression: archive, session, scopeImport...
+}
+
+// operation names the route the way the OpenAPI document does: the method followed by
+// the path template, with wildcards such as {path...} written as {path}.
+func (r registeredRoute) operation() string {
+	return strings.ReplaceAll(r.pattern, "...}", "}")
+}
+
+// registeredRoutes reads the mux.Handle/HandleFunc calls in Run. The handler is the
+// server method that is passed along (s.getMediaItemsHandler) rather than called
+// (s.authMiddleware(...)).
+func (src *serverSource) registeredRoutes(t *testing.T) []registeredRoute {
+	run := src.methods()["Run"]
+	var routes []registeredRoute
+	ast.Inspect(run.Body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) != 2 {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || (sel.Sel.Name != "Handle" && sel.Sel.Name != "HandleFunc") {
+			return true
+		}
+		pattern := src.info.Types[call.Args[0]].Value
+		if pattern == nil {
+			t.Errorf("route pattern %v is not a constant", call.Args[0])
+			return false
+		}
+		route := registeredRoute{pattern: constant.StringVal(pattern), idents: map[string]bool{}}
+		called := map[ast.Expr]bool{}
+		ast.Inspect(call.Args[1], func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.Ident:
+				route.idents[n.Name] = true
+			case *ast.CallExpr:
+				called[n.Fun] = true
+			case *ast.SelectorExpr:
+				if id, ok := n.X.(*ast.Ident); ok && id.Name == "s" && !called[n] {
+					route.handler = n.Sel.Name
+				}
+			}
+			return true
+		})
+		if route.handler == "" {
+			t.Errorf("no handler found for %s", route.pattern)
+		}
+		routes = append(routes, route)
+		return false
+	})
+	return routes
+}
+
+// encodedShapes returns the shape of every value the handler, or a server method it
+// calls, writes with json.NewEncoder(w).Encode.
+func (src *serverSource) encodedShapes(handler string) map[string]ast.Expr {
+	methods := src.methods()
+	shapes := map[string]ast.Expr{}
+	visited := map[string]bool{}
+	var visit func(name string)
+	visit = func(name string) {
+		fn, ok := methods[name]
+		if !ok || visited[name] {
+			return
+		}
+		visited[name] = true
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			if inner, ok := sel.X.(*ast.CallExpr); ok && sel.Sel.Name == "Encode" && len(call.Args) == 1 {
+				if innerSel, ok := inner.Fun.(*ast.SelectorExpr); ok && innerSel.Sel.Name == "NewEncoder" {
+					shapes[exprShape(src.info, call.Args[0]).String()] = call.Args[0]
+				}
+			}
+			if id, ok := sel.X.(*ast.Ident); ok && id.Name == "s" {
+				visit(sel.Sel.Name)
+			}
+			return true
+		})
+	}
+	visit(handler)
+	return shapes
+}
+
+// --- Tests ---
+
+func loadOpenAPIDocument(t *testing.T) map[string]interface{} {
+	t.Helper()
+	var doc map[string]interface{}
+	if err := json.Unmarshal(openAPIJSON(), &doc); err != nil {
+		t.Fatalf("openapi.json is not valid JSON: %v", err)
+	}
+	return doc
+}
+
+// documentedOperations lists the document's operations as "METHOD /path".
+func documentedOperations(paths map[string]interface{}) map[string]map[string]interface{} {
+	ops := map[string]map[string]interface{}{}
+	for specPath, item := range paths {
+		for method, op := range item.(map[string]interface{}) {
+			ops[strings.ToUpper(method)+" "+specPath] = op.(map[string]interface{})
+		}
+	}
+	return ops
+}
+
+func TestOpenAPIDocumentsEveryRoute(t *testing.T) {
+	src := loadServerSource()
+	routes := src.registeredRoutes(t)
+	ops := documentedOperations(loadOpenAPIDocument(t)["paths"].(map[string]interface{}))
+
+	registered := map[string]bool{}
+	for _, r := range routes {
+		registered[r.operation()] = true
+		if _, ok := ops[r.operation()]; !ok {
+			t.Errorf("route %s (%s) is missing from the OpenAPI document", r.pattern, r.handler)
+		}
+	}
+	for op := range ops {
+		if !registered[op] {
+			t.Errorf("%s is documented but no route serves it", op)
+		}
+	}
+}
+
+// auth is the apiAuth the route's middleware enforces, read off the wrappers in Run.
+func (r registeredRoute) auth() apiAuth {
+	switch {
+	case r.idents["admin"]:
+		return authAdmin
+	case r.idents["session"] || r.idents["scopeSession"]:
+		return authSession
+	case r.idents["shared"]:
+		return authShare
+	case r.idents["scopeImport"]:
+		return authImport
+	case r.idents["archive"] || r.idents["authMiddleware"]:
+		return authRead
+	}
+	return authNone
+}
+
+func TestOpenAPIAuthMatchesRoutes(t *testing.T) {
+	documented := map[string]apiAuth{}
+	for _, rt := range apiRoutes(&schemaGenerator{components: map[string]*openAPISchema{}}) {
+		documented[rt.method+" "+rt.path] = rt.auth
+	}
+	for _, r := range loadServerSource().registeredRoutes(t) {
+		if auth, ok := documented[r.operation()]; ok && auth != r.auth() {
+			t.Errorf("%s is documented with auth %d but served with %d", r.pattern, auth, r.auth())
+		}
+	}
+}
+
+func TestOpenAPIResponsesMatchHandlers(t *testing.T) {
+	src := loadServerSource()
+	routes := src.registeredRoutes(t)
+	doc := loadOpenAPIDocument(t)
+	ops := documentedOperations(doc["paths"].(map[string]interface{}))
+
+	for _, r := range routes {
+		op, ok := ops[r.operation()]
+		if !ok || r.handler == "openAPIHandler" {
+			continue // undocumented routes are reported above; the spec serves pre-encoded bytes
+		}
+
+		documented := map[string]string{} // shape -> status
+		for status, resp := range op["responses"].(map[string]interface{}) {
+			content, _ := resp.(map[string]interface{})["content"].(map[string]interface{})
+			media, ok := content["application/json"].(map[string]interface{})
+			if !ok || !strings.HasPrefix(status, "2") {
+				continue
+			}
+			schema := media["schema"].(map[string]interface{})
+			variants := []interface{}{schema}
+			if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+				variants = oneOf
+			}
+			for _, v := range variants {
+				documented[schemaShape(doc, v.(map[string]interface{})).String()] = status
+			}
+		}
+
+		encoded := src.encodedShapes(r.handler)
+		for shape, expr := range encoded {
+			if _, ok := documented[shape]; !ok {
+				t.Errorf("%s encodes %s (from %s), which %s does not document",
+					r.handler, shape, types.ExprString(expr), r.pattern)
+			}
+		}
+		for shape, status := range documented {
+			if _, ok := encoded[shape]; !ok {
+				t.Errorf("%s documents %s for %s but %s never encodes it", r.pattern, shape, status, r.handler)
+			}
+		}
+	}
+}
+
+func TestOpenAPIListParametersMatchSpecs(t *testing.T) {
+	doc := loadOpenAPIDocument(t)
+	op := doc["paths"].(map[string]interface{})["/api/v1/connections"].(map[string]interface{})["get"].(map[string]interface{})
+	var names []string
+	for _, p := range op["parameters"].([]interface{}) {
+		names = append(names, p.(map[string]interface{})["name"].(string))
+	}
+	want := []string{"account", "limit", "cursor", "type", "from", "to", "username", "sort", "order"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("connections parameters = %v, want %v", names, want)
+	}
+}
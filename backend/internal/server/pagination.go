@@ -0,0 +1,98 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// List endpoints page with a keyset over (sort value, id) rather than OFFSET, so a page
+// costs the same wherever it sits in the archive and rows imported between requests
+// don't shift the pages. The cursor is opaque to clients.
+const (
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// pageCursor carries both the row's time and its text sort key (for lists sortable by
+// e.g. username); the repository uses whichever the current sort orders by.
+type pageCursor struct {
+	At  time.Time `json:"t"`
+	Key string    `json:"k,omitempty"`
+	ID  int       `json:"id"`
+}
+
+type pageRequest struct {
+	Limit int
+	After *pageCursor
+}
+
+// page is the envelope every paginated endpoint responds with. NextCursor is null on the last page.
+type page[T any] struct {
+	Items      []T     `json:"items"`
+	NextCursor *string `json:"next_cursor"`
+}
+
+func encodeCursor(c pageCursor) string {
+	c.At = c.At.UTC()
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(s string) (*pageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	var c pageCursor
+	if err := json.Unmarshal(raw, &c); err != nil || c.ID <= 0 {
+		return nil, errInvalidCursor
+	}
+	return &c, nil
+}
+
+func parsePageRequest(r *http.Request) (pageRequest, error) {
+	p := pageRequest{Limit: defaultPageLimit}
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return p, fmt.Errorf("limit must be between 1 and %d", maxPageLimit)
+		}
+		p.Limit = limit
+	}
+	if v := r.URL.Query().Get("cursor"); v != "" {
+		c, err := decodeCursor(v)
+		if err != nil {
+			return p, err
+		}
+		p.After = c
+	}
+	return p, nil
+}
+
+// pageFromRequest parses limit/cursor, writing a 400 itself when they are malformed.
+func pageFromRequest(w http.ResponseWriter, r *http.Request) (pageRequest, bool) {
+	p, err := parsePageRequest(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return p, false
+	}
+	return p, true
+}
+
+// newPage trims the look-ahead row the repository fetched and builds the cursor for the next page.
+func newPage[T any](items []T, p pageRequest, key func(T) pageCursor) page[T] {
+	pg := page[T]{Items: items}
+	if len(items) > p.Limit {
+		pg.Items = items[:p.Limit]
+		next := encodeCursor(key(pg.Items[p.Limit-1]))
+		pg.NextCursor = &next
+	}
+	return pg
+}
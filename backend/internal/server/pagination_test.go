@@ -0,0 +1,53 @@
+package server
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	at := time.Date(2023, 4, 5, 6, 7, 8, 123456000, time.FixedZone("CET", 3600))
+	c, err := decodeCursor(encodeCursor(pageCursor{At: at, ID: 99}))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !c.At.Equal(at) || c.ID != 99 {
+		t.Errorf("round trip = %+v", c)
+	}
+	for _, bad := range []string{"not base64!", "e30", encodeCursor(pageCursor{At: at})} {
+		if _, err := decodeCursor(bad); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestParsePageRequest(t *testing.T) {
+	p, err := parsePageRequest(httptest.NewRequest("GET", "/api/v1/media", nil))
+	if err != nil || p.Limit != defaultPageLimit || p.After != nil {
+		t.Errorf("defaults = %+v, %v", p, err)
+	}
+	for _, q := range []string{"limit=0", "limit=abc", "limit=100000", "cursor=bogus"} {
+		if _, err := parsePageRequest(httptest.NewRequest("GET", "/api/v1/media?"+q, nil)); err == nil {
+			t.Errorf("expected %q to be rejected", q)
+		}
+	}
+}
+
+func TestNewPage(t *testing.T) {
+	key := func(i int) pageCursor { return pageCursor{At: time.Unix(int64(i), 0), ID: i} }
+	p := pageRequest{Limit: 2}
+
+	last := newPage([]int{1, 2}, p, key)
+	if len(last.Items) != 2 || last.NextCursor != nil {
+		t.Errorf("last page = %+v", last)
+	}
+
+	more := newPage([]int{1, 2, 3}, p, key)
+	if len(more.Items) != 2 || more.NextCursor == nil {
+		t.Fatalf("page with more = %+v", more)
+	}
+	if c, _ := decodeCursor(*more.NextCursor); c == nil || c.ID != 2 {
+		t.Errorf("next cursor should point after item 2, got %+v", c)
+	}
+}
@@ -26,7 +26,7 @@ func isoDecodeFile(path string) (*os.File, *transform.Reader, error) {
 
 // --- Likes ---
 
-func (s *APIServer) processLikedPosts(path string, userID int) error {
+func (s *APIServer) processLikedPosts(path string, userID, accountID int) error {
 	f, r, err := isoDecodeFile(path)
 	if err != nil {
 		return fmt.Errorf("open liked_posts: %w", err)
@@ -38,21 +38,20 @@ func (s *APIServer) processLikedPosts(path string, userID int) error {
 		return fmt.Errorf("decode liked_posts: %w", err)
 	}
 
-	sql := `INSERT INTO post_likes (user_id, creator_username, post_url, liked_at)
-	        VALUES ($1,$2,$3,$4) ON CONFLICT DO NOTHING`
+	var likes []models.PostLike
 	for _, item := range wrapper.Likes {
 		for _, d := range item.StringListData {
-			_, err := s.db.Exec(context.Background(), sql, userID, item.Title, d.Href, time.Unix(d.Timestamp, 0))
-			if err != nil {
-				log.Printf("insert post_like %s: %v", item.Title, err)
-			}
+			likes = append(likes, models.PostLike{CreatorUsername: item.Title, PostURL: d.Href, LikedAt: time.Unix(d.Timestamp, 0)})
 		}
 	}
+	if err := s.store.Interactions.SavePostLikes(context.Background(), userID, accountID, likes); err != nil {
+		log.Printf("insert post_likes: %v", err)
+	}
 	log.Printf("Processed liked_posts (%d items)", len(wrapper.Likes))
 	return nil
 }
 
-func (s *APIServer) processLikedComments(path string, userID int) error {
+func (s *APIServer) processLikedComments(path string, userID, accountID int) error {
 	f, r, err := isoDecodeFile(path)
 	if err != nil {
 		return fmt.Errorf("open liked_comments: %w", err)
@@ -64,21 +63,20 @@ func (s *APIServer) processLikedComments(path string, userID int) error {
 		return fmt.Errorf("decode liked_comments: %w", err)
 	}
 
-	sql := `INSERT INTO comment_likes (user_id, owner_username, post_url, liked_at)
-	        VALUES ($1,$2,$3,$4) ON CONFLICT DO NOTHING`
+	var likes []models.CommentLike
 	for _, item := range wrapper.Likes {
 		for _, d := range item.StringListData {
-			_, err := s.db.Exec(context.Background(), sql, userID, item.Title, d.Href, time.Unix(d.Timestamp, 0))
-			if err != nil {
-				log.Printf("insert comment_like %s: %v", item.Title, err)
-			}
+			likes = append(likes, models.CommentLike{OwnerUsername: item.Title, PostURL: d.Href, LikedAt: time.Unix(d.Timestamp, 0)})
 		}
 	}
+	if err := s.store.Interactions.SaveCommentLikes(context.Background(), userID, accountID, likes); err != nil {
+		log.Printf("insert comment_likes: %v", err)
+	}
 	log.Printf("Processed liked_comments (%d items)", len(wrapper.Likes))
 	return nil
 }
 
-func (s *APIServer) processStoryLikes(path string, userID int) error {
+func (s *APIServer) processStoryLikes(path string, userID, accountID int) error {
 	f, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("open story_likes: %w", err)
@@ -90,23 +88,22 @@ func (s *APIServer) processStoryLikes(path string, userID int) error {
 		return fmt.Errorf("decode story_likes: %w", err)
 	}
 
-	sql := `INSERT INTO story_likes (user_id, creator_username, liked_at)
-	        VALUES ($1,$2,$3) ON CONFLICT DO NOTHING`
+	var likes []models.StoryLike
 	for _, item := range wrapper.Likes {
 		for _, d := range item.StringListData {
-			_, err := s.db.Exec(context.Background(), sql, userID, item.Title, time.Unix(d.Timestamp, 0))
-			if err != nil {
-				log.Printf("insert story_like %s: %v", item.Title, err)
-			}
+			likes = append(likes, models.StoryLike{CreatorUsername: item.Title, LikedAt: time.Unix(d.Timestamp, 0)})
 		}
 	}
+	if err := s.store.Interactions.SaveStoryLikes(context.Background(), userID, accountID, likes); err != nil {
+		log.Printf("insert story_likes: %v", err)
+	}
 	log.Printf("Processed story_likes (%d items)", len(wrapper.Likes))
 	return nil
 }
 
 // --- Comments ---
 
-func (s *APIServer) processPostComments(path string, userID int) error {
+func (s *APIServer) processPostComments(path string, userID, accountID int) error {
 	f, r, err := isoDecodeFile(path)
 	if err != nil {
 		return fmt.Errorf("open post_comments: %w", err)
@@ -119,23 +116,22 @@ func (s *APIServer) processPostComments(path string, userID int) error {
 		return fmt.Errorf("decode post_comments: %w", err)
 	}
 
-	sql := `INSERT INTO post_comments (user_id, post_owner_username, comment_text, commented_at)
-	        VALUES ($1,$2,$3,$4) ON CONFLICT DO NOTHING`
+	var comments []models.PostComment
 	for _, e := range entries {
-		ts := time.Unix(e.StringMapData.Time.Timestamp, 0)
-		_, err := s.db.Exec(context.Background(), sql, userID,
-			e.StringMapData.MediaOwner.Value,
-			e.StringMapData.Comment.Value,
-			ts)
-		if err != nil {
-			log.Printf("insert post_comment: %v", err)
-		}
+		comments = append(comments, models.PostComment{
+			PostOwnerUsername: e.StringMapData.MediaOwner.Value,
+			CommentText:       e.StringMapData.Comment.Value,
+			CommentedAt:       time.Unix(e.StringMapData.Time.Timestamp, 0),
+		})
+	}
+	if err := s.store.Interactions.SavePostComments(context.Background(), userID, accountID, comments); err != nil {
+		log.Printf("insert post_comments: %v", err)
 	}
 	log.Printf("Processed post_comments (%d items)", len(entries))
 	return nil
 }
 
-func (s *APIServer) processReelComments(path string, userID int) error {
+func (s *APIServer) processReelComments(path string, userID, accountID int) error {
 	f, r, err := isoDecodeFile(path)
 	if err != nil {
 		return fmt.Errorf("open reel_comments: %w", err)
@@ -147,17 +143,16 @@ func (s *APIServer) processReelComments(path string, userID int) error {
 		return fmt.Errorf("decode reel_comments: %w", err)
 	}
 
-	sql := `INSERT INTO reel_comments (user_id, reel_owner_username, comment_text, commented_at)
-	        VALUES ($1,$2,$3,$4) ON CONFLICT DO NOTHING`
+	var comments []models.ReelComment
 	for _, c := range wrapper.Comments {
-		ts := time.Unix(c.StringMapData.Time.Timestamp, 0)
-		_, err := s.db.Exec(context.Background(), sql, userID,
-			c.StringMapData.MediaOwner.Value,
-			c.StringMapData.Comment.Value,
-			ts)
-		if err != nil {
-			log.Printf("insert reel_comment: %v", err)
-		}
+		comments = append(comments, models.ReelComment{
+			ReelOwnerUsername: c.StringMapData.MediaOwner.Value,
+			CommentText:       c.StringMapData.Comment.Value,
+			CommentedAt:       time.Unix(c.StringMapData.Time.Timestamp, 0),
+		})
+	}
+	if err := s.store.Interactions.SaveReelComments(context.Background(), userID, accountID, comments); err != nil {
+		log.Printf("insert reel_comments: %v", err)
 	}
 	log.Printf("Processed reel_comments (%d items)", len(wrapper.Comments))
 	return nil
@@ -165,7 +160,7 @@ func (s *APIServer) processReelComments(path string, userID int) error {
 
 // --- Saved ---
 
-func (s *APIServer) processSavedPosts(path string, userID int) error {
+func (s *APIServer) processSavedPosts(path string, userID, accountID int) error {
 	f, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("open saved_posts: %w", err)
@@ -177,20 +172,22 @@ func (s *APIServer) processSavedPosts(path string, userID int) error {
 		return fmt.Errorf("decode saved_posts: %w", err)
 	}
 
-	sql := `INSERT INTO saved_media (user_id, creator_username, post_url, saved_at)
-	        VALUES ($1,$2,$3,$4) ON CONFLICT DO NOTHING`
+	var saved []models.SavedMedia
 	for _, item := range wrapper.Media {
-		ts := time.Unix(item.StringMapData.SavedOn.Timestamp, 0)
-		_, err := s.db.Exec(context.Background(), sql, userID, item.Title, item.StringMapData.SavedOn.Href, ts)
-		if err != nil {
-			log.Printf("insert saved_media %s: %v", item.Title, err)
-		}
+		saved = append(saved, models.SavedMedia{
+			CreatorUsername: item.Title,
+			PostURL:         item.StringMapData.SavedOn.Href,
+			SavedAt:         time.Unix(item.StringMapData.SavedOn.Timestamp, 0),
+		})
+	}
+	if err := s.store.Saved.SaveSavedMedia(context.Background(), userID, accountID, saved); err != nil {
+		log.Printf("insert saved_media: %v", err)
 	}
 	log.Printf("Processed saved_posts (%d items)", len(wrapper.Media))
 	return nil
 }
 
-func (s *APIServer) processSavedCollections(path string, userID int) error {
+func (s *APIServer) processSavedCollections(path string, userID, accountID int) error {
 	f, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("open saved_collections: %w", err)
@@ -202,38 +199,40 @@ func (s *APIServer) processSavedCollections(path string, userID int) error {
 		return fmt.Errorf("decode saved_collections: %w", err)
 	}
 
-	collectionSQL := `INSERT INTO saved_collections (user_id, collection_name, created_at, updated_at)
-	                  VALUES ($1,$2,$3,$4) ON CONFLICT DO NOTHING`
-	itemSQL := `INSERT INTO saved_collection_items (user_id, collection_name, item_url, creator_username, added_at)
-	            VALUES ($1,$2,$3,$4,$5) ON CONFLICT DO NOTHING`
-
+	var collections []models.SavedCollection
+	var items []models.SavedCollectionItem
 	var currentCollection string
 	for _, entry := range wrapper.Collections {
 		if entry.StringMapData.CreationTime.Timestamp > 0 {
 			// This is a collection header
 			currentCollection = entry.StringMapData.Name.Value
-			created := time.Unix(entry.StringMapData.CreationTime.Timestamp, 0)
-			updated := time.Unix(entry.StringMapData.UpdateTime.Timestamp, 0)
-			_, err := s.db.Exec(context.Background(), collectionSQL, userID, currentCollection, created, updated)
-			if err != nil {
-				log.Printf("insert saved_collection %s: %v", currentCollection, err)
-			}
+			collections = append(collections, models.SavedCollection{
+				CollectionName: currentCollection,
+				CreatedAt:      time.Unix(entry.StringMapData.CreationTime.Timestamp, 0),
+				UpdatedAt:      time.Unix(entry.StringMapData.UpdateTime.Timestamp, 0),
+			})
 		} else if entry.StringMapData.AddedTime.Timestamp > 0 && entry.StringMapData.Name.Href != "" {
 			// This is a collection item
-			added := time.Unix(entry.StringMapData.AddedTime.Timestamp, 0)
-			_, err := s.db.Exec(context.Background(), itemSQL, userID, currentCollection,
-				entry.StringMapData.Name.Href, entry.StringMapData.Name.Value, added)
-			if err != nil {
-				log.Printf("insert saved_collection_item: %v", err)
-			}
+			items = append(items, models.SavedCollectionItem{
+				CollectionName:  currentCollection,
+				ItemURL:         entry.StringMapData.Name.Href,
+				CreatorUsername: entry.StringMapData.Name.Value,
+				AddedAt:         time.Unix(entry.StringMapData.AddedTime.Timestamp, 0),
+			})
 		}
 	}
+	if err := s.store.Saved.SaveCollections(context.Background(), userID, accountID, collections); err != nil {
+		log.Printf("insert saved_collections: %v", err)
+	}
+	if err := s.store.Saved.SaveCollectionItems(context.Background(), userID, accountID, items); err != nil {
+		log.Printf("insert saved_collection_items: %v", err)
+	}
 	return nil
 }
 
 // --- Profile ---
 
-func (s *APIServer) processPersonalInfo(path string, userID int) error {
+func (s *APIServer) processPersonalInfo(path string, userID, accountID int) error {
 	f, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("open personal_information: %w", err)
@@ -255,21 +254,15 @@ func (s *APIServer) processPersonalInfo(path string, userID int) error {
 		dobPtr = &dob
 	}
 
-	sql := `INSERT INTO user_profile (user_id, email, phone_number, username, bio, gender, date_of_birth, profile_photo_uri)
-	        VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
-	        ON CONFLICT (user_id) DO UPDATE SET
-	          email=EXCLUDED.email, phone_number=EXCLUDED.phone_number,
-	          username=EXCLUDED.username, bio=EXCLUDED.bio,
-	          gender=EXCLUDED.gender, date_of_birth=EXCLUDED.date_of_birth,
-	          profile_photo_uri=EXCLUDED.profile_photo_uri, updated_at=NOW()`
-	_, err = s.db.Exec(context.Background(), sql, userID,
-		p.StringMapData.Email.Value,
-		p.StringMapData.PhoneNumber.Value,
-		p.StringMapData.Username.Value,
-		p.StringMapData.Bio.Value,
-		p.StringMapData.Gender.Value,
-		dobPtr,
-		p.MediaMapData.ProfilePhoto.URI)
+	err = s.store.Profile.SaveProfile(context.Background(), userID, accountID, models.UserProfile{
+		Email:           p.StringMapData.Email.Value,
+		PhoneNumber:     p.StringMapData.PhoneNumber.Value,
+		Username:        p.StringMapData.Username.Value,
+		Bio:             p.StringMapData.Bio.Value,
+		Gender:          p.StringMapData.Gender.Value,
+		DateOfBirth:     dobPtr,
+		ProfilePhotoURI: p.MediaMapData.ProfilePhoto.URI,
+	})
 	if err != nil {
 		return fmt.Errorf("upsert user_profile: %w", err)
 	}
@@ -277,7 +270,7 @@ func (s *APIServer) processPersonalInfo(path string, userID int) error {
 	return nil
 }
 
-func (s *APIServer) processProfileChanges(path string, userID int) error {
+func (s *APIServer) processProfileChanges(path string, userID, accountID int) error {
 	f, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("open profile_changes: %w", err)
@@ -289,24 +282,23 @@ func (s *APIServer) processProfileChanges(path string, userID int) error {
 		return fmt.Errorf("decode profile_changes: %w", err)
 	}
 
-	sql := `INSERT INTO profile_changes (user_id, field_changed, previous_value, new_value, changed_at)
-	        VALUES ($1,$2,$3,$4,$5)`
+	var changes []models.ProfileChange
 	for _, c := range wrapper.Changes {
-		ts := time.Unix(c.StringMapData.ChangeDate.Timestamp, 0)
-		_, err := s.db.Exec(context.Background(), sql, userID,
-			c.StringMapData.Changed.Value,
-			c.StringMapData.PreviousValue.Value,
-			c.StringMapData.NewValue.Value,
-			ts)
-		if err != nil {
-			log.Printf("insert profile_change: %v", err)
-		}
+		changes = append(changes, models.ProfileChange{
+			FieldChanged:  c.StringMapData.Changed.Value,
+			PreviousValue: c.StringMapData.PreviousValue.Value,
+			NewValue:      c.StringMapData.NewValue.Value,
+			ChangedAt:     time.Unix(c.StringMapData.ChangeDate.Timestamp, 0),
+		})
+	}
+	if err := s.store.Profile.SaveProfileChanges(context.Background(), userID, accountID, changes); err != nil {
+		log.Printf("insert profile_changes: %v", err)
 	}
 	log.Printf("Processed profile_changes (%d items)", len(wrapper.Changes))
 	return nil
 }
 
-func (s *APIServer) processProfilePhotos(path string, userID int) error {
+func (s *APIServer) processProfilePhotos(path string, userID, accountID int) error {
 	f, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("open profile_photos: %w", err)
@@ -318,18 +310,18 @@ func (s *APIServer) processProfilePhotos(path string, userID int) error {
 		return fmt.Errorf("decode profile_photos: %w", err)
 	}
 
-	sql := `INSERT INTO profile_photos (user_id, photo_uri, set_at) VALUES ($1,$2,$3) ON CONFLICT DO NOTHING`
+	var photos []models.ProfilePhoto
 	for _, p := range wrapper.Photos {
-		_, err := s.db.Exec(context.Background(), sql, userID, p.URI, time.Unix(p.CreationTimestamp, 0))
-		if err != nil {
-			log.Printf("insert profile_photo: %v", err)
-		}
+		photos = append(photos, models.ProfilePhoto{PhotoURI: p.URI, SetAt: time.Unix(p.CreationTimestamp, 0)})
+	}
+	if err := s.store.Profile.SaveProfilePhotos(context.Background(), userID, accountID, photos); err != nil {
+		log.Printf("insert profile_photos: %v", err)
 	}
 	log.Printf("Processed profile_photos (%d items)", len(wrapper.Photos))
 	return nil
 }
 
-func (s *APIServer) processArchivedPosts(path string, userID int) error {
+func (s *APIServer) processArchivedPosts(path string, userID, accountID int) error {
 	f, r, err := isoDecodeFile(path)
 	if err != nil {
 		return fmt.Errorf("open archived_posts: %w", err)
@@ -341,24 +333,22 @@ func (s *APIServer) processArchivedPosts(path string, userID int) error {
 		return fmt.Errorf("decode archived_posts: %w", err)
 	}
 
-	sql := `INSERT INTO archived_posts (user_id, uri, caption, taken_at) VALUES ($1,$2,$3,$4) ON CONFLICT DO NOTHING`
-	count := 0
+	var posts []models.ArchivedPost
 	for _, post := range wrapper.Posts {
 		for _, m := range post.Media {
-			_, err := s.db.Exec(context.Background(), sql, userID, m.URI, m.Title, time.Unix(m.CreationTimestamp, 0))
-			if err != nil {
-				log.Printf("insert archived_post: %v", err)
-			}
-			count++
+			posts = append(posts, models.ArchivedPost{URI: m.URI, Caption: m.Title, TakenAt: time.Unix(m.CreationTimestamp, 0)})
 		}
 	}
-	log.Printf("Processed archived_posts (%d items)", count)
+	if err := s.store.Media.SaveArchivedPosts(context.Background(), userID, accountID, posts); err != nil {
+		log.Printf("insert archived_posts: %v", err)
+	}
+	log.Printf("Processed archived_posts (%d items)", len(posts))
 	return nil
 }
 
 // --- Security ---
 
-func (s *APIServer) processLoginActivity(path string, userID int) error {
+func (s *APIServer) processLoginActivity(path string, userID, accountID int) error {
 	f, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("open login_activity: %w", err)
@@ -370,24 +360,23 @@ func (s *APIServer) processLoginActivity(path string, userID int) error {
 		return fmt.Errorf("decode login_activity: %w", err)
 	}
 
-	sql := `INSERT INTO login_history (user_id, ip_address, user_agent, language_code, logged_in_at)
-	        VALUES ($1,$2,$3,$4,$5)`
+	var logins []models.LoginHistory
 	for _, h := range wrapper.History {
-		ts := time.Unix(h.StringMapData.Time.Timestamp, 0)
-		_, err := s.db.Exec(context.Background(), sql, userID,
-			h.StringMapData.IPAddress.Value,
-			h.StringMapData.UserAgent.Value,
-			h.StringMapData.LanguageCode.Value,
-			ts)
-		if err != nil {
-			log.Printf("insert login_history: %v", err)
-		}
+		logins = append(logins, models.LoginHistory{
+			IPAddress:    h.StringMapData.IPAddress.Value,
+			UserAgent:    h.StringMapData.UserAgent.Value,
+			LanguageCode: h.StringMapData.LanguageCode.Value,
+			LoggedInAt:   time.Unix(h.StringMapData.Time.Timestamp, 0),
+		})
+	}
+	if err := s.store.Security.SaveLogins(context.Background(), userID, accountID, logins); err != nil {
+		log.Printf("insert login_history: %v", err)
 	}
 	log.Printf("Processed login_activity (%d items)", len(wrapper.History))
 	return nil
 }
 
-func (s *APIServer) processLogoutActivity(path string, userID int) error {
+func (s *APIServer) processLogoutActivity(path string, userID, accountID int) error {
 	f, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("open logout_activity: %w", err)
@@ -399,23 +388,22 @@ func (s *APIServer) processLogoutActivity(path string, userID int) error {
 		return fmt.Errorf("decode logout_activity: %w", err)
 	}
 
-	sql := `INSERT INTO logout_history (user_id, ip_address, user_agent, logged_out_at)
-	        VALUES ($1,$2,$3,$4)`
+	var logouts []models.LogoutHistory
 	for _, h := range wrapper.History {
-		ts := time.Unix(h.StringMapData.Time.Timestamp, 0)
-		_, err := s.db.Exec(context.Background(), sql, userID,
-			h.StringMapData.IPAddress.Value,
-			h.StringMapData.UserAgent.Value,
-			ts)
-		if err != nil {
-			log.Printf("insert logout_history: %v", err)
-		}
+		logouts = append(logouts, models.LogoutHistory{
+			IPAddress:   h.StringMapData.IPAddress.Value,
+			UserAgent:   h.StringMapData.UserAgent.Value,
+			LoggedOutAt: time.Unix(h.StringMapData.Time.Timestamp, 0),
+		})
+	}
+	if err := s.store.Security.SaveLogouts(context.Background(), userID, accountID, logouts); err != nil {
+		log.Printf("insert logout_history: %v", err)
 	}
 	log.Printf("Processed logout_activity (%d items)", len(wrapper.History))
 	return nil
 }
 
-func (s *APIServer) processPasswordChanges(path string, userID int) error {
+func (s *APIServer) processPasswordChanges(path string, userID, accountID int) error {
 	f, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("open password_changes: %w", err)
@@ -427,18 +415,18 @@ func (s *APIServer) processPasswordChanges(path string, userID int) error {
 		return fmt.Errorf("decode password_changes: %w", err)
 	}
 
-	sql := `INSERT INTO password_change_history (user_id, changed_at) VALUES ($1,$2)`
+	var changes []models.PasswordChange
 	for _, h := range wrapper.History {
-		_, err := s.db.Exec(context.Background(), sql, userID, time.Unix(h.StringMapData.Time.Timestamp, 0))
-		if err != nil {
-			log.Printf("insert password_change: %v", err)
-		}
+		changes = append(changes, models.PasswordChange{ChangedAt: time.Unix(h.StringMapData.Time.Timestamp, 0)})
+	}
+	if err := s.store.Security.SavePasswordChanges(context.Background(), userID, accountID, changes); err != nil {
+		log.Printf("insert password_changes: %v", err)
 	}
 	log.Printf("Processed password_changes (%d items)", len(wrapper.History))
 	return nil
 }
 
-func (s *APIServer) processSignupInfo(path string, userID int) error {
+func (s *APIServer) processSignupInfo(path string, userID, accountID int) error {
 	f, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("open signup_details: %w", err)
@@ -455,21 +443,20 @@ func (s *APIServer) processSignupInfo(path string, userID int) error {
 	}
 	info := wrapper.Info[0]
 	ts := time.Unix(info.StringMapData.Time.Timestamp, 0)
-	sql := `INSERT INTO signup_info (user_id, username_at_signup, email_at_signup, signup_ip, device_model, signed_up_at)
-	        VALUES ($1,$2,$3,$4,$5,$6) ON CONFLICT (user_id) DO NOTHING`
-	_, err = s.db.Exec(context.Background(), sql, userID,
-		info.StringMapData.Username.Value,
-		info.StringMapData.Email.Value,
-		info.StringMapData.IPAddress.Value,
-		info.StringMapData.Device.Value,
-		ts)
+	err = s.store.Security.SaveSignupInfo(context.Background(), userID, accountID, models.SignupInfo{
+		UsernameAtSignup: info.StringMapData.Username.Value,
+		EmailAtSignup:    info.StringMapData.Email.Value,
+		SignupIP:         info.StringMapData.IPAddress.Value,
+		DeviceModel:      info.StringMapData.Device.Value,
+		SignedUpAt:       &ts,
+	})
 	if err != nil {
 		return fmt.Errorf("insert signup_info: %w", err)
 	}
 	return nil
 }
 
-func (s *APIServer) processPrivacyChanges(path string, userID int) error {
+func (s *APIServer) processPrivacyChanges(path string, userID, accountID int) error {
 	f, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("open privacy_changes: %w", err)
@@ -481,7 +468,7 @@ func (s *APIServer) processPrivacyChanges(path string, userID int) error {
 		return fmt.Errorf("decode privacy_changes: %w", err)
 	}
 
-	sql := `INSERT INTO privacy_changes (user_id, privacy_status, changed_at) VALUES ($1,$2,$3)`
+	var changes []models.PrivacyChange
 	for _, h := range wrapper.History {
 		status := strings.ToLower(h.Title)
 		if strings.Contains(status, "private") {
@@ -489,16 +476,16 @@ func (s *APIServer) processPrivacyChanges(path string, userID int) error {
 		} else {
 			status = "public"
 		}
-		_, err := s.db.Exec(context.Background(), sql, userID, status, time.Unix(h.StringMapData.Time.Timestamp, 0))
-		if err != nil {
-			log.Printf("insert privacy_change: %v", err)
-		}
+		changes = append(changes, models.PrivacyChange{PrivacyStatus: status, ChangedAt: time.Unix(h.StringMapData.Time.Timestamp, 0)})
+	}
+	if err := s.store.Security.SavePrivacyChanges(context.Background(), userID, accountID, changes); err != nil {
+		log.Printf("insert privacy_changes: %v", err)
 	}
 	log.Printf("Processed privacy_changes (%d items)", len(wrapper.History))
 	return nil
 }
 
-func (s *APIServer) processAccountStatus(path string, userID int) error {
+func (s *APIServer) processAccountStatus(path string, userID, accountID int) error {
 	f, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("open account_status: %w", err)
@@ -510,22 +497,23 @@ func (s *APIServer) processAccountStatus(path string, userID int) error {
 		return fmt.Errorf("decode account_status: %w", err)
 	}
 
-	sql := `INSERT INTO account_status_history (user_id, activation_type, reason, changed_at) VALUES ($1,$2,$3,$4)`
+	var entries []models.AccountStatusEntry
 	for _, h := range wrapper.History {
-		_, err := s.db.Exec(context.Background(), sql, userID,
-			h.StringMapData.ActivationType.Value,
-			h.StringMapData.Reason.Value,
-			time.Unix(h.StringMapData.Time.Timestamp, 0))
-		if err != nil {
-			log.Printf("insert account_status: %v", err)
-		}
+		entries = append(entries, models.AccountStatusEntry{
+			ActivationType: h.StringMapData.ActivationType.Value,
+			Reason:         h.StringMapData.Reason.Value,
+			ChangedAt:      time.Unix(h.StringMapData.Time.Timestamp, 0),
+		})
+	}
+	if err := s.store.Security.SaveAccountStatus(context.Background(), userID, accountID, entries); err != nil {
+		log.Printf("insert account_status: %v", err)
 	}
 	return nil
 }
 
 // --- Story Interactions ---
 
-func (s *APIServer) processStoryPolls(path string, userID int) error {
+func (s *APIServer) processStoryPolls(path string, userID, accountID int) error {
 	f, r, err := isoDecodeFile(path)
 	if err != nil {
 		return fmt.Errorf("open polls: %w", err)
@@ -537,21 +525,20 @@ func (s *APIServer) processStoryPolls(path string, userID int) error {
 		return fmt.Errorf("decode polls: %w", err)
 	}
 
-	sql := `INSERT INTO story_polls (user_id, creator_username, poll_answer, answered_at)
-	        VALUES ($1,$2,$3,$4) ON CONFLICT DO NOTHING`
+	var polls []models.StoryPoll
 	for _, p := range wrapper.Polls {
 		for _, d := range p.StringListData {
-			_, err := s.db.Exec(context.Background(), sql, userID, p.Title, d.Value, time.Unix(d.Timestamp, 0))
-			if err != nil {
-				log.Printf("insert story_poll: %v", err)
-			}
+			polls = append(polls, models.StoryPoll{CreatorUsername: p.Title, PollAnswer: d.Value, AnsweredAt: time.Unix(d.Timestamp, 0)})
 		}
 	}
+	if err := s.store.Interactions.SaveStoryPolls(context.Background(), userID, accountID, polls); err != nil {
+		log.Printf("insert story_polls: %v", err)
+	}
 	log.Printf("Processed polls (%d items)", len(wrapper.Polls))
 	return nil
 }
 
-func (s *APIServer) processStoryQuizzes(path string, userID int) error {
+func (s *APIServer) processStoryQuizzes(path string, userID, accountID int) error {
 	f, r, err := isoDecodeFile(path)
 	if err != nil {
 		return fmt.Errorf("open quizzes: %w", err)
@@ -563,20 +550,19 @@ func (s *APIServer) processStoryQuizzes(path string, userID int) error {
 		return fmt.Errorf("decode quizzes: %w", err)
 	}
 
-	sql := `INSERT INTO story_quizzes (user_id, creator_username, quiz_answer, answered_at)
-	        VALUES ($1,$2,$3,$4) ON CONFLICT DO NOTHING`
+	var quizzes []models.StoryQuiz
 	for _, q := range wrapper.Quizzes {
 		for _, d := range q.StringListData {
-			_, err := s.db.Exec(context.Background(), sql, userID, q.Title, d.Value, time.Unix(d.Timestamp, 0))
-			if err != nil {
-				log.Printf("insert story_quiz: %v", err)
-			}
+			quizzes = append(quizzes, models.StoryQuiz{CreatorUsername: q.Title, QuizAnswer: d.Value, AnsweredAt: time.Unix(d.Timestamp, 0)})
 		}
 	}
+	if err := s.store.Interactions.SaveStoryQuizzes(context.Background(), userID, accountID, quizzes); err != nil {
+		log.Printf("insert story_quizzes: %v", err)
+	}
 	return nil
 }
 
-func (s *APIServer) processStoryQuestions(path string, userID int) error {
+func (s *APIServer) processStoryQuestions(path string, userID, accountID int) error {
 	f, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("open questions: %w", err)
@@ -588,20 +574,19 @@ func (s *APIServer) processStoryQuestions(path string, userID int) error {
 		return fmt.Errorf("decode questions: %w", err)
 	}
 
-	sql := `INSERT INTO story_questions (user_id, creator_username, responded_at)
-	        VALUES ($1,$2,$3) ON CONFLICT DO NOTHING`
+	var questions []models.StoryQuestion
 	for _, q := range wrapper.Questions {
 		for _, d := range q.StringListData {
-			_, err := s.db.Exec(context.Background(), sql, userID, q.Title, time.Unix(d.Timestamp, 0))
-			if err != nil {
-				log.Printf("insert story_question: %v", err)
-			}
+			questions = append(questions, models.StoryQuestion{CreatorUsername: q.Title, RespondedAt: time.Unix(d.Timestamp, 0)})
 		}
 	}
+	if err := s.store.Interactions.SaveStoryQuestions(context.Background(), userID, accountID, questions); err != nil {
+		log.Printf("insert story_questions: %v", err)
+	}
 	return nil
 }
 
-func (s *APIServer) processEmojiSliders(path string, userID int) error {
+func (s *APIServer) processEmojiSliders(path string, userID, accountID int) error {
 	f, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("open emoji_sliders: %w", err)
@@ -613,21 +598,20 @@ func (s *APIServer) processEmojiSliders(path string, userID int) error {
 		return fmt.Errorf("decode emoji_sliders: %w", err)
 	}
 
-	sql := `INSERT INTO story_emoji_sliders (user_id, creator_username, slider_value, responded_at)
-	        VALUES ($1,$2,$3,$4) ON CONFLICT DO NOTHING`
+	var sliders []models.StoryEmojiSlider
 	for _, s2 := range wrapper.Sliders {
 		for _, d := range s2.StringListData {
 			val, _ := strconv.ParseFloat(d.Value, 64)
-			_, err := s.db.Exec(context.Background(), sql, userID, s2.Title, val, time.Unix(d.Timestamp, 0))
-			if err != nil {
-				log.Printf("insert emoji_slider: %v", err)
-			}
+			sliders = append(sliders, models.StoryEmojiSlider{CreatorUsername: s2.Title, SliderValue: val, RespondedAt: time.Unix(d.Timestamp, 0)})
 		}
 	}
+	if err := s.store.Interactions.SaveStoryEmojiSliders(context.Background(), userID, accountID, sliders); err != nil {
+		log.Printf("insert emoji_sliders: %v", err)
+	}
 	return nil
 }
 
-func (s *APIServer) processStoryReactions(path string, userID int) error {
+func (s *APIServer) processStoryReactions(path string, userID, accountID int) error {
 	f, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("open story_reactions: %w", err)
@@ -639,22 +623,21 @@ func (s *APIServer) processStoryReactions(path string, userID int) error {
 		return fmt.Errorf("decode story_reactions: %w", err)
 	}
 
-	sql := `INSERT INTO story_reactions (user_id, creator_username, responded_at)
-	        VALUES ($1,$2,$3) ON CONFLICT DO NOTHING`
+	var reactions []models.StoryReaction
 	for _, r := range wrapper.Reactions {
 		for _, d := range r.StringListData {
-			_, err := s.db.Exec(context.Background(), sql, userID, r.Title, time.Unix(d.Timestamp, 0))
-			if err != nil {
-				log.Printf("insert story_reaction: %v", err)
-			}
+			reactions = append(reactions, models.StoryReaction{CreatorUsername: r.Title, RespondedAt: time.Unix(d.Timestamp, 0)})
 		}
 	}
+	if err := s.store.Interactions.SaveStoryReactions(context.Background(), userID, accountID, reactions); err != nil {
+		log.Printf("insert story_reactions: %v", err)
+	}
 	return nil
 }
 
 // --- Search History ---
 
-func (s *APIServer) processProfileSearches(path string, userID int) error {
+func (s *APIServer) processProfileSearches(path string, userID, accountID int) error {
 	f, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("open profile_searches: %w", err)
@@ -666,21 +649,22 @@ func (s *APIServer) processProfileSearches(path string, userID int) error {
 		return fmt.Errorf("decode profile_searches: %w", err)
 	}
 
-	sql := `INSERT INTO search_history (user_id, search_query, search_type, searched_at)
-	        VALUES ($1,$2,$3,$4) ON CONFLICT DO NOTHING`
+	var searches []models.SearchHistoryEntry
 	for _, item := range wrapper.Searches {
-		_, err := s.db.Exec(context.Background(), sql, userID,
-			item.StringMapData.Search.Value, "user",
-			time.Unix(item.StringMapData.Time.Timestamp, 0))
-		if err != nil {
-			log.Printf("insert profile_search: %v", err)
-		}
+		searches = append(searches, models.SearchHistoryEntry{
+			SearchQuery: item.StringMapData.Search.Value,
+			SearchType:  "user",
+			SearchedAt:  time.Unix(item.StringMapData.Time.Timestamp, 0),
+		})
+	}
+	if err := s.store.Activity.SaveSearchHistory(context.Background(), userID, accountID, searches); err != nil {
+		log.Printf("insert profile_searches: %v", err)
 	}
 	log.Printf("Processed profile_searches (%d items)", len(wrapper.Searches))
 	return nil
 }
 
-func (s *APIServer) processKeywordSearches(path string, userID int) error {
+func (s *APIServer) processKeywordSearches(path string, userID, accountID int) error {
 	f, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("open keyword_searches: %w", err)
@@ -692,15 +676,16 @@ func (s *APIServer) processKeywordSearches(path string, userID int) error {
 		return fmt.Errorf("decode keyword_searches: %w", err)
 	}
 
-	sql := `INSERT INTO search_history (user_id, search_query, search_type, searched_at)
-	        VALUES ($1,$2,$3,$4) ON CONFLICT DO NOTHING`
+	var searches []models.SearchHistoryEntry
 	for _, item := range wrapper.Searches {
-		_, err := s.db.Exec(context.Background(), sql, userID,
-			item.StringMapData.Search.Value, "keyword",
-			time.Unix(item.StringMapData.Time.Timestamp, 0))
-		if err != nil {
-			log.Printf("insert keyword_search: %v", err)
-		}
+		searches = append(searches, models.SearchHistoryEntry{
+			SearchQuery: item.StringMapData.Search.Value,
+			SearchType:  "keyword",
+			SearchedAt:  time.Unix(item.StringMapData.Time.Timestamp, 0),
+		})
+	}
+	if err := s.store.Activity.SaveSearchHistory(context.Background(), userID, accountID, searches); err != nil {
+		log.Printf("insert keyword_searches: %v", err)
 	}
 	log.Printf("Processed keyword_searches (%d items)", len(wrapper.Searches))
 	return nil
@@ -708,7 +693,7 @@ func (s *APIServer) processKeywordSearches(path string, userID int) error {
 
 // --- Messages ---
 
-func (s *APIServer) processMessageFile(path string, userID int) error {
+func (s *APIServer) processMessageFile(path string, userID, accountID int) error {
 	f, r, err := isoDecodeFile(path)
 	if err != nil {
 		return fmt.Errorf("open message file: %w", err)
@@ -730,22 +715,13 @@ func (s *APIServer) processMessageFile(path string, userID int) error {
 	// conversation_id = parent directory name
 	conversationID := filepath.Base(filepath.Dir(path))
 
-	convSQL := `INSERT INTO message_conversations (user_id, conversation_id, participants, thread_type)
-	            VALUES ($1,$2,$3,$4) ON CONFLICT (user_id, conversation_id) DO NOTHING`
-	_, err = s.db.Exec(context.Background(), convSQL, userID, conversationID, participants, mf.ThreadType)
-	if err != nil {
-		log.Printf("insert conversation %s: %v", conversationID, err)
-	}
-
-	msgSQL := `INSERT INTO messages (user_id, conversation_id, sender_name, content, sent_at)
-	           VALUES ($1,$2,$3,$4,$5) ON CONFLICT DO NOTHING`
+	conversation := models.MessageConversation{ConversationID: conversationID, Participants: participants, ThreadType: mf.ThreadType}
+	var messages []models.Message
 	for _, msg := range mf.Messages {
-		sentAt := time.UnixMilli(msg.TimestampMs)
-		_, err := s.db.Exec(context.Background(), msgSQL, userID,
-			conversationID, msg.SenderName, msg.Content, sentAt)
-		if err != nil {
-			log.Printf("insert message: %v", err)
-		}
+		messages = append(messages, models.Message{SenderName: msg.SenderName, Content: msg.Content, SentAt: time.UnixMilli(msg.TimestampMs)})
+	}
+	if err := s.store.Messages.SaveConversation(context.Background(), userID, accountID, conversation, messages); err != nil {
+		log.Printf("insert conversation %s: %v", conversationID, err)
 	}
 	log.Printf("Processed message file %s (%d messages)", conversationID, len(mf.Messages))
 	return nil
@@ -753,7 +729,7 @@ func (s *APIServer) processMessageFile(path string, userID int) error {
 
 // --- AI / Topics / Location ---
 
-func (s *APIServer) processAIInterests(path string, userID int) error {
+func (s *APIServer) processAIInterests(path string, userID, accountID int) error {
 	f, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("open interest_categories: %w", err)
@@ -766,24 +742,23 @@ func (s *APIServer) processAIInterests(path string, userID int) error {
 		return fmt.Errorf("decode interest_categories: %w", err)
 	}
 
-	sql := `INSERT INTO ai_interests (user_id, interest_description, detected_at)
-	        VALUES ($1,$2,$3) ON CONFLICT DO NOTHING`
+	var interests []models.AIInterest
 	for _, entry := range entries {
 		for _, lv := range entry.LabelValues {
 			if lv.Label == "Interest" && lv.Value != "" {
 				detectedAt := time.Unix(entry.Timestamp, 0)
-				_, err := s.db.Exec(context.Background(), sql, userID, lv.Value, detectedAt)
-				if err != nil {
-					log.Printf("insert ai_interest: %v", err)
-				}
+				interests = append(interests, models.AIInterest{InterestDescription: lv.Value, DetectedAt: &detectedAt})
 			}
 		}
 	}
+	if err := s.store.Interests.SaveAIInterests(context.Background(), userID, accountID, interests); err != nil {
+		log.Printf("insert ai_interests: %v", err)
+	}
 	log.Printf("Processed interest_categories (%d entries)", len(entries))
 	return nil
 }
 
-func (s *APIServer) processUserTopics(path string, userID int) error {
+func (s *APIServer) processUserTopics(path string, userID, accountID int) error {
 	f, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("open recommended_topics: %w", err)
@@ -795,18 +770,18 @@ func (s *APIServer) processUserTopics(path string, userID int) error {
 		return fmt.Errorf("decode recommended_topics: %w", err)
 	}
 
-	sql := `INSERT INTO user_topics (user_id, topic_name) VALUES ($1,$2) ON CONFLICT DO NOTHING`
+	var names []string
 	for _, t := range wrapper.Topics {
-		_, err := s.db.Exec(context.Background(), sql, userID, t.StringMapData.Name.Value)
-		if err != nil {
-			log.Printf("insert user_topic: %v", err)
-		}
+		names = append(names, t.StringMapData.Name.Value)
+	}
+	if err := s.store.Interests.SaveTopics(context.Background(), userID, accountID, names); err != nil {
+		log.Printf("insert user_topics: %v", err)
 	}
 	log.Printf("Processed recommended_topics (%d items)", len(wrapper.Topics))
 	return nil
 }
 
-func (s *APIServer) processInferredLocation(path string, userID int) error {
+func (s *APIServer) processInferredLocation(path string, userID, accountID int) error {
 	f, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("open profile_based_in: %w", err)
@@ -821,13 +796,10 @@ func (s *APIServer) processInferredLocation(path string, userID int) error {
 	if len(wrapper.Location) == 0 {
 		return nil
 	}
-	sql := `INSERT INTO inferred_location (user_id, city_name) VALUES ($1,$2)
-	        ON CONFLICT (user_id) DO UPDATE SET city_name=EXCLUDED.city_name`
-	_, err = s.db.Exec(context.Background(), sql, userID, wrapper.Location[0].StringMapData.CityName.Value)
-	return err
+	return s.store.Interests.SaveInferredLocation(context.Background(), userID, accountID, wrapper.Location[0].StringMapData.CityName.Value)
 }
 
-func (s *APIServer) processLocationsOfInterest(path string, userID int) error {
+func (s *APIServer) processLocationsOfInterest(path string, userID, accountID int) error {
 	f, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("open locations_of_interest: %w", err)
@@ -839,23 +811,23 @@ func (s *APIServer) processLocationsOfInterest(path string, userID int) error {
 		return fmt.Errorf("decode locations_of_interest: %w", err)
 	}
 
-	sql := `INSERT INTO locations_of_interest (user_id, location_name) VALUES ($1,$2) ON CONFLICT DO NOTHING`
+	var names []string
 	for _, lv := range wrapper.LabelValues {
 		if lv.Label == "Locations of interest" {
 			for _, v := range lv.Vec {
-				_, err := s.db.Exec(context.Background(), sql, userID, v.Value)
-				if err != nil {
-					log.Printf("insert location_of_interest: %v", err)
-				}
+				names = append(names, v.Value)
 			}
 		}
 	}
+	if err := s.store.Interests.SaveLocationsOfInterest(context.Background(), userID, accountID, names); err != nil {
+		log.Printf("insert locations_of_interest: %v", err)
+	}
 	return nil
 }
 
 // --- Off-Meta Activity ---
 
-func (s *APIServer) processOffMetaActivity(path string, userID int) error {
+func (s *APIServer) processOffMetaActivity(path string, userID, accountID int) error {
 	f, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("open off_meta_activity: %w", err)
@@ -867,19 +839,15 @@ func (s *APIServer) processOffMetaActivity(path string, userID int) error {
 		return fmt.Errorf("decode off_meta_activity: %w", err)
 	}
 
-	sql := `INSERT INTO off_meta_activity (user_id, app_name, event_type, event_id, event_at)
-	        VALUES ($1,$2,$3,$4,$5)`
-	count := 0
+	var events []models.OffMetaActivity
 	for _, app := range wrapper.Activity {
 		for _, ev := range app.Events {
-			_, err := s.db.Exec(context.Background(), sql, userID,
-				app.Name, ev.Type, ev.ID, time.Unix(ev.Timestamp, 0))
-			if err != nil {
-				log.Printf("insert off_meta_activity %s: %v", app.Name, err)
-			}
-			count++
+			events = append(events, models.OffMetaActivity{AppName: app.Name, EventType: ev.Type, EventID: ev.ID, EventAt: time.Unix(ev.Timestamp, 0)})
 		}
 	}
-	log.Printf("Processed off_meta_activity (%d events)", count)
+	if err := s.store.Activity.SaveOffMetaActivity(context.Background(), userID, accountID, events); err != nil {
+		log.Printf("insert off_meta_activity: %v", err)
+	}
+	log.Printf("Processed off_meta_activity (%d events)", len(events))
 	return nil
 }
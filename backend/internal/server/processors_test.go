@@ -2,6 +2,7 @@ package server
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -499,3 +500,23 @@ func TestProcessorMapHasAllExpectedKeys(t *testing.T) {
 	}
 	t.Logf("processorMap has %d entries", len(processorMap))
 }
+
+func TestProcessorsSaveThroughTheStore(t *testing.T) {
+	s, mem, accountID := newTestServer(t)
+	path := filepath.Join(t.TempDir(), "followers_1.json")
+	for _, ts := range []int64{100, 200} {
+		body := fmt.Sprintf(`[{"string_list_data":[{"value":"jane","timestamp":%d}]}]`, ts)
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.processFollowers(path, testUserID, accountID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// importing the list again doesn't duplicate it, and a follower keeps the date they followed
+	connections := mem.Archive(accountID).Connections
+	if len(connections) != 1 || connections[0].Username != "jane" || connections[0].Timestamp.Unix() != 100 {
+		t.Errorf("connections = %+v", connections)
+	}
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Routes are registered with method-qualified patterns ("GET /api/v1/media"), so the
+// mux itself answers requests for unknown paths (404) and known paths with the wrong
+// method (405 plus an Allow header). routeErrorsAsJSON keeps its status codes and
+// headers but replaces the plain-text bodies with the usual {"error": ...} envelope.
+
+func routeErrorsAsJSON(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pattern := mux.Handler(r); pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+		mux.ServeHTTP(&routeErrorWriter{ResponseWriter: w}, r)
+	})
+}
+
+// routeErrorWriter swallows the mux's own error body after writing a JSON one.
+type routeErrorWriter struct {
+	http.ResponseWriter
+	wroteError bool
+}
+
+func (rw *routeErrorWriter) WriteHeader(status int) {
+	var message string
+	switch status {
+	case http.StatusNotFound:
+		message = "Not found"
+	case http.StatusMethodNotAllowed:
+		message = "Method not allowed"
+	default:
+		rw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	rw.Header().Del("X-Content-Type-Options")
+	writeJSONError(rw.ResponseWriter, status, message)
+	rw.wroteError = true
+}
+
+func (rw *routeErrorWriter) Write(b []byte) (int, error) {
+	if rw.wroteError {
+		return len(b), nil
+	}
+	return rw.ResponseWriter.Write(b)
+}
+
+// pathInt reads an integer path parameter, writing a 400 itself when it is malformed.
+func pathInt(w http.ResponseWriter, r *http.Request, name, label string) (int, bool) {
+	n, err := strconv.Atoi(r.PathValue(name))
+	if err != nil || n <= 0 {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("Invalid %s", label))
+		return 0, false
+	}
+	return n, true
+}
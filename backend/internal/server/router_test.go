@@ -0,0 +1,61 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func routerTestHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/media", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("DELETE /api/v1/tokens/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := pathInt(w, r, "id", "token id"); ok {
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+	return routeErrorsAsJSON(mux)
+}
+
+func TestRouteErrorsAsJSON(t *testing.T) {
+	for _, tc := range []struct {
+		method, path string
+		status       int
+		message      string
+		allow        string
+	}{
+		{http.MethodGet, "/api/v1/nope", http.StatusNotFound, "Not found", ""},
+		{http.MethodPost, "/api/v1/media", http.StatusMethodNotAllowed, "Method not allowed", "GET, HEAD"},
+		{http.MethodGet, "/api/v1/tokens/3", http.StatusMethodNotAllowed, "Method not allowed", "DELETE"},
+		{http.MethodDelete, "/api/v1/tokens/abc", http.StatusBadRequest, "Invalid token id", ""},
+	} {
+		rec := httptest.NewRecorder()
+		routerTestHandler().ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+
+		if rec.Code != tc.status {
+			t.Errorf("%s %s: status %d, want %d", tc.method, tc.path, rec.Code, tc.status)
+		}
+		if got := rec.Header().Get("Allow"); got != tc.allow {
+			t.Errorf("%s %s: Allow = %q, want %q", tc.method, tc.path, got, tc.allow)
+		}
+		if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+			t.Errorf("%s %s: Content-Type = %q", tc.method, tc.path, ct)
+		}
+		var body map[string]string
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body["error"] != tc.message {
+			t.Errorf("%s %s: body %q, want error %q", tc.method, tc.path, rec.Body.String(), tc.message)
+		}
+	}
+}
+
+func TestRouteErrorsAsJSONPassesMatchedRoutes(t *testing.T) {
+	rec := httptest.NewRecorder()
+	routerTestHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/v1/tokens/3", nil))
+	if rec.Code != http.StatusNoContent || rec.Body.Len() != 0 {
+		t.Errorf("status %d, body %q", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Sa-Te/IAV/backend/internal/models"
+	"github.com/Sa-Te/IAV/backend/internal/store"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	maxSearchQueryLen  = 200
+)
+
+var searchListSpec = listSpec{
+	typed:       true,
+	types:       store.SearchTypes,
+	dated:       true,
+	extraParams: []string{"q"},
+}
+
+// searchHandler handles GET /api/v1/search?q=. `q` uses web search syntax ("quoted
+// phrases", OR, -excluded); `type`, `from`/`to` and `limit` narrow the results.
+func (s *APIServer) searchHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(int)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+	accountID, ok := s.accountFromRequest(w, r, userID)
+	if !ok {
+		return
+	}
+
+	q, ok := listQueryFromRequest(w, r, searchListSpec)
+	if !ok {
+		return
+	}
+	// hits are ranked, not paged: there is no stable order to resume from
+	if r.URL.Query().Has("cursor") {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("search is not paginated; raise limit (at most %d) instead", maxSearchLimit))
+		return
+	}
+	text := strings.TrimSpace(r.URL.Query().Get("q"))
+	if text == "" {
+		writeJSONError(w, http.StatusBadRequest, "q is required")
+		return
+	}
+	if len(text) > maxSearchQueryLen {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("q must be at most %d characters", maxSearchQueryLen))
+		return
+	}
+	limit := defaultSearchLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxSearchLimit {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxSearchLimit))
+			return
+		}
+		limit = n
+	}
+
+	hits, err := s.store.Search.Search(r.Context(), accountID, text, store.ListOptions{Types: q.Types, From: q.From, To: q.To, Limit: limit})
+	if err != nil {
+		log.Printf("Search failed for user %d: %v", userID, err)
+		writeJSONError(w, http.StatusInternalServerError, "Search failed")
+		return
+	}
+
+	type Response struct {
+		Query string             `json:"query"`
+		Hits  []models.SearchHit `json:"hits"`
+	}
+	resp := Response{Query: text, Hits: make([]models.SearchHit, 0, len(hits))}
+	for _, hit := range hits {
+		hit.Snippet = highlightSnippet(hit.Snippet)
+		hit.Link = searchHitLink(hit.Type, hit.ID, hit.Ref)
+		resp.Hits = append(resp.Hits, hit)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// highlightSnippet escapes a snippet and only then turns its match markers into <mark>
+// tags, so archive text can never inject markup.
+func highlightSnippet(snippet string) string {
+	escaped := html.EscapeString(snippet)
+	return strings.NewReplacer(store.HighlightStart, "<mark>", store.HighlightStop, "</mark>").Replace(escaped)
+}
+
+// searchHitLink points a hit at the record it came from: an API route for data
+// that lives in the archive, the original Instagram URL for saved posts.
+func searchHitLink(hitType string, id int, ref string) string {
+	switch hitType {
+	case store.SearchCaption:
+		return "/api/v1/mediafile/" + ref
+	case store.SearchMessage:
+		return "/api/v1/messages/" + url.PathEscape(ref)
+	case store.SearchPostComment:
+		return "/api/v1/comments/post/" + strconv.Itoa(id)
+	case store.SearchReelComment:
+		return "/api/v1/comments/reel/" + strconv.Itoa(id)
+	case store.SearchHistory:
+		return "/api/v1/search-history/" + strconv.Itoa(id)
+	case store.SearchSavedMedia, store.SearchCollectionItem:
+		return ref
+	}
+	return ""
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Sa-Te/IAV/backend/internal/models"
+	"github.com/Sa-Te/IAV/backend/internal/store"
+)
+
+func TestHighlightSnippetEscapesArchiveText(t *testing.T) {
+	got := highlightSnippet("<b>hi</b> " + store.HighlightStart + "beach" + store.HighlightStop + " & sun")
+	want := "&lt;b&gt;hi&lt;/b&gt; <mark>beach</mark> &amp; sun"
+	if got != want {
+		t.Errorf("highlightSnippet = %q, want %q", got, want)
+	}
+}
+
+func TestSearchHitLink(t *testing.T) {
+	cases := []struct {
+		hitType string
+		id      int
+		ref     string
+		want    string
+	}{
+		{store.SearchCaption, 1, "media/posts/1.jpg", "/api/v1/mediafile/media/posts/1.jpg"},
+		{store.SearchMessage, 2, "jane doe_123", "/api/v1/messages/jane%20doe_123"},
+		{store.SearchPostComment, 3, "", "/api/v1/comments/post/3"},
+		{store.SearchReelComment, 4, "", "/api/v1/comments/reel/4"},
+		{store.SearchHistory, 5, "", "/api/v1/search-history/5"},
+		{store.SearchSavedMedia, 6, "https://www.instagram.com/p/x/", "https://www.instagram.com/p/x/"},
+		{"unknown", 7, "x", ""},
+	}
+	for _, c := range cases {
+		if got := searchHitLink(c.hitType, c.id, c.ref); got != c.want {
+			t.Errorf("searchHitLink(%q, %d, %q) = %q, want %q", c.hitType, c.id, c.ref, got, c.want)
+		}
+	}
+}
+
+func TestSearchHandler(t *testing.T) {
+	s, mem, accountID := newTestServer(t)
+	archive := mem.Archive(accountID)
+	archive.Media = []models.MediaItem{{ID: 1, URI: "media/posts/1.jpg", Caption: "Beach day", TakenAt: day(1), MediaType: "post"}}
+	archive.Messages = []models.Message{{ID: 2, ConversationID: "jane_1", SenderName: "Jane", Content: "<b>beach</b> party?", SentAt: day(2)}}
+
+	rec := serveAs(testUserID, s.searchHandler, httptest.NewRequest(http.MethodGet, "/api/v1/search?q=beach&type=message", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	resp := decodeBody[struct {
+		Hits []models.SearchHit `json:"hits"`
+	}](t, rec)
+	if len(resp.Hits) != 1 {
+		t.Fatalf("hits = %+v, want the message only", resp.Hits)
+	}
+	hit := resp.Hits[0]
+	if hit.Link != "/api/v1/messages/jane_1" || !strings.Contains(hit.Snippet, "&lt;b&gt;<mark>beach</mark>") {
+		t.Errorf("hit = %+v", hit)
+	}
+
+	if rec := serveAs(testUserID, s.searchHandler, httptest.NewRequest(http.MethodGet, "/api/v1/search?type=message", nil)); rec.Code != http.StatusBadRequest {
+		t.Errorf("missing q: status %d, want 400", rec.Code)
+	}
+	if rec := serveAs(testUserID, s.searchHandler, httptest.NewRequest(http.MethodGet, "/api/v1/search?q=beach&cursor=x", nil)); rec.Code != http.StatusBadRequest {
+		t.Errorf("cursor: status %d, want 400", rec.Code)
+	}
+}
+
+// A comment hit links to that one comment.
+func TestSearchHitLinksToTheComment(t *testing.T) {
+	s, mem, accountID := newTestServer(t)
+	archive := mem.Archive(accountID)
+	archive.PostComments = []models.PostComment{
+		{ID: 1, PostOwnerUsername: "jane", CommentText: "great view", CommentedAt: day(1)},
+		{ID: 2, PostOwnerUsername: "joe", CommentText: "beach again", CommentedAt: day(2)},
+	}
+
+	rec := serveAs(testUserID, s.searchHandler, httptest.NewRequest(http.MethodGet, "/api/v1/search?q=beach", nil))
+	resp := decodeBody[struct {
+		Hits []models.SearchHit `json:"hits"`
+	}](t, rec)
+	if len(resp.Hits) != 1 || resp.Hits[0].Link != "/api/v1/comments/post/2" {
+		t.Fatalf("hits = %+v", resp.Hits)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, resp.Hits[0].Link, nil)
+	req.SetPathValue("id", "2")
+	rec = serveAs(testUserID, s.getPostCommentHandler, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	if c := decodeBody[models.PostComment](t, rec); c.ID != 2 || c.CommentText != "beach again" {
+		t.Errorf("comment = %+v", c)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/comments/reel/2", nil)
+	req.SetPathValue("id", "2")
+	if rec := serveAs(testUserID, s.getReelCommentHandler, req); rec.Code != http.StatusNotFound {
+		t.Errorf("reel comment 2: status %d, want 404", rec.Code)
+	}
+}
@@ -3,56 +3,133 @@ package server
 import (
 	"log"
 	"net/http"
+	"net/netip"
 
+	"github.com/Sa-Te/IAV/backend/internal/store"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/rs/cors"
 )
 
 type contextKey string
 
-const userIDKey contextKey = "userID"
+const (
+	userIDKey    contextKey = "userID"
+	authScopeKey contextKey = "authScope"
+	shareLinkKey contextKey = "shareLink"
+	userRoleKey  contextKey = "userRole"
+)
+
+// jwtSecretKey signs every token the server issues (sessions, 2FA challenges and share links).
+var jwtSecretKey = []byte("complete-random-string-that-is-ver-long")
 
 // API SERVER
 type APIServer struct {
-	db *pgxpool.Pool
+	store *store.Store   // every read and write goes through the store; handlers never see SQL
+	cache *responseCache // nil unless EnableResponseCache is called
+	geo   ipLocator      // nil unless EnableGeoIP is called
+
+	trustedProxies []netip.Prefix // empty unless TrustProxies is called
 }
 
 func NewAPIServer(db *pgxpool.Pool) *APIServer {
 	return &APIServer{
-		db: db,
+		store: store.NewPostgres(db),
 	}
 }
 
 func (s *APIServer) Run() {
 	mux := http.NewServeMux()
 
-	mux.HandleFunc("/api/v1/register", s.registerHandler)
-	mux.HandleFunc("/api/v1/login", s.loginHandler)
-	mux.Handle("/api/v1/upload", authMiddleware(http.HandlerFunc(s.uploadHandler)))
-	mux.Handle("/api/v1/media", authMiddleware(http.HandlerFunc(s.getMediaItemsHandler)))
-	mux.Handle("/api/v1/mediafile/", authMiddleware(http.HandlerFunc(s.serveMediaFileHandler)))
-	mux.Handle("/api/v1/connections", authMiddleware(http.HandlerFunc(s.getConnectionsHandler)))
-	mux.Handle("/api/v1/hashtags", authMiddleware(http.HandlerFunc(s.getHashtagsHandler)))
-	mux.Handle("/api/v1/ad-interests", authMiddleware(http.HandlerFunc(s.getAdInterestsHandler)))
-	mux.Handle("/api/v1/activity", authMiddleware(http.HandlerFunc(s.getActivityLogHandler)))
-	mux.Handle("/api/v1/likes", authMiddleware(http.HandlerFunc(s.getLikesHandler)))
-	mux.Handle("/api/v1/comments", authMiddleware(http.HandlerFunc(s.getCommentsHandler)))
-	mux.Handle("/api/v1/saved", authMiddleware(http.HandlerFunc(s.getSavedHandler)))
-	mux.Handle("/api/v1/profile", authMiddleware(http.HandlerFunc(s.getProfileHandler)))
-	mux.Handle("/api/v1/security", authMiddleware(http.HandlerFunc(s.getSecurityHandler)))
-	mux.Handle("/api/v1/search-history", authMiddleware(http.HandlerFunc(s.getSearchHistoryHandler)))
-	mux.Handle("/api/v1/story-interactions", authMiddleware(http.HandlerFunc(s.getStoryInteractionsHandler)))
-	mux.Handle("/api/v1/messages", authMiddleware(http.HandlerFunc(s.getMessagesHandler)))
-	mux.Handle("/api/v1/topics", authMiddleware(http.HandlerFunc(s.getTopicsHandler)))
-	mux.Handle("/api/v1/off-meta-activity", authMiddleware(http.HandlerFunc(s.getOffMetaActivityHandler)))
-	mux.Handle("/api/v1/archived-posts", authMiddleware(http.HandlerFunc(s.getArchivedPostsHandler)))
+	// archive reads are served with import-versioned ETags (see cache.go)
+	archive := func(h http.HandlerFunc) http.Handler {
+		return s.authMiddleware(s.cacheMiddleware(h))
+	}
+	// account management is off limits to API tokens
+	session := func(h http.HandlerFunc) http.Handler {
+		return s.authMiddleware(requireScope(scopeSession, h))
+	}
+	admin := func(h http.HandlerFunc) http.Handler {
+		return s.authMiddleware(requireScope(scopeSession, requireRole(roleAdmin, h)))
+	}
+	shared := func(h http.HandlerFunc) http.Handler {
+		return s.shareMiddleware(h)
+	}
+
+	mux.HandleFunc("POST /api/v1/register", s.registerHandler)
+	mux.HandleFunc("POST /api/v1/login", s.loginHandler)
+	mux.HandleFunc("POST /api/v1/login/2fa", s.loginTwoFactorHandler)
+	mux.Handle("POST /api/v1/2fa/enroll", session(s.enrollTwoFactorHandler))
+	mux.Handle("POST /api/v1/2fa/confirm", session(s.confirmTwoFactorHandler))
+	mux.Handle("POST /api/v1/2fa/disable", session(s.disableTwoFactorHandler))
+
+	mux.Handle("GET /api/v1/tokens", session(s.listAPITokens))
+	mux.Handle("POST /api/v1/tokens", session(s.createAPIToken))
+	mux.Handle("DELETE /api/v1/tokens/{id}", session(s.revokeAPITokenHandler))
+
+	mux.Handle("GET /api/v1/shares", session(s.listShareLinks))
+	mux.Handle("POST /api/v1/shares", session(s.createShareLink))
+	mux.Handle("DELETE /api/v1/shares/{id}", session(s.revokeShareLink))
+	mux.Handle("GET /api/v1/shares/{id}/access-log", session(s.getShareLinkAccessLog))
+	mux.Handle("GET /api/v1/shared", shared(s.getSharedInfoHandler))
+	mux.Handle("GET /api/v1/shared/media", shared(s.getSharedMediaHandler))
+	mux.Handle("GET /api/v1/shared/mediafile/{path...}", shared(s.serveSharedMediaFileHandler))
+	mux.Handle("GET /api/v1/shared/saved", shared(s.getSharedSavedHandler))
+
+	mux.Handle("GET /api/v1/admin/users", admin(s.adminListUsersHandler))
+	mux.Handle("POST /api/v1/admin/users/{id}/disable", admin(s.adminDisableUserHandler))
+	mux.Handle("POST /api/v1/admin/users/{id}/enable", admin(s.adminEnableUserHandler))
+	mux.Handle("DELETE /api/v1/admin/users/{id}", admin(s.adminDeleteUserHandler))
+
+	mux.Handle("GET /api/v1/accounts", s.authMiddleware(http.HandlerFunc(s.listAccounts)))
+	mux.Handle("POST /api/v1/accounts", session(s.createAccount))
+	mux.Handle("POST /api/v1/upload", s.authMiddleware(requireScope(scopeImport, http.HandlerFunc(s.uploadHandler))))
+
+	mux.Handle("GET /api/v1/media", archive(s.getMediaItemsHandler))
+	mux.Handle("GET /api/v1/mediafile/{path...}", s.authMiddleware(http.HandlerFunc(s.serveMediaFileHandler)))
+	mux.Handle("GET /api/v1/connections", archive(s.getConnectionsHandler))
+	mux.Handle("GET /api/v1/hashtags", archive(s.getHashtagsHandler))
+	mux.Handle("GET /api/v1/ad-interests", archive(s.getAdInterestsHandler))
+	mux.Handle("GET /api/v1/activity", archive(s.getActivityLogHandler))
+	mux.Handle("GET /api/v1/likes", archive(s.getLikesHandler))
+	mux.Handle("GET /api/v1/comments", archive(s.getCommentsHandler))
+	mux.Handle("GET /api/v1/comments/post/{id}", archive(s.getPostCommentHandler))
+	mux.Handle("GET /api/v1/comments/reel/{id}", archive(s.getReelCommentHandler))
+	mux.Handle("GET /api/v1/saved", archive(s.getSavedHandler))
+	mux.Handle("GET /api/v1/saved/collections/{name}", archive(s.getSavedCollectionHandler))
+	mux.Handle("GET /api/v1/profile", archive(s.getProfileHandler))
+	mux.Handle("GET /api/v1/security", archive(s.getSecurityHandler))
+	mux.Handle("GET /api/v1/search-history", archive(s.getSearchHistoryHandler))
+	mux.Handle("GET /api/v1/search-history/{id}", archive(s.getSearchHandler))
+	mux.Handle("GET /api/v1/story-interactions", archive(s.getStoryInteractionsHandler))
+	mux.Handle("GET /api/v1/messages", archive(s.getMessagesHandler))
+	mux.Handle("GET /api/v1/messages/{conversationID}", archive(s.getConversationHandler))
+	mux.Handle("GET /api/v1/topics", archive(s.getTopicsHandler))
+	mux.Handle("GET /api/v1/off-meta-activity", archive(s.getOffMetaActivityHandler))
+	mux.Handle("GET /api/v1/archived-posts", archive(s.getArchivedPostsHandler))
+	mux.Handle("GET /api/v1/search", archive(s.searchHandler))
+	mux.Handle("GET /api/v1/timeline", archive(s.getTimelineHandler))
+	// not cached: without a date the answer changes at midnight, not with the archive
+	mux.Handle("GET /api/v1/memories/on-this-day", s.authMiddleware(http.HandlerFunc(s.getOnThisDayHandler)))
+	mux.Handle("GET /api/v1/insights/relationships", archive(s.getRelationshipsHandler))
+	mux.Handle("GET /api/v1/insights/snapshots", archive(s.getSnapshotsHandler))
+	mux.Handle("GET /api/v1/insights/snapshots/diff", archive(s.getSnapshotDiffHandler))
+	mux.Handle("GET /api/v1/insights/messages", archive(s.getMessageStatsHandler))
+	mux.Handle("GET /api/v1/insights/activity", archive(s.getActivityHeatmapHandler))
+	mux.Handle("GET /api/v1/insights/ads", archive(s.getAdReportHandler))
+	mux.Handle("GET /api/v1/insights/interactions", archive(s.getInteractionsHandler))
+	mux.Handle("GET /api/v1/insights/interactions/{username}", archive(s.getInteractionDetailHandler))
+	mux.Handle("GET /api/v1/insights/security", archive(s.getLoginSecurityHandler))
+	mux.Handle("GET /api/v1/insights/login-locations", archive(s.getLoginLocationsHandler))
+	mux.Handle("GET /api/v1/insights/words", archive(s.getWordsHandler))
+	mux.HandleFunc("GET "+openAPIPath, s.openAPIHandler)
 
 	c := cors.New(cors.Options{
 		AllowedOrigins: []string{"http://localhost:3000"},
 		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowedHeaders: []string{"*"},
+		ExposedHeaders: []string{"ETag", "Allow"},
 	})
-	handler := c.Handler(mux)
+	handler := c.Handler(compressMiddleware(routeErrorsAsJSON(mux)))
 
 	log.Println("Server starting on port 8080...")
 	log.Fatal(http.ListenAndServe(":8080", handler))
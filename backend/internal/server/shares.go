@@ -0,0 +1,564 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Sa-Te/IAV/backend/internal/models"
+	"github.com/Sa-Te/IAV/backend/internal/store"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Sections a share link can expose. Everything else in the archive stays private.
+const (
+	shareSectionMedia = "media"
+	shareSectionSaved = "saved"
+)
+
+const (
+	shareDateLayout        = "2006-01-02"
+	defaultShareLinkDays   = 7
+	maxShareLinkDays       = 365
+	shareLinkPurpose       = "share"
+	shareTokenHeader       = "X-Share-Token"
+	shareAccessLogMaxItems = 500
+)
+
+var errInvalidShareToken = errors.New("invalid or expired share link")
+
+// issueShareToken signs a token naming the link. Like the 2FA challenge it carries no
+// "userID" claim, so authMiddleware never mistakes it for a session.
+func issueShareToken(linkID int, expiresAt time.Time) (string, error) {
+	claims := jwt.MapClaims{
+		"shareLinkID": linkID,
+		"purpose":     shareLinkPurpose,
+		"exp":         expiresAt.Unix(),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtSecretKey)
+}
+
+func parseShareToken(tokenString string) (int, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return jwtSecretKey, nil
+	})
+	if err != nil || !token.Valid {
+		return 0, errInvalidShareToken
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["purpose"] != shareLinkPurpose {
+		return 0, errInvalidShareToken
+	}
+	linkID, ok := claims["shareLinkID"].(float64)
+	if !ok {
+		return 0, errInvalidShareToken
+	}
+	return int(linkID), nil
+}
+
+// normalizeShareSections validates the requested sections and drops duplicates.
+func normalizeShareSections(sections []string) ([]string, error) {
+	seen := map[string]bool{}
+	out := make([]string, 0, len(sections))
+	for _, section := range sections {
+		section = strings.ToLower(strings.TrimSpace(section))
+		switch section {
+		case shareSectionMedia, shareSectionSaved:
+		default:
+			return nil, fmt.Errorf("unknown section %q", section)
+		}
+		if !seen[section] {
+			seen[section] = true
+			out = append(out, section)
+		}
+	}
+	if len(out) == 0 {
+		return nil, errors.New("at least one section is required")
+	}
+	return out, nil
+}
+
+func shareAllows(link *models.ShareLink, section string) bool {
+	for _, s := range link.Sections {
+		if s == section {
+			return true
+		}
+	}
+	return false
+}
+
+// shareMiddleware authenticates requests made with a share link instead of a login.
+// The token comes from the X-Share-Token header, or the `token` query parameter so
+// that <img> tags can load shared media. Every accepted request is written to the
+// link's access log. Share links are read-only, so only GET routes may be mounted behind it.
+func (s *APIServer) shareMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenString := r.Header.Get(shareTokenHeader)
+		if tokenString == "" {
+			tokenString = r.URL.Query().Get("token")
+		}
+		if tokenString == "" {
+			writeJSONError(w, http.StatusUnauthorized, "Share token required")
+			return
+		}
+
+		linkID, err := parseShareToken(tokenString)
+		if err != nil {
+			writeJSONError(w, http.StatusUnauthorized, "Invalid or expired share link")
+			return
+		}
+
+		link, err := s.store.Shares.LiveShareLink(r.Context(), linkID)
+		if err != nil {
+			if !errors.Is(err, store.ErrNotFound) {
+				log.Printf("Failed to load share link %d: %v", linkID, err)
+			}
+			writeJSONError(w, http.StatusUnauthorized, "Invalid or expired share link")
+			return
+		}
+
+		ip, userAgent := s.clientIP(r), r.UserAgent()
+		err = s.store.Shares.LogShareAccess(r.Context(), link.ID, models.ShareLinkAccess{Path: r.URL.Path, IPAddress: &ip, UserAgent: &userAgent})
+		if err != nil {
+			log.Printf("Failed to log access to share link %d: %v", link.ID, err)
+		}
+
+		ctx := context.WithValue(r.Context(), shareLinkKey, &link)
+		ctx = context.WithValue(ctx, userIDKey, link.UserID)
+		ctx = context.WithValue(ctx, authScopeKey, scopeRead)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// TrustProxies makes clientIP believe X-Forwarded-For on requests that arrive from one
+// of these addresses or CIDR ranges, i.e. the reverse proxies in front of the server.
+func (s *APIServer) TrustProxies(proxies []string) error {
+	for _, p := range proxies {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if !strings.Contains(p, "/") {
+			addr, err := netip.ParseAddr(p)
+			if err != nil {
+				return fmt.Errorf("trusted proxy %q: %w", p, err)
+			}
+			s.trustedProxies = append(s.trustedProxies, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(p)
+		if err != nil {
+			return fmt.Errorf("trusted proxy %q: %w", p, err)
+		}
+		s.trustedProxies = append(s.trustedProxies, prefix.Masked())
+	}
+	return nil
+}
+
+func (s *APIServer) trustedProxy(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	return slices.ContainsFunc(s.trustedProxies, func(p netip.Prefix) bool { return p.Contains(addr.Unmap()) })
+}
+
+// clientIP is the address the share access log records. From anyone but a trusted proxy
+// X-Forwarded-For is whatever the client chose to write, so only the connection's own
+// address counts; behind trusted proxies it is the last hop they didn't add themselves.
+func (s *APIServer) clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	if !s.trustedProxy(ip) {
+		return ip
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if _, err := netip.ParseAddr(hop); err != nil {
+			break
+		}
+		ip = hop
+		if !s.trustedProxy(hop) {
+			break
+		}
+	}
+	return ip
+}
+
+// --- Owner endpoints ---
+
+type shareLinkResponse struct {
+	models.ShareLink
+	Token string `json:"token"`
+}
+
+// listShareLinks handles GET /api/v1/shares.
+func (s *APIServer) listShareLinks(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(int)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+
+	stored, err := s.store.Shares.ListShareLinks(r.Context(), userID)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve share links")
+		return
+	}
+
+	links := make([]shareLinkResponse, 0, len(stored))
+	for _, l := range stored {
+		resp := shareLinkResponse{ShareLink: l}
+		// tokens are deterministic, so live links can be copied again from the list
+		if l.RevokedAt == nil && l.ExpiresAt.After(time.Now()) {
+			resp.Token, _ = issueShareToken(l.ID, l.ExpiresAt)
+		}
+		links = append(links, resp)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(links)
+}
+
+// createShareLink handles POST /api/v1/shares.
+func (s *APIServer) createShareLink(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(int)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+	accountID, ok := s.accountFromRequest(w, r, userID)
+	if !ok {
+		return
+	}
+
+	var reqBody struct {
+		Name          string   `json:"name"`
+		Sections      []string `json:"sections"`
+		From          string   `json:"from"`
+		To            string   `json:"to"`
+		Collection    string   `json:"collection"`
+		ExpiresInDays int      `json:"expires_in_days"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	l := models.ShareLink{UserID: userID, AccountID: accountID, Name: strings.TrimSpace(reqBody.Name)}
+	if l.Name == "" {
+		writeJSONError(w, http.StatusBadRequest, "Link name is required")
+		return
+	}
+
+	var err error
+	if l.Sections, err = normalizeShareSections(reqBody.Sections); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if l.DateFrom, err = parseShareDate(reqBody.From); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "from must be a date like 2024-01-31")
+		return
+	}
+	if l.DateTo, err = parseShareDate(reqBody.To); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "to must be a date like 2024-01-31")
+		return
+	}
+	if l.DateFrom != nil && l.DateTo != nil && l.DateTo.Before(*l.DateFrom) {
+		writeJSONError(w, http.StatusBadRequest, "to must not be before from")
+		return
+	}
+	if collection := strings.TrimSpace(reqBody.Collection); collection != "" {
+		if !shareAllows(&l, shareSectionSaved) {
+			writeJSONError(w, http.StatusBadRequest, "collection requires the saved section")
+			return
+		}
+		_, _, err := s.store.Saved.Collection(r.Context(), accountID, collection)
+		if errors.Is(err, store.ErrNotFound) {
+			writeJSONError(w, http.StatusNotFound, "Collection not found")
+			return
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to look up collection")
+			return
+		}
+		l.CollectionName = &collection
+	}
+
+	days := reqBody.ExpiresInDays
+	if days == 0 {
+		days = defaultShareLinkDays
+	}
+	if days < 0 || days > maxShareLinkDays {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("expires_in_days must be between 1 and %d", maxShareLinkDays))
+		return
+	}
+	l.ExpiresAt = time.Now().AddDate(0, 0, days)
+
+	l, err = s.store.Shares.CreateShareLink(r.Context(), l)
+	if err != nil {
+		log.Printf("Failed to create share link for user %d: %v", userID, err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to create share link")
+		return
+	}
+
+	token, err := issueShareToken(l.ID, l.ExpiresAt)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to sign share link")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(shareLinkResponse{l, token})
+}
+
+func parseShareDate(value string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(shareDateLayout, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// revokeShareLink handles DELETE /api/v1/shares/{id}.
+func (s *APIServer) revokeShareLink(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(int)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+	linkID, ok := pathInt(w, r, "id", "share link id")
+	if !ok {
+		return
+	}
+
+	err := s.store.Shares.RevokeShareLink(r.Context(), userID, linkID)
+	if errors.Is(err, store.ErrNotFound) {
+		writeJSONError(w, http.StatusNotFound, "Share link not found")
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to revoke share link")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getShareLinkAccessLog handles GET /api/v1/shares/{id}/access-log.
+func (s *APIServer) getShareLinkAccessLog(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(int)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+	linkID, ok := pathInt(w, r, "id", "share link id")
+	if !ok {
+		return
+	}
+
+	entries, err := s.store.Shares.ListShareAccess(r.Context(), userID, linkID, shareAccessLogMaxItems)
+	if errors.Is(err, store.ErrNotFound) {
+		writeJSONError(w, http.StatusNotFound, "Share link not found")
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve access log")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// --- Shared (read-only) endpoints, mounted behind shareMiddleware ---
+
+func shareLinkFromContext(w http.ResponseWriter, r *http.Request, section string) (*models.ShareLink, bool) {
+	link, ok := r.Context().Value(shareLinkKey).(*models.ShareLink)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid share link")
+		return nil, false
+	}
+	if section != "" && !shareAllows(link, section) {
+		writeJSONError(w, http.StatusForbidden, "This section is not shared")
+		return nil, false
+	}
+	return link, true
+}
+
+// shareRangeEnd turns the inclusive date_to into an exclusive upper bound.
+func shareRangeEnd(link *models.ShareLink) *time.Time {
+	if link.DateTo == nil {
+		return nil
+	}
+	end := link.DateTo.AddDate(0, 0, 1)
+	return &end
+}
+
+// shareCovers reports whether t falls inside the link's date range.
+func shareCovers(link *models.ShareLink, t time.Time) bool {
+	if link.DateFrom != nil && t.Before(*link.DateFrom) {
+		return false
+	}
+	end := shareRangeEnd(link)
+	return end == nil || t.Before(*end)
+}
+
+// getSharedInfoHandler describes what the link exposes, so the viewer knows which sections to load.
+func (s *APIServer) getSharedInfoHandler(w http.ResponseWriter, r *http.Request) {
+	link, ok := shareLinkFromContext(w, r, "")
+	if !ok {
+		return
+	}
+	type Response struct {
+		Name           string     `json:"name"`
+		Sections       []string   `json:"sections"`
+		DateFrom       *time.Time `json:"date_from"`
+		DateTo         *time.Time `json:"date_to"`
+		CollectionName *string    `json:"collection_name"`
+		ExpiresAt      time.Time  `json:"expires_at"`
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{link.Name, link.Sections, link.DateFrom, link.DateTo, link.CollectionName, link.ExpiresAt})
+}
+
+func (s *APIServer) getSharedMediaHandler(w http.ResponseWriter, r *http.Request) {
+	link, ok := shareLinkFromContext(w, r, shareSectionMedia)
+	if !ok {
+		return
+	}
+
+	pg, ok := pageFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	mediaItems, err := s.store.Media.ListMedia(r.Context(), link.AccountID,
+		listOptions(listQuery{From: link.DateFrom, To: shareRangeEnd(link), Sort: store.SortDate}, pg))
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to get media items")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(newPage(mediaItems, pg, func(m models.MediaItem) pageCursor { return pageCursor{At: m.TakenAt, ID: m.ID} }))
+}
+
+// serveSharedMediaFileHandler only serves files that belong to a media item inside the shared range.
+func (s *APIServer) serveSharedMediaFileHandler(w http.ResponseWriter, r *http.Request) {
+	link, ok := shareLinkFromContext(w, r, shareSectionMedia)
+	if !ok {
+		return
+	}
+	relPath := r.PathValue("path")
+
+	item, err := s.store.Media.FindMedia(r.Context(), link.AccountID, relPath)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && !shareCovers(link, item.TakenAt)) {
+		writeJSONError(w, http.StatusNotFound, "File not found")
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to look up media file")
+		return
+	}
+
+	fullPath, err := s.mediaFilePath(r.Context(), link.UserID, link.AccountID, relPath)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to look up media file")
+		return
+	}
+	http.ServeFile(w, r, fullPath)
+}
+
+// getSharedSavedHandler answers in the same shape as getSavedHandler, narrowed to what the
+// link exposes: item counts cover only the items inside the shared range.
+func (s *APIServer) getSharedSavedHandler(w http.ResponseWriter, r *http.Request) {
+	link, ok := shareLinkFromContext(w, r, shareSectionSaved)
+	if !ok {
+		return
+	}
+	type Collection struct {
+		models.SavedCollection
+		ItemCount int                          `json:"item_count"`
+		Items     []models.SavedCollectionItem `json:"items"`
+	}
+	type Counts struct {
+		SavedMedia      int `json:"saved_media"`
+		Collections     int `json:"collections"`
+		CollectionItems int `json:"collection_items"`
+	}
+	type Response struct {
+		SavedMedia  []models.SavedMedia `json:"saved_media"`
+		Collections []*Collection       `json:"collections"`
+		Counts      Counts              `json:"counts"`
+	}
+	resp := Response{SavedMedia: make([]models.SavedMedia, 0), Collections: make([]*Collection, 0)}
+	ctx := r.Context()
+
+	// a link scoped to one collection exposes only that collection, not the loose saved posts
+	if link.CollectionName == nil {
+		saved, err := s.store.Saved.ListSavedMedia(ctx, link.AccountID)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve saved items")
+			return
+		}
+		for _, m := range saved {
+			if shareCovers(link, m.SavedAt) {
+				resp.SavedMedia = append(resp.SavedMedia, m)
+			}
+		}
+	}
+
+	collections, err := s.store.Saved.ListCollections(ctx, link.AccountID)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve saved items")
+		return
+	}
+	collection := ""
+	if link.CollectionName != nil {
+		collection = *link.CollectionName
+	}
+	byName := map[string]*Collection{}
+	for _, sc := range collections {
+		if collection == "" || sc.CollectionName == collection {
+			c := &Collection{SavedCollection: sc, Items: make([]models.SavedCollectionItem, 0)}
+			byName[c.CollectionName] = c
+			resp.Collections = append(resp.Collections, c)
+		}
+	}
+
+	items, err := s.store.Saved.ListCollectionItems(ctx, link.AccountID, collection,
+		store.ListOptions{From: link.DateFrom, To: shareRangeEnd(link), Sort: store.SortDate})
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve saved items")
+		return
+	}
+	for _, ci := range items {
+		if c, ok := byName[ci.CollectionName]; ok {
+			c.Items = append(c.Items, ci)
+			c.ItemCount++
+			resp.Counts.CollectionItems++
+		}
+	}
+
+	resp.Counts.SavedMedia = len(resp.SavedMedia)
+	resp.Counts.Collections = len(resp.Collections)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
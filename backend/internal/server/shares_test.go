@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Sa-Te/IAV/backend/internal/models"
+)
+
+func TestShareTokenRoundTrip(t *testing.T) {
+	token, err := issueShareToken(7, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+	linkID, err := parseShareToken(token)
+	if err != nil || linkID != 7 {
+		t.Fatalf("parse = %d, %v", linkID, err)
+	}
+}
+
+func TestShareTokenRejectsOtherTokens(t *testing.T) {
+	expired, _ := issueShareToken(7, time.Now().Add(-time.Minute))
+	if _, err := parseShareToken(expired); err == nil {
+		t.Error("expired share token must be rejected")
+	}
+	session, _ := issueSessionToken(7)
+	if _, err := parseShareToken(session); err == nil {
+		t.Error("session token must not be accepted as a share token")
+	}
+	challenge, _ := issueTwoFactorChallenge(7)
+	if _, err := parseShareToken(challenge); err == nil {
+		t.Error("2fa challenge must not be accepted as a share token")
+	}
+}
+
+func TestNormalizeShareSections(t *testing.T) {
+	got, err := normalizeShareSections([]string{" Media", "saved", "media"})
+	if err != nil {
+		t.Fatalf("normalize: %v", err)
+	}
+	if len(got) != 2 || got[0] != shareSectionMedia || got[1] != shareSectionSaved {
+		t.Errorf("unexpected sections %v", got)
+	}
+	if _, err := normalizeShareSections([]string{"messages"}); err == nil {
+		t.Error("expected unknown section to be rejected")
+	}
+	if _, err := normalizeShareSections(nil); err == nil {
+		t.Error("expected empty sections to be rejected")
+	}
+}
+
+func TestSharedMediaHandlerStaysInsideTheRange(t *testing.T) {
+	s, mem, accountID := newTestServer(t)
+	archive := mem.Archive(accountID)
+	for i := 1; i <= 5; i++ {
+		archive.Media = append(archive.Media, models.MediaItem{ID: i, UserID: testUserID, URI: fmt.Sprintf("media/posts/%d.jpg", i), TakenAt: day(i), MediaType: "post"})
+	}
+	from, to := time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC)
+	link, err := mem.CreateShareLink(context.Background(), models.ShareLink{UserID: testUserID, AccountID: accountID, Name: "trip",
+		Sections: []string{shareSectionMedia}, DateFrom: &from, DateTo: &to, ExpiresAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := issueShareToken(link.ID, link.ExpiresAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/shared/media", nil)
+	req.Header.Set(shareTokenHeader, token)
+	rec := httptest.NewRecorder()
+	s.shareMiddleware(http.HandlerFunc(s.getSharedMediaHandler)).ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	var ids []int
+	for _, m := range decodeBody[page[models.MediaItem]](t, rec).Items {
+		ids = append(ids, m.ID)
+	}
+	if fmt.Sprint(ids) != "[3 2]" {
+		t.Errorf("shared ids = %v, want [3 2]", ids)
+	}
+
+	accesses, err := mem.ListShareAccess(context.Background(), testUserID, link.ID, 10)
+	if err != nil || len(accesses) != 1 || accesses[0].Path != "/api/v1/shared/media" {
+		t.Errorf("access log = %+v, %v", accesses, err)
+	}
+}
+
+// The shared saved view has the owner's /saved shape, narrowed to the link's collection and range.
+func TestSharedSavedHandlerNestsCollections(t *testing.T) {
+	s, mem, accountID := newTestServer(t)
+	archive := mem.Archive(accountID)
+	archive.SavedMedia = []models.SavedMedia{{ID: 1, PostURL: "https://www.instagram.com/p/a/", SavedAt: day(2)}}
+	archive.Collections = []models.SavedCollection{{ID: 1, CollectionName: "trips"}, {ID: 2, CollectionName: "food"}}
+	archive.CollectionItems = []models.SavedCollectionItem{
+		{ID: 1, CollectionName: "trips", ItemURL: "https://www.instagram.com/p/b/", AddedAt: day(2)},
+		{ID: 2, CollectionName: "trips", ItemURL: "https://www.instagram.com/p/c/", AddedAt: day(5)},
+		{ID: 3, CollectionName: "food", ItemURL: "https://www.instagram.com/p/d/", AddedAt: day(2)},
+	}
+	name := "trips"
+	from, to := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC)
+	link, err := mem.CreateShareLink(context.Background(), models.ShareLink{UserID: testUserID, AccountID: accountID, Name: "trips",
+		Sections: []string{shareSectionSaved}, DateFrom: &from, DateTo: &to, CollectionName: &name, ExpiresAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := issueShareToken(link.ID, link.ExpiresAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/shared/saved", nil)
+	req.Header.Set(shareTokenHeader, token)
+	rec := httptest.NewRecorder()
+	s.shareMiddleware(http.HandlerFunc(s.getSharedSavedHandler)).ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	resp := decodeBody[struct {
+		SavedMedia  []models.SavedMedia `json:"saved_media"`
+		Collections []struct {
+			CollectionName string                       `json:"collection_name"`
+			ItemCount      int                          `json:"item_count"`
+			Items          []models.SavedCollectionItem `json:"items"`
+		} `json:"collections"`
+		Counts struct {
+			SavedMedia      int `json:"saved_media"`
+			Collections     int `json:"collections"`
+			CollectionItems int `json:"collection_items"`
+		} `json:"counts"`
+	}](t, rec)
+	if len(resp.SavedMedia) != 0 || len(resp.Collections) != 1 {
+		t.Fatalf("resp = %+v, want only the trips collection", resp)
+	}
+	if c := resp.Collections[0]; c.CollectionName != "trips" || c.ItemCount != 1 || len(c.Items) != 1 || c.Items[0].ID != 1 {
+		t.Errorf("collection = %+v, want item 1 only", c)
+	}
+	if resp.Counts.SavedMedia != 0 || resp.Counts.Collections != 1 || resp.Counts.CollectionItems != 1 {
+		t.Errorf("counts = %+v", resp.Counts)
+	}
+}
+
+// Only a trusted proxy gets to say where a request came from.
+func TestClientIPIgnoresForgedForwardedFor(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/shared/media", nil)
+	req.RemoteAddr = "198.51.100.7:51234"
+	req.Header.Set("X-Forwarded-For", "203.0.113.1")
+	if got := s.clientIP(req); got != "198.51.100.7" {
+		t.Errorf("untrusted client: %q, want its own address", got)
+	}
+
+	if err := s.TrustProxies([]string{"10.0.0.0/8", " 192.0.2.1"}); err != nil {
+		t.Fatal(err)
+	}
+	req.RemoteAddr = "10.1.2.3:51234"
+	req.Header.Set("X-Forwarded-For", "203.0.113.1, 198.51.100.7, 192.0.2.1")
+	if got := s.clientIP(req); got != "198.51.100.7" {
+		t.Errorf("behind proxies: %q, want the last untrusted hop", got)
+	}
+	if err := s.TrustProxies([]string{"not-an-ip"}); err == nil {
+		t.Error("TrustProxies accepted a bad address")
+	}
+}
@@ -0,0 +1,161 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Sa-Te/IAV/backend/internal/models"
+)
+
+// snapshotDiff is what changed in the follower and following lists between two snapshots.
+type snapshotDiff struct {
+	NewFollowers  []models.SnapshotEntry `json:"new_followers"`
+	LostFollowers []models.SnapshotEntry `json:"lost_followers"`
+	NewFollowing  []models.SnapshotEntry `json:"new_following"`
+	Unfollowed    []models.SnapshotEntry `json:"unfollowed"`
+}
+
+// diffSnapshots compares two snapshots' entries, each ordered by username. Added entries
+// come from the newer snapshot and removed ones from the older.
+func diffSnapshots(from, to []models.SnapshotEntry) snapshotDiff {
+	d := snapshotDiff{
+		NewFollowers:  []models.SnapshotEntry{},
+		LostFollowers: []models.SnapshotEntry{},
+		NewFollowing:  []models.SnapshotEntry{},
+		Unfollowed:    []models.SnapshotEntry{},
+	}
+	key := func(e models.SnapshotEntry) string { return e.ConnectionType + "\x00" + strings.ToLower(e.Username) }
+	in := func(entries []models.SnapshotEntry) map[string]bool {
+		set := map[string]bool{}
+		for _, e := range entries {
+			set[key(e)] = true
+		}
+		return set
+	}
+	before, after := in(from), in(to)
+
+	for _, e := range to {
+		if before[key(e)] {
+			continue
+		}
+		switch e.ConnectionType {
+		case "follower":
+			d.NewFollowers = append(d.NewFollowers, e)
+		case "following":
+			d.NewFollowing = append(d.NewFollowing, e)
+		}
+	}
+	for _, e := range from {
+		if after[key(e)] {
+			continue
+		}
+		switch e.ConnectionType {
+		case "follower":
+			d.LostFollowers = append(d.LostFollowers, e)
+		case "following":
+			d.Unfollowed = append(d.Unfollowed, e)
+		}
+	}
+	return d
+}
+
+// getSnapshotsHandler lists every import's follower and following counts, oldest first,
+// which is the follower count over time.
+func (s *APIServer) getSnapshotsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+	accountID, ok := s.accountFromRequest(w, r, userID)
+	if !ok {
+		return
+	}
+
+	snapshots, err := s.store.Snapshots.ListSnapshots(r.Context(), accountID)
+	if err != nil {
+		log.Printf("Database query error in getSnapshotsHandler: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to get snapshots")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snapshots)
+}
+
+// getSnapshotDiffHandler compares two snapshots, chosen by import id with `from` and `to`.
+// By default the latest snapshot is compared with the one before it.
+func (s *APIServer) getSnapshotDiffHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+	accountID, ok := s.accountFromRequest(w, r, userID)
+	if !ok {
+		return
+	}
+
+	snapshots, err := s.store.Snapshots.ListSnapshots(r.Context(), accountID)
+	if err != nil {
+		log.Printf("Database query error in getSnapshotDiffHandler: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to get snapshots")
+		return
+	}
+
+	// find returns the index of the snapshot named by the parameter, or def when it is absent
+	find := func(param string, def int) (int, bool) {
+		v := r.URL.Query().Get(param)
+		if v == "" {
+			if def < 0 {
+				writeJSONError(w, http.StatusNotFound, "At least two imports are needed to compare")
+				return 0, false
+			}
+			return def, true
+		}
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, param+" must be an import id")
+			return 0, false
+		}
+		for i, snapshot := range snapshots {
+			if snapshot.ImportID == id {
+				return i, true
+			}
+		}
+		writeJSONError(w, http.StatusNotFound, "No snapshot for import "+v)
+		return 0, false
+	}
+	to, ok := find("to", len(snapshots)-1)
+	if !ok {
+		return
+	}
+	from, ok := find("from", to-1)
+	if !ok {
+		return
+	}
+
+	types := []string{"follower", "following"}
+	fromEntries, err := s.store.Snapshots.ListSnapshotEntries(r.Context(), accountID, snapshots[from].ImportID, types)
+	var toEntries []models.SnapshotEntry
+	if err == nil {
+		toEntries, err = s.store.Snapshots.ListSnapshotEntries(r.Context(), accountID, snapshots[to].ImportID, types)
+	}
+	if err != nil {
+		log.Printf("Database query error in getSnapshotDiffHandler: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to get snapshots")
+		return
+	}
+
+	type Response struct {
+		From models.ConnectionSnapshot `json:"from"`
+		To   models.ConnectionSnapshot `json:"to"`
+		snapshotDiff
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{From: snapshots[from], To: snapshots[to], snapshotDiff: diffSnapshots(fromEntries, toEntries)})
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sa-Te/IAV/backend/internal/models"
+)
+
+func snapshotEntries(importID int, connectionType string, usernames ...string) []models.SnapshotEntry {
+	var entries []models.SnapshotEntry
+	for _, u := range usernames {
+		entries = append(entries, models.SnapshotEntry{ImportID: importID, Username: u, ConnectionType: connectionType, Timestamp: day(1)})
+	}
+	return entries
+}
+
+func usernamesOf(entries []models.SnapshotEntry) string {
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Username)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestSnapshotDiffHandler(t *testing.T) {
+	s, mem, accountID := newTestServer(t)
+	archive := mem.Archive(accountID)
+	archive.Imports = map[int]time.Time{3: day(1), 5: day(10), 8: day(20), 9: day(25)}
+	for _, entries := range [][]models.SnapshotEntry{
+		snapshotEntries(3, "follower", "anna", "bob", "carl"),
+		snapshotEntries(3, "following", "anna", "dora"),
+		snapshotEntries(5, "follower", "Anna", "carl", "eve"),
+		snapshotEntries(5, "following", "anna"),
+		snapshotEntries(8, "follower", "carl", "eve", "finn"),
+		snapshotEntries(8, "following", "anna", "gus"),
+	} {
+		archive.SnapshotEntries = append(archive.SnapshotEntries, entries...)
+	}
+
+	// import 9 had no connection lists, so it is not a snapshot
+	rec := serveAs(testUserID, s.getSnapshotsHandler, httptest.NewRequest(http.MethodGet, "/api/v1/insights/snapshots", nil))
+	series := decodeBody[[]models.ConnectionSnapshot](t, rec)
+	if len(series) != 3 || series[0].ImportID != 3 || series[0].Followers != 3 || series[2].Followers != 3 || series[2].Following != 2 {
+		t.Errorf("snapshots = %+v", series)
+	}
+
+	type diff struct {
+		From          models.ConnectionSnapshot `json:"from"`
+		To            models.ConnectionSnapshot `json:"to"`
+		NewFollowers  []models.SnapshotEntry    `json:"new_followers"`
+		LostFollowers []models.SnapshotEntry    `json:"lost_followers"`
+		NewFollowing  []models.SnapshotEntry    `json:"new_following"`
+		Unfollowed    []models.SnapshotEntry    `json:"unfollowed"`
+	}
+	get := func(target string) *httptest.ResponseRecorder {
+		return serveAs(testUserID, s.getSnapshotDiffHandler, httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	// the latest two by default
+	d := decodeBody[diff](t, get("/api/v1/insights/snapshots/diff"))
+	if d.From.ImportID != 5 || d.To.ImportID != 8 || usernamesOf(d.NewFollowers) != "finn" || usernamesOf(d.LostFollowers) != "Anna" ||
+		usernamesOf(d.NewFollowing) != "gus" || len(d.Unfollowed) != 0 {
+		t.Errorf("default diff = %+v", d)
+	}
+
+	// usernames compare case-insensitively
+	d = decodeBody[diff](t, get("/api/v1/insights/snapshots/diff?from=3&to=5"))
+	if usernamesOf(d.NewFollowers) != "eve" || usernamesOf(d.LostFollowers) != "bob" || usernamesOf(d.Unfollowed) != "dora" {
+		t.Errorf("diff 3..5 = %+v", d)
+	}
+
+	for target, want := range map[string]int{
+		"/api/v1/insights/snapshots/diff?to=3":   http.StatusNotFound,
+		"/api/v1/insights/snapshots/diff?from=9": http.StatusNotFound,
+		"/api/v1/insights/snapshots/diff?from=x": http.StatusBadRequest,
+		"/api/v1/insights/snapshots/diff?from=8": http.StatusOK,
+		"/api/v1/insights/snapshots/diff?to=5":   http.StatusOK,
+	} {
+		if rec := get(target); rec.Code != want {
+			t.Errorf("%s: status %d, want %d", target, rec.Code, want)
+		}
+	}
+}
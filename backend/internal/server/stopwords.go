@@ -0,0 +1,80 @@
+package server
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+// stopwordLists are the most common function words of each language, lowercase and
+// with apostrophes as '. They are left out of the word counts and, as the words most
+// likely to show up in any text of the language, are what languageOf goes by.
+var stopwordLists = map[string]string{
+	"en": `a about above after again against all also am an and any are as at be because been
+		before being below between both but by can can't could couldn't did didn't do does doesn't
+		doing don't down during each few for from further get got had hadn't has hasn't have haven't
+		having he her here hers herself him himself his how i i'd i'll i'm i've if in into is isn't
+		it its itself just let me more most my myself no nor not now of off on once only or other
+		our ours ourselves out over own same she should shouldn't so some such than that the their
+		theirs them themselves then there these they they're this those through to too under until
+		up very was wasn't we we're were weren't what when where which while who whom why will with
+		won't would wouldn't you you'd you'll you're you've your yours yourself yourselves`,
+	"de": `aber alle allem allen aller alles als also am an ander andere anderen auch auf aus bei
+		bin bis bist da damit dann das dass dein deine dem den denn der des dich die dir doch dort du
+		durch ein eine einem einen einer eines er es etwas euch euer für gegen gewesen hab habe haben
+		hat hatte hier hin hinter ich ihm ihn ihr ihre im in ist ja jede jedem jeden jeder jetzt kann
+		kein keine man mein meine mich mir mit muss nach nicht nichts noch nun nur ob oder ohne schon
+		sehr sein seine sich sie sind so solche soll über um und uns unser unter viel vom von vor war
+		waren warst was weil wenn wer wie wieder will wir wird wo wurde zu zum zur zwischen`,
+	"es": `a al algo algunos ante antes como con contra cual cuando de del desde donde durante e
+		el ella ellas ellos en entre era es esa esas ese eso esos esta estaba estamos están estar
+		estas este esto estos estoy fue fueron fui ha había han has hasta hay la las le les lo los
+		más me mi mis mucho muy nada ni no nos nosotros o os otra otro para pero poco por porque que
+		qué quien se sea ser si sí sin sobre somos son soy su sus también tan te tengo ti tiene todo
+		todos tu tú tus un una uno unos usted vosotros y ya yo`,
+	"fr": `à ai aie alors as au aucun aussi autre aux avec avez avoir avons bien ça car ce cela ces
+		cet cette ceux chez comme dans de des donc du elle elles en encore est et étaient était été
+		être eu fait faire il ils je la le les leur leurs lui ma mais me même mes moi mon ne nos notre
+		nous on ont ou où par pas peu plus pour qu quand que quel quelle qui sa sans se ses si son
+		sont sur ta te tes toi ton tous tout très tu un une vos votre vous y`,
+	"it": `a abbiamo ad agli ai al alla alle allo anche avere aveva c che chi ci come con contro cui
+		da dai dal dalla dalle degli dei del dell della delle dello di dove e è ed era erano gli ha
+		hai hanno ho i il in io la le lei li lo loro lui ma me mi mia mie miei mio ne negli nei nel
+		nella nelle no noi non nostra nostro o per perché più quale quando quella quelle quelli
+		quello questa queste questi questo sei si sia siamo siete sono su sua sue sui sul sulla suo
+		suoi ti tra tu tua tue tuo tutti tutto un una uno vi voi`,
+	"nl": `aan al alles als altijd andere ben bij daar dan dat de der deze die dit doch doen door
+		dus een eens en er ge geen geweest haar had heb hebben heeft hem het hier hij hoe hun iemand
+		iets ik in is ja je jij kan kon kunnen maar me meer men met mij mijn moet na naar niet niets
+		nog nu of om omdat onder ons ook op over reeds te tegen toch toen tot u uit uw van veel voor
+		want waren was wat we wel werd wezen wie wij wil worden zal ze zei zelf zich zij zijn zo zonder
+		zou`,
+	"pt": `a ao aos as até com como da das de dela dele deles depois do dos e é ela elas ele eles em
+		entre era essa esse esta está estão este eu foi for foram há isso isto já lhe lhes mais mas me
+		mesmo meu meus minha minhas muito na nas não nem no nos nós o os ou para pela pelas pelo pelos
+		por qual quando que quem se seja sem ser seu seus só sua suas também te tem tinha tu tua um
+		uma você vocês`,
+}
+
+// stopwordLanguages are the keys of stopwordLists, sorted.
+var stopwordLanguages = slices.Sorted(maps.Keys(stopwordLists))
+
+// stopwords are stopwordLists as sets.
+var stopwords = func() map[string]map[string]bool {
+	sets := make(map[string]map[string]bool, len(stopwordLists))
+	for lang, list := range stopwordLists {
+		sets[lang] = map[string]bool{}
+		for _, w := range strings.Fields(list) {
+			sets[lang][w] = true
+		}
+	}
+	return sets
+}()
+
+// elisions are the French and Italian words that lose their vowel to the next word:
+// l'amour counts as amour.
+var elisions = map[string]bool{
+	"c": true, "d": true, "j": true, "l": true, "m": true, "n": true, "s": true, "t": true,
+	"qu": true, "jusqu": true, "lorsqu": true, "puisqu": true,
+	"all": true, "dall": true, "dell": true, "nell": true, "sull": true, "un": true,
+}
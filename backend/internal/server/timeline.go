@@ -0,0 +1,53 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/Sa-Te/IAV/backend/internal/models"
+	"github.com/Sa-Te/IAV/backend/internal/store"
+)
+
+var timelineListSpec = listSpec{
+	typed:       true,
+	types:       store.TimelineTypes,
+	dated:       true,
+	byUsername:  true,
+	sorts:       []string{store.SortDate},
+	defaultSort: store.SortDate,
+}
+
+// getTimelineHandler handles GET /api/v1/timeline: every timestamped record of the
+// account as one stream of typed events, newest first unless order=asc.
+func (s *APIServer) getTimelineHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(int)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+	accountID, ok := s.accountFromRequest(w, r, userID)
+	if !ok {
+		return
+	}
+	pg, ok := pageFromRequest(w, r)
+	if !ok {
+		return
+	}
+	q, ok := listQueryFromRequest(w, r, timelineListSpec)
+	if !ok {
+		return
+	}
+
+	events, err := s.store.Timeline.Timeline(r.Context(), accountID, listOptions(q, pg))
+	if err != nil {
+		log.Printf("Failed to query timeline for user %d: %v", userID, err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve timeline")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newPage(events, pg, func(e models.TimelineEvent) pageCursor {
+		return pageCursor{At: e.At, Key: e.Source, ID: e.ID}
+	}))
+}
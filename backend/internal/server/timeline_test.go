@@ -0,0 +1,44 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Sa-Te/IAV/backend/internal/models"
+)
+
+// A post like and a comment like can share their time, type and id (ids are per table);
+// paging one event at a time must still return each of them exactly once.
+func TestTimelinePagesAcrossTies(t *testing.T) {
+	s, mem, accountID := newTestServer(t)
+	archive := mem.Archive(accountID)
+	archive.PostLikes = []models.PostLike{{ID: 3, CreatorUsername: "jane", LikedAt: day(2)}}
+	archive.CommentLikes = []models.CommentLike{{ID: 3, OwnerUsername: "joe", LikedAt: day(2)}}
+	archive.StoryLikes = []models.StoryLike{{ID: 1, CreatorUsername: "ann", LikedAt: day(1)}}
+
+	var seen []string
+	url := "/api/v1/timeline?type=like&limit=1"
+	for pages := 0; url != ""; pages++ {
+		if pages > 5 {
+			t.Fatal("cursor never ran out")
+		}
+		rec := serveAs(testUserID, s.getTimelineHandler, httptest.NewRequest(http.MethodGet, url, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: %d %s", url, rec.Code, rec.Body.String())
+		}
+		pg := decodeBody[page[models.TimelineEvent]](t, rec)
+		for _, e := range pg.Items {
+			seen = append(seen, *e.Username)
+		}
+		url = ""
+		if pg.NextCursor != nil {
+			url = "/api/v1/timeline?type=like&limit=1&cursor=" + *pg.NextCursor
+		}
+	}
+	// newest first; the tie is broken on the source table, descending
+	if fmt.Sprint(seen) != "[jane joe ann]" {
+		t.Errorf("paged events = %v, want [jane joe ann]", seen)
+	}
+}
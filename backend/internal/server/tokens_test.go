@@ -0,0 +1,80 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Sa-Te/IAV/backend/internal/models"
+)
+
+func TestScopeAllows(t *testing.T) {
+	cases := []struct {
+		have, want string
+		ok         bool
+	}{
+		{scopeSession, scopeSession, true},
+		{scopeSession, scopeImport, true},
+		{scopeImport, scopeRead, true},
+		{scopeImport, scopeImport, true},
+		{scopeImport, scopeSession, false},
+		{scopeRead, scopeRead, true},
+		{scopeRead, scopeImport, false},
+		{"", scopeRead, false},
+	}
+	for _, c := range cases {
+		if got := scopeAllows(c.have, c.want); got != c.ok {
+			t.Errorf("scopeAllows(%q, %q) = %v, want %v", c.have, c.want, got, c.ok)
+		}
+	}
+}
+
+func TestGenerateAPITokenIsPrefixedAndUnique(t *testing.T) {
+	a, err := generateAPIToken()
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	b, _ := generateAPIToken()
+	if !strings.HasPrefix(a, apiTokenPrefix) {
+		t.Errorf("token %q missing prefix", a)
+	}
+	if a == b || hashAPIToken(a) == hashAPIToken(b) {
+		t.Error("expected distinct tokens and hashes")
+	}
+	if len(hashAPIToken(a)) != 64 {
+		t.Errorf("expected 64 hex chars, got %d", len(hashAPIToken(a)))
+	}
+}
+
+func TestAPITokenLifecycle(t *testing.T) {
+	s, _, _ := newTestServer(t)
+
+	rec := serveAs(testUserID, s.createAPIToken,
+		httptest.NewRequest(http.MethodPost, "/api/v1/tokens", strings.NewReader(`{"name":"backup","scope":"read"}`)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", rec.Code, rec.Body.String())
+	}
+	created := decodeBody[struct {
+		models.APIToken
+		Token string `json:"token"`
+	}](t, rec)
+
+	userID, scope, err := s.lookupAPIToken(created.Token)
+	if err != nil || userID != testUserID || scope != scopeRead {
+		t.Fatalf("lookup = %d, %q, %v", userID, scope, err)
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/tokens/x", nil)
+	req.SetPathValue("id", fmt.Sprint(created.ID))
+	if rec := serveAs(testUserID, s.revokeAPITokenHandler, req); rec.Code != http.StatusNoContent {
+		t.Fatalf("revoke: %d %s", rec.Code, rec.Body.String())
+	}
+	if _, _, err := s.lookupAPIToken(created.Token); err == nil {
+		t.Error("a revoked token must not authenticate")
+	}
+	if rec := serveAs(testUserID, s.revokeAPITokenHandler, req); rec.Code != http.StatusNotFound {
+		t.Errorf("revoking twice: %d, want 404", rec.Code)
+	}
+}
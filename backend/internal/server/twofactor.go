@@ -0,0 +1,430 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Sa-Te/IAV/backend/internal/store"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TOTP parameters follow RFC 6238 defaults, which is what every authenticator app expects.
+const (
+	totpIssuer        = "IAV"
+	totpPeriod        = 30
+	totpDigits        = 6
+	totpSkewSteps     = 1
+	recoveryCodeCount = 10
+	challengeLifetime = 5 * time.Minute
+	// a user gets maxTwoFactorAttempts second-factor attempts, successful or not, before
+	// the second step is locked for twoFactorLockout
+	maxTwoFactorAttempts = 5
+	twoFactorLockout     = 15 * time.Minute
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// --- TOTP primitives ---
+
+func generateTOTPSecret() (string, error) {
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// totpCode computes the HOTP value for a single time step (RFC 4226 dynamic truncation).
+func totpCode(secret []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, value%mod)
+}
+
+// verifyTOTP checks code against the steps around now. Steps at or before lastStep
+// are rejected so an observed code cannot be replayed. It returns the matched step.
+func verifyTOTP(secret, code string, now time.Time, lastStep int64) (int64, bool) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimSpace(secret)))
+	if err != nil {
+		return 0, false
+	}
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != totpDigits {
+		return 0, false
+	}
+
+	current := now.Unix() / totpPeriod
+	for step := current - totpSkewSteps; step <= current+totpSkewSteps; step++ {
+		if step <= lastStep {
+			continue
+		}
+		if hmac.Equal([]byte(totpCode(key, step)), []byte(code)) {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// totpURI builds the otpauth:// URI that authenticator apps import via QR code.
+func totpURI(accountName, secret string) string {
+	label := url.PathEscape(totpIssuer + ":" + accountName)
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", totpIssuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprintf("%d", totpDigits))
+	params.Set("period", fmt.Sprintf("%d", totpPeriod))
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// generateRecoveryCodes returns n random codes formatted as xxxxx-xxxxx.
+func generateRecoveryCodes(n int) ([]string, error) {
+	const alphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+	codes := make([]string, 0, n)
+	buf := make([]byte, 10)
+	for i := 0; i < n; i++ {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		var b strings.Builder
+		for j, c := range buf {
+			if j == 5 {
+				b.WriteByte('-')
+			}
+			b.WriteByte(alphabet[int(c)%len(alphabet)])
+		}
+		codes = append(codes, b.String())
+	}
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.TrimSpace(code))
+}
+
+// --- Challenge tokens ---
+// A challenge token proves the password step succeeded. It deliberately carries no
+// "userID" claim, so authMiddleware will never accept it as a session token.
+
+func issueTwoFactorChallenge(userID int) (string, error) {
+	claims := jwt.MapClaims{
+		"pendingUserID": userID,
+		"purpose":       "2fa",
+		"exp":           time.Now().Add(challengeLifetime).Unix(),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtSecretKey)
+}
+
+func parseTwoFactorChallenge(tokenString string) (int, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return jwtSecretKey, nil
+	})
+	if err != nil || !token.Valid {
+		return 0, fmt.Errorf("invalid or expired challenge")
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["purpose"] != "2fa" {
+		return 0, fmt.Errorf("invalid challenge claims")
+	}
+	userIDFloat, ok := claims["pendingUserID"].(float64)
+	if !ok {
+		return 0, fmt.Errorf("invalid challenge claims")
+	}
+	return int(userIDFloat), nil
+}
+
+// --- Second factor verification ---
+
+// checkSecondFactor accepts either a current TOTP code or an unused recovery code.
+// Successful use is recorded so neither can be presented twice. Every call counts
+// towards maxTwoFactorAttempts; store.ErrLocked means the code wasn't checked.
+func (s *APIServer) checkSecondFactor(userID int, code, recoveryCode string) (bool, error) {
+	if code == "" && recoveryCode == "" {
+		return false, nil
+	}
+	if err := s.store.Users.ReserveTwoFactorAttempt(context.Background(), userID, maxTwoFactorAttempts, twoFactorLockout); err != nil {
+		return false, err
+	}
+	ok, err := s.verifySecondFactor(userID, code, recoveryCode)
+	if ok {
+		err = s.store.Users.ResetTwoFactorAttempts(context.Background(), userID)
+		return err == nil, err
+	}
+	return false, err
+}
+
+func (s *APIServer) verifySecondFactor(userID int, code, recoveryCode string) (bool, error) {
+	if code != "" {
+		user, err := s.store.Users.User(context.Background(), userID)
+		if err != nil || !user.TOTPEnabled || user.TOTPSecret == nil {
+			return false, err
+		}
+		step, ok := verifyTOTP(*user.TOTPSecret, code, time.Now(), user.TOTPLastStep)
+		if !ok {
+			return false, nil
+		}
+		// a concurrent request may have accepted the same or a later step meanwhile
+		return s.store.Users.AcceptTOTPStep(context.Background(), userID, step)
+	}
+
+	if recoveryCode != "" {
+		codes, err := s.store.Users.UnusedRecoveryCodes(context.Background(), userID)
+		if err != nil {
+			return false, err
+		}
+		candidate := []byte(normalizeRecoveryCode(recoveryCode))
+		for _, c := range codes {
+			if bcrypt.CompareHashAndPassword([]byte(c.CodeHash), candidate) == nil {
+				return s.store.Users.UseRecoveryCode(context.Background(), c.ID)
+			}
+		}
+		return false, nil
+	}
+
+	return false, nil
+}
+
+// --- Handlers ---
+
+func writeTwoFactorLocked(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", fmt.Sprintf("%d", int(twoFactorLockout.Seconds())))
+	writeJSONError(w, http.StatusTooManyRequests, "Too many attempts, try again later")
+}
+
+// enrollTwoFactorHandler generates a fresh secret and stores it as pending.
+// 2FA is not enforced until the user proves possession via confirmTwoFactorHandler.
+func (s *APIServer) enrollTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(int)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+
+	user, err := s.store.Users.User(r.Context(), userID)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to load user")
+		return
+	}
+	if user.TOTPEnabled {
+		writeJSONError(w, http.StatusConflict, "Two-factor authentication is already enabled")
+		return
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to generate secret")
+		return
+	}
+	if err := s.store.Users.SetTOTPSecret(r.Context(), userID, secret); err != nil {
+		log.Printf("Failed to store totp secret for user %d: %v", userID, err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to start enrollment")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"secret":      secret,
+		"otpauth_uri": totpURI(user.Email, secret),
+	})
+}
+
+// confirmTwoFactorHandler enables 2FA once a valid code for the pending secret is
+// supplied, and returns a one-time batch of recovery codes.
+func (s *APIServer) confirmTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(int)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+
+	var reqBody struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	user, err := s.store.Users.User(r.Context(), userID)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to load user")
+		return
+	}
+	if user.TOTPEnabled {
+		writeJSONError(w, http.StatusConflict, "Two-factor authentication is already enabled")
+		return
+	}
+	if user.TOTPSecret == nil {
+		writeJSONError(w, http.StatusBadRequest, "Start enrollment first")
+		return
+	}
+	step, ok := verifyTOTP(*user.TOTPSecret, reqBody.Code, time.Now(), 0)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid code")
+		return
+	}
+
+	codes, err := generateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to generate recovery codes")
+		return
+	}
+
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to generate recovery codes")
+			return
+		}
+		hashes = append(hashes, string(hash))
+	}
+	if err := s.store.Users.EnableTwoFactor(r.Context(), userID, step, hashes); err != nil {
+		log.Printf("Failed to commit 2fa enrollment for user %d: %v", userID, err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to enable two-factor authentication")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"enabled":        true,
+		"recovery_codes": codes,
+	})
+}
+
+// loginTwoFactorHandler is the second login step: it exchanges a challenge token
+// plus a TOTP or recovery code for a regular session token.
+func (s *APIServer) loginTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	var reqBody struct {
+		ChallengeToken string `json:"challenge_token"`
+		Code           string `json:"code"`
+		RecoveryCode   string `json:"recovery_code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userID, err := parseTwoFactorChallenge(reqBody.ChallengeToken)
+	if err != nil {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid or expired challenge")
+		return
+	}
+
+	ok, err := s.checkSecondFactor(userID, reqBody.Code, reqBody.RecoveryCode)
+	if errors.Is(err, store.ErrLocked) {
+		writeTwoFactorLocked(w)
+		return
+	}
+	if err != nil {
+		log.Printf("Second factor check failed for user %d: %v", userID, err)
+	}
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid code")
+		return
+	}
+
+	// an admin may have disabled the account since the password step
+	user, err := s.store.Users.User(r.Context(), userID)
+	if err != nil {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid or expired challenge")
+		return
+	}
+	if user.DisabledAt != nil {
+		writeJSONError(w, http.StatusForbidden, "This account has been disabled")
+		return
+	}
+
+	tokenString, err := issueSessionToken(userID)
+	if err != nil {
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"token": tokenString})
+}
+
+// disableTwoFactorHandler turns 2FA off. A stolen session token alone is not
+// enough: the caller must re-enter the password and a current second factor.
+func (s *APIServer) disableTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(int)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+
+	var reqBody struct {
+		Password     string `json:"password"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	user, err := s.store.Users.User(r.Context(), userID)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to load user")
+		return
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(reqBody.Password)) != nil {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid password")
+		return
+	}
+	if !user.TOTPEnabled {
+		writeJSONError(w, http.StatusBadRequest, "Two-factor authentication is not enabled")
+		return
+	}
+
+	ok, err = s.checkSecondFactor(userID, reqBody.Code, reqBody.RecoveryCode)
+	if errors.Is(err, store.ErrLocked) {
+		writeTwoFactorLocked(w)
+		return
+	}
+	if err != nil {
+		log.Printf("Second factor check failed for user %d: %v", userID, err)
+	}
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid code")
+		return
+	}
+
+	if err := s.store.Users.DisableTwoFactor(r.Context(), userID); err != nil {
+		log.Printf("Failed to disable 2fa for user %d: %v", userID, err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to disable two-factor authentication")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"enabled": false})
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTOTPCodeRFC6238Vectors(t *testing.T) {
+	// RFC 6238 appendix B, SHA1 seed, truncated to our 6 digits.
+	secret := []byte("12345678901234567890")
+	cases := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, want := range cases {
+		if got := totpCode(secret, unix/totpPeriod); got != want {
+			t.Errorf("totpCode at %d = %s, want %s", unix, got, want)
+		}
+	}
+}
+
+func TestVerifyTOTPRejectsReplay(t *testing.T) {
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	key, _ := totpEncoding.DecodeString(secret)
+	now := time.Unix(1700000000, 0)
+	code := totpCode(key, now.Unix()/totpPeriod)
+
+	step, ok := verifyTOTP(secret, code, now, 0)
+	if !ok {
+		t.Fatal("expected current code to verify")
+	}
+	if _, ok := verifyTOTP(secret, code, now, step); ok {
+		t.Error("expected replayed code to be rejected")
+	}
+	if _, ok := verifyTOTP(secret, code, now.Add(5*time.Minute), 0); ok {
+		t.Error("expected stale code to be rejected")
+	}
+}
+
+func TestTwoFactorChallengeIsNotASessionToken(t *testing.T) {
+	challenge, err := issueTwoFactorChallenge(42)
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+	userID, err := parseTwoFactorChallenge(challenge)
+	if err != nil || userID != 42 {
+		t.Fatalf("parse = %d, %v", userID, err)
+	}
+
+	session, _ := issueSessionToken(42)
+	if _, err := parseTwoFactorChallenge(session); err == nil {
+		t.Error("session token must not be accepted as a 2fa challenge")
+	}
+}
+
+func TestRecoveryCodesFormat(t *testing.T) {
+	codes, err := generateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	seen := map[string]bool{}
+	for _, c := range codes {
+		if len(c) != 11 || strings.Index(c, "-") != 5 {
+			t.Errorf("unexpected recovery code format %q", c)
+		}
+		seen[c] = true
+	}
+	if len(seen) != recoveryCodeCount {
+		t.Errorf("expected %d unique codes, got %d", recoveryCodeCount, len(seen))
+	}
+}
+
+// Disabling a user between the password and the second factor step stops the login.
+func TestLoginTwoFactorRejectsDisabledUser(t *testing.T) {
+	s, mem, _ := newTestServer(t)
+	ctx := context.Background()
+	userID, err := mem.CreateUser(ctx, "user@example.com", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mem.SetTOTPSecret(ctx, userID, secret); err != nil {
+		t.Fatal(err)
+	}
+	if err := mem.EnableTwoFactor(ctx, userID, 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	challenge, err := issueTwoFactorChallenge(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mem.SetUserDisabled(ctx, userID, true); err != nil {
+		t.Fatal(err)
+	}
+
+	key, _ := totpEncoding.DecodeString(secret)
+	body := fmt.Sprintf(`{"challenge_token":%q,"code":%q}`, challenge, totpCode(key, time.Now().Unix()/totpPeriod))
+	rec := httptest.NewRecorder()
+	s.loginTwoFactorHandler(rec, httptest.NewRequest(http.MethodPost, "/api/v1/login/2fa", strings.NewReader(body)))
+	if rec.Code != http.StatusForbidden || strings.Contains(rec.Body.String(), `"token"`) {
+		t.Errorf("status %d: %s, want 403 without a token", rec.Code, rec.Body.String())
+	}
+}
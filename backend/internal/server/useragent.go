@@ -0,0 +1,87 @@
+package server
+
+import (
+	"regexp"
+	"strings"
+)
+
+// userAgent is what a login's user agent string says about the client. Instagram's
+// apps name the phone model; browsers only name the operating system.
+type userAgent struct {
+	Client        string `json:"client"` // "Instagram" for the apps, otherwise the browser
+	ClientVersion string `json:"client_version"`
+	OS            string `json:"os"`
+	OSVersion     string `json:"os_version"`
+	Model         string `json:"model"` // e.g. "samsung SM-G991B" or "iPhone14,5"; empty for browsers
+}
+
+var (
+	// Instagram 275.0.0.27.98 Android (33/13; 420dpi; 1080x2220; samsung; SM-G991B; o1s; exynos2100; en_GB; 458229237)
+	instagramAndroidUA = regexp.MustCompile(`Instagram ([\d.]+) Android \([^/;]*/([^;]*); [^;]*; [^;]*; ([^;]*); ([^;]*);`)
+	// Instagram 289.0.0.18.109 (iPhone14,5; iOS 16_5; en_US; en-US; scale=3.00; 1170x2532; 489720145)
+	instagramIOSUA = regexp.MustCompile(`Instagram ([\d.]+) \(((?:iPhone|iPad|iPod)[\d,]+); (iOS|iPadOS) ([\d_]+);`)
+
+	// browsers, most specific first: Edge and Opera also claim to be Chrome, and Chrome to be Safari
+	browserUAs = []struct {
+		name string
+		re   *regexp.Regexp
+	}{
+		{"Edge", regexp.MustCompile(`Edg(?:e|A|iOS)?/([\d.]+)`)},
+		{"Opera", regexp.MustCompile(`OPR/([\d.]+)`)},
+		{"Samsung Internet", regexp.MustCompile(`SamsungBrowser/([\d.]+)`)},
+		{"Firefox", regexp.MustCompile(`(?:Firefox|FxiOS)/([\d.]+)`)},
+		{"Chrome", regexp.MustCompile(`(?:Chrome|CriOS)/([\d.]+)`)},
+		{"Safari", regexp.MustCompile(`Version/([\d.]+).*Safari/`)},
+	}
+	osUAs = []struct {
+		name string
+		re   *regexp.Regexp
+	}{
+		{"Windows", regexp.MustCompile(`Windows NT ([\d.]+)`)},
+		{"iOS", regexp.MustCompile(`(?:iPhone|iPad|iPod).*? OS ([\d_]+)`)},
+		{"macOS", regexp.MustCompile(`Mac OS X ([\d_.]+)`)},
+		{"Android", regexp.MustCompile(`Android ([\d.]+)`)},
+		{"ChromeOS", regexp.MustCompile(`CrOS \S+ ([\d.]+)`)},
+		{"Linux", regexp.MustCompile(`Linux()`)},
+	}
+)
+
+// parseUserAgent reads the Instagram app and common browser user agents. Anything it
+// doesn't recognise comes back with empty fields.
+func parseUserAgent(ua string) userAgent {
+	if m := instagramAndroidUA.FindStringSubmatch(ua); m != nil {
+		return userAgent{Client: "Instagram", ClientVersion: m[1], OS: "Android", OSVersion: m[2], Model: strings.TrimSpace(m[3] + " " + m[4])}
+	}
+	if m := instagramIOSUA.FindStringSubmatch(ua); m != nil {
+		return userAgent{Client: "Instagram", ClientVersion: m[1], OS: m[3], OSVersion: strings.ReplaceAll(m[4], "_", "."), Model: m[2]}
+	}
+
+	var parsed userAgent
+	for _, b := range browserUAs {
+		if m := b.re.FindStringSubmatch(ua); m != nil {
+			parsed.Client, parsed.ClientVersion = b.name, m[1]
+			break
+		}
+	}
+	for _, o := range osUAs {
+		if m := o.re.FindStringSubmatch(ua); m != nil {
+			parsed.OS, parsed.OSVersion = o.name, strings.ReplaceAll(m[1], "_", ".")
+			break
+		}
+	}
+	return parsed
+}
+
+// device names the device behind the user agent, leaving out versions so that app and
+// system updates don't make a phone look new: the model where the app reports one,
+// otherwise the browser and system. Empty when the user agent says neither.
+func (ua userAgent) device() string {
+	switch {
+	case ua.Model != "":
+		return ua.Model
+	case ua.Client != "" && ua.OS != "":
+		return ua.Client + " on " + ua.OS
+	default:
+		return ua.Client + ua.OS
+	}
+}
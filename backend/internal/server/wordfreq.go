@@ -0,0 +1,397 @@
+package server
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/Sa-Te/IAV/backend/internal/models"
+	"github.com/Sa-Te/IAV/backend/internal/store"
+)
+
+const (
+	defaultWordsLimit = 50
+	maxWordsLimit     = 500
+	// a text is put down to a language when that language's stopwords in it outnumber
+	// any other's and there are at least this many
+	minLanguageHits = 2
+	unknownLanguage = "und"
+)
+
+var wordsListSpec = listSpec{
+	typed:       true,
+	types:       store.TextSources,
+	dated:       true,
+	extraParams: []string{"conversation", "me", "stopwords"},
+}
+
+// termCount is how often a word, hashtag, mention or emoji was used.
+type termCount struct {
+	Term  string `json:"term"`
+	Count int    `json:"count"`
+}
+
+type languageCount struct {
+	Language string `json:"language"` // a stopwordLists key, or und
+	Texts    int    `json:"texts"`
+}
+
+// wordCounts tallies the terms of a set of texts. Words leave out the stopwords of the
+// chosen languages; hashtags and mentions are lowercase and without their # and @.
+type wordCounts struct {
+	Texts     int
+	Sources   map[string]int
+	Languages map[string]int
+	Words     map[string]int
+	Hashtags  map[string]int
+	Mentions  map[string]int
+	Emoji     map[string]int
+}
+
+func newWordCounts() *wordCounts {
+	return &wordCounts{Sources: map[string]int{}, Languages: map[string]int{}, Words: map[string]int{},
+		Hashtags: map[string]int{}, Mentions: map[string]int{}, Emoji: map[string]int{}}
+}
+
+// add tokenizes one text. Words with a stopword of skip are not counted, but every
+// language's stopwords decide which language the text is in.
+func (c *wordCounts) add(t models.OwnText, skip []string) {
+	c.Texts++
+	c.Sources[t.Source]++
+	hits := map[string]int{}
+	tokenize(t.Text, func(kind, term string) {
+		switch kind {
+		case "hashtag":
+			c.Hashtags[term]++
+		case "mention":
+			c.Mentions[term]++
+		case "emoji":
+			c.Emoji[term]++
+		case "word":
+			stop := false
+			for lang, set := range stopwords {
+				if set[term] {
+					hits[lang]++
+					stop = stop || slices.Contains(skip, lang)
+				}
+			}
+			if !stop {
+				c.Words[term]++
+			}
+		}
+	})
+	c.Languages[languageOf(hits)]++
+}
+
+// languageOf picks the language with the most stopword hits, or und when there are
+// too few or two languages tie.
+func languageOf(hits map[string]int) string {
+	best, bestHits, tied := unknownLanguage, 0, false
+	for lang, n := range hits {
+		switch {
+		case n > bestHits:
+			best, bestHits, tied = lang, n, false
+		case n == bestHits:
+			tied = true
+		}
+	}
+	if bestHits < minLanguageHits || tied {
+		return unknownLanguage
+	}
+	return best
+}
+
+// tokenize calls emit with each hashtag, mention, emoji and word of text. Links are
+// skipped, as are words without a letter. An @ or # directly after a letter or digit,
+// as in an email address, doesn't start a mention or hashtag.
+func tokenize(text string, emit func(kind, term string)) {
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		wordBefore := i > 0 && isWordRune(runes[i-1])
+		switch {
+		case (r == '#' || r == '@') && !wordBefore:
+			j := i + 1
+			for j < len(runes) && isTagRune(r, runes[j]) {
+				j++
+			}
+			term := strings.ToLower(string(runes[i+1 : j]))
+			if r == '@' {
+				term = strings.TrimRight(term, ".")
+			}
+			switch {
+			case term == "":
+				j = i + 1
+			case r == '#':
+				emit("hashtag", term)
+			default:
+				emit("mention", term)
+			}
+			i = j
+		case !wordBefore && isLinkAt(runes, i):
+			for i < len(runes) && !unicode.IsSpace(runes[i]) {
+				i++
+			}
+		case isEmojiStart(runes, i):
+			j := emojiEnd(runes, i)
+			emit("emoji", strings.ReplaceAll(string(runes[i:j]), "\uFE0F", ""))
+			i = j
+		case isWordRune(r):
+			j := i + 1
+			for j < len(runes) && (isWordRune(runes[j]) ||
+				(isApostrophe(runes[j]) && j+1 < len(runes) && unicode.IsLetter(runes[j+1]))) {
+				j++
+			}
+			if word := normalizeWord(string(runes[i:j])); word != "" {
+				emit("word", word)
+			}
+			i = j
+		default:
+			i++
+		}
+	}
+}
+
+// normalizeWord lowercases w and drops elided articles (l'amour) and the English
+// possessive 's. Words without a letter, such as numbers, and single letters come back
+// empty.
+func normalizeWord(w string) string {
+	w = strings.ToLower(strings.Map(func(r rune) rune {
+		if isApostrophe(r) {
+			return '\''
+		}
+		return r
+	}, w))
+	if before, after, ok := strings.Cut(w, "'"); ok && elisions[before] {
+		w = after
+	}
+	if strings.HasSuffix(w, "'s") {
+		w = strings.TrimSuffix(w, "'s")
+	}
+	if len([]rune(w)) < 2 || !strings.ContainsFunc(w, unicode.IsLetter) {
+		return ""
+	}
+	return w
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
+
+func isApostrophe(r rune) bool {
+	return r == '\'' || r == '’'
+}
+
+// isTagRune reports whether r continues a hashtag (mark '#') or mention (mark '@').
+// Instagram usernames are ASCII letters, digits, periods and underscores.
+func isTagRune(mark, r rune) bool {
+	if mark == '@' {
+		return r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '_')
+	}
+	return isWordRune(r) || r == '_'
+}
+
+func isLinkAt(runes []rune, i int) bool {
+	rest := strings.ToLower(string(runes[i:min(i+8, len(runes))]))
+	return strings.HasPrefix(rest, "http://") || strings.HasPrefix(rest, "https://") || strings.HasPrefix(rest, "www.")
+}
+
+// isEmojiStart reports whether runes[i] starts an emoji: a pictograph, or a pair of
+// regional indicators making a flag.
+func isEmojiStart(runes []rune, i int) bool {
+	r := runes[i]
+	if isRegionalIndicator(r) {
+		return i+1 < len(runes) && isRegionalIndicator(runes[i+1])
+	}
+	return isPictograph(r)
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+func isPictograph(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF && !isRegionalIndicator(r) && !isSkinTone(r):
+		return true
+	case r >= 0x2600 && r <= 0x27BF, r >= 0x2B05 && r <= 0x2B55, r >= 0x2300 && r <= 0x23FF:
+		return true
+	}
+	return false
+}
+
+func isSkinTone(r rune) bool {
+	return r >= 0x1F3FB && r <= 0x1F3FF
+}
+
+// emojiEnd returns the end of the emoji starting at runes[i], keeping skin tones,
+// variation selectors, tags and zero-width-joined sequences (👩‍💻) in one piece.
+func emojiEnd(runes []rune, i int) int {
+	if isRegionalIndicator(runes[i]) {
+		return i + 2
+	}
+	j := i + 1
+	for j < len(runes) {
+		r := runes[j]
+		switch {
+		case r == 0xFE0F || r == 0x20E3 || isSkinTone(r) || (r >= 0xE0020 && r <= 0xE007F):
+			j++
+		case r == 0x200D && j+1 < len(runes) && isPictograph(runes[j+1]):
+			j += 2
+		default:
+			return j
+		}
+	}
+	return j
+}
+
+// topTerms returns the n most used terms, ties broken alphabetically.
+func topTerms(counts map[string]int, n int) []termCount {
+	terms := make([]termCount, 0, len(counts))
+	for term, count := range counts {
+		terms = append(terms, termCount{Term: term, Count: count})
+	}
+	slices.SortFunc(terms, func(a, b termCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), strings.Compare(a.Term, b.Term))
+	})
+	return atMost(terms, n)
+}
+
+func total(counts map[string]int) int {
+	n := 0
+	for _, count := range counts {
+		n += count
+	}
+	return n
+}
+
+// parseStopwordLanguages reads the stopwords parameter: comma-separated stopwordLists
+// keys, none, or every language when empty.
+func parseStopwordLanguages(v string) ([]string, error) {
+	switch v {
+	case "":
+		return stopwordLanguages, nil
+	case "none":
+		return nil, nil
+	}
+	var langs []string
+	for _, lang := range strings.Split(v, ",") {
+		lang = strings.ToLower(strings.TrimSpace(lang))
+		if _, ok := stopwordLists[lang]; !ok {
+			return nil, fmt.Errorf("stopwords must be none or a comma-separated list of %s", strings.Join(stopwordLanguages, ", "))
+		}
+		langs = append(langs, lang)
+	}
+	return langs, nil
+}
+
+// getWordsHandler serves /api/v1/insights/words: the most used words, hashtags,
+// mentions and emoji in the account's captions, comments and sent messages, and the
+// languages they are written in.
+func (s *APIServer) getWordsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+	accountID, ok := s.accountFromRequest(w, r, userID)
+	if !ok {
+		return
+	}
+
+	q, ok := listQueryFromRequest(w, r, wordsListSpec)
+	if !ok {
+		return
+	}
+	// the counts are ranked, not paged: there is no next page to resume from
+	if r.URL.Query().Has("cursor") {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("words are not paginated; raise limit (at most %d) instead", maxWordsLimit))
+		return
+	}
+	limit := defaultWordsLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxWordsLimit {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxWordsLimit))
+			return
+		}
+		limit = n
+	}
+	skip, err := parseStopwordLanguages(r.URL.Query().Get("stopwords"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	conversationID := r.URL.Query().Get("conversation")
+	if conversationID != "" {
+		exists, err := s.store.Messages.ConversationExists(ctx, accountID, conversationID)
+		if err != nil {
+			log.Printf("Database query error in getWordsHandler: %v", err)
+			writeJSONError(w, http.StatusInternalServerError, "Failed to count words")
+			return
+		}
+		if !exists {
+			writeJSONError(w, http.StatusNotFound, "Conversation not found")
+			return
+		}
+	}
+
+	me, err := s.ownerName(r, accountID)
+	var texts []models.OwnText
+	if err == nil {
+		texts, err = s.store.Insights.OwnTexts(ctx, accountID, me, conversationID, store.ListOptions{Types: q.Types, From: q.From, To: q.To})
+	}
+	if err != nil {
+		log.Printf("Database query error in getWordsHandler: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to count words")
+		return
+	}
+
+	counts := newWordCounts()
+	for _, t := range texts {
+		counts.add(t, skip)
+	}
+
+	type Response struct {
+		Me        string   `json:"me"`
+		Stopwords []string `json:"stopwords"` // the languages whose stopwords were left out
+		Summary   struct {
+			Texts       int            `json:"texts"`
+			Sources     map[string]int `json:"sources"`
+			Words       int            `json:"words"`
+			UniqueWords int            `json:"unique_words"`
+			Hashtags    int            `json:"hashtags"`
+			Mentions    int            `json:"mentions"`
+			Emoji       int            `json:"emoji"`
+		} `json:"summary"`
+		Languages []languageCount `json:"languages"`
+		Words     []termCount     `json:"words"`
+		Hashtags  []termCount     `json:"hashtags"`
+		Mentions  []termCount     `json:"mentions"`
+		Emoji     []termCount     `json:"emoji"`
+	}
+	resp := Response{Me: me, Stopwords: append(make([]string, 0), skip...), Languages: make([]languageCount, 0, len(counts.Languages)),
+		Words: topTerms(counts.Words, limit), Hashtags: topTerms(counts.Hashtags, limit),
+		Mentions: topTerms(counts.Mentions, limit), Emoji: topTerms(counts.Emoji, limit)}
+	resp.Summary.Texts = counts.Texts
+	resp.Summary.Sources = counts.Sources
+	resp.Summary.Words = total(counts.Words)
+	resp.Summary.UniqueWords = len(counts.Words)
+	resp.Summary.Hashtags = total(counts.Hashtags)
+	resp.Summary.Mentions = total(counts.Mentions)
+	resp.Summary.Emoji = total(counts.Emoji)
+	for _, l := range topTerms(counts.Languages, len(counts.Languages)) {
+		resp.Languages = append(resp.Languages, languageCount{Language: l.Term, Texts: l.Count})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
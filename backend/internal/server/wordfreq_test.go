@@ -0,0 +1,88 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/Sa-Te/IAV/backend/internal/models"
+)
+
+func TestTokenize(t *testing.T) {
+	var got []string
+	tokenize("Sunset at L’Île-Rousse #Corsica #été with @jane.doe. 🌅🌅 👩🏽‍💻 🇫🇷 ❤️ mail me@example.com, see https://x.com/a 2024 it's Anna's",
+		func(kind, term string) { got = append(got, kind+":"+term) })
+	want := []string{"word:sunset", "word:at", "word:île", "word:rousse", "hashtag:corsica", "hashtag:été", "word:with",
+		"mention:jane.doe", "emoji:🌅", "emoji:🌅", "emoji:👩🏽‍💻", "emoji:🇫🇷", "emoji:❤", "word:mail", "word:me", "word:example",
+		"word:com", "word:see", "word:it", "word:anna"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tokenize =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestLanguageOf(t *testing.T) {
+	for text, want := range map[string]string{
+		"I think this is the best day of the summer": "en",
+		"Das ist der schönste Tag des Sommers":       "de",
+		"C'est le plus beau jour de l'été":           "fr",
+		"Sunset":                                     unknownLanguage,
+	} {
+		c := newWordCounts()
+		c.add(models.OwnText{Text: text}, nil)
+		if c.Languages[want] != 1 {
+			t.Errorf("language of %q = %v, want %s", text, c.Languages, want)
+		}
+	}
+}
+
+func TestWordsHandler(t *testing.T) {
+	s, mem, accountID := newTestServer(t)
+	archive := mem.Archive(accountID)
+	archive.Media = []models.MediaItem{{ID: 1, Caption: "The beach and the waves #summer", TakenAt: day(1)}}
+	archive.PostComments = []models.PostComment{{ID: 1, CommentText: "Beach day! 🌊 @jane", CommentedAt: day(2)}}
+	archive.Conversations = []models.MessageConversation{{ID: 1, ConversationID: "jane_123", Participants: "jane, me"}}
+	archive.Messages = []models.Message{
+		{ID: 1, ConversationID: "jane_123", SenderName: "me", Content: "see you at the beach", SentAt: day(3)},
+		{ID: 2, ConversationID: "jane_123", SenderName: "jane", Content: "beach beach beach", SentAt: day(3)},
+	}
+
+	type body struct {
+		Summary struct {
+			Texts   int            `json:"texts"`
+			Sources map[string]int `json:"sources"`
+		} `json:"summary"`
+		Words    []termCount `json:"words"`
+		Hashtags []termCount `json:"hashtags"`
+		Mentions []termCount `json:"mentions"`
+		Emoji    []termCount `json:"emoji"`
+	}
+	get := func(query string) *httptest.ResponseRecorder {
+		return serveAs(testUserID, s.getWordsHandler, httptest.NewRequest(http.MethodGet, "/api/v1/insights/words?me=me&"+query, nil))
+	}
+
+	// jane's messages are not counted, and neither are stopwords
+	b := decodeBody[body](t, get(""))
+	if b.Summary.Texts != 3 || b.Summary.Sources["message"] != 1 || b.Words[0] != (termCount{"beach", 3}) || len(b.Words) != 4 {
+		t.Errorf("body = %+v", b)
+	}
+	if b.Hashtags[0].Term != "summer" || b.Mentions[0].Term != "jane" || b.Emoji[0].Term != "🌊" {
+		t.Errorf("body = %+v", b)
+	}
+
+	b = decodeBody[body](t, get("stopwords=none&type=caption&to=2024-03-01"))
+	if b.Summary.Texts != 1 || len(b.Words) != 4 || b.Words[0] != (termCount{"the", 2}) {
+		t.Errorf("caption only = %+v", b)
+	}
+	b = decodeBody[body](t, get("conversation=jane_123"))
+	if b.Summary.Texts != 1 || len(b.Hashtags) != 0 {
+		t.Errorf("conversation = %+v", b)
+	}
+
+	for query, code := range map[string]int{"conversation=nope": http.StatusNotFound, "stopwords=xx": http.StatusBadRequest,
+		"limit=0": http.StatusBadRequest, "type=story": http.StatusBadRequest, "cursor=x": http.StatusBadRequest} {
+		if rec := get(query); rec.Code != code {
+			t.Errorf("%s: status %d, want %d", query, rec.Code, code)
+		}
+	}
+}
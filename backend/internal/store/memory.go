@@ -0,0 +1,1348 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sa-Te/IAV/backend/internal/models"
+)
+
+// Memory implements every repository in process, for handler tests. Create accounts
+// through the AccountRepository methods and fill each one's data through Archive.
+type Memory struct {
+	mu       sync.Mutex
+	nextID   int
+	accounts []models.IGAccount
+	archives map[int]*MemoryArchive
+	memoryAuth
+}
+
+// MemoryArchive is one Instagram account's data. Rows can be added in any order; the
+// repositories sort them the way Postgres would.
+type MemoryArchive struct {
+	Media         []models.MediaItem
+	ArchivedPosts []models.ArchivedPost
+
+	Connections []models.Connection
+	Hashtags    []models.FollowedHashtag
+	// connectionSeen is when SaveConnections last saw each connection, by id.
+	connectionSeen map[int]time.Time
+
+	// Imports dates each import by id; its snapshot is the SnapshotEntries with its id.
+	Imports         map[int]time.Time
+	SnapshotEntries []models.SnapshotEntry
+
+	Activity      []models.ActivityLog
+	SearchHistory []models.SearchHistoryEntry
+	OffMeta       []models.OffMetaActivity
+
+	Conversations []models.MessageConversation
+	Messages      []models.Message
+
+	Logins          []models.LoginHistory
+	Logouts         []models.LogoutHistory
+	PasswordChanges []models.PasswordChange
+	PrivacyChanges  []models.PrivacyChange
+	AccountStatus   []models.AccountStatusEntry
+	SignupInfo      *models.SignupInfo
+	// IPLocations is what SaveIPLocations saved, by address.
+	IPLocations map[string]models.IPLocation
+
+	Profile        *models.UserProfile
+	ProfileChanges []models.ProfileChange
+	ProfilePhotos  []models.ProfilePhoto
+
+	PostLikes      []models.PostLike
+	CommentLikes   []models.CommentLike
+	StoryLikes     []models.StoryLike
+	PostComments   []models.PostComment
+	ReelComments   []models.ReelComment
+	StoryPolls     []models.StoryPoll
+	StoryQuizzes   []models.StoryQuiz
+	StoryQuestions []models.StoryQuestion
+	StorySliders   []models.StoryEmojiSlider
+	StoryReactions []models.StoryReaction
+
+	SavedMedia      []models.SavedMedia
+	Collections     []models.SavedCollection
+	CollectionItems []models.SavedCollectionItem
+
+	Advertisers         []string
+	AdTopics            []string
+	AIInterests         []models.AIInterest
+	Topics              []models.UserTopic
+	LocationsOfInterest []string
+	InferredLocation    *models.InferredLocation
+}
+
+func NewMemory() *Memory {
+	return &Memory{archives: map[int]*MemoryArchive{}}
+}
+
+// Store returns the repositories backed by m.
+func (m *Memory) Store() *Store {
+	return &Store{
+		Users:        m,
+		Tokens:       m,
+		Shares:       m,
+		Imports:      m,
+		Accounts:     m,
+		Media:        m,
+		Connections:  m,
+		Snapshots:    m,
+		Activity:     m,
+		Messages:     m,
+		Security:     m,
+		Profile:      m,
+		Interactions: m,
+		Saved:        m,
+		Interests:    m,
+		Insights:     m,
+		Search:       m,
+		Timeline:     m,
+	}
+}
+
+// Archive returns the account's data for the test to fill in.
+func (m *Memory) Archive(accountID int) *MemoryArchive {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.archive(accountID)
+}
+
+func (m *Memory) archive(accountID int) *MemoryArchive {
+	a, ok := m.archives[accountID]
+	if !ok {
+		a = &MemoryArchive{}
+		m.archives[accountID] = a
+	}
+	return a
+}
+
+// read runs f on the account's archive with the lock held.
+func read[T any](m *Memory, accountID int, f func(*MemoryArchive) T) (T, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return f(m.archive(accountID)), nil
+}
+
+// listRow is what ListOptions filter and sort a row on. Rows of a table without a type
+// column leave typ empty, and like in SQL the Types filter is then ignored.
+type listRow struct {
+	id       int
+	typ      string
+	at       time.Time
+	username string
+}
+
+// list applies ListOptions the way listTable does in SQL.
+func list[T any](rows []T, opts ListOptions, key func(T) listRow) []T {
+	type keyed struct {
+		row T
+		key listRow
+	}
+	var matched []keyed
+	for _, row := range rows {
+		k := key(row)
+		if len(opts.Types) > 0 && k.typ != "" && !slices.Contains(opts.Types, k.typ) {
+			continue
+		}
+		if (opts.From != nil && k.at.Before(*opts.From)) || (opts.To != nil && !k.at.Before(*opts.To)) {
+			continue
+		}
+		if opts.Username != "" && !strings.Contains(strings.ToLower(k.username), strings.ToLower(opts.Username)) {
+			continue
+		}
+		matched = append(matched, keyed{row, k})
+	}
+
+	compare := func(a, b listRow) int {
+		c := a.at.Compare(b.at)
+		if opts.Sort == SortUsername {
+			c = strings.Compare(a.username, b.username)
+		}
+		if c == 0 {
+			c = cmp.Compare(a.id, b.id)
+		}
+		if !opts.Ascending {
+			c = -c
+		}
+		return c
+	}
+	slices.SortFunc(matched, func(a, b keyed) int { return compare(a.key, b.key) })
+
+	result := make([]T, 0)
+	for _, k := range matched {
+		if opts.After != nil && compare(k.key, listRow{id: opts.After.ID, at: opts.After.At, username: opts.After.Key}) <= 0 {
+			continue
+		}
+		result = append(result, k.row)
+		if opts.Limit > 0 && len(result) > opts.Limit {
+			break
+		}
+	}
+	return result
+}
+
+// newestFirst returns a sorted copy of rows, latest first.
+func newestFirst[T any](rows []T, at func(T) time.Time) []T {
+	result := append(make([]T, 0, len(rows)), rows...)
+	slices.SortStableFunc(result, func(a, b T) int { return at(b).Compare(at(a)) })
+	return result
+}
+
+// byName returns a sorted copy of rows.
+func byName[T any](rows []T, name func(T) string) []T {
+	result := append(make([]T, 0, len(rows)), rows...)
+	slices.SortStableFunc(result, func(a, b T) int { return strings.Compare(name(a), name(b)) })
+	return result
+}
+
+// --- Accounts ---
+
+func (m *Memory) DefaultAccount(ctx context.Context, userID int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, a := range m.accounts {
+		if a.UserID == userID && a.IsDefault {
+			return a.ID, nil
+		}
+	}
+	return m.addAccount(userID, defaultAccountName, true).ID, nil
+}
+
+func (m *Memory) addAccount(userID int, name string, isDefault bool) models.IGAccount {
+	m.nextID++
+	a := models.IGAccount{ID: m.nextID, UserID: userID, Name: name, IsDefault: isDefault, CreatedAt: time.Now()}
+	m.accounts = append(m.accounts, a)
+	return a
+}
+
+func (m *Memory) FindAccount(ctx context.Context, userID int, ref string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	found := 0
+	for _, a := range m.accounts {
+		if a.UserID != userID {
+			continue
+		}
+		if strconv.Itoa(a.ID) == ref {
+			return a.ID, nil
+		}
+		if a.Name == ref && found == 0 {
+			found = a.ID
+		}
+	}
+	if found == 0 {
+		return 0, ErrNotFound
+	}
+	return found, nil
+}
+
+func (m *Memory) ListAccounts(ctx context.Context, userID int) ([]models.IGAccount, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	accounts := make([]models.IGAccount, 0)
+	for _, a := range m.accounts {
+		if a.UserID == userID {
+			accounts = append(accounts, a)
+		}
+	}
+	slices.SortStableFunc(accounts, func(a, b models.IGAccount) int {
+		if a.IsDefault != b.IsDefault {
+			if a.IsDefault {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return accounts, nil
+}
+
+func (m *Memory) CreateAccount(ctx context.Context, userID int, name string) (models.IGAccount, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, a := range m.accounts {
+		if a.UserID == userID && a.Name == name {
+			return models.IGAccount{UserID: userID, Name: name}, ErrConflict
+		}
+	}
+	return m.addAccount(userID, name, false), nil
+}
+
+// ownerOf returns the user an account belongs to; the caller holds the lock.
+func (m *Memory) ownerOf(accountID int) int {
+	for _, a := range m.accounts {
+		if a.ID == accountID {
+			return a.UserID
+		}
+	}
+	return 0
+}
+
+// --- Media ---
+
+func (m *Memory) ListMedia(ctx context.Context, accountID int, opts ListOptions) ([]models.MediaItem, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.MediaItem {
+		return list(a.Media, opts, func(i models.MediaItem) listRow {
+			return listRow{id: i.ID, typ: i.MediaType, at: i.TakenAt}
+		})
+	})
+}
+
+func (m *Memory) FindMedia(ctx context.Context, accountID int, uri string) (models.MediaItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, i := range m.archive(accountID).Media {
+		if i.URI == uri {
+			return i, nil
+		}
+	}
+	return models.MediaItem{}, ErrNotFound
+}
+
+func (m *Memory) ListArchivedPosts(ctx context.Context, accountID int) ([]models.ArchivedPost, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.ArchivedPost {
+		return newestFirst(a.ArchivedPosts, func(p models.ArchivedPost) time.Time { return p.TakenAt })
+	})
+}
+
+// --- Connections ---
+
+func (m *Memory) ListConnections(ctx context.Context, accountID int, opts ListOptions) ([]models.Connection, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.Connection {
+		return list(a.Connections, opts, func(c models.Connection) listRow {
+			return listRow{id: c.ID, typ: c.ConnectionType, at: c.Timestamp, username: c.Username}
+		})
+	})
+}
+
+func (m *Memory) ListFollowedHashtags(ctx context.Context, accountID int) ([]models.FollowedHashtag, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.FollowedHashtag {
+		return byName(a.Hashtags, func(h models.FollowedHashtag) string { return h.Name })
+	})
+}
+
+// --- Snapshots ---
+
+func (m *Memory) ListSnapshots(ctx context.Context, accountID int) ([]models.ConnectionSnapshot, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.ConnectionSnapshot {
+		snapshots := make([]models.ConnectionSnapshot, 0)
+		for id, at := range a.Imports {
+			s := models.ConnectionSnapshot{ImportID: id, TakenAt: at}
+			entries := 0
+			for _, e := range a.SnapshotEntries {
+				if e.ImportID != id {
+					continue
+				}
+				entries++
+				switch e.ConnectionType {
+				case "follower":
+					s.Followers++
+				case "following":
+					s.Following++
+				}
+			}
+			if entries > 0 {
+				snapshots = append(snapshots, s)
+			}
+		}
+		slices.SortFunc(snapshots, func(x, y models.ConnectionSnapshot) int {
+			return cmp.Or(x.TakenAt.Compare(y.TakenAt), cmp.Compare(x.ImportID, y.ImportID))
+		})
+		return snapshots
+	})
+}
+
+func (m *Memory) ListSnapshotEntries(ctx context.Context, accountID, importID int, types []string) ([]models.SnapshotEntry, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.SnapshotEntry {
+		entries := make([]models.SnapshotEntry, 0)
+		for _, e := range a.SnapshotEntries {
+			if e.ImportID == importID && slices.Contains(types, e.ConnectionType) {
+				entries = append(entries, e)
+			}
+		}
+		slices.SortFunc(entries, func(x, y models.SnapshotEntry) int {
+			return cmp.Or(strings.Compare(x.Username, y.Username), strings.Compare(x.ConnectionType, y.ConnectionType))
+		})
+		return entries
+	})
+}
+
+// --- Activity ---
+
+func (m *Memory) ListActivity(ctx context.Context, accountID int, opts ListOptions) ([]models.ActivityLog, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.ActivityLog {
+		return list(a.Activity, opts, func(l models.ActivityLog) listRow {
+			row := listRow{id: l.ID, typ: l.ActivityType, at: l.Timestamp}
+			if l.Author != nil {
+				row.username = *l.Author
+			}
+			return row
+		})
+	})
+}
+
+func (m *Memory) ListSearchHistory(ctx context.Context, accountID int, opts ListOptions) ([]models.SearchHistoryEntry, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.SearchHistoryEntry {
+		return list(a.SearchHistory, opts, func(e models.SearchHistoryEntry) listRow {
+			return listRow{id: e.ID, typ: e.SearchType, at: e.SearchedAt}
+		})
+	})
+}
+
+func (m *Memory) FindSearch(ctx context.Context, accountID, id int) (models.SearchHistoryEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, e := range m.archive(accountID).SearchHistory {
+		if e.ID == id {
+			return e, nil
+		}
+	}
+	return models.SearchHistoryEntry{}, ErrNotFound
+}
+
+func (m *Memory) ListOffMetaActivity(ctx context.Context, accountID int, opts ListOptions) ([]models.OffMetaActivity, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.OffMetaActivity {
+		return list(a.OffMeta, opts, func(o models.OffMetaActivity) listRow {
+			return listRow{id: o.ID, typ: o.EventType, at: o.EventAt}
+		})
+	})
+}
+
+// --- Messages ---
+
+func (m *Memory) ListConversations(ctx context.Context, accountID int) ([]models.MessageConversation, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.MessageConversation {
+		latest := map[string]time.Time{}
+		for _, msg := range a.Messages {
+			if msg.SentAt.After(latest[msg.ConversationID]) {
+				latest[msg.ConversationID] = msg.SentAt
+			}
+		}
+		return newestFirst(a.Conversations, func(c models.MessageConversation) time.Time { return latest[c.ConversationID] })
+	})
+}
+
+func (m *Memory) ConversationExists(ctx context.Context, accountID int, conversationID string) (bool, error) {
+	return read(m, accountID, func(a *MemoryArchive) bool {
+		return slices.ContainsFunc(a.Conversations, func(c models.MessageConversation) bool { return c.ConversationID == conversationID })
+	})
+}
+
+func (m *Memory) ListMessages(ctx context.Context, accountID int, conversationID string, opts ListOptions) ([]models.Message, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.Message {
+		messages := a.Messages
+		if conversationID != "" {
+			messages = nil
+			for _, msg := range a.Messages {
+				if msg.ConversationID == conversationID {
+					messages = append(messages, msg)
+				}
+			}
+		}
+		return list(messages, opts, func(msg models.Message) listRow {
+			return listRow{id: msg.ID, at: msg.SentAt, username: msg.SenderName}
+		})
+	})
+}
+
+// --- Security ---
+
+// firstN keeps the first n rows; n 0 keeps them all.
+func firstN[T any](rows []T, n int) []T {
+	if n > 0 && len(rows) > n {
+		return rows[:n]
+	}
+	return rows
+}
+
+func (m *Memory) ListLogins(ctx context.Context, accountID, limit int) ([]models.LoginHistory, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.LoginHistory {
+		return firstN(newestFirst(a.Logins, func(l models.LoginHistory) time.Time { return l.LoggedInAt }), limit)
+	})
+}
+
+func (m *Memory) ListLogouts(ctx context.Context, accountID, limit int) ([]models.LogoutHistory, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.LogoutHistory {
+		return firstN(newestFirst(a.Logouts, func(l models.LogoutHistory) time.Time { return l.LoggedOutAt }), limit)
+	})
+}
+
+func (m *Memory) ListPasswordChanges(ctx context.Context, accountID int) ([]models.PasswordChange, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.PasswordChange {
+		return newestFirst(a.PasswordChanges, func(c models.PasswordChange) time.Time { return c.ChangedAt })
+	})
+}
+
+func (m *Memory) ListPrivacyChanges(ctx context.Context, accountID int) ([]models.PrivacyChange, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.PrivacyChange {
+		return newestFirst(a.PrivacyChanges, func(c models.PrivacyChange) time.Time { return c.ChangedAt })
+	})
+}
+
+func (m *Memory) ListAccountStatus(ctx context.Context, accountID int) ([]models.AccountStatusEntry, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.AccountStatusEntry {
+		return newestFirst(a.AccountStatus, func(e models.AccountStatusEntry) time.Time { return e.ChangedAt })
+	})
+}
+
+func (m *Memory) SignupInfo(ctx context.Context, accountID int) (*models.SignupInfo, error) {
+	return read(m, accountID, func(a *MemoryArchive) *models.SignupInfo { return a.SignupInfo })
+}
+
+func (m *Memory) UnlocatedIPs(ctx context.Context, accountID int) ([]string, error) {
+	return read(m, accountID, func(a *MemoryArchive) []string {
+		ips := make([]string, 0)
+		add := func(ip string) {
+			if _, located := a.IPLocations[ip]; ip != "" && !located && !slices.Contains(ips, ip) {
+				ips = append(ips, ip)
+			}
+		}
+		for _, l := range a.Logins {
+			add(l.IPAddress)
+		}
+		for _, l := range a.Logouts {
+			add(l.IPAddress)
+		}
+		if a.SignupInfo != nil {
+			add(a.SignupInfo.SignupIP)
+		}
+		slices.Sort(ips)
+		return ips
+	})
+}
+
+func (m *Memory) SaveIPLocations(ctx context.Context, accountID int, locations []models.IPLocation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	a := m.archive(accountID)
+	if a.IPLocations == nil {
+		a.IPLocations = map[string]models.IPLocation{}
+	}
+	for _, l := range locations {
+		a.IPLocations[l.IPAddress] = l
+	}
+	return nil
+}
+
+func (m *Memory) ListIPLocations(ctx context.Context, accountID int) ([]models.IPLocation, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.IPLocation {
+		locations := make([]models.IPLocation, 0, len(a.IPLocations))
+		for _, l := range a.IPLocations {
+			locations = append(locations, l)
+		}
+		slices.SortFunc(locations, func(x, y models.IPLocation) int { return strings.Compare(x.IPAddress, y.IPAddress) })
+		return locations
+	})
+}
+
+// --- Profile ---
+
+func (m *Memory) Profile(ctx context.Context, accountID int) (*models.UserProfile, error) {
+	return read(m, accountID, func(a *MemoryArchive) *models.UserProfile { return a.Profile })
+}
+
+func (m *Memory) ListProfileChanges(ctx context.Context, accountID int) ([]models.ProfileChange, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.ProfileChange {
+		return newestFirst(a.ProfileChanges, func(c models.ProfileChange) time.Time { return c.ChangedAt })
+	})
+}
+
+func (m *Memory) ListProfilePhotos(ctx context.Context, accountID int) ([]models.ProfilePhoto, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.ProfilePhoto {
+		return newestFirst(a.ProfilePhotos, func(p models.ProfilePhoto) time.Time { return p.SetAt })
+	})
+}
+
+// --- Interactions ---
+
+func (m *Memory) ListPostLikes(ctx context.Context, accountID int) ([]models.PostLike, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.PostLike {
+		return newestFirst(a.PostLikes, func(l models.PostLike) time.Time { return l.LikedAt })
+	})
+}
+
+func (m *Memory) ListCommentLikes(ctx context.Context, accountID int) ([]models.CommentLike, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.CommentLike {
+		return newestFirst(a.CommentLikes, func(l models.CommentLike) time.Time { return l.LikedAt })
+	})
+}
+
+func (m *Memory) ListStoryLikes(ctx context.Context, accountID int) ([]models.StoryLike, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.StoryLike {
+		return newestFirst(a.StoryLikes, func(l models.StoryLike) time.Time { return l.LikedAt })
+	})
+}
+
+func (m *Memory) ListPostComments(ctx context.Context, accountID int) ([]models.PostComment, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.PostComment {
+		return newestFirst(a.PostComments, func(c models.PostComment) time.Time { return c.CommentedAt })
+	})
+}
+
+func (m *Memory) ListReelComments(ctx context.Context, accountID int) ([]models.ReelComment, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.ReelComment {
+		return newestFirst(a.ReelComments, func(c models.ReelComment) time.Time { return c.CommentedAt })
+	})
+}
+
+func (m *Memory) FindPostComment(ctx context.Context, accountID, id int) (models.PostComment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, c := range m.archive(accountID).PostComments {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+	return models.PostComment{}, ErrNotFound
+}
+
+func (m *Memory) FindReelComment(ctx context.Context, accountID, id int) (models.ReelComment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, c := range m.archive(accountID).ReelComments {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+	return models.ReelComment{}, ErrNotFound
+}
+
+func (m *Memory) ListStoryPolls(ctx context.Context, accountID int) ([]models.StoryPoll, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.StoryPoll {
+		return newestFirst(a.StoryPolls, func(s models.StoryPoll) time.Time { return s.AnsweredAt })
+	})
+}
+
+func (m *Memory) ListStoryQuizzes(ctx context.Context, accountID int) ([]models.StoryQuiz, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.StoryQuiz {
+		return newestFirst(a.StoryQuizzes, func(s models.StoryQuiz) time.Time { return s.AnsweredAt })
+	})
+}
+
+func (m *Memory) ListStoryQuestions(ctx context.Context, accountID int) ([]models.StoryQuestion, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.StoryQuestion {
+		return newestFirst(a.StoryQuestions, func(s models.StoryQuestion) time.Time { return s.RespondedAt })
+	})
+}
+
+func (m *Memory) ListStoryEmojiSliders(ctx context.Context, accountID int) ([]models.StoryEmojiSlider, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.StoryEmojiSlider {
+		return newestFirst(a.StorySliders, func(s models.StoryEmojiSlider) time.Time { return s.RespondedAt })
+	})
+}
+
+func (m *Memory) ListStoryReactions(ctx context.Context, accountID int) ([]models.StoryReaction, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.StoryReaction {
+		return newestFirst(a.StoryReactions, func(s models.StoryReaction) time.Time { return s.RespondedAt })
+	})
+}
+
+// --- Saved ---
+
+func (m *Memory) ListSavedMedia(ctx context.Context, accountID int) ([]models.SavedMedia, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.SavedMedia {
+		return newestFirst(a.SavedMedia, func(s models.SavedMedia) time.Time { return s.SavedAt })
+	})
+}
+
+func (m *Memory) ListCollections(ctx context.Context, accountID int) ([]models.SavedCollection, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.SavedCollection {
+		collections := append([]models.SavedCollection(nil), a.Collections...)
+		for _, item := range a.CollectionItems {
+			listed := slices.ContainsFunc(collections, func(c models.SavedCollection) bool { return c.CollectionName == item.CollectionName })
+			if !listed {
+				collections = append(collections, models.SavedCollection{UserID: m.ownerOf(accountID), CollectionName: item.CollectionName})
+			}
+		}
+		return byName(collections, func(c models.SavedCollection) string { return c.CollectionName })
+	})
+}
+
+func (m *Memory) Collection(ctx context.Context, accountID int, name string) (models.SavedCollection, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	a := m.archive(accountID)
+
+	c := models.SavedCollection{UserID: m.ownerOf(accountID), CollectionName: name}
+	for _, listed := range a.Collections {
+		if listed.CollectionName == name {
+			c = listed
+		}
+	}
+	itemCount := 0
+	for _, item := range a.CollectionItems {
+		if item.CollectionName == name {
+			itemCount++
+		}
+	}
+	if c.ID == 0 && itemCount == 0 {
+		return c, 0, ErrNotFound
+	}
+	return c, itemCount, nil
+}
+
+func (m *Memory) ListCollectionItems(ctx context.Context, accountID int, collection string, opts ListOptions) ([]models.SavedCollectionItem, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.SavedCollectionItem {
+		items := a.CollectionItems
+		if collection != "" {
+			items = nil
+			for _, item := range a.CollectionItems {
+				if item.CollectionName == collection {
+					items = append(items, item)
+				}
+			}
+		}
+		return list(items, opts, func(item models.SavedCollectionItem) listRow {
+			return listRow{id: item.ID, at: item.AddedAt, username: item.CreatorUsername}
+		})
+	})
+}
+
+// --- Interests ---
+
+func (m *Memory) ListAdvertisers(ctx context.Context, accountID int) ([]string, error) {
+	return read(m, accountID, func(a *MemoryArchive) []string { return append(make([]string, 0), a.Advertisers...) })
+}
+
+func (m *Memory) ListAdTopics(ctx context.Context, accountID int) ([]string, error) {
+	return read(m, accountID, func(a *MemoryArchive) []string { return append(make([]string, 0), a.AdTopics...) })
+}
+
+func (m *Memory) ListAIInterests(ctx context.Context, accountID int) ([]models.AIInterest, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.AIInterest {
+		return append(make([]models.AIInterest, 0), a.AIInterests...)
+	})
+}
+
+func (m *Memory) ListTopics(ctx context.Context, accountID int) ([]models.UserTopic, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.UserTopic {
+		return byName(a.Topics, func(t models.UserTopic) string { return t.TopicName })
+	})
+}
+
+func (m *Memory) ListLocationsOfInterest(ctx context.Context, accountID int) ([]string, error) {
+	return read(m, accountID, func(a *MemoryArchive) []string {
+		return byName(a.LocationsOfInterest, func(s string) string { return s })
+	})
+}
+
+func (m *Memory) InferredLocation(ctx context.Context, accountID int) (*models.InferredLocation, error) {
+	return read(m, accountID, func(a *MemoryArchive) *models.InferredLocation { return a.InferredLocation })
+}
+
+// --- Insights ---
+
+func (m *Memory) ConversationStats(ctx context.Context, accountID int, me string, maxReplyGap time.Duration) ([]models.ConversationStats, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.ConversationStats {
+		byConversation := map[string][]models.Message{}
+		for _, msg := range a.Messages {
+			byConversation[msg.ConversationID] = append(byConversation[msg.ConversationID], msg)
+		}
+		stats := make([]models.ConversationStats, 0, len(byConversation))
+		for id, messages := range byConversation {
+			slices.SortFunc(messages, func(x, y models.Message) int {
+				return cmp.Or(x.SentAt.Compare(y.SentAt), cmp.Compare(x.ID, y.ID))
+			})
+			s := models.ConversationStats{ConversationID: id, Messages: len(messages),
+				FirstMessageAt: messages[0].SentAt, LastMessageAt: messages[len(messages)-1].SentAt}
+			for _, c := range a.Conversations {
+				if c.ConversationID == id {
+					s.Participants = c.Participants
+				}
+			}
+			var yours, theirs float64
+			for i, msg := range messages {
+				if msg.SenderName == me {
+					s.Sent++
+				}
+				if i == 0 {
+					continue
+				}
+				prev := messages[i-1]
+				gap := msg.SentAt.Sub(prev.SentAt)
+				if gap > maxReplyGap {
+					continue
+				}
+				switch {
+				case msg.SenderName == me && prev.SenderName != me:
+					s.YourReplies++
+					yours += gap.Seconds()
+				case msg.SenderName != me && prev.SenderName == me:
+					s.TheirReplies++
+					theirs += gap.Seconds()
+				}
+			}
+			s.Received = s.Messages - s.Sent
+			if s.YourReplies > 0 {
+				avg := yours / float64(s.YourReplies)
+				s.YourAvgReplySeconds = &avg
+			}
+			if s.TheirReplies > 0 {
+				avg := theirs / float64(s.TheirReplies)
+				s.TheirAvgReplySeconds = &avg
+			}
+			stats = append(stats, s)
+		}
+		slices.SortFunc(stats, func(x, y models.ConversationStats) int {
+			return cmp.Or(cmp.Compare(y.Messages, x.Messages), strings.Compare(x.ConversationID, y.ConversationID))
+		})
+		return stats
+	})
+}
+
+func (m *Memory) MessageDays(ctx context.Context, accountID int) ([]models.MessageDay, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.MessageDay {
+		type key struct {
+			conversation string
+			day          time.Time
+		}
+		counts := map[key]int{}
+		for _, msg := range a.Messages {
+			y, mo, d := msg.SentAt.UTC().Date()
+			counts[key{msg.ConversationID, time.Date(y, mo, d, 0, 0, 0, 0, time.UTC)}]++
+		}
+		days := make([]models.MessageDay, 0, len(counts))
+		for k, n := range counts {
+			days = append(days, models.MessageDay{ConversationID: k.conversation, Day: k.day, Messages: n})
+		}
+		slices.SortFunc(days, func(x, y models.MessageDay) int {
+			return cmp.Or(strings.Compare(x.ConversationID, y.ConversationID), x.Day.Compare(y.Day))
+		})
+		return days
+	})
+}
+
+// activityEvents returns the time of every event in the categories.
+func (a *MemoryArchive) activityEvents(categories []string) map[string][]time.Time {
+	events := map[string][]time.Time{}
+	add := func(category string, at time.Time) {
+		if slices.Contains(categories, category) && !at.IsZero() {
+			events[category] = append(events[category], at)
+		}
+	}
+	for _, l := range a.Activity {
+		if l.ActivityType == "ad_viewed" {
+			add(CategoryAds, l.Timestamp)
+		} else {
+			add(CategoryViews, l.Timestamp)
+		}
+	}
+	for _, msg := range a.Messages {
+		add(CategoryMessages, msg.SentAt)
+	}
+	for _, l := range a.PostLikes {
+		add(CategoryLikes, l.LikedAt)
+	}
+	for _, l := range a.CommentLikes {
+		add(CategoryLikes, l.LikedAt)
+	}
+	for _, l := range a.StoryLikes {
+		add(CategoryLikes, l.LikedAt)
+	}
+	for _, c := range a.PostComments {
+		add(CategoryComments, c.CommentedAt)
+	}
+	for _, c := range a.ReelComments {
+		add(CategoryComments, c.CommentedAt)
+	}
+	for _, l := range a.Logins {
+		add(CategoryLogins, l.LoggedInAt)
+	}
+	return events
+}
+
+func (m *Memory) ActivityByHour(ctx context.Context, accountID int, categories []string, loc *time.Location) ([]models.ActivityHour, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.ActivityHour {
+		counts := map[models.ActivityHour]int{}
+		for category, times := range a.activityEvents(categories) {
+			for _, at := range times {
+				local := at.In(loc)
+				counts[models.ActivityHour{Category: category, Weekday: int(local.Weekday()), Hour: local.Hour()}]++
+			}
+		}
+		hours := make([]models.ActivityHour, 0, len(counts))
+		for h, n := range counts {
+			h.Count = n
+			hours = append(hours, h)
+		}
+		slices.SortFunc(hours, func(x, y models.ActivityHour) int {
+			return cmp.Or(strings.Compare(x.Category, y.Category), cmp.Compare(x.Weekday, y.Weekday), cmp.Compare(x.Hour, y.Hour))
+		})
+		return hours
+	})
+}
+
+func (m *Memory) ActivityByMonth(ctx context.Context, accountID int, categories []string, loc *time.Location) ([]models.ActivityMonth, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.ActivityMonth {
+		counts := map[models.ActivityMonth]int{}
+		for category, times := range a.activityEvents(categories) {
+			for _, at := range times {
+				local := at.In(loc)
+				month := time.Date(local.Year(), local.Month(), 1, 0, 0, 0, 0, time.UTC)
+				counts[models.ActivityMonth{Category: category, Month: month}]++
+			}
+		}
+		months := make([]models.ActivityMonth, 0, len(counts))
+		for mo, n := range counts {
+			mo.Count = n
+			months = append(months, mo)
+		}
+		slices.SortFunc(months, func(x, y models.ActivityMonth) int {
+			return cmp.Or(x.Month.Compare(y.Month), strings.Compare(x.Category, y.Category))
+		})
+		return months
+	})
+}
+
+func (m *Memory) AdViewsByAuthor(ctx context.Context, accountID int) ([]models.AdViews, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.AdViews {
+		byAuthor := map[string]*models.AdViews{}
+		for _, l := range a.Activity {
+			if l.ActivityType != "ad_viewed" || l.Author == nil || *l.Author == "" {
+				continue
+			}
+			v := byAuthor[*l.Author]
+			if v == nil {
+				v = &models.AdViews{Author: *l.Author}
+				byAuthor[*l.Author] = v
+			}
+			v.Views++
+			if at := l.Timestamp; !at.IsZero() {
+				if v.FirstSeen == nil || at.Before(*v.FirstSeen) {
+					v.FirstSeen = &at
+				}
+				if v.LastSeen == nil || at.After(*v.LastSeen) {
+					v.LastSeen = &at
+				}
+			}
+		}
+		views := make([]models.AdViews, 0, len(byAuthor))
+		for _, v := range byAuthor {
+			views = append(views, *v)
+		}
+		slices.SortFunc(views, func(x, y models.AdViews) int {
+			return cmp.Or(cmp.Compare(y.Views, x.Views), strings.Compare(x.Author, y.Author))
+		})
+		return views
+	})
+}
+
+func (m *Memory) OffMetaApps(ctx context.Context, accountID int) ([]models.OffMetaApp, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.OffMetaApp {
+		byApp := map[string]*models.OffMetaApp{}
+		for _, o := range a.OffMeta {
+			app := byApp[o.AppName]
+			if app == nil {
+				app = &models.OffMetaApp{AppName: o.AppName, FirstSeen: o.EventAt, LastSeen: o.EventAt}
+				byApp[o.AppName] = app
+			}
+			app.Events++
+			if !slices.Contains(app.EventTypes, o.EventType) {
+				app.EventTypes = append(app.EventTypes, o.EventType)
+			}
+			if o.EventAt.Before(app.FirstSeen) {
+				app.FirstSeen = o.EventAt
+			}
+			if o.EventAt.After(app.LastSeen) {
+				app.LastSeen = o.EventAt
+			}
+		}
+		apps := make([]models.OffMetaApp, 0, len(byApp))
+		for _, app := range byApp {
+			slices.Sort(app.EventTypes)
+			apps = append(apps, *app)
+		}
+		slices.SortFunc(apps, func(x, y models.OffMetaApp) int {
+			return cmp.Or(cmp.Compare(y.Events, x.Events), strings.Compare(x.AppName, y.AppName))
+		})
+		return apps
+	})
+}
+
+// interactionEvent is one interaction with the account named by username.
+type interactionEvent struct {
+	username string
+	models.Interaction
+}
+
+// interactionEvents lists every interaction behind InteractionKinds, usernames lowercased.
+func (a *MemoryArchive) interactionEvents(me string) []interactionEvent {
+	var events []interactionEvent
+	add := func(kind, username string, id int, at time.Time, detail *string) {
+		if username != "" {
+			events = append(events, interactionEvent{strings.ToLower(username), models.Interaction{Kind: kind, ID: id, At: at, Detail: detail}})
+		}
+	}
+	text := func(s string) *string { return &s }
+	for _, l := range a.PostLikes {
+		add(InteractionPostLike, l.CreatorUsername, l.ID, l.LikedAt, text(l.PostURL))
+	}
+	for _, l := range a.CommentLikes {
+		add(InteractionCommentLike, l.OwnerUsername, l.ID, l.LikedAt, text(l.PostURL))
+	}
+	for _, l := range a.StoryLikes {
+		add(InteractionStoryLike, l.CreatorUsername, l.ID, l.LikedAt, nil)
+	}
+	for _, c := range a.PostComments {
+		add(InteractionPostComment, c.PostOwnerUsername, c.ID, c.CommentedAt, text(c.CommentText))
+	}
+	for _, c := range a.ReelComments {
+		add(InteractionReelComment, c.ReelOwnerUsername, c.ID, c.CommentedAt, text(c.CommentText))
+	}
+	for _, p := range a.StoryPolls {
+		add(InteractionStoryPoll, p.CreatorUsername, p.ID, p.AnsweredAt, text(p.PollAnswer))
+	}
+	for _, q := range a.StoryQuizzes {
+		add(InteractionStoryQuiz, q.CreatorUsername, q.ID, q.AnsweredAt, text(q.QuizAnswer))
+	}
+	for _, q := range a.StoryQuestions {
+		add(InteractionStoryQuestion, q.CreatorUsername, q.ID, q.RespondedAt, nil)
+	}
+	for _, s := range a.StorySliders {
+		add(InteractionStorySlider, s.CreatorUsername, s.ID, s.RespondedAt, text(strconv.FormatFloat(s.SliderValue, 'f', -1, 64)))
+	}
+	for _, r := range a.StoryReactions {
+		add(InteractionStoryReaction, r.CreatorUsername, r.ID, r.RespondedAt, nil)
+	}
+	oneToOne := map[string]bool{}
+	for _, c := range a.Conversations {
+		oneToOne[c.ConversationID] = len(strings.Split(c.Participants, ", ")) == 2
+	}
+	for _, msg := range a.Messages {
+		if msg.SenderName == me && oneToOne[msg.ConversationID] {
+			add(InteractionMessage, conversationUsername.ReplaceAllString(msg.ConversationID, ""), msg.ID, msg.SentAt, text(msg.Content))
+		}
+	}
+	return events
+}
+
+// conversationUsername strips the thread number from a conversation id, as Postgres'
+// regexp_replace does.
+var conversationUsername = regexp.MustCompile(`_[0-9]+$`)
+
+func (m *Memory) InteractionCounts(ctx context.Context, accountID int, me string) ([]models.InteractionCount, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.InteractionCount {
+		type key struct{ username, kind string }
+		byKey := map[key]*models.InteractionCount{}
+		for _, e := range a.interactionEvents(me) {
+			c := byKey[key{e.username, e.Kind}]
+			if c == nil {
+				c = &models.InteractionCount{Username: e.username, Kind: e.Kind, FirstAt: e.At, LastAt: e.At}
+				byKey[key{e.username, e.Kind}] = c
+			}
+			c.Count++
+			if e.At.Before(c.FirstAt) {
+				c.FirstAt = e.At
+			}
+			if e.At.After(c.LastAt) {
+				c.LastAt = e.At
+			}
+		}
+		counts := make([]models.InteractionCount, 0, len(byKey))
+		for _, c := range byKey {
+			counts = append(counts, *c)
+		}
+		slices.SortFunc(counts, func(x, y models.InteractionCount) int {
+			return cmp.Or(strings.Compare(x.Username, y.Username), strings.Compare(x.Kind, y.Kind))
+		})
+		return counts
+	})
+}
+
+func (m *Memory) ListInteractions(ctx context.Context, accountID int, me, username string, opts ListOptions) ([]models.Interaction, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.Interaction {
+		interactions := make([]models.Interaction, 0)
+		for _, e := range a.interactionEvents(me) {
+			if e.username == strings.ToLower(username) {
+				interactions = append(interactions, e.Interaction)
+			}
+		}
+		compare := func(x, y models.Interaction) int {
+			return cmp.Or(x.At.Compare(y.At), strings.Compare(x.Kind, y.Kind), cmp.Compare(x.ID, y.ID))
+		}
+		slices.SortFunc(interactions, compare)
+		if opts.After != nil {
+			after := models.Interaction{At: opts.After.At, Kind: opts.After.Key, ID: opts.After.ID}
+			interactions = slices.DeleteFunc(interactions, func(i models.Interaction) bool { return compare(i, after) <= 0 })
+		}
+		if opts.Limit > 0 {
+			interactions = firstN(interactions, opts.Limit+1)
+		}
+		return interactions
+	})
+}
+
+func (m *Memory) OnThisDay(ctx context.Context, accountID int, year int, month time.Month, days []int, loc *time.Location) ([]models.MemoryItem, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.MemoryItem {
+		items := make([]models.MemoryItem, 0)
+		add := func(item models.MemoryItem) {
+			local := item.At.In(loc)
+			if local.Year() < year && local.Month() == month && slices.Contains(days, local.Day()) {
+				items = append(items, item)
+			}
+		}
+		text := func(s string) *string { return &s }
+		for _, media := range a.Media {
+			typ := media.MediaType
+			if typ == "" {
+				typ = MemoryPost
+			}
+			add(models.MemoryItem{Type: typ, ID: media.ID, At: media.TakenAt, URI: text(media.URI), Text: text(media.Caption)})
+		}
+		for _, post := range a.ArchivedPosts {
+			if !post.TakenAt.IsZero() {
+				add(models.MemoryItem{Type: MemoryArchivedPost, ID: post.ID, At: post.TakenAt, URI: text(post.URI), Text: text(post.Caption)})
+			}
+		}
+		for _, photo := range a.ProfilePhotos {
+			add(models.MemoryItem{Type: MemoryProfilePhoto, ID: photo.ID, At: photo.SetAt, URI: text(photo.PhotoURI)})
+		}
+		first := map[string]models.Message{}
+		for _, msg := range a.Messages {
+			f, ok := first[msg.ConversationID]
+			if !ok || msg.SentAt.Before(f.SentAt) || (msg.SentAt.Equal(f.SentAt) && msg.ID < f.ID) {
+				first[msg.ConversationID] = msg
+			}
+		}
+		for id, msg := range first {
+			item := models.MemoryItem{Type: MemoryFirstMessage, ID: msg.ID, At: msg.SentAt, Text: text(msg.Content), ConversationID: text(id)}
+			for _, c := range a.Conversations {
+				if c.ConversationID == id {
+					item.Participants = text(c.Participants)
+				}
+			}
+			add(item)
+		}
+		slices.SortFunc(items, func(x, y models.MemoryItem) int {
+			return cmp.Or(x.At.Compare(y.At), strings.Compare(x.Type, y.Type), cmp.Compare(x.ID, y.ID))
+		})
+		return items
+	})
+}
+
+func (m *Memory) OwnTexts(ctx context.Context, accountID int, me, conversationID string, opts ListOptions) ([]models.OwnText, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.OwnText {
+		texts := make([]models.OwnText, 0)
+		add := func(t models.OwnText) {
+			if t.Text == "" || (len(opts.Types) > 0 && !slices.Contains(opts.Types, t.Source)) ||
+				(opts.From != nil && t.At.Before(*opts.From)) || (opts.To != nil && !t.At.Before(*opts.To)) {
+				return
+			}
+			texts = append(texts, t)
+		}
+		if conversationID == "" {
+			for _, media := range a.Media {
+				add(models.OwnText{Source: TextCaption, ID: media.ID, At: media.TakenAt, Text: media.Caption})
+			}
+			for _, c := range a.PostComments {
+				add(models.OwnText{Source: TextPostComment, ID: c.ID, At: c.CommentedAt, Text: c.CommentText})
+			}
+			for _, c := range a.ReelComments {
+				add(models.OwnText{Source: TextReelComment, ID: c.ID, At: c.CommentedAt, Text: c.CommentText})
+			}
+		}
+		for _, msg := range a.Messages {
+			if msg.SenderName == me && (conversationID == "" || msg.ConversationID == conversationID) {
+				add(models.OwnText{Source: TextMessage, ID: msg.ID, At: msg.SentAt, Text: msg.Content})
+			}
+		}
+		slices.SortFunc(texts, func(x, y models.OwnText) int {
+			return cmp.Or(x.At.Compare(y.At), strings.Compare(x.Source, y.Source), cmp.Compare(x.ID, y.ID))
+		})
+		return texts
+	})
+}
+
+// --- Search ---
+
+// Search matches the words of the query as case-insensitive substrings and leaves out
+// hits with an -excluded word. Unlike websearch_to_tsquery it treats a quoted phrase as
+// separate words and ignores OR; rank is the number of matches.
+func (m *Memory) Search(ctx context.Context, accountID int, query string, opts ListOptions) ([]models.SearchHit, error) {
+	var include, exclude []string
+	for _, word := range strings.Fields(strings.ToLower(strings.ReplaceAll(query, `"`, " "))) {
+		switch {
+		case word == "or":
+		case strings.HasPrefix(word, "-") && len(word) > 1:
+			exclude = append(exclude, word[1:])
+		default:
+			include = append(include, word)
+		}
+	}
+	if len(include) == 0 {
+		return make([]models.SearchHit, 0), nil
+	}
+	patterns := make([]string, len(include))
+	for i, word := range include {
+		patterns[i] = regexp.QuoteMeta(word)
+	}
+	match := regexp.MustCompile("(?i)" + strings.Join(patterns, "|"))
+
+	return read(m, accountID, func(a *MemoryArchive) []models.SearchHit {
+		var hits []models.SearchHit
+		add := func(typ string, id int, at time.Time, body, label, ref string) {
+			lower := strings.ToLower(body)
+			for _, word := range include {
+				if !strings.Contains(lower, word) {
+					return
+				}
+			}
+			for _, word := range exclude {
+				if strings.Contains(lower, word) {
+					return
+				}
+			}
+			hits = append(hits, models.SearchHit{Type: typ, ID: id, At: &at, Label: label, Ref: ref,
+				Rank:    float32(len(match.FindAllStringIndex(body, -1))),
+				Snippet: match.ReplaceAllString(body, HighlightStart+"$0"+HighlightStop)})
+		}
+		for _, i := range a.Media {
+			add(SearchCaption, i.ID, i.TakenAt, i.Caption, i.MediaType, i.URI)
+		}
+		for _, msg := range a.Messages {
+			add(SearchMessage, msg.ID, msg.SentAt, msg.Content, msg.SenderName, msg.ConversationID)
+		}
+		for _, c := range a.PostComments {
+			add(SearchPostComment, c.ID, c.CommentedAt, c.CommentText, c.PostOwnerUsername, "")
+		}
+		for _, c := range a.ReelComments {
+			add(SearchReelComment, c.ID, c.CommentedAt, c.CommentText, c.ReelOwnerUsername, "")
+		}
+		for _, s := range a.SearchHistory {
+			add(SearchHistory, s.ID, s.SearchedAt, s.SearchQuery, s.SearchType, "")
+		}
+		for _, s := range a.SavedMedia {
+			add(SearchSavedMedia, s.ID, s.SavedAt, s.CreatorUsername+" "+s.PostURL, s.CreatorUsername, s.PostURL)
+		}
+		for _, i := range a.CollectionItems {
+			add(SearchCollectionItem, i.ID, i.AddedAt, i.CollectionName+" "+i.CreatorUsername+" "+i.ItemURL, i.CollectionName, i.ItemURL)
+		}
+
+		hits = list(hits, ListOptions{Types: opts.Types, From: opts.From, To: opts.To}, func(h models.SearchHit) listRow {
+			return listRow{id: h.ID, typ: h.Type, at: *h.At}
+		})
+		slices.SortStableFunc(hits, func(x, y models.SearchHit) int { return cmp.Compare(y.Rank, x.Rank) })
+		return firstN(hits, opts.Limit)
+	})
+}
+
+// --- Timeline ---
+
+func (m *Memory) Timeline(ctx context.Context, accountID int, opts ListOptions) ([]models.TimelineEvent, error) {
+	return read(m, accountID, func(a *MemoryArchive) []models.TimelineEvent {
+		var events []models.TimelineEvent
+		optional := func(s string) *string {
+			if s == "" {
+				return nil
+			}
+			return &s
+		}
+		add := func(source, typ, subtype string, id int, at time.Time, username, text string) {
+			events = append(events, models.TimelineEvent{Type: typ, Subtype: optional(subtype), ID: id, At: at,
+				Username: optional(username), Text: optional(text), Source: source})
+		}
+		for _, i := range a.Media {
+			add("media_items", i.MediaType, "", i.ID, i.TakenAt, "", i.Caption)
+		}
+		for _, l := range a.PostLikes {
+			add("post_likes", TimelineLike, "post", l.ID, l.LikedAt, l.CreatorUsername, l.PostURL)
+		}
+		for _, l := range a.CommentLikes {
+			add("comment_likes", TimelineLike, "comment", l.ID, l.LikedAt, l.OwnerUsername, l.PostURL)
+		}
+		for _, l := range a.StoryLikes {
+			add("story_likes", TimelineLike, "story", l.ID, l.LikedAt, l.CreatorUsername, "")
+		}
+		for _, c := range a.PostComments {
+			add("post_comments", TimelineComment, "post", c.ID, c.CommentedAt, c.PostOwnerUsername, c.CommentText)
+		}
+		for _, c := range a.ReelComments {
+			add("reel_comments", TimelineComment, "reel", c.ID, c.CommentedAt, c.ReelOwnerUsername, c.CommentText)
+		}
+		for _, msg := range a.Messages {
+			add("messages", TimelineMessage, msg.ConversationID, msg.ID, msg.SentAt, msg.SenderName, msg.Content)
+		}
+		for _, s := range a.StoryPolls {
+			add("story_polls", TimelineStoryInteraction, "poll", s.ID, s.AnsweredAt, s.CreatorUsername, s.PollAnswer)
+		}
+		for _, s := range a.StoryQuizzes {
+			add("story_quizzes", TimelineStoryInteraction, "quiz", s.ID, s.AnsweredAt, s.CreatorUsername, s.QuizAnswer)
+		}
+		for _, s := range a.StoryQuestions {
+			add("story_questions", TimelineStoryInteraction, "question", s.ID, s.RespondedAt, s.CreatorUsername, "")
+		}
+		for _, s := range a.StorySliders {
+			add("story_emoji_sliders", TimelineStoryInteraction, "emoji_slider", s.ID, s.RespondedAt, s.CreatorUsername, strconv.FormatFloat(s.SliderValue, 'f', -1, 64))
+		}
+		for _, s := range a.StoryReactions {
+			add("story_reactions", TimelineStoryInteraction, "reaction", s.ID, s.RespondedAt, s.CreatorUsername, "")
+		}
+		for _, c := range a.Connections {
+			add("connections", TimelineConnection, c.ConnectionType, c.ID, c.Timestamp, c.Username, "")
+		}
+		for _, s := range a.SearchHistory {
+			add("search_history", TimelineSearch, s.SearchType, s.ID, s.SearchedAt, "", s.SearchQuery)
+		}
+		for _, s := range a.SavedMedia {
+			add("saved_media", TimelineSaved, "", s.ID, s.SavedAt, s.CreatorUsername, s.PostURL)
+		}
+		for _, l := range a.Activity {
+			if !l.Timestamp.IsZero() {
+				events = append(events, models.TimelineEvent{Type: TimelineActivity, Subtype: optional(l.ActivityType), ID: l.ID,
+					At: l.Timestamp, Username: l.Author, Text: l.Details, Source: "activity_log"})
+			}
+		}
+		for _, l := range a.Logins {
+			add("login_history", TimelineLogin, "", l.ID, l.LoggedInAt, "", l.IPAddress)
+		}
+		for _, l := range a.Logouts {
+			add("logout_history", TimelineLogout, "", l.ID, l.LoggedOutAt, "", l.IPAddress)
+		}
+		for _, c := range a.PasswordChanges {
+			add("password_change_history", TimelinePasswordChange, "", c.ID, c.ChangedAt, "", "")
+		}
+		for _, c := range a.PrivacyChanges {
+			add("privacy_changes", TimelinePrivacyChange, c.PrivacyStatus, c.ID, c.ChangedAt, "", "")
+		}
+		for _, e := range a.AccountStatus {
+			add("account_status_history", TimelineAccountStatus, e.ActivationType, e.ID, e.ChangedAt, "", e.Reason)
+		}
+		for _, c := range a.ProfileChanges {
+			add("profile_changes", TimelineProfileChange, c.FieldChanged, c.ID, c.ChangedAt, "", c.NewValue)
+		}
+
+		events = list(events, ListOptions{Types: opts.Types, From: opts.From, To: opts.To, Username: opts.Username},
+			func(e models.TimelineEvent) listRow {
+				row := listRow{id: e.ID, typ: e.Type, at: e.At}
+				if e.Username != nil {
+					row.username = *e.Username
+				}
+				return row
+			})
+		compare := func(x, y models.TimelineEvent) int {
+			c := cmp.Or(x.At.Compare(y.At), strings.Compare(x.Source, y.Source), cmp.Compare(x.ID, y.ID))
+			if !opts.Ascending {
+				c = -c
+			}
+			return c
+		}
+		slices.SortFunc(events, compare)
+		if opts.After != nil {
+			after := models.TimelineEvent{At: opts.After.At, Source: opts.After.Key, ID: opts.After.ID}
+			events = slices.DeleteFunc(events, func(e models.TimelineEvent) bool { return compare(e, after) <= 0 })
+		}
+		if opts.Limit > 0 {
+			events = firstN(events, opts.Limit+1)
+		}
+		return events
+	})
+}
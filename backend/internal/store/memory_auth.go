@@ -0,0 +1,390 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/Sa-Te/IAV/backend/internal/models"
+)
+
+// memoryAuth is what Memory keeps of the server's own records.
+type memoryAuth struct {
+	users         []models.User
+	twoFactor     map[int]*memoryTwoFactor
+	tokens        []memoryToken
+	shares        []models.ShareLink
+	shareAccesses map[int][]models.ShareLinkAccess
+}
+
+type memoryTwoFactor struct {
+	attempts      int
+	lockedUntil   time.Time
+	recoveryCodes []models.RecoveryCode
+	used          map[int]bool
+}
+
+type memoryToken struct {
+	models.APIToken
+	hash string
+}
+
+// user returns the user's record for changing in place; the caller holds the lock.
+func (m *Memory) user(userID int) (*models.User, error) {
+	for i := range m.users {
+		if m.users[i].ID == userID {
+			return &m.users[i], nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *Memory) twoFactorOf(userID int) *memoryTwoFactor {
+	if m.twoFactor == nil {
+		m.twoFactor = map[int]*memoryTwoFactor{}
+	}
+	tf, ok := m.twoFactor[userID]
+	if !ok {
+		tf = &memoryTwoFactor{used: map[int]bool{}}
+		m.twoFactor[userID] = tf
+	}
+	return tf
+}
+
+// --- Users ---
+
+// CreateUser checks for an admin and adds the user under one lock, as the Postgres
+// store does with its advisory lock, so racing sign-ups can't both become admin.
+func (m *Memory) CreateUser(ctx context.Context, email, passwordHash string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	role := "admin"
+	for _, u := range m.users {
+		if u.Email == email {
+			return 0, ErrConflict
+		}
+		if u.Role == "admin" {
+			role = "user"
+		}
+	}
+	m.nextID++
+	m.users = append(m.users, models.User{ID: m.nextID, Email: email, PasswordHash: passwordHash, Role: role, CreatedAt: time.Now()})
+	return m.nextID, nil
+}
+
+func (m *Memory) User(ctx context.Context, userID int) (models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, err := m.user(userID)
+	if err != nil {
+		return models.User{}, err
+	}
+	return *u, nil
+}
+
+func (m *Memory) UserByEmail(ctx context.Context, email string) (models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, u := range m.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return models.User{}, ErrNotFound
+}
+
+func (m *Memory) ListUsers(ctx context.Context) ([]models.AdminUser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	users := make([]models.AdminUser, 0, len(m.users))
+	for _, u := range m.users {
+		au := models.AdminUser{ID: u.ID, Email: u.Email, Role: u.Role, CreatedAt: u.CreatedAt, DisabledAt: u.DisabledAt}
+		for _, a := range m.accounts {
+			if a.UserID != u.ID {
+				continue
+			}
+			au.AccountCount++
+			for _, at := range m.archive(a.ID).Imports {
+				au.ImportCount++
+				if au.LastImportAt == nil || at.After(*au.LastImportAt) {
+					au.LastImportAt = &at
+				}
+			}
+		}
+		users = append(users, au)
+	}
+	slices.SortFunc(users, func(a, b models.AdminUser) int { return a.ID - b.ID })
+	return users, nil
+}
+
+func (m *Memory) SetUserDisabled(ctx context.Context, userID int, disabled bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, err := m.user(userID)
+	if err != nil {
+		return err
+	}
+	if !disabled {
+		u.DisabledAt = nil
+	} else if u.DisabledAt == nil {
+		now := time.Now()
+		u.DisabledAt = &now
+	}
+	return nil
+}
+
+func (m *Memory) DeleteUser(ctx context.Context, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := m.user(userID); err != nil {
+		return err
+	}
+	m.users = slices.DeleteFunc(m.users, func(u models.User) bool { return u.ID == userID })
+	m.accounts = slices.DeleteFunc(m.accounts, func(a models.IGAccount) bool {
+		if a.UserID == userID {
+			delete(m.archives, a.ID)
+		}
+		return a.UserID == userID
+	})
+	m.tokens = slices.DeleteFunc(m.tokens, func(t memoryToken) bool { return t.UserID == userID })
+	m.shares = slices.DeleteFunc(m.shares, func(l models.ShareLink) bool { return l.UserID == userID })
+	delete(m.twoFactor, userID)
+	return nil
+}
+
+// --- Second factor ---
+
+func (m *Memory) SetTOTPSecret(ctx context.Context, userID int, secret string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, err := m.user(userID)
+	if err != nil {
+		return err
+	}
+	u.TOTPSecret, u.TOTPLastStep = &secret, 0
+	return nil
+}
+
+func (m *Memory) EnableTwoFactor(ctx context.Context, userID int, step int64, recoveryCodeHashes []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, err := m.user(userID)
+	if err != nil {
+		return err
+	}
+	tf := m.twoFactorOf(userID)
+	tf.recoveryCodes = nil
+	for _, hash := range recoveryCodeHashes {
+		m.nextID++
+		tf.recoveryCodes = append(tf.recoveryCodes, models.RecoveryCode{ID: m.nextID, CodeHash: hash})
+	}
+	u.TOTPEnabled, u.TOTPLastStep = true, step
+	return nil
+}
+
+func (m *Memory) DisableTwoFactor(ctx context.Context, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, err := m.user(userID)
+	if err != nil {
+		return err
+	}
+	u.TOTPEnabled, u.TOTPSecret, u.TOTPLastStep = false, nil, 0
+	m.twoFactorOf(userID).recoveryCodes = nil
+	return nil
+}
+
+func (m *Memory) ReserveTwoFactorAttempt(ctx context.Context, userID, maxAttempts int, lockout time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tf := m.twoFactorOf(userID)
+	now := time.Now()
+	if now.Before(tf.lockedUntil) {
+		return ErrLocked
+	}
+	if !tf.lockedUntil.IsZero() {
+		tf.attempts, tf.lockedUntil = 0, time.Time{}
+	}
+	tf.attempts++
+	if tf.attempts >= maxAttempts {
+		tf.lockedUntil = now.Add(lockout)
+	}
+	return nil
+}
+
+func (m *Memory) ResetTwoFactorAttempts(ctx context.Context, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tf := m.twoFactorOf(userID)
+	tf.attempts, tf.lockedUntil = 0, time.Time{}
+	return nil
+}
+
+func (m *Memory) AcceptTOTPStep(ctx context.Context, userID int, step int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, err := m.user(userID)
+	if err != nil || u.TOTPLastStep >= step {
+		return false, err
+	}
+	u.TOTPLastStep = step
+	return true, nil
+}
+
+func (m *Memory) UnusedRecoveryCodes(ctx context.Context, userID int) ([]models.RecoveryCode, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tf := m.twoFactorOf(userID)
+	codes := make([]models.RecoveryCode, 0)
+	for _, c := range tf.recoveryCodes {
+		if !tf.used[c.ID] {
+			codes = append(codes, c)
+		}
+	}
+	return codes, nil
+}
+
+func (m *Memory) UseRecoveryCode(ctx context.Context, codeID int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, tf := range m.twoFactor {
+		for _, c := range tf.recoveryCodes {
+			if c.ID == codeID && !tf.used[codeID] {
+				tf.used[codeID] = true
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// --- API tokens ---
+
+func (m *Memory) CreateAPIToken(ctx context.Context, t models.APIToken, hash string) (models.APIToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	t.ID, t.CreatedAt = m.nextID, time.Now()
+	m.tokens = append(m.tokens, memoryToken{t, hash})
+	return t, nil
+}
+
+func (m *Memory) LiveAPIToken(ctx context.Context, hash string) (models.APIToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, t := range m.tokens {
+		if t.hash == hash && t.RevokedAt == nil && (t.ExpiresAt == nil || t.ExpiresAt.After(time.Now())) {
+			return t.APIToken, nil
+		}
+	}
+	return models.APIToken{}, ErrNotFound
+}
+
+func (m *Memory) TouchAPIToken(ctx context.Context, tokenID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.tokens {
+		if m.tokens[i].ID == tokenID {
+			now := time.Now()
+			m.tokens[i].LastUsedAt = &now
+		}
+	}
+	return nil
+}
+
+func (m *Memory) ListAPITokens(ctx context.Context, userID int) ([]models.APIToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tokens := make([]models.APIToken, 0)
+	for _, t := range m.tokens {
+		if t.UserID == userID {
+			tokens = append(tokens, t.APIToken)
+		}
+	}
+	return newestFirst(tokens, func(t models.APIToken) time.Time { return t.CreatedAt }), nil
+}
+
+func (m *Memory) RevokeAPIToken(ctx context.Context, userID, tokenID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.tokens {
+		if t := &m.tokens[i]; t.ID == tokenID && t.UserID == userID && t.RevokedAt == nil {
+			now := time.Now()
+			t.RevokedAt = &now
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// --- Share links ---
+
+func (m *Memory) CreateShareLink(ctx context.Context, l models.ShareLink) (models.ShareLink, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	l.ID, l.CreatedAt = m.nextID, time.Now()
+	m.shares = append(m.shares, l)
+	return l, nil
+}
+
+func (m *Memory) LiveShareLink(ctx context.Context, linkID int) (models.ShareLink, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, l := range m.shares {
+		if l.ID != linkID || l.RevokedAt != nil || !l.ExpiresAt.After(time.Now()) {
+			continue
+		}
+		if owner, err := m.user(l.UserID); err == nil && owner.DisabledAt != nil {
+			break
+		}
+		return l, nil
+	}
+	return models.ShareLink{}, ErrNotFound
+}
+
+func (m *Memory) ListShareLinks(ctx context.Context, userID int) ([]models.ShareLink, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	links := make([]models.ShareLink, 0)
+	for _, l := range m.shares {
+		if l.UserID == userID {
+			links = append(links, l)
+		}
+	}
+	return newestFirst(links, func(l models.ShareLink) time.Time { return l.CreatedAt }), nil
+}
+
+func (m *Memory) RevokeShareLink(ctx context.Context, userID, linkID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.shares {
+		if l := &m.shares[i]; l.ID == linkID && l.UserID == userID && l.RevokedAt == nil {
+			now := time.Now()
+			l.RevokedAt = &now
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (m *Memory) LogShareAccess(ctx context.Context, linkID int, access models.ShareLinkAccess) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.shareAccesses == nil {
+		m.shareAccesses = map[int][]models.ShareLinkAccess{}
+	}
+	m.nextID++
+	access.ID, access.AccessedAt = m.nextID, time.Now()
+	m.shareAccesses[linkID] = append(m.shareAccesses[linkID], access)
+	return nil
+}
+
+func (m *Memory) ListShareAccess(ctx context.Context, userID, linkID, limit int) ([]models.ShareLinkAccess, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !slices.ContainsFunc(m.shares, func(l models.ShareLink) bool { return l.ID == linkID && l.UserID == userID }) {
+		return nil, ErrNotFound
+	}
+	return firstN(newestFirst(m.shareAccesses[linkID], func(a models.ShareLinkAccess) time.Time { return a.AccessedAt }), limit), nil
+}
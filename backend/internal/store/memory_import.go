@@ -0,0 +1,417 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Sa-Te/IAV/backend/internal/models"
+)
+
+// The write side of the archive repositories. Rows are deduplicated on the columns of
+// the table's unique constraint in Postgres.
+
+// write runs f on the account's archive with the lock held.
+func write(m *Memory, accountID int, f func(*MemoryArchive)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f(m.archive(accountID))
+	return nil
+}
+
+// insertNew appends the rows whose key is not in the table yet, like INSERT ... ON
+// CONFLICT DO NOTHING. stamp gives each appended row its id.
+func insertNew[T any, K comparable](m *Memory, table *[]T, rows []T, key func(T) K, stamp func(*T, int)) {
+	taken := map[K]bool{}
+	for _, row := range *table {
+		taken[key(row)] = true
+	}
+	for _, row := range rows {
+		if taken[key(row)] {
+			continue
+		}
+		taken[key(row)] = true
+		m.nextID++
+		stamp(&row, m.nextID)
+		*table = append(*table, row)
+	}
+}
+
+// insertAll appends every row, for tables without a unique constraint.
+func insertAll[T any](m *Memory, table *[]T, rows []T, stamp func(*T, int)) {
+	for _, row := range rows {
+		m.nextID++
+		stamp(&row, m.nextID)
+		*table = append(*table, row)
+	}
+}
+
+// --- Imports ---
+
+func (m *Memory) Now(ctx context.Context) (time.Time, error) {
+	return time.Now(), nil
+}
+
+func (m *Memory) CreateImport(ctx context.Context, userID, accountID int, archiveBytes int64) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	a := m.archive(accountID)
+	if a.Imports == nil {
+		a.Imports = map[int]time.Time{}
+	}
+	m.nextID++
+	a.Imports[m.nextID] = time.Now()
+	return m.nextID, nil
+}
+
+func (m *Memory) LatestImport(ctx context.Context, accountID int) (int, error) {
+	return read(m, accountID, func(a *MemoryArchive) int {
+		latest := 0
+		for id := range a.Imports {
+			latest = max(latest, id)
+		}
+		return latest
+	})
+}
+
+func (m *Memory) SaveSnapshot(ctx context.Context, accountID, importID int, started time.Time) error {
+	return write(m, accountID, func(a *MemoryArchive) {
+		var entries []models.SnapshotEntry
+		for _, c := range a.Connections {
+			if !a.connectionSeen[c.ID].Before(started) {
+				entries = append(entries, models.SnapshotEntry{ImportID: importID, Username: c.Username, ConnectionType: c.ConnectionType, Timestamp: c.Timestamp})
+			}
+		}
+		insertNew(m, &a.SnapshotEntries, entries, func(e models.SnapshotEntry) string {
+			return fmt.Sprint(e.ImportID, "\x00", e.Username, "\x00", e.ConnectionType)
+		}, func(*models.SnapshotEntry, int) {})
+	})
+}
+
+// --- Media ---
+
+func (m *Memory) SaveMedia(ctx context.Context, userID, accountID int, items []models.MediaItem) error {
+	return write(m, accountID, func(a *MemoryArchive) {
+		insertNew(m, &a.Media, items, func(i models.MediaItem) string { return i.URI },
+			func(i *models.MediaItem, id int) { i.ID, i.UserID = id, userID })
+	})
+}
+
+func (m *Memory) SaveArchivedPosts(ctx context.Context, userID, accountID int, posts []models.ArchivedPost) error {
+	return write(m, accountID, func(a *MemoryArchive) {
+		insertNew(m, &a.ArchivedPosts, posts, func(p models.ArchivedPost) string { return p.URI },
+			func(p *models.ArchivedPost, id int) { p.ID, p.UserID = id, userID })
+	})
+}
+
+// --- Connections ---
+
+func (m *Memory) SaveConnections(ctx context.Context, userID, accountID int, connections []models.Connection) error {
+	return write(m, accountID, func(a *MemoryArchive) {
+		if a.connectionSeen == nil {
+			a.connectionSeen = map[int]time.Time{}
+		}
+		for _, c := range connections {
+			i := -1
+			for j, existing := range a.Connections {
+				if existing.Username == c.Username && existing.ConnectionType == c.ConnectionType {
+					i = j
+					break
+				}
+			}
+			if i < 0 {
+				m.nextID++
+				c.ID, c.UserID = m.nextID, userID
+				a.Connections = append(a.Connections, c)
+				i = len(a.Connections) - 1
+			} else {
+				existing := &a.Connections[i]
+				switch existing.ConnectionType {
+				case "contact", "follower", "following":
+				default:
+					existing.Timestamp = c.Timestamp
+				}
+				if c.ContactInfo != nil {
+					existing.ContactInfo = c.ContactInfo
+				}
+			}
+			a.connectionSeen[a.Connections[i].ID] = time.Now()
+		}
+	})
+}
+
+func (m *Memory) SaveFollowedHashtags(ctx context.Context, userID, accountID int, hashtags []models.FollowedHashtag) error {
+	return write(m, accountID, func(a *MemoryArchive) {
+		for _, h := range hashtags {
+			for i := range a.Hashtags {
+				if a.Hashtags[i].Name == h.Name {
+					a.Hashtags[i].Timestamp = h.Timestamp
+				}
+			}
+		}
+		insertNew(m, &a.Hashtags, hashtags, func(h models.FollowedHashtag) string { return h.Name },
+			func(h *models.FollowedHashtag, id int) { h.ID, h.UserID = id, userID })
+	})
+}
+
+// --- Activity ---
+
+func (m *Memory) SaveActivity(ctx context.Context, userID, accountID int, activity []models.ActivityLog) error {
+	return write(m, accountID, func(a *MemoryArchive) {
+		insertNew(m, &a.Activity, activity, func(l models.ActivityLog) string {
+			author := ""
+			if l.Author != nil {
+				author = *l.Author
+			}
+			return fmt.Sprint(l.ActivityType, "\x00", author, "\x00", l.Timestamp.UnixNano())
+		}, func(l *models.ActivityLog, id int) { l.ID, l.UserID = id, userID })
+	})
+}
+
+func (m *Memory) SaveSearchHistory(ctx context.Context, userID, accountID int, searches []models.SearchHistoryEntry) error {
+	return write(m, accountID, func(a *MemoryArchive) {
+		insertNew(m, &a.SearchHistory, searches, func(s models.SearchHistoryEntry) string {
+			return fmt.Sprint(s.SearchQuery, "\x00", s.SearchType, "\x00", s.SearchedAt.UnixNano())
+		}, func(s *models.SearchHistoryEntry, id int) { s.ID, s.UserID = id, userID })
+	})
+}
+
+func (m *Memory) SaveOffMetaActivity(ctx context.Context, userID, accountID int, events []models.OffMetaActivity) error {
+	return write(m, accountID, func(a *MemoryArchive) {
+		insertAll(m, &a.OffMeta, events, func(e *models.OffMetaActivity, id int) { e.ID, e.UserID = id, userID })
+	})
+}
+
+// --- Messages ---
+
+func (m *Memory) SaveConversation(ctx context.Context, userID, accountID int, c models.MessageConversation, messages []models.Message) error {
+	messages = append([]models.Message(nil), messages...)
+	for i := range messages {
+		messages[i].ConversationID = c.ConversationID
+	}
+	return write(m, accountID, func(a *MemoryArchive) {
+		insertNew(m, &a.Conversations, []models.MessageConversation{c}, func(c models.MessageConversation) string { return c.ConversationID },
+			func(c *models.MessageConversation, id int) { c.ID, c.UserID = id, userID })
+		insertNew(m, &a.Messages, messages, func(msg models.Message) string {
+			return fmt.Sprint(msg.ConversationID, "\x00", msg.SenderName, "\x00", msg.SentAt.UnixNano(), "\x00", msg.Content)
+		}, func(msg *models.Message, id int) { msg.ID, msg.UserID = id, userID })
+	})
+}
+
+// --- Security ---
+
+func (m *Memory) SaveLogins(ctx context.Context, userID, accountID int, logins []models.LoginHistory) error {
+	return write(m, accountID, func(a *MemoryArchive) {
+		insertAll(m, &a.Logins, logins, func(l *models.LoginHistory, id int) { l.ID, l.UserID = id, userID })
+	})
+}
+
+func (m *Memory) SaveLogouts(ctx context.Context, userID, accountID int, logouts []models.LogoutHistory) error {
+	return write(m, accountID, func(a *MemoryArchive) {
+		insertAll(m, &a.Logouts, logouts, func(l *models.LogoutHistory, id int) { l.ID, l.UserID = id, userID })
+	})
+}
+
+func (m *Memory) SavePasswordChanges(ctx context.Context, userID, accountID int, changes []models.PasswordChange) error {
+	return write(m, accountID, func(a *MemoryArchive) {
+		insertAll(m, &a.PasswordChanges, changes, func(c *models.PasswordChange, id int) { c.ID, c.UserID = id, userID })
+	})
+}
+
+func (m *Memory) SavePrivacyChanges(ctx context.Context, userID, accountID int, changes []models.PrivacyChange) error {
+	return write(m, accountID, func(a *MemoryArchive) {
+		insertAll(m, &a.PrivacyChanges, changes, func(c *models.PrivacyChange, id int) { c.ID, c.UserID = id, userID })
+	})
+}
+
+func (m *Memory) SaveAccountStatus(ctx context.Context, userID, accountID int, entries []models.AccountStatusEntry) error {
+	return write(m, accountID, func(a *MemoryArchive) {
+		insertAll(m, &a.AccountStatus, entries, func(e *models.AccountStatusEntry, id int) { e.ID, e.UserID = id, userID })
+	})
+}
+
+func (m *Memory) SaveSignupInfo(ctx context.Context, userID, accountID int, info models.SignupInfo) error {
+	return write(m, accountID, func(a *MemoryArchive) {
+		if a.SignupInfo == nil {
+			m.nextID++
+			info.ID, info.UserID = m.nextID, userID
+			a.SignupInfo = &info
+		}
+	})
+}
+
+// --- Profile ---
+
+func (m *Memory) SaveProfile(ctx context.Context, userID, accountID int, profile models.UserProfile) error {
+	return write(m, accountID, func(a *MemoryArchive) {
+		if a.Profile != nil {
+			profile.ID = a.Profile.ID
+		} else {
+			m.nextID++
+			profile.ID = m.nextID
+		}
+		profile.UserID = userID
+		a.Profile = &profile
+	})
+}
+
+func (m *Memory) SaveProfileChanges(ctx context.Context, userID, accountID int, changes []models.ProfileChange) error {
+	return write(m, accountID, func(a *MemoryArchive) {
+		insertAll(m, &a.ProfileChanges, changes, func(c *models.ProfileChange, id int) { c.ID, c.UserID = id, userID })
+	})
+}
+
+func (m *Memory) SaveProfilePhotos(ctx context.Context, userID, accountID int, photos []models.ProfilePhoto) error {
+	return write(m, accountID, func(a *MemoryArchive) {
+		insertNew(m, &a.ProfilePhotos, photos, func(p models.ProfilePhoto) string { return p.PhotoURI },
+			func(p *models.ProfilePhoto, id int) { p.ID, p.UserID = id, userID })
+	})
+}
+
+// --- Interactions ---
+
+// dated keys the interaction tables, which are unique on the other account and the time.
+func dated(username string, at time.Time) string {
+	return fmt.Sprint(username, "\x00", at.UnixNano())
+}
+
+func (m *Memory) SavePostLikes(ctx context.Context, userID, accountID int, likes []models.PostLike) error {
+	return write(m, accountID, func(a *MemoryArchive) {
+		insertNew(m, &a.PostLikes, likes, func(l models.PostLike) string { return dated(l.CreatorUsername, l.LikedAt) },
+			func(l *models.PostLike, id int) { l.ID, l.UserID = id, userID })
+	})
+}
+
+func (m *Memory) SaveCommentLikes(ctx context.Context, userID, accountID int, likes []models.CommentLike) error {
+	return write(m, accountID, func(a *MemoryArchive) {
+		insertNew(m, &a.CommentLikes, likes, func(l models.CommentLike) string { return dated(l.OwnerUsername, l.LikedAt) },
+			func(l *models.CommentLike, id int) { l.ID, l.UserID = id, userID })
+	})
+}
+
+func (m *Memory) SaveStoryLikes(ctx context.Context, userID, accountID int, likes []models.StoryLike) error {
+	return write(m, accountID, func(a *MemoryArchive) {
+		insertNew(m, &a.StoryLikes, likes, func(l models.StoryLike) string { return dated(l.CreatorUsername, l.LikedAt) },
+			func(l *models.StoryLike, id int) { l.ID, l.UserID = id, userID })
+	})
+}
+
+func (m *Memory) SavePostComments(ctx context.Context, userID, accountID int, comments []models.PostComment) error {
+	return write(m, accountID, func(a *MemoryArchive) {
+		insertNew(m, &a.PostComments, comments, func(c models.PostComment) string { return dated(c.PostOwnerUsername, c.CommentedAt) },
+			func(c *models.PostComment, id int) { c.ID, c.UserID = id, userID })
+	})
+}
+
+func (m *Memory) SaveReelComments(ctx context.Context, userID, accountID int, comments []models.ReelComment) error {
+	return write(m, accountID, func(a *MemoryArchive) {
+		insertNew(m, &a.ReelComments, comments, func(c models.ReelComment) string { return dated(c.ReelOwnerUsername, c.CommentedAt) },
+			func(c *models.ReelComment, id int) { c.ID, c.UserID = id, userID })
+	})
+}
+
+func (m *Memory) SaveStoryPolls(ctx context.Context, userID, accountID int, polls []models.StoryPoll) error {
+	return write(m, accountID, func(a *MemoryArchive) {
+		insertNew(m, &a.StoryPolls, polls, func(s models.StoryPoll) string { return dated(s.CreatorUsername, s.AnsweredAt) },
+			func(s *models.StoryPoll, id int) { s.ID, s.UserID = id, userID })
+	})
+}
+
+func (m *Memory) SaveStoryQuizzes(ctx context.Context, userID, accountID int, quizzes []models.StoryQuiz) error {
+	return write(m, accountID, func(a *MemoryArchive) {
+		insertNew(m, &a.StoryQuizzes, quizzes, func(s models.StoryQuiz) string { return dated(s.CreatorUsername, s.AnsweredAt) },
+			func(s *models.StoryQuiz, id int) { s.ID, s.UserID = id, userID })
+	})
+}
+
+func (m *Memory) SaveStoryQuestions(ctx context.Context, userID, accountID int, questions []models.StoryQuestion) error {
+	return write(m, accountID, func(a *MemoryArchive) {
+		insertNew(m, &a.StoryQuestions, questions, func(s models.StoryQuestion) string { return dated(s.CreatorUsername, s.RespondedAt) },
+			func(s *models.StoryQuestion, id int) { s.ID, s.UserID = id, userID })
+	})
+}
+
+func (m *Memory) SaveStoryEmojiSliders(ctx context.Context, userID, accountID int, sliders []models.StoryEmojiSlider) error {
+	return write(m, accountID, func(a *MemoryArchive) {
+		insertNew(m, &a.StorySliders, sliders, func(s models.StoryEmojiSlider) string { return dated(s.CreatorUsername, s.RespondedAt) },
+			func(s *models.StoryEmojiSlider, id int) { s.ID, s.UserID = id, userID })
+	})
+}
+
+func (m *Memory) SaveStoryReactions(ctx context.Context, userID, accountID int, reactions []models.StoryReaction) error {
+	return write(m, accountID, func(a *MemoryArchive) {
+		insertNew(m, &a.StoryReactions, reactions, func(s models.StoryReaction) string { return dated(s.CreatorUsername, s.RespondedAt) },
+			func(s *models.StoryReaction, id int) { s.ID, s.UserID = id, userID })
+	})
+}
+
+// --- Saved ---
+
+func (m *Memory) SaveSavedMedia(ctx context.Context, userID, accountID int, saved []models.SavedMedia) error {
+	return write(m, accountID, func(a *MemoryArchive) {
+		insertNew(m, &a.SavedMedia, saved, func(s models.SavedMedia) string { return s.PostURL },
+			func(s *models.SavedMedia, id int) { s.ID, s.UserID = id, userID })
+	})
+}
+
+func (m *Memory) SaveCollections(ctx context.Context, userID, accountID int, collections []models.SavedCollection) error {
+	return write(m, accountID, func(a *MemoryArchive) {
+		insertNew(m, &a.Collections, collections, func(c models.SavedCollection) string { return c.CollectionName },
+			func(c *models.SavedCollection, id int) { c.ID, c.UserID = id, userID })
+	})
+}
+
+func (m *Memory) SaveCollectionItems(ctx context.Context, userID, accountID int, items []models.SavedCollectionItem) error {
+	return write(m, accountID, func(a *MemoryArchive) {
+		insertNew(m, &a.CollectionItems, items, func(i models.SavedCollectionItem) string { return i.ItemURL },
+			func(i *models.SavedCollectionItem, id int) { i.ID, i.UserID = id, userID })
+	})
+}
+
+// --- Interests ---
+
+func (m *Memory) SaveAdvertisers(ctx context.Context, userID, accountID int, names []string) error {
+	return write(m, accountID, func(a *MemoryArchive) {
+		insertNew(m, &a.Advertisers, names, func(name string) string { return name }, func(*string, int) {})
+	})
+}
+
+func (m *Memory) SaveAdTopics(ctx context.Context, userID, accountID int, names []string) error {
+	return write(m, accountID, func(a *MemoryArchive) {
+		insertNew(m, &a.AdTopics, names, func(name string) string { return name }, func(*string, int) {})
+	})
+}
+
+func (m *Memory) SaveAIInterests(ctx context.Context, userID, accountID int, interests []models.AIInterest) error {
+	return write(m, accountID, func(a *MemoryArchive) {
+		insertNew(m, &a.AIInterests, interests, func(i models.AIInterest) string { return i.InterestDescription },
+			func(i *models.AIInterest, id int) { i.ID, i.UserID = id, userID })
+	})
+}
+
+func (m *Memory) SaveTopics(ctx context.Context, userID, accountID int, names []string) error {
+	topics := make([]models.UserTopic, len(names))
+	for i, name := range names {
+		topics[i].TopicName = name
+	}
+	return write(m, accountID, func(a *MemoryArchive) {
+		insertNew(m, &a.Topics, topics, func(t models.UserTopic) string { return t.TopicName },
+			func(t *models.UserTopic, id int) { t.ID, t.UserID = id, userID })
+	})
+}
+
+func (m *Memory) SaveLocationsOfInterest(ctx context.Context, userID, accountID int, names []string) error {
+	return write(m, accountID, func(a *MemoryArchive) {
+		insertNew(m, &a.LocationsOfInterest, names, func(name string) string { return name }, func(*string, int) {})
+	})
+}
+
+func (m *Memory) SaveInferredLocation(ctx context.Context, userID, accountID int, city string) error {
+	return write(m, accountID, func(a *MemoryArchive) {
+		if a.InferredLocation == nil {
+			m.nextID++
+			a.InferredLocation = &models.InferredLocation{ID: m.nextID, UserID: userID}
+		}
+		a.InferredLocation.CityName = city
+	})
+}
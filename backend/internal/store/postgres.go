@@ -0,0 +1,958 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Sa-Te/IAV/backend/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// defaultAccountName labels the account created for every user on registration
+// (and for pre-existing users by migration 023).
+const defaultAccountName = "default"
+
+// postgres implements every repository on the server's connection pool.
+type postgres struct {
+	db *pgxpool.Pool
+}
+
+func NewPostgres(db *pgxpool.Pool) *Store {
+	p := &postgres{db: db}
+	return &Store{
+		Users:        p,
+		Tokens:       p,
+		Shares:       p,
+		Imports:      p,
+		Accounts:     p,
+		Media:        p,
+		Connections:  p,
+		Snapshots:    p,
+		Activity:     p,
+		Messages:     p,
+		Security:     p,
+		Profile:      p,
+		Interactions: p,
+		Saved:        p,
+		Interests:    p,
+		Insights:     p,
+		Search:       p,
+		Timeline:     p,
+	}
+}
+
+// queryRows runs a query and scans each row with scan.
+func queryRows[T any](ctx context.Context, db *pgxpool.Pool, scan func(pgx.Rows, *T) error, query string, args ...interface{}) ([]T, error) {
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]T, 0)
+	for rows.Next() {
+		var v T
+		if err := scan(rows, &v); err != nil {
+			return nil, err
+		}
+		result = append(result, v)
+	}
+	return result, rows.Err()
+}
+
+// queryOptional scans a single row, returning nil rather than an error when there is none.
+func queryOptional[T any](ctx context.Context, db *pgxpool.Pool, scan func(pgx.Row, *T) error, query string, args ...interface{}) (*T, error) {
+	var v T
+	err := scan(db.QueryRow(ctx, query, args...), &v)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// listTable names the columns ListOptions filter and sort on. Column names come from
+// here, never from the request, so the generated SQL only carries user input as bind
+// parameters. Filters on a column the table doesn't have are ignored.
+type listTable struct {
+	typeColumn     string
+	dateColumn     string
+	usernameColumn string
+	// dateSort is what the "date" sort orders by; it must never be NULL
+	dateSort string
+}
+
+// list appends the filters, keyset condition, ordering and limit to a query whose WHERE
+// clause is already open. The query must select `id`.
+func (t listTable) list(query string, args []interface{}, opts ListOptions) (string, []interface{}) {
+	query, args = t.filter(query, args, opts)
+
+	sortExpr, textSort := t.dateSort, false
+	if sortExpr == "" {
+		sortExpr = t.dateColumn
+	}
+	if opts.Sort == SortUsername && t.usernameColumn != "" {
+		sortExpr, textSort = t.usernameColumn, true
+	}
+	cmp, dir := "<", "DESC"
+	if opts.Ascending {
+		cmp, dir = ">", "ASC"
+	}
+	if opts.After != nil {
+		var after interface{} = opts.After.At
+		if textSort {
+			after = opts.After.Key
+		}
+		query += fmt.Sprintf(" AND (%s, id) %s ($%d, $%d)", sortExpr, cmp, len(args)+1, len(args)+2)
+		args = append(args, after, opts.After.ID)
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, id %s", sortExpr, dir, dir)
+	if opts.Limit > 0 {
+		args = append(args, opts.Limit+1)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	return query, args
+}
+
+// filter appends the type, date and username filters of opts.
+func (t listTable) filter(query string, args []interface{}, opts ListOptions) (string, []interface{}) {
+	if len(opts.Types) > 0 && t.typeColumn != "" {
+		args = append(args, opts.Types)
+		query += fmt.Sprintf(" AND %s = ANY($%d)", t.typeColumn, len(args))
+	}
+	if opts.From != nil && t.dateColumn != "" {
+		args = append(args, *opts.From)
+		query += fmt.Sprintf(" AND %s >= $%d", t.dateColumn, len(args))
+	}
+	if opts.To != nil && t.dateColumn != "" {
+		args = append(args, *opts.To)
+		query += fmt.Sprintf(" AND %s < $%d", t.dateColumn, len(args))
+	}
+	if opts.Username != "" && t.usernameColumn != "" {
+		args = append(args, "%"+escapeLike(opts.Username)+"%")
+		query += fmt.Sprintf(" AND %s ILIKE $%d", t.usernameColumn, len(args))
+	}
+	return query, args
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// --- Accounts ---
+
+func (p *postgres) DefaultAccount(ctx context.Context, userID int) (int, error) {
+	var accountID int
+	err := p.db.QueryRow(ctx, `SELECT id FROM ig_accounts WHERE user_id=$1 AND is_default`, userID).Scan(&accountID)
+	if err == nil {
+		return accountID, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return 0, err
+	}
+
+	err = p.db.QueryRow(ctx,
+		`INSERT INTO ig_accounts (user_id, name, is_default) VALUES ($1, $2, TRUE)
+		 ON CONFLICT (user_id) WHERE is_default DO UPDATE SET is_default = TRUE
+		 RETURNING id`, userID, defaultAccountName).Scan(&accountID)
+	return accountID, err
+}
+
+func (p *postgres) FindAccount(ctx context.Context, userID int, ref string) (int, error) {
+	var accountID int
+	err := p.db.QueryRow(ctx,
+		`SELECT id FROM ig_accounts WHERE user_id=$1 AND (id::TEXT=$2 OR name=$2)
+		 ORDER BY (id::TEXT=$2) DESC LIMIT 1`, userID, ref).Scan(&accountID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrNotFound
+	}
+	return accountID, err
+}
+
+func (p *postgres) ListAccounts(ctx context.Context, userID int) ([]models.IGAccount, error) {
+	return queryRows(ctx, p.db, func(rows pgx.Rows, a *models.IGAccount) error {
+		return rows.Scan(&a.ID, &a.UserID, &a.Name, &a.IsDefault, &a.CreatedAt)
+	}, `SELECT id, user_id, name, is_default, created_at FROM ig_accounts WHERE user_id=$1 ORDER BY is_default DESC, name`, userID)
+}
+
+func (p *postgres) CreateAccount(ctx context.Context, userID int, name string) (models.IGAccount, error) {
+	a := models.IGAccount{UserID: userID, Name: name}
+	err := p.db.QueryRow(ctx,
+		`INSERT INTO ig_accounts (user_id, name) VALUES ($1, $2)
+		 ON CONFLICT (user_id, name) DO NOTHING RETURNING id, is_default, created_at`, userID, name).
+		Scan(&a.ID, &a.IsDefault, &a.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return a, ErrConflict
+	}
+	return a, err
+}
+
+// --- Media ---
+
+var mediaTable = listTable{typeColumn: "media_type", dateColumn: "taken_at"}
+
+func (p *postgres) ListMedia(ctx context.Context, accountID int, opts ListOptions) ([]models.MediaItem, error) {
+	query, args := mediaTable.list(
+		`SELECT id, user_id, uri, caption, taken_at, media_type FROM media_items WHERE ig_account_id=$1`,
+		[]interface{}{accountID}, opts)
+	return queryRows(ctx, p.db, func(rows pgx.Rows, m *models.MediaItem) error {
+		return rows.Scan(&m.ID, &m.UserID, &m.URI, &m.Caption, &m.TakenAt, &m.MediaType)
+	}, query, args...)
+}
+
+func (p *postgres) FindMedia(ctx context.Context, accountID int, uri string) (models.MediaItem, error) {
+	var m models.MediaItem
+	err := p.db.QueryRow(ctx,
+		`SELECT id, user_id, uri, caption, taken_at, media_type FROM media_items WHERE ig_account_id=$1 AND uri=$2`,
+		accountID, uri).Scan(&m.ID, &m.UserID, &m.URI, &m.Caption, &m.TakenAt, &m.MediaType)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return m, ErrNotFound
+	}
+	return m, err
+}
+
+func (p *postgres) ListArchivedPosts(ctx context.Context, accountID int) ([]models.ArchivedPost, error) {
+	return queryRows(ctx, p.db, func(rows pgx.Rows, a *models.ArchivedPost) error {
+		return rows.Scan(&a.ID, &a.UserID, &a.URI, &a.Caption, &a.TakenAt)
+	}, `SELECT id, user_id, uri, COALESCE(caption,''), COALESCE(taken_at, NOW()) FROM archived_posts WHERE ig_account_id=$1 ORDER BY taken_at DESC`, accountID)
+}
+
+// --- Connections ---
+
+var connectionsTable = listTable{typeColumn: "connection_type", dateColumn: "timestamp", usernameColumn: "username"}
+
+func (p *postgres) ListConnections(ctx context.Context, accountID int, opts ListOptions) ([]models.Connection, error) {
+	query, args := connectionsTable.list(
+		`SELECT id, user_id, username, connection_type, timestamp, contact_info FROM connections WHERE ig_account_id=$1`,
+		[]interface{}{accountID}, opts)
+	return queryRows(ctx, p.db, func(rows pgx.Rows, c *models.Connection) error {
+		return rows.Scan(&c.ID, &c.UserID, &c.Username, &c.ConnectionType, &c.Timestamp, &c.ContactInfo)
+	}, query, args...)
+}
+
+func (p *postgres) ListFollowedHashtags(ctx context.Context, accountID int) ([]models.FollowedHashtag, error) {
+	return queryRows(ctx, p.db, func(rows pgx.Rows, h *models.FollowedHashtag) error {
+		return rows.Scan(&h.ID, &h.UserID, &h.Name, &h.Timestamp)
+	}, `SELECT id, user_id, name, timestamp FROM followed_hashtags WHERE ig_account_id=$1 ORDER BY name ASC`, accountID)
+}
+
+// --- Snapshots ---
+
+func (p *postgres) ListSnapshots(ctx context.Context, accountID int) ([]models.ConnectionSnapshot, error) {
+	return queryRows(ctx, p.db, func(rows pgx.Rows, s *models.ConnectionSnapshot) error {
+		return rows.Scan(&s.ImportID, &s.TakenAt, &s.Followers, &s.Following)
+	}, `SELECT i.id, i.created_at,
+			COUNT(*) FILTER (WHERE c.connection_type = 'follower'),
+			COUNT(*) FILTER (WHERE c.connection_type = 'following')
+		FROM imports i JOIN connection_snapshots c ON c.import_id = i.id
+		WHERE i.ig_account_id=$1
+		GROUP BY i.id, i.created_at
+		ORDER BY i.created_at ASC, i.id ASC`, accountID)
+}
+
+func (p *postgres) ListSnapshotEntries(ctx context.Context, accountID, importID int, types []string) ([]models.SnapshotEntry, error) {
+	return queryRows(ctx, p.db, func(rows pgx.Rows, e *models.SnapshotEntry) error {
+		return rows.Scan(&e.ImportID, &e.Username, &e.ConnectionType, &e.Timestamp)
+	}, `SELECT import_id, username, connection_type, timestamp FROM connection_snapshots
+		WHERE ig_account_id=$1 AND import_id=$2 AND connection_type = ANY($3)
+		ORDER BY username ASC, connection_type ASC`, accountID, importID, types)
+}
+
+// --- Activity ---
+
+// activity_log.timestamp is nullable; undated impressions sort as the epoch, after everything else
+var activityTable = listTable{typeColumn: "activity_type", dateColumn: "timestamp", usernameColumn: "author", dateSort: "COALESCE(timestamp, 'epoch')"}
+
+func (p *postgres) ListActivity(ctx context.Context, accountID int, opts ListOptions) ([]models.ActivityLog, error) {
+	query, args := activityTable.list(
+		`SELECT id, user_id, activity_type, author, COALESCE(timestamp, 'epoch'), details FROM activity_log WHERE ig_account_id=$1`,
+		[]interface{}{accountID}, opts)
+	return queryRows(ctx, p.db, func(rows pgx.Rows, a *models.ActivityLog) error {
+		return rows.Scan(&a.ID, &a.UserID, &a.ActivityType, &a.Author, &a.Timestamp, &a.Details)
+	}, query, args...)
+}
+
+var searchHistoryTable = listTable{typeColumn: "search_type", dateColumn: "searched_at"}
+
+func (p *postgres) ListSearchHistory(ctx context.Context, accountID int, opts ListOptions) ([]models.SearchHistoryEntry, error) {
+	query, args := searchHistoryTable.list(
+		`SELECT id, user_id, search_query, search_type, searched_at FROM search_history WHERE ig_account_id=$1`,
+		[]interface{}{accountID}, opts)
+	return queryRows(ctx, p.db, func(rows pgx.Rows, e *models.SearchHistoryEntry) error {
+		return rows.Scan(&e.ID, &e.UserID, &e.SearchQuery, &e.SearchType, &e.SearchedAt)
+	}, query, args...)
+}
+
+func (p *postgres) FindSearch(ctx context.Context, accountID, id int) (models.SearchHistoryEntry, error) {
+	var e models.SearchHistoryEntry
+	err := p.db.QueryRow(ctx,
+		`SELECT id, user_id, search_query, search_type, searched_at FROM search_history WHERE ig_account_id=$1 AND id=$2`,
+		accountID, id).Scan(&e.ID, &e.UserID, &e.SearchQuery, &e.SearchType, &e.SearchedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return e, ErrNotFound
+	}
+	return e, err
+}
+
+var offMetaTable = listTable{typeColumn: "event_type", dateColumn: "event_at"}
+
+func (p *postgres) ListOffMetaActivity(ctx context.Context, accountID int, opts ListOptions) ([]models.OffMetaActivity, error) {
+	query, args := offMetaTable.list(
+		`SELECT id, user_id, app_name, event_type, event_id, event_at FROM off_meta_activity WHERE ig_account_id=$1`,
+		[]interface{}{accountID}, opts)
+	return queryRows(ctx, p.db, func(rows pgx.Rows, a *models.OffMetaActivity) error {
+		return rows.Scan(&a.ID, &a.UserID, &a.AppName, &a.EventType, &a.EventID, &a.EventAt)
+	}, query, args...)
+}
+
+// --- Messages ---
+
+func (p *postgres) ListConversations(ctx context.Context, accountID int) ([]models.MessageConversation, error) {
+	return queryRows(ctx, p.db, func(rows pgx.Rows, c *models.MessageConversation) error {
+		return rows.Scan(&c.ID, &c.UserID, &c.ConversationID, &c.Participants, &c.ThreadType)
+	}, `SELECT id, user_id, conversation_id, participants, COALESCE(thread_type,'')
+	    FROM message_conversations mc WHERE mc.ig_account_id=$1
+	    ORDER BY (SELECT MAX(sent_at) FROM messages m WHERE m.ig_account_id=$1 AND m.conversation_id=mc.conversation_id) DESC NULLS LAST`,
+		accountID)
+}
+
+func (p *postgres) ConversationExists(ctx context.Context, accountID int, conversationID string) (bool, error) {
+	var exists bool
+	err := p.db.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM message_conversations WHERE ig_account_id=$1 AND conversation_id=$2)`,
+		accountID, conversationID).Scan(&exists)
+	return exists, err
+}
+
+var messagesTable = listTable{dateColumn: "sent_at", usernameColumn: "sender_name"}
+
+func (p *postgres) ListMessages(ctx context.Context, accountID int, conversationID string, opts ListOptions) ([]models.Message, error) {
+	query := `SELECT id, user_id, conversation_id, sender_name, COALESCE(content,''), sent_at FROM messages WHERE ig_account_id=$1`
+	args := []interface{}{accountID}
+	if conversationID != "" {
+		query += ` AND conversation_id=$2`
+		args = append(args, conversationID)
+	}
+	query, args = messagesTable.list(query, args, opts)
+	return queryRows(ctx, p.db, func(rows pgx.Rows, m *models.Message) error {
+		return rows.Scan(&m.ID, &m.UserID, &m.ConversationID, &m.SenderName, &m.Content, &m.SentAt)
+	}, query, args...)
+}
+
+// --- Security ---
+
+func (p *postgres) ListLogins(ctx context.Context, accountID, limit int) ([]models.LoginHistory, error) {
+	return queryRows(ctx, p.db, func(rows pgx.Rows, l *models.LoginHistory) error {
+		return rows.Scan(&l.ID, &l.UserID, &l.IPAddress, &l.UserAgent, &l.LanguageCode, &l.LoggedInAt)
+	}, `SELECT id, user_id, COALESCE(ip_address,''), COALESCE(user_agent,''), COALESCE(language_code,''), logged_in_at
+	    FROM login_history WHERE ig_account_id=$1 ORDER BY logged_in_at DESC LIMIT NULLIF($2, 0)`, accountID, limit)
+}
+
+func (p *postgres) ListLogouts(ctx context.Context, accountID, limit int) ([]models.LogoutHistory, error) {
+	return queryRows(ctx, p.db, func(rows pgx.Rows, l *models.LogoutHistory) error {
+		return rows.Scan(&l.ID, &l.UserID, &l.IPAddress, &l.UserAgent, &l.LoggedOutAt)
+	}, `SELECT id, user_id, COALESCE(ip_address,''), COALESCE(user_agent,''), logged_out_at
+	    FROM logout_history WHERE ig_account_id=$1 ORDER BY logged_out_at DESC LIMIT NULLIF($2, 0)`, accountID, limit)
+}
+
+func (p *postgres) ListPasswordChanges(ctx context.Context, accountID int) ([]models.PasswordChange, error) {
+	return queryRows(ctx, p.db, func(rows pgx.Rows, c *models.PasswordChange) error {
+		return rows.Scan(&c.ID, &c.UserID, &c.ChangedAt)
+	}, `SELECT id, user_id, changed_at FROM password_change_history WHERE ig_account_id=$1 ORDER BY changed_at DESC`, accountID)
+}
+
+func (p *postgres) ListPrivacyChanges(ctx context.Context, accountID int) ([]models.PrivacyChange, error) {
+	return queryRows(ctx, p.db, func(rows pgx.Rows, c *models.PrivacyChange) error {
+		return rows.Scan(&c.ID, &c.UserID, &c.PrivacyStatus, &c.ChangedAt)
+	}, `SELECT id, user_id, privacy_status, changed_at FROM privacy_changes WHERE ig_account_id=$1 ORDER BY changed_at DESC`, accountID)
+}
+
+func (p *postgres) ListAccountStatus(ctx context.Context, accountID int) ([]models.AccountStatusEntry, error) {
+	return queryRows(ctx, p.db, func(rows pgx.Rows, a *models.AccountStatusEntry) error {
+		return rows.Scan(&a.ID, &a.UserID, &a.ActivationType, &a.Reason, &a.ChangedAt)
+	}, `SELECT id, user_id, activation_type, COALESCE(reason,''), changed_at FROM account_status_history WHERE ig_account_id=$1 ORDER BY changed_at DESC`, accountID)
+}
+
+func (p *postgres) SignupInfo(ctx context.Context, accountID int) (*models.SignupInfo, error) {
+	return queryOptional(ctx, p.db, func(row pgx.Row, si *models.SignupInfo) error {
+		return row.Scan(&si.ID, &si.UserID, &si.UsernameAtSignup, &si.EmailAtSignup, &si.SignupIP, &si.DeviceModel, &si.SignedUpAt)
+	}, `SELECT id, user_id, COALESCE(username_at_signup,''), COALESCE(email_at_signup,''),
+	           COALESCE(signup_ip,''), COALESCE(device_model,''), signed_up_at
+	    FROM signup_info WHERE ig_account_id=$1`, accountID)
+}
+
+func (p *postgres) UnlocatedIPs(ctx context.Context, accountID int) ([]string, error) {
+	return queryRows(ctx, p.db, scanString, `SELECT ip FROM (
+			SELECT ip_address AS ip FROM login_history WHERE ig_account_id=$1
+			UNION SELECT ip_address FROM logout_history WHERE ig_account_id=$1
+			UNION SELECT signup_ip FROM signup_info WHERE ig_account_id=$1
+		) ips
+		WHERE ip IS NOT NULL AND ip <> ''
+			AND NOT EXISTS (SELECT 1 FROM ip_locations l WHERE l.ig_account_id=$1 AND l.ip_address = ips.ip)
+		ORDER BY ip`, accountID)
+}
+
+func (p *postgres) SaveIPLocations(ctx context.Context, accountID int, locations []models.IPLocation) error {
+	batch := &pgx.Batch{}
+	for _, l := range locations {
+		batch.Queue(`INSERT INTO ip_locations (ig_account_id, ip_address, country_code, country, city, latitude, longitude, asn, as_org)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			ON CONFLICT (ig_account_id, ip_address) DO UPDATE SET country_code = EXCLUDED.country_code, country = EXCLUDED.country,
+				city = EXCLUDED.city, latitude = EXCLUDED.latitude, longitude = EXCLUDED.longitude,
+				asn = EXCLUDED.asn, as_org = EXCLUDED.as_org, located_at = NOW()`,
+			accountID, l.IPAddress, l.CountryCode, l.Country, l.City, l.Latitude, l.Longitude, l.ASN, l.ASOrg)
+	}
+	return p.db.SendBatch(ctx, batch).Close()
+}
+
+func (p *postgres) ListIPLocations(ctx context.Context, accountID int) ([]models.IPLocation, error) {
+	return queryRows(ctx, p.db, func(rows pgx.Rows, l *models.IPLocation) error {
+		return rows.Scan(&l.IPAddress, &l.CountryCode, &l.Country, &l.City, &l.Latitude, &l.Longitude, &l.ASN, &l.ASOrg)
+	}, `SELECT ip_address, country_code, country, city, latitude, longitude, asn, as_org
+		FROM ip_locations WHERE ig_account_id=$1 ORDER BY ip_address`, accountID)
+}
+
+// --- Profile ---
+
+func (p *postgres) Profile(ctx context.Context, accountID int) (*models.UserProfile, error) {
+	return queryOptional(ctx, p.db, func(row pgx.Row, pr *models.UserProfile) error {
+		return row.Scan(&pr.ID, &pr.UserID, &pr.Email, &pr.PhoneNumber, &pr.Username, &pr.Bio, &pr.Gender, &pr.DateOfBirth, &pr.ProfilePhotoURI)
+	}, `SELECT id, user_id, COALESCE(email,''), COALESCE(phone_number,''), COALESCE(username,''),
+	           COALESCE(bio,''), COALESCE(gender,''), date_of_birth::TEXT, COALESCE(profile_photo_uri,'')
+	    FROM user_profile WHERE ig_account_id=$1`, accountID)
+}
+
+func (p *postgres) ListProfileChanges(ctx context.Context, accountID int) ([]models.ProfileChange, error) {
+	return queryRows(ctx, p.db, func(rows pgx.Rows, c *models.ProfileChange) error {
+		return rows.Scan(&c.ID, &c.UserID, &c.FieldChanged, &c.PreviousValue, &c.NewValue, &c.ChangedAt)
+	}, `SELECT id, user_id, field_changed, COALESCE(previous_value,''), COALESCE(new_value,''), changed_at
+	    FROM profile_changes WHERE ig_account_id=$1 ORDER BY changed_at DESC`, accountID)
+}
+
+func (p *postgres) ListProfilePhotos(ctx context.Context, accountID int) ([]models.ProfilePhoto, error) {
+	return queryRows(ctx, p.db, func(rows pgx.Rows, ph *models.ProfilePhoto) error {
+		return rows.Scan(&ph.ID, &ph.UserID, &ph.PhotoURI, &ph.SetAt)
+	}, `SELECT id, user_id, photo_uri, set_at FROM profile_photos WHERE ig_account_id=$1 ORDER BY set_at DESC`, accountID)
+}
+
+// --- Interactions ---
+
+func (p *postgres) ListPostLikes(ctx context.Context, accountID int) ([]models.PostLike, error) {
+	return queryRows(ctx, p.db, func(rows pgx.Rows, l *models.PostLike) error {
+		return rows.Scan(&l.ID, &l.UserID, &l.CreatorUsername, &l.PostURL, &l.LikedAt)
+	}, `SELECT id, user_id, creator_username, post_url, liked_at FROM post_likes WHERE ig_account_id=$1 ORDER BY liked_at DESC`, accountID)
+}
+
+func (p *postgres) ListCommentLikes(ctx context.Context, accountID int) ([]models.CommentLike, error) {
+	return queryRows(ctx, p.db, func(rows pgx.Rows, l *models.CommentLike) error {
+		return rows.Scan(&l.ID, &l.UserID, &l.OwnerUsername, &l.PostURL, &l.LikedAt)
+	}, `SELECT id, user_id, owner_username, post_url, liked_at FROM comment_likes WHERE ig_account_id=$1 ORDER BY liked_at DESC`, accountID)
+}
+
+func (p *postgres) ListStoryLikes(ctx context.Context, accountID int) ([]models.StoryLike, error) {
+	return queryRows(ctx, p.db, func(rows pgx.Rows, l *models.StoryLike) error {
+		return rows.Scan(&l.ID, &l.UserID, &l.CreatorUsername, &l.LikedAt)
+	}, `SELECT id, user_id, creator_username, liked_at FROM story_likes WHERE ig_account_id=$1 ORDER BY liked_at DESC`, accountID)
+}
+
+func (p *postgres) ListPostComments(ctx context.Context, accountID int) ([]models.PostComment, error) {
+	return queryRows(ctx, p.db, func(rows pgx.Rows, c *models.PostComment) error {
+		return rows.Scan(&c.ID, &c.UserID, &c.PostOwnerUsername, &c.CommentText, &c.CommentedAt)
+	}, `SELECT id, user_id, post_owner_username, comment_text, commented_at FROM post_comments WHERE ig_account_id=$1 ORDER BY commented_at DESC`, accountID)
+}
+
+func (p *postgres) ListReelComments(ctx context.Context, accountID int) ([]models.ReelComment, error) {
+	return queryRows(ctx, p.db, func(rows pgx.Rows, c *models.ReelComment) error {
+		return rows.Scan(&c.ID, &c.UserID, &c.ReelOwnerUsername, &c.CommentText, &c.CommentedAt)
+	}, `SELECT id, user_id, reel_owner_username, comment_text, commented_at FROM reel_comments WHERE ig_account_id=$1 ORDER BY commented_at DESC`, accountID)
+}
+
+func (p *postgres) FindPostComment(ctx context.Context, accountID, id int) (models.PostComment, error) {
+	var c models.PostComment
+	err := p.db.QueryRow(ctx,
+		`SELECT id, user_id, post_owner_username, comment_text, commented_at FROM post_comments WHERE ig_account_id=$1 AND id=$2`,
+		accountID, id).Scan(&c.ID, &c.UserID, &c.PostOwnerUsername, &c.CommentText, &c.CommentedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c, ErrNotFound
+	}
+	return c, err
+}
+
+func (p *postgres) FindReelComment(ctx context.Context, accountID, id int) (models.ReelComment, error) {
+	var c models.ReelComment
+	err := p.db.QueryRow(ctx,
+		`SELECT id, user_id, reel_owner_username, comment_text, commented_at FROM reel_comments WHERE ig_account_id=$1 AND id=$2`,
+		accountID, id).Scan(&c.ID, &c.UserID, &c.ReelOwnerUsername, &c.CommentText, &c.CommentedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c, ErrNotFound
+	}
+	return c, err
+}
+
+func (p *postgres) ListStoryPolls(ctx context.Context, accountID int) ([]models.StoryPoll, error) {
+	return queryRows(ctx, p.db, func(rows pgx.Rows, s *models.StoryPoll) error {
+		return rows.Scan(&s.ID, &s.UserID, &s.CreatorUsername, &s.PollAnswer, &s.AnsweredAt)
+	}, `SELECT id, user_id, creator_username, COALESCE(poll_answer,''), answered_at FROM story_polls WHERE ig_account_id=$1 ORDER BY answered_at DESC`, accountID)
+}
+
+func (p *postgres) ListStoryQuizzes(ctx context.Context, accountID int) ([]models.StoryQuiz, error) {
+	return queryRows(ctx, p.db, func(rows pgx.Rows, s *models.StoryQuiz) error {
+		return rows.Scan(&s.ID, &s.UserID, &s.CreatorUsername, &s.QuizAnswer, &s.AnsweredAt)
+	}, `SELECT id, user_id, creator_username, COALESCE(quiz_answer,''), answered_at FROM story_quizzes WHERE ig_account_id=$1 ORDER BY answered_at DESC`, accountID)
+}
+
+func (p *postgres) ListStoryQuestions(ctx context.Context, accountID int) ([]models.StoryQuestion, error) {
+	return queryRows(ctx, p.db, func(rows pgx.Rows, s *models.StoryQuestion) error {
+		return rows.Scan(&s.ID, &s.UserID, &s.CreatorUsername, &s.RespondedAt)
+	}, `SELECT id, user_id, creator_username, responded_at FROM story_questions WHERE ig_account_id=$1 ORDER BY responded_at DESC`, accountID)
+}
+
+func (p *postgres) ListStoryEmojiSliders(ctx context.Context, accountID int) ([]models.StoryEmojiSlider, error) {
+	return queryRows(ctx, p.db, func(rows pgx.Rows, s *models.StoryEmojiSlider) error {
+		return rows.Scan(&s.ID, &s.UserID, &s.CreatorUsername, &s.SliderValue, &s.RespondedAt)
+	}, `SELECT id, user_id, creator_username, COALESCE(slider_value,0), responded_at FROM story_emoji_sliders WHERE ig_account_id=$1 ORDER BY responded_at DESC`, accountID)
+}
+
+func (p *postgres) ListStoryReactions(ctx context.Context, accountID int) ([]models.StoryReaction, error) {
+	return queryRows(ctx, p.db, func(rows pgx.Rows, s *models.StoryReaction) error {
+		return rows.Scan(&s.ID, &s.UserID, &s.CreatorUsername, &s.RespondedAt)
+	}, `SELECT id, user_id, creator_username, responded_at FROM story_reactions WHERE ig_account_id=$1 ORDER BY responded_at DESC`, accountID)
+}
+
+// --- Saved ---
+
+func (p *postgres) ListSavedMedia(ctx context.Context, accountID int) ([]models.SavedMedia, error) {
+	return queryRows(ctx, p.db, func(rows pgx.Rows, m *models.SavedMedia) error {
+		return rows.Scan(&m.ID, &m.UserID, &m.CreatorUsername, &m.PostURL, &m.SavedAt)
+	}, `SELECT id, user_id, creator_username, post_url, saved_at FROM saved_media WHERE ig_account_id=$1 ORDER BY saved_at DESC`, accountID)
+}
+
+func (p *postgres) ListCollections(ctx context.Context, accountID int) ([]models.SavedCollection, error) {
+	return queryRows(ctx, p.db, scanCollection,
+		`SELECT COALESCE(c.id, 0), a.user_id, names.collection_name, c.created_at, c.updated_at
+		 FROM (SELECT collection_name FROM saved_collections WHERE ig_account_id=$1
+		       UNION SELECT collection_name FROM saved_collection_items WHERE ig_account_id=$1) names
+		 JOIN ig_accounts a ON a.id=$1
+		 LEFT JOIN saved_collections c ON c.ig_account_id=$1 AND c.collection_name=names.collection_name
+		 ORDER BY names.collection_name`, accountID)
+}
+
+// scanCollection reads a collection whose dates are NULL when only its items exist.
+func scanCollection(rows pgx.Rows, c *models.SavedCollection) error {
+	var createdAt, updatedAt *time.Time
+	if err := rows.Scan(&c.ID, &c.UserID, &c.CollectionName, &createdAt, &updatedAt); err != nil {
+		return err
+	}
+	if createdAt != nil {
+		c.CreatedAt = *createdAt
+	}
+	if updatedAt != nil {
+		c.UpdatedAt = *updatedAt
+	}
+	return nil
+}
+
+func (p *postgres) Collection(ctx context.Context, accountID int, name string) (models.SavedCollection, int, error) {
+	c := models.SavedCollection{CollectionName: name}
+	var createdAt, updatedAt *time.Time
+	var itemCount int
+	err := p.db.QueryRow(ctx,
+		`SELECT COALESCE(c.id, 0), a.user_id, c.created_at, c.updated_at,
+		        (SELECT COUNT(*) FROM saved_collection_items WHERE ig_account_id=$1 AND collection_name=$2)
+		 FROM ig_accounts a
+		 LEFT JOIN saved_collections c ON c.ig_account_id=a.id AND c.collection_name=$2
+		 WHERE a.id=$1`, accountID, name).Scan(&c.ID, &c.UserID, &createdAt, &updatedAt, &itemCount)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && c.ID == 0 && itemCount == 0) {
+		return c, 0, ErrNotFound
+	}
+	if err != nil {
+		return c, 0, err
+	}
+	if createdAt != nil {
+		c.CreatedAt = *createdAt
+	}
+	if updatedAt != nil {
+		c.UpdatedAt = *updatedAt
+	}
+	return c, itemCount, nil
+}
+
+var collectionItemsTable = listTable{dateColumn: "added_at", usernameColumn: "creator_username"}
+
+func (p *postgres) ListCollectionItems(ctx context.Context, accountID int, collection string, opts ListOptions) ([]models.SavedCollectionItem, error) {
+	query := `SELECT id, user_id, collection_name, item_url, creator_username, added_at FROM saved_collection_items WHERE ig_account_id=$1`
+	args := []interface{}{accountID}
+	if collection != "" {
+		query += ` AND collection_name=$2`
+		args = append(args, collection)
+	}
+	query, args = collectionItemsTable.list(query, args, opts)
+	return queryRows(ctx, p.db, func(rows pgx.Rows, ci *models.SavedCollectionItem) error {
+		return rows.Scan(&ci.ID, &ci.UserID, &ci.CollectionName, &ci.ItemURL, &ci.CreatorUsername, &ci.AddedAt)
+	}, query, args...)
+}
+
+// --- Interests ---
+
+func scanString(rows pgx.Rows, s *string) error {
+	return rows.Scan(s)
+}
+
+func (p *postgres) ListAdvertisers(ctx context.Context, accountID int) ([]string, error) {
+	return queryRows(ctx, p.db, scanString, `SELECT advertiser_name FROM ad_advertisers WHERE ig_account_id=$1`, accountID)
+}
+
+func (p *postgres) ListAdTopics(ctx context.Context, accountID int) ([]string, error) {
+	return queryRows(ctx, p.db, scanString, `SELECT topic_name FROM ad_topics WHERE ig_account_id=$1`, accountID)
+}
+
+func (p *postgres) ListAIInterests(ctx context.Context, accountID int) ([]models.AIInterest, error) {
+	return queryRows(ctx, p.db, func(rows pgx.Rows, a *models.AIInterest) error {
+		return rows.Scan(&a.ID, &a.UserID, &a.InterestDescription, &a.DetectedAt)
+	}, `SELECT id, user_id, interest_description, detected_at FROM ai_interests WHERE ig_account_id=$1`, accountID)
+}
+
+func (p *postgres) ListTopics(ctx context.Context, accountID int) ([]models.UserTopic, error) {
+	return queryRows(ctx, p.db, func(rows pgx.Rows, t *models.UserTopic) error {
+		return rows.Scan(&t.ID, &t.UserID, &t.TopicName)
+	}, `SELECT id, user_id, topic_name FROM user_topics WHERE ig_account_id=$1 ORDER BY topic_name`, accountID)
+}
+
+func (p *postgres) ListLocationsOfInterest(ctx context.Context, accountID int) ([]string, error) {
+	return queryRows(ctx, p.db, scanString, `SELECT location_name FROM locations_of_interest WHERE ig_account_id=$1 ORDER BY location_name`, accountID)
+}
+
+func (p *postgres) InferredLocation(ctx context.Context, accountID int) (*models.InferredLocation, error) {
+	return queryOptional(ctx, p.db, func(row pgx.Row, l *models.InferredLocation) error {
+		return row.Scan(&l.ID, &l.UserID, &l.CityName)
+	}, `SELECT id, user_id, COALESCE(city_name,'') FROM inferred_location WHERE ig_account_id=$1`, accountID)
+}
+
+// --- Insights ---
+
+func (p *postgres) ConversationStats(ctx context.Context, accountID int, me string, maxReplyGap time.Duration) ([]models.ConversationStats, error) {
+	return queryRows(ctx, p.db, func(rows pgx.Rows, c *models.ConversationStats) error {
+		err := rows.Scan(&c.ConversationID, &c.Participants, &c.Messages, &c.Sent, &c.FirstMessageAt, &c.LastMessageAt,
+			&c.YourReplies, &c.YourAvgReplySeconds, &c.TheirReplies, &c.TheirAvgReplySeconds)
+		c.Received = c.Messages - c.Sent
+		return err
+	}, `WITH ordered AS (
+			SELECT conversation_id, sender_name, sent_at,
+				LAG(sender_name) OVER w AS prev_sender,
+				EXTRACT(EPOCH FROM sent_at - LAG(sent_at) OVER w)::FLOAT8 AS gap
+			FROM messages WHERE ig_account_id=$1
+			WINDOW w AS (PARTITION BY conversation_id ORDER BY sent_at, id)
+		), replies AS (
+			SELECT *,
+				sender_name = $2 AND prev_sender <> $2 AND gap <= $3 AS yours,
+				sender_name <> $2 AND prev_sender = $2 AND gap <= $3 AS theirs
+			FROM ordered
+		)
+		SELECT r.conversation_id, COALESCE(c.participants, ''),
+			COUNT(*), COUNT(*) FILTER (WHERE r.sender_name = $2), MIN(r.sent_at), MAX(r.sent_at),
+			COUNT(*) FILTER (WHERE r.yours), AVG(r.gap) FILTER (WHERE r.yours),
+			COUNT(*) FILTER (WHERE r.theirs), AVG(r.gap) FILTER (WHERE r.theirs)
+		FROM replies r
+		LEFT JOIN message_conversations c ON c.ig_account_id=$1 AND c.conversation_id = r.conversation_id
+		GROUP BY r.conversation_id, c.participants
+		ORDER BY COUNT(*) DESC, r.conversation_id ASC`, accountID, me, maxReplyGap.Seconds())
+}
+
+func (p *postgres) MessageDays(ctx context.Context, accountID int) ([]models.MessageDay, error) {
+	return queryRows(ctx, p.db, func(rows pgx.Rows, d *models.MessageDay) error {
+		return rows.Scan(&d.ConversationID, &d.Day, &d.Messages)
+	}, `SELECT conversation_id, (sent_at AT TIME ZONE 'UTC' AT TIME ZONE 'UTC')::DATE, COUNT(*) FROM messages WHERE ig_account_id=$1
+		GROUP BY 1, 2 ORDER BY 1, 2`, accountID)
+}
+
+// activityEventsSQL is every event behind ActivityCategories. Dated columns without a
+// time zone hold UTC and are read AT TIME ZONE 'UTC', whatever the session's TimeZone.
+// They hold UTC only because main pins time.Local to UTC: pgx writes a time.Time's wall
+// clock into them as is, and the processors' time.Unix values are in the local zone.
+const activityEventsSQL = `
+	SELECT CASE WHEN activity_type = 'ad_viewed' THEN 'ads' ELSE 'views' END AS category, timestamp AS at
+	FROM activity_log WHERE ig_account_id=$1 AND timestamp IS NOT NULL
+	UNION ALL SELECT 'messages', sent_at AT TIME ZONE 'UTC' FROM messages WHERE ig_account_id=$1
+	UNION ALL SELECT 'likes', liked_at AT TIME ZONE 'UTC' FROM post_likes WHERE ig_account_id=$1
+	UNION ALL SELECT 'likes', liked_at AT TIME ZONE 'UTC' FROM comment_likes WHERE ig_account_id=$1
+	UNION ALL SELECT 'likes', liked_at AT TIME ZONE 'UTC' FROM story_likes WHERE ig_account_id=$1
+	UNION ALL SELECT 'comments', commented_at AT TIME ZONE 'UTC' FROM post_comments WHERE ig_account_id=$1
+	UNION ALL SELECT 'comments', commented_at AT TIME ZONE 'UTC' FROM reel_comments WHERE ig_account_id=$1
+	UNION ALL SELECT 'logins', logged_in_at AT TIME ZONE 'UTC' FROM login_history WHERE ig_account_id=$1`
+
+func (p *postgres) ActivityByHour(ctx context.Context, accountID int, categories []string, loc *time.Location) ([]models.ActivityHour, error) {
+	return queryRows(ctx, p.db, func(rows pgx.Rows, h *models.ActivityHour) error {
+		return rows.Scan(&h.Category, &h.Weekday, &h.Hour, &h.Count)
+	}, `SELECT category, EXTRACT(DOW FROM at AT TIME ZONE $2)::INT, EXTRACT(HOUR FROM at AT TIME ZONE $2)::INT, COUNT(*)
+		FROM (`+activityEventsSQL+`) events
+		WHERE category = ANY($3)
+		GROUP BY 1, 2, 3 ORDER BY 1, 2, 3`, accountID, loc.String(), categories)
+}
+
+func (p *postgres) ActivityByMonth(ctx context.Context, accountID int, categories []string, loc *time.Location) ([]models.ActivityMonth, error) {
+	return queryRows(ctx, p.db, func(rows pgx.Rows, m *models.ActivityMonth) error {
+		return rows.Scan(&m.Category, &m.Month, &m.Count)
+	}, `SELECT category, date_trunc('month', at AT TIME ZONE $2), COUNT(*)
+		FROM (`+activityEventsSQL+`) events
+		WHERE category = ANY($3)
+		GROUP BY 1, 2 ORDER BY 2, 1`, accountID, loc.String(), categories)
+}
+
+func (p *postgres) AdViewsByAuthor(ctx context.Context, accountID int) ([]models.AdViews, error) {
+	return queryRows(ctx, p.db, func(rows pgx.Rows, v *models.AdViews) error {
+		return rows.Scan(&v.Author, &v.Views, &v.FirstSeen, &v.LastSeen)
+	}, `SELECT author, COUNT(*), MIN(timestamp), MAX(timestamp) FROM activity_log
+		WHERE ig_account_id=$1 AND activity_type = 'ad_viewed' AND author IS NOT NULL AND author <> ''
+		GROUP BY author ORDER BY COUNT(*) DESC, author ASC`, accountID)
+}
+
+func (p *postgres) OffMetaApps(ctx context.Context, accountID int) ([]models.OffMetaApp, error) {
+	return queryRows(ctx, p.db, func(rows pgx.Rows, a *models.OffMetaApp) error {
+		return rows.Scan(&a.AppName, &a.Events, &a.EventTypes, &a.FirstSeen, &a.LastSeen)
+	}, `SELECT app_name, COUNT(*), array_agg(DISTINCT event_type ORDER BY event_type), MIN(event_at), MAX(event_at)
+		FROM off_meta_activity WHERE ig_account_id=$1
+		GROUP BY app_name ORDER BY COUNT(*) DESC, app_name ASC`, accountID)
+}
+
+// interactionEventsSQL is every interaction behind InteractionKinds; $2 is the owner's
+// sender name. Messages take the username the conversation id starts with.
+const interactionEventsSQL = `
+	SELECT 'post_like' AS kind, creator_username AS username, id, liked_at AT TIME ZONE 'UTC' AS at, post_url AS detail
+	FROM post_likes WHERE ig_account_id=$1
+	UNION ALL SELECT 'comment_like', owner_username, id, liked_at AT TIME ZONE 'UTC', post_url FROM comment_likes WHERE ig_account_id=$1
+	UNION ALL SELECT 'story_like', creator_username, id, liked_at AT TIME ZONE 'UTC', NULL FROM story_likes WHERE ig_account_id=$1
+	UNION ALL SELECT 'post_comment', post_owner_username, id, commented_at AT TIME ZONE 'UTC', comment_text FROM post_comments WHERE ig_account_id=$1
+	UNION ALL SELECT 'reel_comment', reel_owner_username, id, commented_at AT TIME ZONE 'UTC', comment_text FROM reel_comments WHERE ig_account_id=$1
+	UNION ALL SELECT 'story_poll', creator_username, id, answered_at AT TIME ZONE 'UTC', poll_answer FROM story_polls WHERE ig_account_id=$1
+	UNION ALL SELECT 'story_quiz', creator_username, id, answered_at AT TIME ZONE 'UTC', quiz_answer FROM story_quizzes WHERE ig_account_id=$1
+	UNION ALL SELECT 'story_question', creator_username, id, responded_at AT TIME ZONE 'UTC', NULL FROM story_questions WHERE ig_account_id=$1
+	UNION ALL SELECT 'story_slider', creator_username, id, responded_at AT TIME ZONE 'UTC', slider_value::FLOAT8::TEXT FROM story_emoji_sliders WHERE ig_account_id=$1
+	UNION ALL SELECT 'story_reaction', creator_username, id, responded_at AT TIME ZONE 'UTC', NULL FROM story_reactions WHERE ig_account_id=$1
+	UNION ALL SELECT 'message', regexp_replace(m.conversation_id, '_[0-9]+$', ''), m.id, m.sent_at AT TIME ZONE 'UTC', m.content
+	FROM messages m JOIN message_conversations c ON c.ig_account_id=$1 AND c.conversation_id = m.conversation_id
+	WHERE m.ig_account_id=$1 AND m.sender_name = $2 AND array_length(string_to_array(c.participants, ', '), 1) = 2`
+
+func (p *postgres) InteractionCounts(ctx context.Context, accountID int, me string) ([]models.InteractionCount, error) {
+	return queryRows(ctx, p.db, func(rows pgx.Rows, c *models.InteractionCount) error {
+		return rows.Scan(&c.Username, &c.Kind, &c.Count, &c.FirstAt, &c.LastAt)
+	}, `SELECT lower(username), kind, COUNT(*), MIN(at), MAX(at)
+		FROM (`+interactionEventsSQL+`) events
+		WHERE username IS NOT NULL AND username <> ''
+		GROUP BY 1, 2 ORDER BY 1, 2`, accountID, me)
+}
+
+func (p *postgres) ListInteractions(ctx context.Context, accountID int, me, username string, opts ListOptions) ([]models.Interaction, error) {
+	query := `SELECT kind, id, at, detail
+		FROM (` + interactionEventsSQL + `) events
+		WHERE lower(username) = lower($3)`
+	args := []interface{}{accountID, me, username}
+	if opts.After != nil {
+		query += " AND (at, kind, id) > ($4, $5, $6)"
+		args = append(args, opts.After.At, opts.After.Key, opts.After.ID)
+	}
+	query += " ORDER BY at, kind, id"
+	if opts.Limit > 0 {
+		args = append(args, opts.Limit+1)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	return queryRows(ctx, p.db, func(rows pgx.Rows, i *models.Interaction) error {
+		return rows.Scan(&i.Kind, &i.ID, &i.At, &i.Detail)
+	}, query, args...)
+}
+
+// memoriesSQL is every record behind MemoryTypes. A conversation's first message is
+// its earliest; DISTINCT ON keeps one per conversation.
+const memoriesSQL = `
+	SELECT COALESCE(media_type, 'post') AS type, id, taken_at AS at, uri, caption AS text,
+		NULL::TEXT AS conversation_id, NULL::TEXT AS participants
+	FROM media_items WHERE ig_account_id=$1
+	UNION ALL SELECT 'archived_post', id, taken_at AT TIME ZONE 'UTC', uri, caption, NULL, NULL
+	FROM archived_posts WHERE ig_account_id=$1 AND taken_at IS NOT NULL
+	UNION ALL SELECT 'profile_photo', id, set_at AT TIME ZONE 'UTC', photo_uri, NULL, NULL, NULL FROM profile_photos WHERE ig_account_id=$1
+	UNION ALL (
+		SELECT DISTINCT ON (m.conversation_id) 'first_message', m.id, m.sent_at AT TIME ZONE 'UTC', NULL, m.content, m.conversation_id, c.participants
+		FROM messages m
+		LEFT JOIN message_conversations c ON c.ig_account_id=$1 AND c.conversation_id = m.conversation_id
+		WHERE m.ig_account_id=$1
+		ORDER BY m.conversation_id, m.sent_at, m.id)`
+
+func (p *postgres) OnThisDay(ctx context.Context, accountID int, year int, month time.Month, days []int, loc *time.Location) ([]models.MemoryItem, error) {
+	return queryRows(ctx, p.db, func(rows pgx.Rows, m *models.MemoryItem) error {
+		return rows.Scan(&m.Type, &m.ID, &m.At, &m.URI, &m.Text, &m.ConversationID, &m.Participants)
+	}, `SELECT type, id, at, uri, text, conversation_id, participants
+		FROM (`+memoriesSQL+`) memories
+		WHERE EXTRACT(MONTH FROM at AT TIME ZONE $2)::INT = $3
+			AND EXTRACT(DAY FROM at AT TIME ZONE $2)::INT = ANY($4)
+			AND EXTRACT(YEAR FROM at AT TIME ZONE $2)::INT < $5
+		ORDER BY at, type, id`, accountID, loc.String(), int(month), days, year)
+}
+
+// ownTextsSQL is every text behind TextSources. $2 is me; $3, when not empty, keeps
+// only that conversation's messages.
+const ownTextsSQL = `
+	SELECT 'caption' AS source, id, taken_at AS at, caption AS text
+	FROM media_items WHERE ig_account_id=$1 AND $3::TEXT = '' AND COALESCE(caption, '') <> ''
+	UNION ALL SELECT 'post_comment', id, commented_at AT TIME ZONE 'UTC', comment_text
+	FROM post_comments WHERE ig_account_id=$1 AND $3 = ''
+	UNION ALL SELECT 'reel_comment', id, commented_at AT TIME ZONE 'UTC', comment_text
+	FROM reel_comments WHERE ig_account_id=$1 AND $3 = ''
+	UNION ALL SELECT 'message', id, sent_at AT TIME ZONE 'UTC', content
+	FROM messages WHERE ig_account_id=$1 AND sender_name = $2 AND ($3 = '' OR conversation_id = $3) AND COALESCE(content, '') <> ''`
+
+func (p *postgres) OwnTexts(ctx context.Context, accountID int, me, conversationID string, opts ListOptions) ([]models.OwnText, error) {
+	query := `SELECT source, id, at, text FROM (` + ownTextsSQL + `) texts WHERE TRUE`
+	args := []interface{}{accountID, me, conversationID}
+	if len(opts.Types) > 0 {
+		args = append(args, opts.Types)
+		query += fmt.Sprintf(" AND source = ANY($%d)", len(args))
+	}
+	if opts.From != nil {
+		args = append(args, *opts.From)
+		query += fmt.Sprintf(" AND at >= $%d", len(args))
+	}
+	if opts.To != nil {
+		args = append(args, *opts.To)
+		query += fmt.Sprintf(" AND at < $%d", len(args))
+	}
+	return queryRows(ctx, p.db, func(rows pgx.Rows, t *models.OwnText) error {
+		return rows.Scan(&t.Source, &t.ID, &t.At, &t.Text)
+	}, query+` ORDER BY at, source, id`, args...)
+}
+
+// --- Search ---
+
+// ts_headline marks matches with HighlightStart and HighlightStop.
+var headlineOptions = fmt.Sprintf("StartSel=%s, StopSel=%s, MaxWords=35, MinWords=12, MaxFragments=2, FragmentDelimiter=\" … \"",
+	HighlightStart, HighlightStop)
+
+// Every source is reduced to the same columns: the searchable body, a short label
+// and `ref`, the record the hit links to.
+const searchHitsSQL = `
+	SELECT 'caption' AS type, id, taken_at AS at, ts_rank(search_vector, q) AS rank,
+	       COALESCE(caption, '') AS body, media_type AS label, uri AS ref
+	FROM media_items, query WHERE ig_account_id=$1 AND search_vector @@ q
+	UNION ALL
+	SELECT 'message', id, sent_at AT TIME ZONE 'UTC', ts_rank(search_vector, q),
+	       COALESCE(content, ''), sender_name, conversation_id
+	FROM messages, query WHERE ig_account_id=$1 AND search_vector @@ q
+	UNION ALL
+	SELECT 'post_comment', id, commented_at AT TIME ZONE 'UTC', ts_rank(search_vector, q),
+	       comment_text, COALESCE(post_owner_username, ''), ''
+	FROM post_comments, query WHERE ig_account_id=$1 AND search_vector @@ q
+	UNION ALL
+	SELECT 'reel_comment', id, commented_at AT TIME ZONE 'UTC', ts_rank(search_vector, q),
+	       comment_text, COALESCE(reel_owner_username, ''), ''
+	FROM reel_comments, query WHERE ig_account_id=$1 AND search_vector @@ q
+	UNION ALL
+	SELECT 'search', id, searched_at AT TIME ZONE 'UTC', ts_rank(search_vector, q),
+	       search_query, search_type, ''
+	FROM search_history, query WHERE ig_account_id=$1 AND search_vector @@ q
+	UNION ALL
+	SELECT 'saved_media', id, saved_at AT TIME ZONE 'UTC', ts_rank(search_vector, q),
+	       COALESCE(creator_username, '') || ' ' || post_url, COALESCE(creator_username, ''), post_url
+	FROM saved_media, query WHERE ig_account_id=$1 AND search_vector @@ q
+	UNION ALL
+	SELECT 'collection_item', id, added_at AT TIME ZONE 'UTC', ts_rank(search_vector, q),
+	       collection_name || ' ' || COALESCE(creator_username, '') || ' ' || item_url, collection_name, item_url
+	FROM saved_collection_items, query WHERE ig_account_id=$1 AND search_vector @@ q`
+
+var searchHitsTable = listTable{typeColumn: "type", dateColumn: "at"}
+
+func (p *postgres) Search(ctx context.Context, accountID int, query string, opts ListOptions) ([]models.SearchHit, error) {
+	// Rank and cut first, then build headlines for the survivors only: ts_headline
+	// re-parses the whole document and is by far the most expensive step.
+	filtered, args := searchHitsTable.filter(`SELECT * FROM hits WHERE TRUE`, []interface{}{accountID, query}, opts)
+	args = append(args, opts.Limit, headlineOptions)
+	sql := fmt.Sprintf(`
+		WITH query AS (SELECT websearch_to_tsquery('simple', $2) AS q),
+		hits AS (%s)
+		SELECT h.type, h.id, h.at, h.rank, ts_headline('simple', h.body, query.q, $%d), h.label, h.ref
+		FROM (%s ORDER BY rank DESC, at DESC NULLS LAST LIMIT $%d) h, query
+		ORDER BY h.rank DESC, h.at DESC NULLS LAST`,
+		searchHitsSQL, len(args), filtered, len(args)-1)
+	return queryRows(ctx, p.db, func(rows pgx.Rows, h *models.SearchHit) error {
+		return rows.Scan(&h.Type, &h.ID, &h.At, &h.Rank, &h.Snippet, &h.Label, &h.Ref)
+	}, sql, args...)
+}
+
+// --- Timeline ---
+
+// timelineEventsSQL maps every timestamped table onto one event shape. Outer filters
+// on type/at are pushed down into each branch by the planner. ids repeat across tables,
+// so source names the table a row came from and (at, source, id) is unique.
+const timelineEventsSQL = `
+	SELECT media_type AS type, NULL::TEXT AS subtype, id, taken_at AS at, NULL::TEXT AS username, caption AS text,
+		'media_items' AS source
+	FROM media_items WHERE ig_account_id=$1
+	UNION ALL
+	SELECT 'like', 'post', id, liked_at AT TIME ZONE 'UTC', creator_username, post_url, 'post_likes' FROM post_likes WHERE ig_account_id=$1
+	UNION ALL
+	SELECT 'like', 'comment', id, liked_at AT TIME ZONE 'UTC', owner_username, post_url, 'comment_likes' FROM comment_likes WHERE ig_account_id=$1
+	UNION ALL
+	SELECT 'like', 'story', id, liked_at AT TIME ZONE 'UTC', creator_username, NULL, 'story_likes' FROM story_likes WHERE ig_account_id=$1
+	UNION ALL
+	SELECT 'comment', 'post', id, commented_at AT TIME ZONE 'UTC', post_owner_username, comment_text, 'post_comments' FROM post_comments WHERE ig_account_id=$1
+	UNION ALL
+	SELECT 'comment', 'reel', id, commented_at AT TIME ZONE 'UTC', reel_owner_username, comment_text, 'reel_comments' FROM reel_comments WHERE ig_account_id=$1
+	UNION ALL
+	SELECT 'message', conversation_id, id, sent_at AT TIME ZONE 'UTC', sender_name, content, 'messages' FROM messages WHERE ig_account_id=$1
+	UNION ALL
+	SELECT 'story_interaction', 'poll', id, answered_at AT TIME ZONE 'UTC', creator_username, poll_answer, 'story_polls' FROM story_polls WHERE ig_account_id=$1
+	UNION ALL
+	SELECT 'story_interaction', 'quiz', id, answered_at AT TIME ZONE 'UTC', creator_username, quiz_answer, 'story_quizzes' FROM story_quizzes WHERE ig_account_id=$1
+	UNION ALL
+	SELECT 'story_interaction', 'question', id, responded_at AT TIME ZONE 'UTC', creator_username, NULL, 'story_questions' FROM story_questions WHERE ig_account_id=$1
+	UNION ALL
+	SELECT 'story_interaction', 'emoji_slider', id, responded_at AT TIME ZONE 'UTC', creator_username, slider_value::TEXT, 'story_emoji_sliders' FROM story_emoji_sliders WHERE ig_account_id=$1
+	UNION ALL
+	SELECT 'story_interaction', 'reaction', id, responded_at AT TIME ZONE 'UTC', creator_username, NULL, 'story_reactions' FROM story_reactions WHERE ig_account_id=$1
+	UNION ALL
+	SELECT 'connection', connection_type, id, timestamp, username, NULL, 'connections' FROM connections WHERE ig_account_id=$1
+	UNION ALL
+	SELECT 'search', search_type, id, searched_at AT TIME ZONE 'UTC', NULL, search_query, 'search_history' FROM search_history WHERE ig_account_id=$1
+	UNION ALL
+	SELECT 'saved', NULL, id, saved_at AT TIME ZONE 'UTC', creator_username, post_url, 'saved_media' FROM saved_media WHERE ig_account_id=$1
+	UNION ALL
+	SELECT 'activity', activity_type, id, timestamp, author, details, 'activity_log' FROM activity_log WHERE ig_account_id=$1 AND timestamp IS NOT NULL
+	UNION ALL
+	SELECT 'login', NULL, id, logged_in_at AT TIME ZONE 'UTC', NULL, ip_address, 'login_history' FROM login_history WHERE ig_account_id=$1
+	UNION ALL
+	SELECT 'logout', NULL, id, logged_out_at AT TIME ZONE 'UTC', NULL, ip_address, 'logout_history' FROM logout_history WHERE ig_account_id=$1
+	UNION ALL
+	SELECT 'password_change', NULL, id, changed_at AT TIME ZONE 'UTC', NULL, NULL, 'password_change_history' FROM password_change_history WHERE ig_account_id=$1
+	UNION ALL
+	SELECT 'privacy_change', privacy_status, id, changed_at AT TIME ZONE 'UTC', NULL, NULL, 'privacy_changes' FROM privacy_changes WHERE ig_account_id=$1
+	UNION ALL
+	SELECT 'account_status', activation_type, id, changed_at AT TIME ZONE 'UTC', NULL, reason, 'account_status_history' FROM account_status_history WHERE ig_account_id=$1
+	UNION ALL
+	SELECT 'profile_change', field_changed, id, changed_at AT TIME ZONE 'UTC', NULL, new_value, 'profile_changes' FROM profile_changes WHERE ig_account_id=$1`
+
+var timelineTable = listTable{typeColumn: "type", dateColumn: "at", usernameColumn: "username"}
+
+func (p *postgres) Timeline(ctx context.Context, accountID int, opts ListOptions) ([]models.TimelineEvent, error) {
+	query, args := timelineTable.filter(`SELECT type, subtype, id, at, username, text, source FROM (`+timelineEventsSQL+`) events WHERE TRUE`,
+		[]interface{}{accountID}, opts)
+
+	cmp, dir := "<", "DESC"
+	if opts.Ascending {
+		cmp, dir = ">", "ASC"
+	}
+	if opts.After != nil {
+		query += fmt.Sprintf(" AND (at, source, id) %s ($%d, $%d, $%d)", cmp, len(args)+1, len(args)+2, len(args)+3)
+		args = append(args, opts.After.At, opts.After.Key, opts.After.ID)
+	}
+	query += fmt.Sprintf(" ORDER BY at %s, source %s, id %s", dir, dir, dir)
+	if opts.Limit > 0 {
+		args = append(args, opts.Limit+1)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	return queryRows(ctx, p.db, func(rows pgx.Rows, e *models.TimelineEvent) error {
+		return rows.Scan(&e.Type, &e.Subtype, &e.ID, &e.At, &e.Username, &e.Text, &e.Source)
+	}, query, args...)
+}
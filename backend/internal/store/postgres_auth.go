@@ -0,0 +1,288 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Sa-Te/IAV/backend/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// The server's own records: users, their second factor, API tokens and share links.
+
+// affectedOne turns an UPDATE or DELETE that matched no row into ErrNotFound.
+func affectedOne(tag pgconn.CommandTag, err error) error {
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// --- Users ---
+
+// createUserLock is the advisory lock key CreateUser holds while it picks the new user's role.
+const createUserLock = 1_000_001
+
+// CreateUser holds a transaction-scoped advisory lock while it checks for an admin:
+// under READ COMMITTED two sign-ups racing on a fresh instance would otherwise both
+// see none and both become admin.
+func (p *postgres) CreateUser(ctx context.Context, email, passwordHash string) (int, error) {
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, createUserLock); err != nil {
+		return 0, err
+	}
+	var userID int
+	err = tx.QueryRow(ctx,
+		`INSERT INTO users (email, password_hash, role)
+		 VALUES ($1, $2, CASE WHEN EXISTS (SELECT 1 FROM users WHERE role = 'admin') THEN 'user' ELSE 'admin' END)
+		 RETURNING id`, email, passwordHash).Scan(&userID)
+	if err != nil {
+		return 0, err
+	}
+	return userID, tx.Commit(ctx)
+}
+
+const userColumns = `id, email, password_hash, role, created_at, disabled_at, totp_enabled, totp_secret, totp_last_step`
+
+func scanUser(row pgx.Row) (models.User, error) {
+	var u models.User
+	err := row.Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Role, &u.CreatedAt, &u.DisabledAt, &u.TOTPEnabled, &u.TOTPSecret, &u.TOTPLastStep)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return u, ErrNotFound
+	}
+	return u, err
+}
+
+func (p *postgres) User(ctx context.Context, userID int) (models.User, error) {
+	return scanUser(p.db.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE id=$1`, userID))
+}
+
+func (p *postgres) UserByEmail(ctx context.Context, email string) (models.User, error) {
+	return scanUser(p.db.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE email=$1`, email))
+}
+
+func (p *postgres) ListUsers(ctx context.Context) ([]models.AdminUser, error) {
+	return queryRows(ctx, p.db, func(rows pgx.Rows, u *models.AdminUser) error {
+		return rows.Scan(&u.ID, &u.Email, &u.Role, &u.CreatedAt, &u.DisabledAt, &u.AccountCount, &u.ImportCount, &u.LastImportAt)
+	}, `SELECT u.id, u.email, u.role, u.created_at, u.disabled_at,
+	           (SELECT COUNT(*) FROM ig_accounts a WHERE a.user_id = u.id),
+	           COUNT(i.id), MAX(i.created_at)
+	    FROM users u LEFT JOIN imports i ON i.user_id = u.id
+	    GROUP BY u.id ORDER BY u.id`)
+}
+
+func (p *postgres) SetUserDisabled(ctx context.Context, userID int, disabled bool) error {
+	query := `UPDATE users SET disabled_at=NULL WHERE id=$1`
+	if disabled {
+		query = `UPDATE users SET disabled_at=COALESCE(disabled_at, NOW()) WHERE id=$1`
+	}
+	return affectedOne(p.db.Exec(ctx, query, userID))
+}
+
+// DeleteUser relies on ON DELETE CASCADE for everything the user owns.
+func (p *postgres) DeleteUser(ctx context.Context, userID int) error {
+	return affectedOne(p.db.Exec(ctx, `DELETE FROM users WHERE id=$1`, userID))
+}
+
+// --- Second factor ---
+
+func (p *postgres) SetTOTPSecret(ctx context.Context, userID int, secret string) error {
+	return affectedOne(p.db.Exec(ctx, `UPDATE users SET totp_secret=$2, totp_last_step=0 WHERE id=$1`, userID, secret))
+}
+
+func (p *postgres) EnableTwoFactor(ctx context.Context, userID int, step int64, recoveryCodeHashes []string) error {
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM recovery_codes WHERE user_id=$1`, userID); err != nil {
+		return err
+	}
+	for _, hash := range recoveryCodeHashes {
+		if _, err := tx.Exec(ctx, `INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hash); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(ctx, `UPDATE users SET totp_enabled=TRUE, totp_last_step=$2 WHERE id=$1`, userID, step); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (p *postgres) DisableTwoFactor(ctx context.Context, userID int) error {
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `UPDATE users SET totp_enabled=FALSE, totp_secret=NULL, totp_last_step=0 WHERE id=$1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM recovery_codes WHERE user_id=$1`, userID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ReserveTwoFactorAttempt is a single UPDATE so that concurrent attempts are counted
+// one after the other. An expired lockout starts the count over.
+func (p *postgres) ReserveTwoFactorAttempt(ctx context.Context, userID, maxAttempts int, lockout time.Duration) error {
+	tag, err := p.db.Exec(ctx, `
+		UPDATE users SET
+			totp_failed_attempts = CASE WHEN totp_locked_until <= NOW() THEN 1 ELSE totp_failed_attempts + 1 END,
+			totp_locked_until = CASE
+				WHEN (CASE WHEN totp_locked_until <= NOW() THEN 1 ELSE totp_failed_attempts + 1 END) >= $2
+				THEN NOW() + $3 * INTERVAL '1 second' END
+		WHERE id=$1 AND (totp_locked_until IS NULL OR totp_locked_until <= NOW())`,
+		userID, maxAttempts, int(lockout.Seconds()))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrLocked
+	}
+	return nil
+}
+
+func (p *postgres) ResetTwoFactorAttempts(ctx context.Context, userID int) error {
+	_, err := p.db.Exec(ctx, `UPDATE users SET totp_failed_attempts=0, totp_locked_until=NULL WHERE id=$1`, userID)
+	return err
+}
+
+// AcceptTOTPStep only moves totp_last_step forward, so of two requests presenting the
+// same code only one updates the row.
+func (p *postgres) AcceptTOTPStep(ctx context.Context, userID int, step int64) (bool, error) {
+	tag, err := p.db.Exec(ctx, `UPDATE users SET totp_last_step=$2 WHERE id=$1 AND totp_last_step < $2`, userID, step)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (p *postgres) UnusedRecoveryCodes(ctx context.Context, userID int) ([]models.RecoveryCode, error) {
+	return queryRows(ctx, p.db, func(rows pgx.Rows, c *models.RecoveryCode) error {
+		return rows.Scan(&c.ID, &c.CodeHash)
+	}, `SELECT id, code_hash FROM recovery_codes WHERE user_id=$1 AND used_at IS NULL ORDER BY id`, userID)
+}
+
+func (p *postgres) UseRecoveryCode(ctx context.Context, codeID int) (bool, error) {
+	tag, err := p.db.Exec(ctx, `UPDATE recovery_codes SET used_at=NOW() WHERE id=$1 AND used_at IS NULL`, codeID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// --- API tokens ---
+
+func (p *postgres) CreateAPIToken(ctx context.Context, t models.APIToken, hash string) (models.APIToken, error) {
+	err := p.db.QueryRow(ctx,
+		`INSERT INTO api_tokens (user_id, name, token_prefix, token_hash, scope, expires_at)
+		 VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`,
+		t.UserID, t.Name, t.Prefix, hash, t.Scope, t.ExpiresAt).Scan(&t.ID, &t.CreatedAt)
+	return t, err
+}
+
+const apiTokenColumns = `id, user_id, name, token_prefix, scope, created_at, last_used_at, expires_at, revoked_at`
+
+func scanAPIToken(row pgx.Row, t *models.APIToken) error {
+	return row.Scan(&t.ID, &t.UserID, &t.Name, &t.Prefix, &t.Scope, &t.CreatedAt, &t.LastUsedAt, &t.ExpiresAt, &t.RevokedAt)
+}
+
+func (p *postgres) LiveAPIToken(ctx context.Context, hash string) (models.APIToken, error) {
+	var t models.APIToken
+	err := scanAPIToken(p.db.QueryRow(ctx,
+		`SELECT `+apiTokenColumns+` FROM api_tokens
+		 WHERE token_hash=$1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())`, hash), &t)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return t, ErrNotFound
+	}
+	return t, err
+}
+
+func (p *postgres) TouchAPIToken(ctx context.Context, tokenID int) error {
+	_, err := p.db.Exec(ctx, `UPDATE api_tokens SET last_used_at=NOW() WHERE id=$1`, tokenID)
+	return err
+}
+
+func (p *postgres) ListAPITokens(ctx context.Context, userID int) ([]models.APIToken, error) {
+	return queryRows(ctx, p.db, func(rows pgx.Rows, t *models.APIToken) error { return scanAPIToken(rows, t) },
+		`SELECT `+apiTokenColumns+` FROM api_tokens WHERE user_id=$1 ORDER BY created_at DESC`, userID)
+}
+
+func (p *postgres) RevokeAPIToken(ctx context.Context, userID, tokenID int) error {
+	return affectedOne(p.db.Exec(ctx,
+		`UPDATE api_tokens SET revoked_at=NOW() WHERE id=$1 AND user_id=$2 AND revoked_at IS NULL`, tokenID, userID))
+}
+
+// --- Share links ---
+
+func (p *postgres) CreateShareLink(ctx context.Context, l models.ShareLink) (models.ShareLink, error) {
+	err := p.db.QueryRow(ctx,
+		`INSERT INTO share_links (user_id, ig_account_id, name, sections, date_from, date_to, collection_name, expires_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, created_at`,
+		l.UserID, l.AccountID, l.Name, l.Sections, l.DateFrom, l.DateTo, l.CollectionName, l.ExpiresAt).Scan(&l.ID, &l.CreatedAt)
+	return l, err
+}
+
+const shareLinkColumns = `l.id, l.user_id, l.ig_account_id, l.name, l.sections, l.date_from, l.date_to, l.collection_name, l.created_at, l.expires_at, l.revoked_at`
+
+func scanShareLink(row pgx.Row, l *models.ShareLink) error {
+	return row.Scan(&l.ID, &l.UserID, &l.AccountID, &l.Name, &l.Sections, &l.DateFrom, &l.DateTo, &l.CollectionName, &l.CreatedAt, &l.ExpiresAt, &l.RevokedAt)
+}
+
+func (p *postgres) LiveShareLink(ctx context.Context, linkID int) (models.ShareLink, error) {
+	var l models.ShareLink
+	err := scanShareLink(p.db.QueryRow(ctx,
+		`SELECT `+shareLinkColumns+` FROM share_links l JOIN users u ON u.id = l.user_id
+		 WHERE l.id=$1 AND l.revoked_at IS NULL AND l.expires_at > NOW() AND u.disabled_at IS NULL`, linkID), &l)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return l, ErrNotFound
+	}
+	return l, err
+}
+
+func (p *postgres) ListShareLinks(ctx context.Context, userID int) ([]models.ShareLink, error) {
+	return queryRows(ctx, p.db, func(rows pgx.Rows, l *models.ShareLink) error { return scanShareLink(rows, l) },
+		`SELECT `+shareLinkColumns+` FROM share_links l WHERE l.user_id=$1 ORDER BY l.created_at DESC`, userID)
+}
+
+func (p *postgres) RevokeShareLink(ctx context.Context, userID, linkID int) error {
+	return affectedOne(p.db.Exec(ctx,
+		`UPDATE share_links SET revoked_at=NOW() WHERE id=$1 AND user_id=$2 AND revoked_at IS NULL`, linkID, userID))
+}
+
+func (p *postgres) LogShareAccess(ctx context.Context, linkID int, access models.ShareLinkAccess) error {
+	_, err := p.db.Exec(ctx,
+		`INSERT INTO share_link_access_log (share_link_id, path, ip_address, user_agent) VALUES ($1, $2, $3, $4)`,
+		linkID, access.Path, access.IPAddress, access.UserAgent)
+	return err
+}
+
+func (p *postgres) ListShareAccess(ctx context.Context, userID, linkID, limit int) ([]models.ShareLinkAccess, error) {
+	var owned bool
+	err := p.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM share_links WHERE id=$1 AND user_id=$2)`, linkID, userID).Scan(&owned)
+	if err != nil {
+		return nil, err
+	}
+	if !owned {
+		return nil, ErrNotFound
+	}
+	return queryRows(ctx, p.db, func(rows pgx.Rows, e *models.ShareLinkAccess) error {
+		return rows.Scan(&e.ID, &e.AccessedAt, &e.Path, &e.IPAddress, &e.UserAgent)
+	}, `SELECT id, accessed_at, path, ip_address, user_agent FROM share_link_access_log
+	    WHERE share_link_id=$1 ORDER BY accessed_at DESC LIMIT $2`, linkID, limit)
+}
@@ -0,0 +1,376 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Sa-Te/IAV/backend/internal/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// The write side of the archive repositories: what the processors save from an upload.
+
+// execEach runs query once per row. Rows are inserted one by one rather than in a batch
+// so that a bad row only loses itself; the errors of every failed row are joined.
+func execEach[T any](ctx context.Context, db *pgxpool.Pool, query string, rows []T, args func(T) []interface{}) error {
+	var errs []error
+	for _, row := range rows {
+		if _, err := db.Exec(ctx, query, args(row)...); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// --- Imports ---
+
+func (p *postgres) Now(ctx context.Context) (time.Time, error) {
+	var now time.Time
+	err := p.db.QueryRow(ctx, `SELECT NOW()`).Scan(&now)
+	return now, err
+}
+
+func (p *postgres) CreateImport(ctx context.Context, userID, accountID int, archiveBytes int64) (int, error) {
+	var importID int
+	err := p.db.QueryRow(ctx,
+		`INSERT INTO imports (user_id, ig_account_id, archive_bytes) VALUES ($1, $2, $3) RETURNING id`,
+		userID, accountID, archiveBytes).Scan(&importID)
+	return importID, err
+}
+
+func (p *postgres) LatestImport(ctx context.Context, accountID int) (int, error) {
+	var version int
+	err := p.db.QueryRow(ctx, `SELECT COALESCE(MAX(id), 0) FROM imports WHERE ig_account_id=$1`, accountID).Scan(&version)
+	return version, err
+}
+
+func (p *postgres) SaveSnapshot(ctx context.Context, accountID, importID int, started time.Time) error {
+	_, err := p.db.Exec(ctx, `
+		INSERT INTO connection_snapshots (import_id, ig_account_id, username, connection_type, timestamp)
+		SELECT $1, ig_account_id, username, connection_type, timestamp FROM connections
+		WHERE ig_account_id=$2 AND last_seen_at >= $3
+		ON CONFLICT DO NOTHING`, importID, accountID, started)
+	return err
+}
+
+// --- Media ---
+
+func (p *postgres) SaveMedia(ctx context.Context, userID, accountID int, items []models.MediaItem) error {
+	return execEach(ctx, p.db,
+		`INSERT INTO media_items (user_id, ig_account_id, uri, caption, taken_at, media_type) VALUES ($1, $2, $3, $4, $5, $6)
+		 ON CONFLICT (ig_account_id, uri) DO NOTHING`,
+		items, func(m models.MediaItem) []interface{} {
+			return []interface{}{userID, accountID, m.URI, m.Caption, m.TakenAt, m.MediaType}
+		})
+}
+
+func (p *postgres) SaveArchivedPosts(ctx context.Context, userID, accountID int, posts []models.ArchivedPost) error {
+	return execEach(ctx, p.db,
+		`INSERT INTO archived_posts (user_id, ig_account_id, uri, caption, taken_at) VALUES ($1, $2, $3, $4, $5) ON CONFLICT DO NOTHING`,
+		posts, func(a models.ArchivedPost) []interface{} {
+			return []interface{}{userID, accountID, a.URI, a.Caption, a.TakenAt}
+		})
+}
+
+// --- Connections ---
+
+func (p *postgres) SaveConnections(ctx context.Context, userID, accountID int, connections []models.Connection) error {
+	return execEach(ctx, p.db,
+		`INSERT INTO connections (user_id, ig_account_id, username, connection_type, timestamp, contact_info)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 ON CONFLICT (ig_account_id, username, connection_type) DO UPDATE SET
+		   timestamp = CASE WHEN connections.connection_type IN ('contact', 'follower', 'following') THEN connections.timestamp ELSE EXCLUDED.timestamp END,
+		   contact_info = COALESCE(EXCLUDED.contact_info, connections.contact_info),
+		   last_seen_at = NOW()`,
+		connections, func(c models.Connection) []interface{} {
+			return []interface{}{userID, accountID, c.Username, c.ConnectionType, c.Timestamp, c.ContactInfo}
+		})
+}
+
+func (p *postgres) SaveFollowedHashtags(ctx context.Context, userID, accountID int, hashtags []models.FollowedHashtag) error {
+	return execEach(ctx, p.db,
+		`INSERT INTO followed_hashtags (user_id, ig_account_id, name, timestamp) VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (ig_account_id, name) DO UPDATE SET timestamp = EXCLUDED.timestamp`,
+		hashtags, func(h models.FollowedHashtag) []interface{} {
+			return []interface{}{userID, accountID, h.Name, h.Timestamp}
+		})
+}
+
+// --- Activity ---
+
+func (p *postgres) SaveActivity(ctx context.Context, userID, accountID int, activity []models.ActivityLog) error {
+	return execEach(ctx, p.db,
+		`INSERT INTO activity_log (user_id, ig_account_id, activity_type, author, timestamp, details)
+		 VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT DO NOTHING`,
+		activity, func(a models.ActivityLog) []interface{} {
+			return []interface{}{userID, accountID, a.ActivityType, a.Author, a.Timestamp, a.Details}
+		})
+}
+
+func (p *postgres) SaveSearchHistory(ctx context.Context, userID, accountID int, searches []models.SearchHistoryEntry) error {
+	return execEach(ctx, p.db,
+		`INSERT INTO search_history (user_id, ig_account_id, search_query, search_type, searched_at)
+		 VALUES ($1, $2, $3, $4, $5) ON CONFLICT DO NOTHING`,
+		searches, func(s models.SearchHistoryEntry) []interface{} {
+			return []interface{}{userID, accountID, s.SearchQuery, s.SearchType, s.SearchedAt}
+		})
+}
+
+func (p *postgres) SaveOffMetaActivity(ctx context.Context, userID, accountID int, events []models.OffMetaActivity) error {
+	return execEach(ctx, p.db,
+		`INSERT INTO off_meta_activity (user_id, ig_account_id, app_name, event_type, event_id, event_at)
+		 VALUES ($1, $2, $3, $4, $5, $6)`,
+		events, func(e models.OffMetaActivity) []interface{} {
+			return []interface{}{userID, accountID, e.AppName, e.EventType, e.EventID, e.EventAt}
+		})
+}
+
+// --- Messages ---
+
+func (p *postgres) SaveConversation(ctx context.Context, userID, accountID int, c models.MessageConversation, messages []models.Message) error {
+	_, err := p.db.Exec(ctx,
+		`INSERT INTO message_conversations (user_id, ig_account_id, conversation_id, participants, thread_type)
+		 VALUES ($1, $2, $3, $4, $5) ON CONFLICT (ig_account_id, conversation_id) DO NOTHING`,
+		userID, accountID, c.ConversationID, c.Participants, c.ThreadType)
+	return errors.Join(err, execEach(ctx, p.db,
+		`INSERT INTO messages (user_id, ig_account_id, conversation_id, sender_name, content, sent_at)
+		 VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT DO NOTHING`,
+		messages, func(m models.Message) []interface{} {
+			return []interface{}{userID, accountID, c.ConversationID, m.SenderName, m.Content, m.SentAt}
+		}))
+}
+
+// --- Security ---
+
+func (p *postgres) SaveLogins(ctx context.Context, userID, accountID int, logins []models.LoginHistory) error {
+	return execEach(ctx, p.db,
+		`INSERT INTO login_history (user_id, ig_account_id, ip_address, user_agent, language_code, logged_in_at)
+		 VALUES ($1, $2, $3, $4, $5, $6)`,
+		logins, func(l models.LoginHistory) []interface{} {
+			return []interface{}{userID, accountID, l.IPAddress, l.UserAgent, l.LanguageCode, l.LoggedInAt}
+		})
+}
+
+func (p *postgres) SaveLogouts(ctx context.Context, userID, accountID int, logouts []models.LogoutHistory) error {
+	return execEach(ctx, p.db,
+		`INSERT INTO logout_history (user_id, ig_account_id, ip_address, user_agent, logged_out_at) VALUES ($1, $2, $3, $4, $5)`,
+		logouts, func(l models.LogoutHistory) []interface{} {
+			return []interface{}{userID, accountID, l.IPAddress, l.UserAgent, l.LoggedOutAt}
+		})
+}
+
+func (p *postgres) SavePasswordChanges(ctx context.Context, userID, accountID int, changes []models.PasswordChange) error {
+	return execEach(ctx, p.db,
+		`INSERT INTO password_change_history (user_id, ig_account_id, changed_at) VALUES ($1, $2, $3)`,
+		changes, func(c models.PasswordChange) []interface{} { return []interface{}{userID, accountID, c.ChangedAt} })
+}
+
+func (p *postgres) SavePrivacyChanges(ctx context.Context, userID, accountID int, changes []models.PrivacyChange) error {
+	return execEach(ctx, p.db,
+		`INSERT INTO privacy_changes (user_id, ig_account_id, privacy_status, changed_at) VALUES ($1, $2, $3, $4)`,
+		changes, func(c models.PrivacyChange) []interface{} {
+			return []interface{}{userID, accountID, c.PrivacyStatus, c.ChangedAt}
+		})
+}
+
+func (p *postgres) SaveAccountStatus(ctx context.Context, userID, accountID int, entries []models.AccountStatusEntry) error {
+	return execEach(ctx, p.db,
+		`INSERT INTO account_status_history (user_id, ig_account_id, activation_type, reason, changed_at) VALUES ($1, $2, $3, $4, $5)`,
+		entries, func(e models.AccountStatusEntry) []interface{} {
+			return []interface{}{userID, accountID, e.ActivationType, e.Reason, e.ChangedAt}
+		})
+}
+
+func (p *postgres) SaveSignupInfo(ctx context.Context, userID, accountID int, info models.SignupInfo) error {
+	_, err := p.db.Exec(ctx,
+		`INSERT INTO signup_info (user_id, ig_account_id, username_at_signup, email_at_signup, signup_ip, device_model, signed_up_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT (ig_account_id) DO NOTHING`,
+		userID, accountID, info.UsernameAtSignup, info.EmailAtSignup, info.SignupIP, info.DeviceModel, info.SignedUpAt)
+	return err
+}
+
+// --- Profile ---
+
+func (p *postgres) SaveProfile(ctx context.Context, userID, accountID int, profile models.UserProfile) error {
+	_, err := p.db.Exec(ctx,
+		`INSERT INTO user_profile (user_id, ig_account_id, email, phone_number, username, bio, gender, date_of_birth, profile_photo_uri)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		 ON CONFLICT (ig_account_id) DO UPDATE SET
+		   email=EXCLUDED.email, phone_number=EXCLUDED.phone_number,
+		   username=EXCLUDED.username, bio=EXCLUDED.bio,
+		   gender=EXCLUDED.gender, date_of_birth=EXCLUDED.date_of_birth,
+		   profile_photo_uri=EXCLUDED.profile_photo_uri, updated_at=NOW()`,
+		userID, accountID, profile.Email, profile.PhoneNumber, profile.Username, profile.Bio, profile.Gender,
+		profile.DateOfBirth, profile.ProfilePhotoURI)
+	return err
+}
+
+func (p *postgres) SaveProfileChanges(ctx context.Context, userID, accountID int, changes []models.ProfileChange) error {
+	return execEach(ctx, p.db,
+		`INSERT INTO profile_changes (user_id, ig_account_id, field_changed, previous_value, new_value, changed_at)
+		 VALUES ($1, $2, $3, $4, $5, $6)`,
+		changes, func(c models.ProfileChange) []interface{} {
+			return []interface{}{userID, accountID, c.FieldChanged, c.PreviousValue, c.NewValue, c.ChangedAt}
+		})
+}
+
+func (p *postgres) SaveProfilePhotos(ctx context.Context, userID, accountID int, photos []models.ProfilePhoto) error {
+	return execEach(ctx, p.db,
+		`INSERT INTO profile_photos (user_id, ig_account_id, photo_uri, set_at) VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING`,
+		photos, func(ph models.ProfilePhoto) []interface{} {
+			return []interface{}{userID, accountID, ph.PhotoURI, ph.SetAt}
+		})
+}
+
+// --- Interactions ---
+
+func (p *postgres) SavePostLikes(ctx context.Context, userID, accountID int, likes []models.PostLike) error {
+	return execEach(ctx, p.db,
+		`INSERT INTO post_likes (user_id, ig_account_id, creator_username, post_url, liked_at) VALUES ($1, $2, $3, $4, $5) ON CONFLICT DO NOTHING`,
+		likes, func(l models.PostLike) []interface{} {
+			return []interface{}{userID, accountID, l.CreatorUsername, l.PostURL, l.LikedAt}
+		})
+}
+
+func (p *postgres) SaveCommentLikes(ctx context.Context, userID, accountID int, likes []models.CommentLike) error {
+	return execEach(ctx, p.db,
+		`INSERT INTO comment_likes (user_id, ig_account_id, owner_username, post_url, liked_at) VALUES ($1, $2, $3, $4, $5) ON CONFLICT DO NOTHING`,
+		likes, func(l models.CommentLike) []interface{} {
+			return []interface{}{userID, accountID, l.OwnerUsername, l.PostURL, l.LikedAt}
+		})
+}
+
+func (p *postgres) SaveStoryLikes(ctx context.Context, userID, accountID int, likes []models.StoryLike) error {
+	return execEach(ctx, p.db,
+		`INSERT INTO story_likes (user_id, ig_account_id, creator_username, liked_at) VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING`,
+		likes, func(l models.StoryLike) []interface{} {
+			return []interface{}{userID, accountID, l.CreatorUsername, l.LikedAt}
+		})
+}
+
+func (p *postgres) SavePostComments(ctx context.Context, userID, accountID int, comments []models.PostComment) error {
+	return execEach(ctx, p.db,
+		`INSERT INTO post_comments (user_id, ig_account_id, post_owner_username, comment_text, commented_at)
+		 VALUES ($1, $2, $3, $4, $5) ON CONFLICT DO NOTHING`,
+		comments, func(c models.PostComment) []interface{} {
+			return []interface{}{userID, accountID, c.PostOwnerUsername, c.CommentText, c.CommentedAt}
+		})
+}
+
+func (p *postgres) SaveReelComments(ctx context.Context, userID, accountID int, comments []models.ReelComment) error {
+	return execEach(ctx, p.db,
+		`INSERT INTO reel_comments (user_id, ig_account_id, reel_owner_username, comment_text, commented_at)
+		 VALUES ($1, $2, $3, $4, $5) ON CONFLICT DO NOTHING`,
+		comments, func(c models.ReelComment) []interface{} {
+			return []interface{}{userID, accountID, c.ReelOwnerUsername, c.CommentText, c.CommentedAt}
+		})
+}
+
+func (p *postgres) SaveStoryPolls(ctx context.Context, userID, accountID int, polls []models.StoryPoll) error {
+	return execEach(ctx, p.db,
+		`INSERT INTO story_polls (user_id, ig_account_id, creator_username, poll_answer, answered_at) VALUES ($1, $2, $3, $4, $5) ON CONFLICT DO NOTHING`,
+		polls, func(s models.StoryPoll) []interface{} {
+			return []interface{}{userID, accountID, s.CreatorUsername, s.PollAnswer, s.AnsweredAt}
+		})
+}
+
+func (p *postgres) SaveStoryQuizzes(ctx context.Context, userID, accountID int, quizzes []models.StoryQuiz) error {
+	return execEach(ctx, p.db,
+		`INSERT INTO story_quizzes (user_id, ig_account_id, creator_username, quiz_answer, answered_at) VALUES ($1, $2, $3, $4, $5) ON CONFLICT DO NOTHING`,
+		quizzes, func(s models.StoryQuiz) []interface{} {
+			return []interface{}{userID, accountID, s.CreatorUsername, s.QuizAnswer, s.AnsweredAt}
+		})
+}
+
+func (p *postgres) SaveStoryQuestions(ctx context.Context, userID, accountID int, questions []models.StoryQuestion) error {
+	return execEach(ctx, p.db,
+		`INSERT INTO story_questions (user_id, ig_account_id, creator_username, responded_at) VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING`,
+		questions, func(s models.StoryQuestion) []interface{} {
+			return []interface{}{userID, accountID, s.CreatorUsername, s.RespondedAt}
+		})
+}
+
+func (p *postgres) SaveStoryEmojiSliders(ctx context.Context, userID, accountID int, sliders []models.StoryEmojiSlider) error {
+	return execEach(ctx, p.db,
+		`INSERT INTO story_emoji_sliders (user_id, ig_account_id, creator_username, slider_value, responded_at) VALUES ($1, $2, $3, $4, $5) ON CONFLICT DO NOTHING`,
+		sliders, func(s models.StoryEmojiSlider) []interface{} {
+			return []interface{}{userID, accountID, s.CreatorUsername, s.SliderValue, s.RespondedAt}
+		})
+}
+
+func (p *postgres) SaveStoryReactions(ctx context.Context, userID, accountID int, reactions []models.StoryReaction) error {
+	return execEach(ctx, p.db,
+		`INSERT INTO story_reactions (user_id, ig_account_id, creator_username, responded_at) VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING`,
+		reactions, func(s models.StoryReaction) []interface{} {
+			return []interface{}{userID, accountID, s.CreatorUsername, s.RespondedAt}
+		})
+}
+
+// --- Saved ---
+
+func (p *postgres) SaveSavedMedia(ctx context.Context, userID, accountID int, saved []models.SavedMedia) error {
+	return execEach(ctx, p.db,
+		`INSERT INTO saved_media (user_id, ig_account_id, creator_username, post_url, saved_at) VALUES ($1, $2, $3, $4, $5) ON CONFLICT DO NOTHING`,
+		saved, func(s models.SavedMedia) []interface{} {
+			return []interface{}{userID, accountID, s.CreatorUsername, s.PostURL, s.SavedAt}
+		})
+}
+
+func (p *postgres) SaveCollections(ctx context.Context, userID, accountID int, collections []models.SavedCollection) error {
+	return execEach(ctx, p.db,
+		`INSERT INTO saved_collections (user_id, ig_account_id, collection_name, created_at, updated_at) VALUES ($1, $2, $3, $4, $5) ON CONFLICT DO NOTHING`,
+		collections, func(c models.SavedCollection) []interface{} {
+			return []interface{}{userID, accountID, c.CollectionName, c.CreatedAt, c.UpdatedAt}
+		})
+}
+
+func (p *postgres) SaveCollectionItems(ctx context.Context, userID, accountID int, items []models.SavedCollectionItem) error {
+	return execEach(ctx, p.db,
+		`INSERT INTO saved_collection_items (user_id, ig_account_id, collection_name, item_url, creator_username, added_at)
+		 VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT DO NOTHING`,
+		items, func(i models.SavedCollectionItem) []interface{} {
+			return []interface{}{userID, accountID, i.CollectionName, i.ItemURL, i.CreatorUsername, i.AddedAt}
+		})
+}
+
+// --- Interests ---
+
+func (p *postgres) SaveAdvertisers(ctx context.Context, userID, accountID int, names []string) error {
+	return execEach(ctx, p.db,
+		`INSERT INTO ad_advertisers (user_id, ig_account_id, advertiser_name) VALUES ($1, $2, $3) ON CONFLICT (ig_account_id, advertiser_name) DO NOTHING`,
+		names, func(name string) []interface{} { return []interface{}{userID, accountID, name} })
+}
+
+func (p *postgres) SaveAdTopics(ctx context.Context, userID, accountID int, names []string) error {
+	return execEach(ctx, p.db,
+		`INSERT INTO ad_topics (user_id, ig_account_id, topic_name) VALUES ($1, $2, $3) ON CONFLICT (ig_account_id, topic_name) DO NOTHING`,
+		names, func(name string) []interface{} { return []interface{}{userID, accountID, name} })
+}
+
+func (p *postgres) SaveAIInterests(ctx context.Context, userID, accountID int, interests []models.AIInterest) error {
+	return execEach(ctx, p.db,
+		`INSERT INTO ai_interests (user_id, ig_account_id, interest_description, detected_at) VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING`,
+		interests, func(i models.AIInterest) []interface{} {
+			return []interface{}{userID, accountID, i.InterestDescription, i.DetectedAt}
+		})
+}
+
+func (p *postgres) SaveTopics(ctx context.Context, userID, accountID int, names []string) error {
+	return execEach(ctx, p.db,
+		`INSERT INTO user_topics (user_id, ig_account_id, topic_name) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`,
+		names, func(name string) []interface{} { return []interface{}{userID, accountID, name} })
+}
+
+func (p *postgres) SaveLocationsOfInterest(ctx context.Context, userID, accountID int, names []string) error {
+	return execEach(ctx, p.db,
+		`INSERT INTO locations_of_interest (user_id, ig_account_id, location_name) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`,
+		names, func(name string) []interface{} { return []interface{}{userID, accountID, name} })
+}
+
+func (p *postgres) SaveInferredLocation(ctx context.Context, userID, accountID int, city string) error {
+	_, err := p.db.Exec(ctx,
+		`INSERT INTO inferred_location (user_id, ig_account_id, city_name) VALUES ($1, $2, $3)
+		 ON CONFLICT (ig_account_id) DO UPDATE SET city_name=EXCLUDED.city_name`, userID, accountID, city)
+	return err
+}
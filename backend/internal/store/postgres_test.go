@@ -0,0 +1,38 @@
+package store
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListTableQuery(t *testing.T) {
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	opts := ListOptions{Types: []string{"follower"}, From: &from, Username: "jo_e", Sort: SortUsername, Ascending: true, Limit: 10,
+		After: &Cursor{At: from, Key: "jo", ID: 5}}
+
+	sql, args := connectionsTable.list("SELECT id FROM connections WHERE ig_account_id=$1", []interface{}{1}, opts)
+	want := "SELECT id FROM connections WHERE ig_account_id=$1 AND connection_type = ANY($2) AND timestamp >= $3" +
+		" AND username ILIKE $4 AND (username, id) > ($5, $6) ORDER BY username ASC, id ASC LIMIT $7"
+	if sql != want {
+		t.Errorf("list =\n%s\nwant\n%s", sql, want)
+	}
+	if len(args) != 7 || args[3] != `%jo\_e%` || args[4] != "jo" || args[6] != 11 {
+		t.Errorf("args = %v", args)
+	}
+}
+
+func TestListTableQueryDefaults(t *testing.T) {
+	// filters the table has no column for are ignored, and Limit 0 lists everything
+	sql, args := activityTable.list("SELECT id FROM activity_log WHERE ig_account_id=$1", []interface{}{1},
+		ListOptions{Sort: SortDate, Types: []string{"ad_viewed"}})
+	if !strings.HasSuffix(sql, "AND activity_type = ANY($2) ORDER BY COALESCE(timestamp, 'epoch') DESC, id DESC") || len(args) != 2 {
+		t.Errorf("activity: %q %v", sql, args)
+	}
+
+	sql, args = messagesTable.list("SELECT id FROM messages WHERE ig_account_id=$1", []interface{}{1},
+		ListOptions{Types: []string{"x"}, Sort: SortDate, Limit: 5})
+	if !strings.HasSuffix(sql, "WHERE ig_account_id=$1 ORDER BY sent_at DESC, id DESC LIMIT $2") || len(args) != 2 {
+		t.Errorf("messages: %q %v", sql, args)
+	}
+}
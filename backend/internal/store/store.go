@@ -0,0 +1,461 @@
+// Package store is the persistence layer behind the HTTP handlers. Each domain of the
+// archive has a repository interface; Postgres implements them for the server and
+// Memory implements them for handler tests, so handlers never see SQL or pgx.
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Sa-Te/IAV/backend/internal/models"
+)
+
+var (
+	// ErrNotFound is returned when the requested record does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when a record would clash with an existing one.
+	ErrConflict = errors.New("already exists")
+	// ErrLocked is returned while a user is locked out of second-factor attempts.
+	ErrLocked = errors.New("locked")
+)
+
+// Store groups the repositories. Every field is set by NewPostgres and NewMemory.
+type Store struct {
+	Users        UserRepository
+	Tokens       TokenRepository
+	Shares       ShareRepository
+	Imports      ImportRepository
+	Accounts     AccountRepository
+	Media        MediaRepository
+	Connections  ConnectionRepository
+	Snapshots    SnapshotRepository
+	Activity     ActivityRepository
+	Messages     MessageRepository
+	Security     SecurityRepository
+	Profile      ProfileRepository
+	Interactions InteractionRepository
+	Saved        SavedRepository
+	Interests    InterestRepository
+	Insights     InsightRepository
+	Search       SearchRepository
+	Timeline     TimelineRepository
+}
+
+// Cursor identifies the last row of the previous page: its sort value and id.
+type Cursor struct {
+	At  time.Time
+	Key string // the text sort value, for lists sorted by e.g. username
+	ID  int
+}
+
+// ListOptions filters, orders and pages a list. Zero values mean "no filter".
+type ListOptions struct {
+	Types    []string
+	From     *time.Time
+	To       *time.Time // exclusive
+	Username string     // case-insensitive substring match
+
+	// Sort is "date" or, for lists that support it, "username". Rows are ordered by
+	// (sort value, id) so pages are stable.
+	Sort      string
+	Ascending bool
+	After     *Cursor
+
+	// Limit 0 returns every row. Otherwise one row more than Limit is returned when
+	// there is one, so the caller can tell whether another page follows.
+	Limit int
+}
+
+const (
+	SortDate     = "date"
+	SortUsername = "username"
+)
+
+// UserRepository manages the users who log in to the server and their second factor.
+type UserRepository interface {
+	// CreateUser makes the first user of the instance its administrator.
+	CreateUser(ctx context.Context, email, passwordHash string) (int, error)
+	// User and UserByEmail return ErrNotFound when there is no such user.
+	User(ctx context.Context, userID int) (models.User, error)
+	UserByEmail(ctx context.Context, email string) (models.User, error)
+	// ListUsers is ordered by id.
+	ListUsers(ctx context.Context) ([]models.AdminUser, error)
+	// SetUserDisabled and DeleteUser return ErrNotFound for an unknown user. Deleting a
+	// user deletes everything imported for them.
+	SetUserDisabled(ctx context.Context, userID int, disabled bool) error
+	DeleteUser(ctx context.Context, userID int) error
+
+	// SetTOTPSecret stores a pending secret; it is only enforced after EnableTwoFactor.
+	SetTOTPSecret(ctx context.Context, userID int, secret string) error
+	// EnableTwoFactor turns 2FA on, starting from the step of the code that confirmed
+	// it, and replaces the recovery codes. Either all of it happens or none.
+	EnableTwoFactor(ctx context.Context, userID int, step int64, recoveryCodeHashes []string) error
+	// DisableTwoFactor forgets the secret and the recovery codes.
+	DisableTwoFactor(ctx context.Context, userID int) error
+	// ReserveTwoFactorAttempt counts an attempt before the code is checked, so concurrent
+	// guesses can't slip past the limit. The attempt that reaches maxAttempts locks the
+	// user out for lockout; until then it returns ErrLocked.
+	ReserveTwoFactorAttempt(ctx context.Context, userID, maxAttempts int, lockout time.Duration) error
+	ResetTwoFactorAttempts(ctx context.Context, userID int) error
+	// AcceptTOTPStep records step as used. It returns false when the same or a later
+	// step was accepted already, so each code only works once.
+	AcceptTOTPStep(ctx context.Context, userID int, step int64) (bool, error)
+	UnusedRecoveryCodes(ctx context.Context, userID int) ([]models.RecoveryCode, error)
+	// UseRecoveryCode returns false when the code was used already.
+	UseRecoveryCode(ctx context.Context, codeID int) (bool, error)
+}
+
+// TokenRepository manages personal API tokens. Only a hash of the secret is stored.
+type TokenRepository interface {
+	// CreateAPIToken returns t with its id and creation time.
+	CreateAPIToken(ctx context.Context, t models.APIToken, hash string) (models.APIToken, error)
+	// LiveAPIToken returns the token with that hash unless it is revoked or expired, or ErrNotFound.
+	LiveAPIToken(ctx context.Context, hash string) (models.APIToken, error)
+	TouchAPIToken(ctx context.Context, tokenID int) error
+	// ListAPITokens is newest first.
+	ListAPITokens(ctx context.Context, userID int) ([]models.APIToken, error)
+	// RevokeAPIToken returns ErrNotFound unless the user has a live token with that id.
+	RevokeAPIToken(ctx context.Context, userID, tokenID int) error
+}
+
+// ShareRepository manages share links and their access log.
+type ShareRepository interface {
+	// CreateShareLink returns l with its id and creation time.
+	CreateShareLink(ctx context.Context, l models.ShareLink) (models.ShareLink, error)
+	// LiveShareLink returns the link unless it is revoked or expired or its owner is
+	// disabled, or ErrNotFound.
+	LiveShareLink(ctx context.Context, linkID int) (models.ShareLink, error)
+	// ListShareLinks is newest first.
+	ListShareLinks(ctx context.Context, userID int) ([]models.ShareLink, error)
+	// RevokeShareLink returns ErrNotFound unless the user has a live link with that id.
+	RevokeShareLink(ctx context.Context, userID, linkID int) error
+	LogShareAccess(ctx context.Context, linkID int, access models.ShareLinkAccess) error
+	// ListShareAccess lists up to limit accesses, newest first, or returns ErrNotFound
+	// when the link is not the user's.
+	ListShareAccess(ctx context.Context, userID, linkID, limit int) ([]models.ShareLinkAccess, error)
+}
+
+// ImportRepository records the archives uploaded for an account.
+type ImportRepository interface {
+	// Now is the database clock, which stamps the connections an import saves.
+	Now(ctx context.Context) (time.Time, error)
+	CreateImport(ctx context.Context, userID, accountID int, archiveBytes int64) (int, error)
+	// LatestImport is the id of the account's latest import, 0 before the first one.
+	LatestImport(ctx context.Context, accountID int) (int, error)
+}
+
+// AccountRepository manages the Instagram accounts a user keeps archives for.
+type AccountRepository interface {
+	// DefaultAccount returns the id of the user's default account, creating it if needed.
+	DefaultAccount(ctx context.Context, userID int) (int, error)
+	// FindAccount looks up one of the user's accounts by id or name.
+	FindAccount(ctx context.Context, userID int, ref string) (int, error)
+	ListAccounts(ctx context.Context, userID int) ([]models.IGAccount, error)
+	// CreateAccount returns ErrConflict when the user already has an account with that name.
+	CreateAccount(ctx context.Context, userID int, name string) (models.IGAccount, error)
+}
+
+// The Save methods of the archive repositories store what the processors read from an
+// uploaded archive. Rows the account already has are skipped, so an archive can be
+// imported again; a row that fails doesn't stop the others, and the errors are joined.
+
+type MediaRepository interface {
+	ListMedia(ctx context.Context, accountID int, opts ListOptions) ([]models.MediaItem, error)
+	// FindMedia returns the media item with that uri, or ErrNotFound.
+	FindMedia(ctx context.Context, accountID int, uri string) (models.MediaItem, error)
+	ListArchivedPosts(ctx context.Context, accountID int) ([]models.ArchivedPost, error)
+	SaveMedia(ctx context.Context, userID, accountID int, items []models.MediaItem) error
+	SaveArchivedPosts(ctx context.Context, userID, accountID int, posts []models.ArchivedPost) error
+}
+
+type ConnectionRepository interface {
+	// ListConnections supports the "date" and "username" sorts.
+	ListConnections(ctx context.Context, accountID int, opts ListOptions) ([]models.Connection, error)
+	ListFollowedHashtags(ctx context.Context, accountID int) ([]models.FollowedHashtag, error)
+	// SaveConnections marks every connection as seen by the running import. One saved
+	// before takes the new timestamp, except a follower, following or contact, which
+	// keeps the first one: that is when the relationship started.
+	SaveConnections(ctx context.Context, userID, accountID int, connections []models.Connection) error
+	SaveFollowedHashtags(ctx context.Context, userID, accountID int, hashtags []models.FollowedHashtag) error
+}
+
+// SnapshotRepository reads the connection lists each import's archive contained.
+type SnapshotRepository interface {
+	// ListSnapshots is oldest first. Imports without connection lists have no snapshot.
+	ListSnapshots(ctx context.Context, accountID int) ([]models.ConnectionSnapshot, error)
+	// ListSnapshotEntries lists one snapshot's connections of the given types by username.
+	ListSnapshotEntries(ctx context.Context, accountID, importID int, types []string) ([]models.SnapshotEntry, error)
+	// SaveSnapshot copies the connections seen since the import started into its snapshot.
+	SaveSnapshot(ctx context.Context, accountID, importID int, started time.Time) error
+}
+
+type ActivityRepository interface {
+	// ListActivity sorts undated impressions as the epoch.
+	ListActivity(ctx context.Context, accountID int, opts ListOptions) ([]models.ActivityLog, error)
+	ListSearchHistory(ctx context.Context, accountID int, opts ListOptions) ([]models.SearchHistoryEntry, error)
+	// FindSearch returns the past search with that id, or ErrNotFound.
+	FindSearch(ctx context.Context, accountID, id int) (models.SearchHistoryEntry, error)
+	ListOffMetaActivity(ctx context.Context, accountID int, opts ListOptions) ([]models.OffMetaActivity, error)
+	SaveActivity(ctx context.Context, userID, accountID int, activity []models.ActivityLog) error
+	SaveSearchHistory(ctx context.Context, userID, accountID int, searches []models.SearchHistoryEntry) error
+	SaveOffMetaActivity(ctx context.Context, userID, accountID int, events []models.OffMetaActivity) error
+}
+
+type MessageRepository interface {
+	// ListConversations orders conversations by their latest message, newest first.
+	ListConversations(ctx context.Context, accountID int) ([]models.MessageConversation, error)
+	ConversationExists(ctx context.Context, accountID int, conversationID string) (bool, error)
+	// ListMessages lists one conversation's messages, or every message when conversationID is "".
+	ListMessages(ctx context.Context, accountID int, conversationID string, opts ListOptions) ([]models.Message, error)
+	// SaveConversation saves the conversation, unless it exists, and its messages.
+	SaveConversation(ctx context.Context, userID, accountID int, c models.MessageConversation, messages []models.Message) error
+}
+
+// SecurityRepository lists are newest first.
+type SecurityRepository interface {
+	// ListLogins and ListLogouts are newest first; limit 0 lists every row.
+	ListLogins(ctx context.Context, accountID, limit int) ([]models.LoginHistory, error)
+	ListLogouts(ctx context.Context, accountID, limit int) ([]models.LogoutHistory, error)
+	ListPasswordChanges(ctx context.Context, accountID int) ([]models.PasswordChange, error)
+	ListPrivacyChanges(ctx context.Context, accountID int) ([]models.PrivacyChange, error)
+	ListAccountStatus(ctx context.Context, accountID int) ([]models.AccountStatusEntry, error)
+	// SignupInfo returns nil when the archive has none.
+	SignupInfo(ctx context.Context, accountID int) (*models.SignupInfo, error)
+	// UnlocatedIPs lists the login, logout and signup IP addresses without a saved location.
+	UnlocatedIPs(ctx context.Context, accountID int) ([]string, error)
+	// SaveIPLocations saves locations, replacing earlier ones for the same addresses.
+	SaveIPLocations(ctx context.Context, accountID int, locations []models.IPLocation) error
+	// ListIPLocations lists the saved locations, ordered by address.
+	ListIPLocations(ctx context.Context, accountID int) ([]models.IPLocation, error)
+	SaveLogins(ctx context.Context, userID, accountID int, logins []models.LoginHistory) error
+	SaveLogouts(ctx context.Context, userID, accountID int, logouts []models.LogoutHistory) error
+	SavePasswordChanges(ctx context.Context, userID, accountID int, changes []models.PasswordChange) error
+	SavePrivacyChanges(ctx context.Context, userID, accountID int, changes []models.PrivacyChange) error
+	SaveAccountStatus(ctx context.Context, userID, accountID int, entries []models.AccountStatusEntry) error
+	// SaveSignupInfo keeps the signup info saved first.
+	SaveSignupInfo(ctx context.Context, userID, accountID int, info models.SignupInfo) error
+}
+
+// ProfileRepository lists are newest first.
+type ProfileRepository interface {
+	// Profile returns nil when the archive has none.
+	Profile(ctx context.Context, accountID int) (*models.UserProfile, error)
+	ListProfileChanges(ctx context.Context, accountID int) ([]models.ProfileChange, error)
+	ListProfilePhotos(ctx context.Context, accountID int) ([]models.ProfilePhoto, error)
+	// SaveProfile replaces the profile saved before.
+	SaveProfile(ctx context.Context, userID, accountID int, profile models.UserProfile) error
+	SaveProfileChanges(ctx context.Context, userID, accountID int, changes []models.ProfileChange) error
+	SaveProfilePhotos(ctx context.Context, userID, accountID int, photos []models.ProfilePhoto) error
+}
+
+// InteractionRepository covers likes, comments and story interactions, newest first.
+type InteractionRepository interface {
+	ListPostLikes(ctx context.Context, accountID int) ([]models.PostLike, error)
+	ListCommentLikes(ctx context.Context, accountID int) ([]models.CommentLike, error)
+	ListStoryLikes(ctx context.Context, accountID int) ([]models.StoryLike, error)
+	ListPostComments(ctx context.Context, accountID int) ([]models.PostComment, error)
+	ListReelComments(ctx context.Context, accountID int) ([]models.ReelComment, error)
+	// FindPostComment and FindReelComment return the comment with that id, or ErrNotFound.
+	FindPostComment(ctx context.Context, accountID, id int) (models.PostComment, error)
+	FindReelComment(ctx context.Context, accountID, id int) (models.ReelComment, error)
+	ListStoryPolls(ctx context.Context, accountID int) ([]models.StoryPoll, error)
+	ListStoryQuizzes(ctx context.Context, accountID int) ([]models.StoryQuiz, error)
+	ListStoryQuestions(ctx context.Context, accountID int) ([]models.StoryQuestion, error)
+	ListStoryEmojiSliders(ctx context.Context, accountID int) ([]models.StoryEmojiSlider, error)
+	ListStoryReactions(ctx context.Context, accountID int) ([]models.StoryReaction, error)
+	SavePostLikes(ctx context.Context, userID, accountID int, likes []models.PostLike) error
+	SaveCommentLikes(ctx context.Context, userID, accountID int, likes []models.CommentLike) error
+	SaveStoryLikes(ctx context.Context, userID, accountID int, likes []models.StoryLike) error
+	SavePostComments(ctx context.Context, userID, accountID int, comments []models.PostComment) error
+	SaveReelComments(ctx context.Context, userID, accountID int, comments []models.ReelComment) error
+	SaveStoryPolls(ctx context.Context, userID, accountID int, polls []models.StoryPoll) error
+	SaveStoryQuizzes(ctx context.Context, userID, accountID int, quizzes []models.StoryQuiz) error
+	SaveStoryQuestions(ctx context.Context, userID, accountID int, questions []models.StoryQuestion) error
+	SaveStoryEmojiSliders(ctx context.Context, userID, accountID int, sliders []models.StoryEmojiSlider) error
+	SaveStoryReactions(ctx context.Context, userID, accountID int, reactions []models.StoryReaction) error
+}
+
+type SavedRepository interface {
+	// ListSavedMedia is newest first.
+	ListSavedMedia(ctx context.Context, accountID int) ([]models.SavedMedia, error)
+	// ListCollections is ordered by name. Items can name a collection the archive's
+	// collection file doesn't list; those come back with ID 0 and no dates.
+	ListCollections(ctx context.Context, accountID int) ([]models.SavedCollection, error)
+	// Collection returns one collection and its item count, or ErrNotFound.
+	Collection(ctx context.Context, accountID int, name string) (models.SavedCollection, int, error)
+	// ListCollectionItems lists one collection's items, or every item when collection is "".
+	ListCollectionItems(ctx context.Context, accountID int, collection string, opts ListOptions) ([]models.SavedCollectionItem, error)
+	SaveSavedMedia(ctx context.Context, userID, accountID int, saved []models.SavedMedia) error
+	SaveCollections(ctx context.Context, userID, accountID int, collections []models.SavedCollection) error
+	SaveCollectionItems(ctx context.Context, userID, accountID int, items []models.SavedCollectionItem) error
+}
+
+// InterestRepository covers what Instagram inferred about the account: ad targeting,
+// topics and locations.
+type InterestRepository interface {
+	ListAdvertisers(ctx context.Context, accountID int) ([]string, error)
+	ListAdTopics(ctx context.Context, accountID int) ([]string, error)
+	ListAIInterests(ctx context.Context, accountID int) ([]models.AIInterest, error)
+	// ListTopics and ListLocationsOfInterest are ordered by name.
+	ListTopics(ctx context.Context, accountID int) ([]models.UserTopic, error)
+	ListLocationsOfInterest(ctx context.Context, accountID int) ([]string, error)
+	// InferredLocation returns nil when the archive has none.
+	InferredLocation(ctx context.Context, accountID int) (*models.InferredLocation, error)
+	SaveAdvertisers(ctx context.Context, userID, accountID int, names []string) error
+	SaveAdTopics(ctx context.Context, userID, accountID int, names []string) error
+	SaveAIInterests(ctx context.Context, userID, accountID int, interests []models.AIInterest) error
+	SaveTopics(ctx context.Context, userID, accountID int, names []string) error
+	SaveLocationsOfInterest(ctx context.Context, userID, accountID int, names []string) error
+	// SaveInferredLocation replaces the location saved before.
+	SaveInferredLocation(ctx context.Context, userID, accountID int, city string) error
+}
+
+// InsightRepository aggregates the archive for the insights endpoints.
+type InsightRepository interface {
+	// ConversationStats lists every conversation with messages, busiest first. me is the
+	// sender name of the archive's owner; a message only counts as a reply when it
+	// follows the other side's within maxReplyGap.
+	ConversationStats(ctx context.Context, accountID int, me string, maxReplyGap time.Duration) ([]models.ConversationStats, error)
+	// MessageDays counts each conversation's messages per UTC day, ordered by conversation and day.
+	MessageDays(ctx context.Context, accountID int) ([]models.MessageDay, error)
+	// ActivityByHour and ActivityByMonth count the events of the given ActivityCategories
+	// in loc's local time. Empty buckets are left out.
+	ActivityByHour(ctx context.Context, accountID int, categories []string, loc *time.Location) ([]models.ActivityHour, error)
+	ActivityByMonth(ctx context.Context, accountID int, categories []string, loc *time.Location) ([]models.ActivityMonth, error)
+	// AdViewsByAuthor groups the ads seen by advertiser account, most viewed first.
+	AdViewsByAuthor(ctx context.Context, accountID int) ([]models.AdViews, error)
+	// OffMetaApps groups off-Meta activity by app, most events first; EventTypes are sorted.
+	OffMetaApps(ctx context.Context, accountID int) ([]models.OffMetaApp, error)
+	// InteractionCounts counts the InteractionKinds per lowercased username, ordered by
+	// username and kind. me is the owner's sender name, whose messages count.
+	InteractionCounts(ctx context.Context, accountID int, me string) ([]models.InteractionCount, error)
+	// ListInteractions lists the interactions with username (case-insensitive), oldest
+	// first. It honours only opts.After, whose Key is the kind, and opts.Limit.
+	ListInteractions(ctx context.Context, accountID int, me, username string, opts ListOptions) ([]models.Interaction, error)
+	// OnThisDay lists the MemoryTypes dated, in loc, on one of days of month in a year
+	// before the given one, oldest first.
+	OnThisDay(ctx context.Context, accountID int, year int, month time.Month, days []int, loc *time.Location) ([]models.MemoryItem, error)
+	// OwnTexts lists what the account wrote, oldest first: its captions, comments and the
+	// messages me sent. opts filters by TextSources and date; a conversationID keeps
+	// only that conversation's messages.
+	OwnTexts(ctx context.Context, accountID int, me, conversationID string, opts ListOptions) ([]models.OwnText, error)
+}
+
+// SearchRepository finds the account's texts matching a query.
+type SearchRepository interface {
+	// Search takes web search syntax ("quoted phrases", OR, -excluded) and returns the
+	// best opts.Limit hits of the SearchTypes, best first. opts filters by type and date.
+	// Snippets mark the matches with HighlightStart and HighlightStop.
+	Search(ctx context.Context, accountID int, query string, opts ListOptions) ([]models.SearchHit, error)
+}
+
+// TimelineRepository merges every timestamped record of the account into one stream.
+type TimelineRepository interface {
+	// Timeline pages through the TimelineTypes by date; ids repeat across the source
+	// tables, so ties are broken on the event's Source (the Cursor's Key) and then the
+	// id. opts filters by type, date and username.
+	Timeline(ctx context.Context, accountID int, opts ListOptions) ([]models.TimelineEvent, error)
+}
+
+// Activity categories: what the timestamped tables of the archive record.
+const (
+	CategoryViews    = "views" // posts, videos and suggested profiles viewed
+	CategoryAds      = "ads"
+	CategoryMessages = "messages" // sent and received
+	CategoryLikes    = "likes"
+	CategoryComments = "comments"
+	CategoryLogins   = "logins"
+)
+
+var ActivityCategories = []string{CategoryViews, CategoryAds, CategoryMessages, CategoryLikes, CategoryComments, CategoryLogins}
+
+// Interaction kinds: the ways the account engages with another one. A message counts
+// towards the other participant of a one-to-one conversation, whose username the
+// archive puts in the conversation id (janedoe_1234567890).
+const (
+	InteractionPostLike      = "post_like"
+	InteractionCommentLike   = "comment_like"
+	InteractionStoryLike     = "story_like"
+	InteractionPostComment   = "post_comment"
+	InteractionReelComment   = "reel_comment"
+	InteractionStoryPoll     = "story_poll"
+	InteractionStoryQuiz     = "story_quiz"
+	InteractionStoryQuestion = "story_question"
+	InteractionStorySlider   = "story_slider"
+	InteractionStoryReaction = "story_reaction"
+	InteractionMessage       = "message" // sent by you
+)
+
+var InteractionKinds = []string{InteractionPostLike, InteractionCommentLike, InteractionStoryLike,
+	InteractionPostComment, InteractionReelComment, InteractionStoryPoll, InteractionStoryQuiz,
+	InteractionStoryQuestion, InteractionStorySlider, InteractionStoryReaction, InteractionMessage}
+
+// Memory types: what the "on this day" memories are made of. media_items rows keep
+// their own media_type, post or story.
+const (
+	MemoryPost         = "post"
+	MemoryStory        = "story"
+	MemoryArchivedPost = "archived_post"
+	MemoryProfilePhoto = "profile_photo"
+	MemoryFirstMessage = "first_message" // the message that started a conversation
+)
+
+var MemoryTypes = []string{MemoryPost, MemoryStory, MemoryArchivedPost, MemoryProfilePhoto, MemoryFirstMessage}
+
+// Text sources: where the account's own words come from.
+const (
+	TextCaption     = "caption"
+	TextPostComment = "post_comment"
+	TextReelComment = "reel_comment"
+	TextMessage     = "message" // sent by you
+)
+
+var TextSources = []string{TextCaption, TextPostComment, TextReelComment, TextMessage}
+
+// Search hit types, one per searchable source.
+const (
+	SearchCaption        = "caption"
+	SearchMessage        = "message"
+	SearchPostComment    = "post_comment"
+	SearchReelComment    = "reel_comment"
+	SearchHistory        = "search"
+	SearchSavedMedia     = "saved_media"
+	SearchCollectionItem = "collection_item"
+)
+
+var SearchTypes = []string{SearchCaption, SearchMessage, SearchPostComment, SearchReelComment, SearchHistory,
+	SearchSavedMedia, SearchCollectionItem}
+
+// Search snippets mark matches with private-use characters rather than tags, so the
+// caller can escape the archive text before turning them into markup.
+const (
+	HighlightStart = "\ue000"
+	HighlightStop  = "\ue001"
+)
+
+// Timeline event types. Subtype carries the finer-grained kind where a source has one
+// (media_type, connection_type, activity_type, ...).
+const (
+	TimelinePost             = "post"
+	TimelineStory            = "story"
+	TimelineLike             = "like"
+	TimelineComment          = "comment"
+	TimelineMessage          = "message"
+	TimelineStoryInteraction = "story_interaction"
+	TimelineConnection       = "connection"
+	TimelineSearch           = "search"
+	TimelineSaved            = "saved"
+	TimelineActivity         = "activity"
+	TimelineLogin            = "login"
+	TimelineLogout           = "logout"
+	TimelinePasswordChange   = "password_change"
+	TimelinePrivacyChange    = "privacy_change"
+	TimelineAccountStatus    = "account_status"
+	TimelineProfileChange    = "profile_change"
+)
+
+var TimelineTypes = []string{TimelinePost, TimelineStory, TimelineLike, TimelineComment, TimelineMessage,
+	TimelineStoryInteraction, TimelineConnection, TimelineSearch, TimelineSaved, TimelineActivity,
+	TimelineLogin, TimelineLogout, TimelinePasswordChange, TimelinePrivacyChange, TimelineAccountStatus,
+	TimelineProfileChange}
@@ -0,0 +1,68 @@
+-- One IAV user can hold archives for several Instagram accounts.
+CREATE TABLE IF NOT EXISTS ig_accounts (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE(user_id, name)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS ig_accounts_one_default_idx ON ig_accounts (user_id) WHERE is_default;
+
+-- Everything imported so far belongs to a "default" account per user
+INSERT INTO ig_accounts (user_id, name, is_default)
+SELECT id, 'default', TRUE FROM users
+ON CONFLICT DO NOTHING;
+
+-- Attach every archive data table to an account, and re-key the per-user unique
+-- constraints per account so two accounts can hold the same follower, hashtag, etc.
+DO $$
+DECLARE
+    t TEXT;
+    c RECORD;
+BEGIN
+    FOREACH t IN ARRAY ARRAY[
+        'media_items', 'connections', 'followed_hashtags', 'ad_advertisers', 'ad_topics',
+        'activity_log', 'post_likes', 'comment_likes', 'story_likes', 'post_comments',
+        'reel_comments', 'saved_media', 'saved_collections', 'saved_collection_items',
+        'user_profile', 'profile_changes', 'profile_photos', 'archived_posts',
+        'login_history', 'logout_history', 'password_change_history', 'signup_info',
+        'privacy_changes', 'account_status_history', 'story_polls', 'story_quizzes',
+        'story_questions', 'story_emoji_sliders', 'story_reactions', 'search_history',
+        'message_conversations', 'messages', 'devices', 'user_settings',
+        'notification_preferences', 'ai_interests', 'user_topics', 'inferred_location',
+        'locations_of_interest', 'off_meta_activity', 'link_history'
+    ] LOOP
+        EXECUTE format('ALTER TABLE %I ADD COLUMN IF NOT EXISTS ig_account_id INT REFERENCES ig_accounts(id) ON DELETE CASCADE', t);
+        EXECUTE format('UPDATE %I d SET ig_account_id = a.id FROM ig_accounts a
+                        WHERE a.user_id = d.user_id AND a.is_default AND d.ig_account_id IS NULL', t);
+        EXECUTE format('ALTER TABLE %I ALTER COLUMN ig_account_id SET NOT NULL', t);
+        EXECUTE format('CREATE INDEX IF NOT EXISTS %I ON %I (ig_account_id)', 'idx_' || t || '_ig_account_id', t);
+
+        FOR c IN
+            SELECT con.conname, array_agg(att.attname::TEXT ORDER BY k.ord) AS cols
+            FROM pg_constraint con
+            CROSS JOIN LATERAL unnest(con.conkey) WITH ORDINALITY AS k(attnum, ord)
+            JOIN pg_attribute att ON att.attrelid = con.conrelid AND att.attnum = k.attnum
+            WHERE con.conrelid = t::regclass AND con.contype = 'u'
+            GROUP BY con.conname
+        LOOP
+            IF 'user_id' = ANY(c.cols) THEN
+                EXECUTE format('ALTER TABLE %I DROP CONSTRAINT %I', t, c.conname);
+                EXECUTE format('CREATE UNIQUE INDEX IF NOT EXISTS %I ON %I (%s)',
+                    t || '_account_key', t,
+                    (SELECT string_agg(quote_ident(col), ', ') FROM unnest(array_replace(c.cols, 'user_id', 'ig_account_id')) AS col));
+            END IF;
+        END LOOP;
+    END LOOP;
+END $$;
+
+-- The dedup indexes from 020 are expression indexes, not constraints, so re-key them by hand
+DROP INDEX IF EXISTS messages_dedup_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS messages_dedup_idx
+  ON messages (ig_account_id, conversation_id, sender_name, sent_at, COALESCE(content, ''));
+
+DROP INDEX IF EXISTS activity_log_dedup_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS activity_log_dedup_idx
+  ON activity_log (ig_account_id, activity_type, COALESCE(author, ''), timestamp);
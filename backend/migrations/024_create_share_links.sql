@@ -0,0 +1,28 @@
+-- Read-only share links. The link token itself is a signed JWT naming the link id,
+-- so nothing secret is stored here; revoking or expiring the row kills the token.
+CREATE TABLE IF NOT EXISTS share_links (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    ig_account_id INT NOT NULL REFERENCES ig_accounts(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    sections TEXT[] NOT NULL,
+    date_from DATE,
+    date_to DATE,
+    collection_name VARCHAR(255),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_share_links_user_id ON share_links (user_id);
+
+CREATE TABLE IF NOT EXISTS share_link_access_log (
+    id SERIAL PRIMARY KEY,
+    share_link_id INT NOT NULL REFERENCES share_links(id) ON DELETE CASCADE,
+    accessed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    path TEXT NOT NULL,
+    ip_address VARCHAR(64),
+    user_agent TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_share_link_access_log_link ON share_link_access_log (share_link_id, accessed_at DESC);
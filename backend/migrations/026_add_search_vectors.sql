@@ -0,0 +1,31 @@
+-- Full-text search. Each searchable table gets a generated tsvector kept in sync by
+-- Postgres itself, indexed with GIN. The 'simple' configuration does no stemming or
+-- stopword removal, which suits archives that mix languages, usernames and emoji.
+ALTER TABLE media_items ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple', COALESCE(caption, ''))) STORED;
+CREATE INDEX IF NOT EXISTS idx_media_items_search ON media_items USING GIN (search_vector);
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple', COALESCE(content, ''))) STORED;
+CREATE INDEX IF NOT EXISTS idx_messages_search ON messages USING GIN (search_vector);
+
+ALTER TABLE post_comments ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple', COALESCE(comment_text, ''))) STORED;
+CREATE INDEX IF NOT EXISTS idx_post_comments_search ON post_comments USING GIN (search_vector);
+
+ALTER TABLE reel_comments ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple', COALESCE(comment_text, ''))) STORED;
+CREATE INDEX IF NOT EXISTS idx_reel_comments_search ON reel_comments USING GIN (search_vector);
+
+ALTER TABLE search_history ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple', COALESCE(search_query, ''))) STORED;
+CREATE INDEX IF NOT EXISTS idx_search_history_search ON search_history USING GIN (search_vector);
+
+ALTER TABLE saved_media ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple', COALESCE(creator_username, '') || ' ' || post_url)) STORED;
+CREATE INDEX IF NOT EXISTS idx_saved_media_search ON saved_media USING GIN (search_vector);
+
+ALTER TABLE saved_collection_items ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple',
+        collection_name || ' ' || COALESCE(creator_username, '') || ' ' || item_url)) STORED;
+CREATE INDEX IF NOT EXISTS idx_saved_collection_items_search ON saved_collection_items USING GIN (search_vector);
@@ -0,0 +1,16 @@
+-- connections merges every import, so on its own it can't show who disappeared between
+-- two exports. Each import stamps the rows its archive contained with last_seen_at and
+-- then copies them into connection_snapshots, keyed by the import.
+ALTER TABLE connections ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+-- Imports from before this migration have no snapshot.
+CREATE TABLE IF NOT EXISTS connection_snapshots (
+    import_id INT NOT NULL REFERENCES imports(id) ON DELETE CASCADE,
+    ig_account_id INT NOT NULL REFERENCES ig_accounts(id) ON DELETE CASCADE,
+    username TEXT NOT NULL,
+    connection_type TEXT NOT NULL,
+    timestamp TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (import_id, connection_type, username)
+);
+
+CREATE INDEX IF NOT EXISTS idx_connection_snapshots_ig_account_id ON connection_snapshots (ig_account_id, import_id);
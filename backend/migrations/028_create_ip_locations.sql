@@ -0,0 +1,16 @@
+-- Where the offline geolocation database (GEOIP_DB_PATH) places the IP addresses of an
+-- account's logins, logouts and signup. Addresses it doesn't know have no row and are
+-- looked up again later, so a newer database can still place them.
+CREATE TABLE IF NOT EXISTS ip_locations (
+    ig_account_id INT NOT NULL REFERENCES ig_accounts(id) ON DELETE CASCADE,
+    ip_address VARCHAR(100) NOT NULL,
+    country_code VARCHAR(2) NOT NULL DEFAULT '',
+    country TEXT NOT NULL DEFAULT '',
+    city TEXT NOT NULL DEFAULT '',
+    latitude DOUBLE PRECISION,
+    longitude DOUBLE PRECISION,
+    asn BIGINT NOT NULL DEFAULT 0,
+    as_org TEXT NOT NULL DEFAULT '',
+    located_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (ig_account_id, ip_address)
+);
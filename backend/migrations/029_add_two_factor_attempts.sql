@@ -0,0 +1,4 @@
+-- Second-factor attempts since the last success or lockout. Reaching the limit locks
+-- the second step until totp_locked_until, so codes can't be brute-forced.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_failed_attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_locked_until TIMESTAMPTZ;
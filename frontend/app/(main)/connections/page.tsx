@@ -7,6 +7,7 @@ import PageHeader from "@/components/ui/PageHeader";
 import TabNav from "@/components/ui/TabNav";
 import EmptyState from "@/components/ui/EmptyState";
 import { formatDistanceToNow } from "date-fns";
+import { fetchAllPages } from "@/lib/fetchAllPages";
 
 interface Connection {
   id: number;
@@ -37,8 +38,7 @@ export default function ConnectionsPage() {
 
   useEffect(() => {
     if (!token) return;
-    fetch("/api/v1/connections", { headers: { Authorization: `Bearer ${token}` } })
-      .then((r) => r.json())
+    fetchAllPages<Connection>("/api/v1/connections", token)
       .then(setConnections)
       .catch(console.error)
       .finally(() => setLoading(false));
@@ -0,0 +1,26 @@
+/**
+ * Backend list endpoints are paginated: each response carries one page of rows plus a
+ * `next_cursor` (null on the last page). This follows the cursors and concatenates
+ * every page. `pick` selects the rows for endpoints whose envelope isn't `items`.
+ */
+export async function fetchAllPages<T>(
+  url: string,
+  token: string,
+  pick: (page: Record<string, unknown>) => T[] = (page) => page.items as T[],
+  limit = 1000,
+): Promise<T[]> {
+  const rows: T[] = [];
+  let cursor: string | null = null;
+  do {
+    const params = new URLSearchParams({ limit: String(limit) });
+    if (cursor) params.set("cursor", cursor);
+    const res = await fetch(`${url}${url.includes("?") ? "&" : "?"}${params}`, {
+      headers: { Authorization: `Bearer ${token}` },
+    });
+    if (!res.ok) throw new Error(`Request to ${url} failed (${res.status})`);
+    const page: Record<string, unknown> = await res.json();
+    rows.push(...(pick(page) ?? []));
+    cursor = (page.next_cursor as string | null) ?? null;
+  } while (cursor);
+  return rows;
+}
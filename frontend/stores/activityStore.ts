@@ -1,4 +1,5 @@
 import { create } from "zustand";
+import { fetchAllPages } from "@/lib/fetchAllPages";
 
 // Matches the Go model models.ActivityLog
 interface ActivityLog {
@@ -43,12 +44,7 @@ export const useActivityStore = create<ActivityState>((set, get) => ({
 
     set({ loading: true, error: null });
     try {
-      const response = await fetch("/api/v1/activity", {
-        headers: { Authorization: `Bearer ${token}` },
-      });
-      if (!response.ok)
-        throw new Error("Failed to fetch activity from server.");
-      const data: ActivityLog[] = await response.json();
+      const data = await fetchAllPages<ActivityLog>("/api/v1/activity", token);
       set({ activities: data, loading: false });
     } catch (error) {
       const errorMessage =
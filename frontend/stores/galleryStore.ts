@@ -1,4 +1,5 @@
 import { create } from "zustand";
+import { fetchAllPages } from "@/lib/fetchAllPages";
 
 export interface MediaItem {
   id: number;
@@ -38,11 +39,7 @@ export const useGalleryStore = create<GalleryState>((set, get) => ({
     if (get().items.length > 0) return; // already cached
     set({ loading: true, error: null });
     try {
-      const res = await fetch("/api/v1/media", {
-        headers: { Authorization: `Bearer ${token}` },
-      });
-      if (!res.ok) throw new Error("Failed to load media");
-      const data: MediaItem[] = await res.json();
+      const data = await fetchAllPages<MediaItem>("/api/v1/media", token);
       const unique = Array.from(new Map(data.map((i) => [i.id, i])).values());
       set({ items: unique, loading: false });
     } catch (e) {
@@ -1,4 +1,5 @@
 import { create } from "zustand";
+import { fetchAllPages } from "@/lib/fetchAllPages";
 
 export interface Conversation {
   id: number;
@@ -20,6 +21,8 @@ interface MessagesState {
   conversations: Conversation[];
   messages: Message[];
   activeConversation: string | null;
+  // conversations whose full history has been paged in (the first page only holds recent messages)
+  loadedConversations: string[];
   loading: boolean;
   loadingMessages: boolean;
   error: string | null;
@@ -32,6 +35,7 @@ export const useMessagesStore = create<MessagesState>((set, get) => ({
   conversations: [],
   messages: [],
   activeConversation: null,
+  loadedConversations: [],
   loading: false,
   loadingMessages: false,
   error: null,
@@ -39,7 +43,7 @@ export const useMessagesStore = create<MessagesState>((set, get) => ({
   fetchMessages: async (token) => {
     set({ loading: true, error: null });
     try {
-      const res = await fetch("/api/v1/messages", {
+      const res = await fetch("/api/v1/messages?limit=1000", {
         headers: { Authorization: `Bearer ${token}` },
       });
       if (!res.ok) throw new Error("Failed to fetch messages");
@@ -47,7 +51,8 @@ export const useMessagesStore = create<MessagesState>((set, get) => ({
       const convs: Conversation[] = data.conversations ?? [];
       const msgs: Message[] = data.messages ?? [];
       const firstConvId = convs[0]?.conversation_id ?? null;
-      set({ conversations: convs, messages: msgs, activeConversation: firstConvId, loading: false });
+      set({ conversations: convs, messages: msgs, activeConversation: firstConvId, loadedConversations: [], loading: false });
+      if (firstConvId) get().fetchConversationMessages(token, firstConvId);
     } catch (e) {
       set({ error: (e as Error).message, loading: false });
     }
@@ -56,15 +61,17 @@ export const useMessagesStore = create<MessagesState>((set, get) => ({
   fetchConversationMessages: async (token, conversationId) => {
     set({ loadingMessages: true });
     try {
-      const res = await fetch(`/api/v1/messages?conversation_id=${encodeURIComponent(conversationId)}`, {
-        headers: { Authorization: `Bearer ${token}` },
-      });
-      if (!res.ok) return;
-      const data = await res.json();
-      const newMsgs: Message[] = data.messages ?? [];
+      const newMsgs = await fetchAllPages<Message>(
+        `/api/v1/messages/${encodeURIComponent(conversationId)}`,
+        token,
+      );
       set((state) => {
         const existing = state.messages.filter((m) => m.conversation_id !== conversationId);
-        return { messages: [...existing, ...newMsgs], loadingMessages: false };
+        return {
+          messages: [...existing, ...newMsgs],
+          loadedConversations: [...state.loadedConversations, conversationId],
+          loadingMessages: false,
+        };
       });
     } catch {
       set({ loadingMessages: false });
@@ -74,8 +81,7 @@ export const useMessagesStore = create<MessagesState>((set, get) => ({
   setActiveConversation: (token, id) => {
     set({ activeConversation: id });
     if (!id) return;
-    const loaded = get().messages.filter((m) => m.conversation_id === id);
-    if (loaded.length === 0) {
+    if (!get().loadedConversations.includes(id)) {
       get().fetchConversationMessages(token, id);
     }
   },
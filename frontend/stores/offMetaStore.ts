@@ -1,4 +1,5 @@
 import { create } from "zustand";
+import { fetchAllPages } from "@/lib/fetchAllPages";
 
 export interface OffMetaActivity { id: number; user_id: number; app_name: string; event_type: string; event_id: string; event_at: string; }
 
@@ -16,10 +17,8 @@ export const useOffMetaStore = create<OffMetaState>((set) => ({
   fetchOffMeta: async (token) => {
     set({ loading: true, error: null });
     try {
-      const res = await fetch("/api/v1/off-meta-activity", { headers: { Authorization: `Bearer ${token}` } });
-      if (!res.ok) throw new Error("Failed to fetch off-meta activity");
-      const data = await res.json();
-      set({ activities: data ?? [], loading: false });
+      const activities = await fetchAllPages<OffMetaActivity>("/api/v1/off-meta-activity", token);
+      set({ activities, loading: false });
     } catch (e) {
       set({ error: (e as Error).message, loading: false });
     }
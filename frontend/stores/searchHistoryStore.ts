@@ -1,4 +1,5 @@
 import { create } from "zustand";
+import { fetchAllPages } from "@/lib/fetchAllPages";
 
 export interface SearchEntry { id: number; user_id: number; search_query: string; search_type: string; searched_at: string; }
 
@@ -16,10 +17,8 @@ export const useSearchHistoryStore = create<SearchHistoryState>((set) => ({
   fetchSearchHistory: async (token) => {
     set({ loading: true, error: null });
     try {
-      const res = await fetch("/api/v1/search-history", { headers: { Authorization: `Bearer ${token}` } });
-      if (!res.ok) throw new Error("Failed to fetch search history");
-      const data = await res.json();
-      set({ entries: data ?? [], loading: false });
+      const entries = await fetchAllPages<SearchEntry>("/api/v1/search-history", token);
+      set({ entries, loading: false });
     } catch (e) {
       set({ error: (e as Error).message, loading: false });
     }